@@ -0,0 +1,227 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// APICall describes a request a runtime made to its backend, e.g. dockerd, a
+// CRI runtime, or the Kubernetes API server. Runtimes report them through
+// their ObserveAPI methods so that operators can tell when the backend, not
+// the workload, is slow or failing.
+type APICall struct {
+	// Backend names the API, e.g. "docker", "cri", or "kubernetes".
+	Backend string
+
+	// Operation names the request, e.g. "ContainerInspect" or "GET pods".
+	Operation string
+
+	Duration time.Duration
+
+	// Err is the reason the request failed, or nil if it succeeded.
+	Err error
+
+	// Class categorizes Err. It's empty if the request succeeded.
+	Class APIErrorClass
+}
+
+// APIErrorClass is a backend-independent category of API failure.
+type APIErrorClass string
+
+const (
+	// APIErrorNotFound indicates the requested object doesn't exist. It's
+	// often expected, e.g. when checking whether a container was removed.
+	APIErrorNotFound APIErrorClass = "not-found"
+
+	// APIErrorCanceled indicates the caller canceled the request.
+	APIErrorCanceled APIErrorClass = "canceled"
+
+	// APIErrorTimeout indicates the request timed out.
+	APIErrorTimeout APIErrorClass = "timeout"
+
+	// APIErrorUnavailable indicates the backend couldn't be reached or was
+	// overloaded.
+	APIErrorUnavailable APIErrorClass = "unavailable"
+
+	// APIErrorOther covers all other failures.
+	APIErrorOther APIErrorClass = "other"
+)
+
+// ClassifyAPIError categorizes an error by the errors it wraps. Runtimes
+// classify errors specific to their backend before falling back to it.
+func ClassifyAPIError(err error) APIErrorClass {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNotFound):
+		return APIErrorNotFound
+	case errors.Is(err, context.Canceled):
+		return APIErrorCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return APIErrorTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return APIErrorTimeout
+	case errors.As(err, &netErr):
+		return APIErrorUnavailable
+	default:
+		return APIErrorOther
+	}
+}
+
+// APILatencyBuckets are the upper bounds of the latency histogram of each
+// operation in APIStats. Slower requests are counted in a final bucket.
+var APILatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// APIOperation identifies an operation of a backend.
+type APIOperation struct {
+	Backend   string
+	Operation string
+}
+
+// APIOperationStats aggregates the calls of one operation.
+type APIOperationStats struct {
+	Calls int
+
+	// Errors counts failed calls by class.
+	Errors map[APIErrorClass]int
+
+	// Duration is the total time spent in calls, including failed calls.
+	Duration time.Duration
+
+	// Latency counts calls by duration. Each count corresponds to the bucket
+	// of APILatencyBuckets at the same index, plus one for slower calls.
+	Latency []int
+}
+
+// ErrorRate returns the fraction of calls which failed.
+func (s APIOperationStats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	var failures int
+	for _, n := range s.Errors {
+		failures += n
+	}
+	return float64(failures) / float64(s.Calls)
+}
+
+// MeanLatency returns the average duration of a call.
+func (s APIOperationStats) MeanLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Duration / time.Duration(s.Calls)
+}
+
+// LatencyQuantile estimates a quantile of latency, e.g. 0.99, as the upper
+// bound of the bucket which contains it. It returns -1 if the quantile falls
+// in the final, unbounded bucket.
+func (s APIOperationStats) LatencyQuantile(q float64) time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	target := q * float64(s.Calls)
+	var seen int
+	for i, n := range s.Latency {
+		seen += n
+		if seen != 0 && float64(seen) >= target {
+			if i == len(APILatencyBuckets) {
+				break
+			}
+			return APILatencyBuckets[i]
+		}
+	}
+	return -1
+}
+
+// APIStats aggregates API calls by backend and operation. It's safe for
+// concurrent use, so its Observe method may be passed directly to a runtime.
+type APIStats struct {
+	mu         sync.Mutex
+	operations map[APIOperation]*APIOperationStats
+}
+
+// NewAPIStats creates an empty set of API statistics.
+func NewAPIStats() *APIStats {
+	return &APIStats{operations: make(map[APIOperation]*APIOperationStats)}
+}
+
+// Observe records a call.
+func (s *APIStats) Observe(c APICall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := APIOperation{Backend: c.Backend, Operation: c.Operation}
+	stats, ok := s.operations[key]
+	if !ok {
+		stats = &APIOperationStats{
+			Errors:  make(map[APIErrorClass]int),
+			Latency: make([]int, len(APILatencyBuckets)+1),
+		}
+		s.operations[key] = stats
+	}
+	stats.Calls++
+	if c.Err != nil {
+		class := c.Class
+		if class == "" {
+			class = ClassifyAPIError(c.Err)
+		}
+		stats.Errors[class]++
+	}
+	stats.Duration += c.Duration
+	stats.Latency[sort.Search(len(APILatencyBuckets), func(i int) bool {
+		return c.Duration <= APILatencyBuckets[i]
+	})]++
+}
+
+// Operations returns all operations called, sorted by backend and operation.
+func (s *APIStats) Operations() []APIOperation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ops := make([]APIOperation, 0, len(s.operations))
+	for op := range s.operations {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Backend != ops[j].Backend {
+			return ops[i].Backend < ops[j].Backend
+		}
+		return ops[i].Operation < ops[j].Operation
+	})
+	return ops
+}
+
+// Operation returns the statistics for an operation.
+func (s *APIStats) Operation(op APIOperation) APIOperationStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.operations[op]
+	if !ok {
+		return APIOperationStats{}
+	}
+	result := *stats
+	result.Errors = make(map[APIErrorClass]int, len(stats.Errors))
+	for class, n := range stats.Errors {
+		result.Errors[class] = n
+	}
+	result.Latency = append([]int(nil), stats.Latency...)
+	return result
+}
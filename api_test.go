@@ -0,0 +1,53 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyAPIError(t *testing.T) {
+	assert.Equal(t, APIErrorClass(""), ClassifyAPIError(nil))
+	assert.Equal(t, APIErrorNotFound, ClassifyAPIError(fmt.Errorf("container: %w", ErrNotFound)))
+	assert.Equal(t, APIErrorCanceled, ClassifyAPIError(context.Canceled))
+	assert.Equal(t, APIErrorTimeout, ClassifyAPIError(context.DeadlineExceeded))
+	assert.Equal(t, APIErrorUnavailable, ClassifyAPIError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.Equal(t, APIErrorOther, ClassifyAPIError(errors.New("boom")))
+}
+
+func TestAPIStats(t *testing.T) {
+	stats := NewAPIStats()
+	inspect := APIOperation{Backend: "docker", Operation: "ContainerInspect"}
+	stats.Observe(APICall{Backend: "docker", Operation: "ContainerInspect", Duration: 2 * time.Millisecond})
+	stats.Observe(APICall{Backend: "docker", Operation: "ContainerInspect", Duration: 3 * time.Millisecond})
+	stats.Observe(APICall{
+		Backend:   "docker",
+		Operation: "ContainerInspect",
+		Duration:  time.Minute,
+		Err:       errors.New("timed out"),
+		Class:     APIErrorTimeout,
+	})
+	stats.Observe(APICall{Backend: "docker", Operation: "ContainerInspect", Duration: 7 * time.Millisecond, Err: ErrNotFound})
+	stats.Observe(APICall{Backend: "cri", Operation: "StartContainer"})
+
+	assert.Equal(t, []APIOperation{{"cri", "StartContainer"}, inspect}, stats.Operations())
+
+	s := stats.Operation(inspect)
+	assert.Equal(t, 4, s.Calls)
+	assert.Equal(t, map[APIErrorClass]int{APIErrorTimeout: 1, APIErrorNotFound: 1}, s.Errors)
+	assert.Equal(t, []int{0, 2, 1, 0, 0, 0, 0, 0, 0, 0, 1}, s.Latency)
+	assert.Equal(t, 0.5, s.ErrorRate())
+	assert.Equal(t, (time.Minute+12*time.Millisecond)/4, s.MeanLatency())
+	assert.Equal(t, 5*time.Millisecond, s.LatencyQuantile(0.5))
+	assert.Equal(t, 10*time.Millisecond, s.LatencyQuantile(0.75))
+	assert.Equal(t, time.Duration(-1), s.LatencyQuantile(0.99))
+
+	assert.Equal(t, APIOperationStats{}, stats.Operation(APIOperation{Backend: "kubernetes", Operation: "get pods"}))
+	assert.Zero(t, APIOperationStats{}.ErrorRate())
+	assert.Zero(t, APIOperationStats{}.LatencyQuantile(0.5))
+}
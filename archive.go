@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/beaker/runtime/logging"
+)
+
+// ArchiveLogs copies all of a container's logs to a file in dir, encoded with
+// logging.Encoder, and returns the file's path. The file is named after the
+// container and replaces any earlier archive of the same container. Options
+// such as StripTerminal can be used to shrink the archive.
+//
+// Logs read before an error are kept in the archive to aid debugging.
+func ArchiveLogs(ctx context.Context, c Container, dir string, opts logging.ReaderOpts) (string, error) {
+	logs, err := c.Logs(ctx, LogsOpts{ReaderOpts: opts})
+	if err != nil {
+		return "", fmt.Errorf("archiving logs: %w", err)
+	}
+	defer logs.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("archiving logs: %w", err)
+	}
+
+	path := filepath.Join(dir, c.Name()+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("archiving logs: %w", err)
+	}
+
+	if err := copyLogs(logging.NewEncoder(f), logs); err != nil {
+		f.Close()
+		return "", fmt.Errorf("archiving logs: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("archiving logs: %w", err)
+	}
+	return path, nil
+}
+
+func copyLogs(enc *logging.Encoder, logs logging.LogReader) error {
+	for {
+		msg, err := logs.ReadMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(*msg); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime/logging"
+)
+
+// loggingContainer is a fakeContainer which emits a fixed set of logs.
+type loggingContainer struct {
+	fakeContainer
+	logs []logging.Message
+}
+
+func (c *loggingContainer) Logs(ctx context.Context, opts LogsOpts) (logging.LogReader, error) {
+	return &sliceReader{messages: c.logs}, nil
+}
+
+type sliceReader struct{ messages []logging.Message }
+
+func (r *sliceReader) Close() error { return nil }
+
+func (r *sliceReader) ReadMessage() (*logging.Message, error) {
+	if len(r.messages) == 0 {
+		return nil, io.EOF
+	}
+	msg := r.messages[0]
+	r.messages = r.messages[1:]
+	return &msg, nil
+}
+
+func TestArchiveLogs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	c := &loggingContainer{logs: []logging.Message{
+		{Stream: logging.Stdout, Time: time.Unix(1, 0).UTC(), Text: "out\n"},
+		{Stream: logging.Stderr, Time: time.Unix(2, 0).UTC(), Text: "err\n"},
+	}}
+
+	path, err := ArchiveLogs(context.Background(), c, dir, logging.ReaderOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "fake.log"), path)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var archived []logging.Message
+	dec := logging.NewDecoder(f)
+	for {
+		var msg logging.Message
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		archived = append(archived, msg)
+	}
+	assert.Equal(t, c.logs, archived)
+}
@@ -0,0 +1,301 @@
+// Package artifact pulls OCI artifacts, such as datasets and models packaged
+// with ORAS, from container registries so that they can be mounted into
+// containers.
+package artifact
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/beaker/runtime"
+)
+
+// Media types and annotations used by OCI artifacts.
+const (
+	mediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+
+	// Each of an artifact's layers is a file named by its title.
+	annotationTitle = "org.opencontainers.image.title"
+
+	// ORAS packages directories as gzipped tarballs marked for unpacking.
+	annotationUnpack = "io.deis.oras.content.unpack"
+)
+
+// maxManifestSize bounds the manifests read from registries.
+const maxManifestSize = 4 << 20
+
+// Opts configures how artifacts are pulled.
+type Opts struct {
+	// (optional) Auth contains credentials for private registry access.
+	Auth *runtime.RegistryAuth
+
+	// (optional) CacheDir stores downloaded blobs by digest so that content
+	// shared between pulls is only downloaded once. If empty, blobs are not
+	// cached.
+	CacheDir string
+
+	// (optional) Client makes requests to the registry. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+type manifest struct {
+	Layers []descriptor `json:"layers"`
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Pull downloads an artifact's files into dir, which is created if missing and
+// may then be used as a mount's host path. The reference names the artifact
+// and its registry, e.g. "ghcr.io/org/dataset:v1".
+//
+// Each blob is verified against its digest. The manifest is also verified if
+// the reference is a digest. Pull returns the digest of the manifest, which can
+// be used to pull the same content again.
+func Pull(ctx context.Context, ref, dir string, opts *Opts) (string, error) {
+	if opts == nil {
+		opts = &Opts{}
+	}
+	parsed, err := parseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	client := &registryClient{client: opts.Client, ref: parsed, auth: opts.Auth}
+	if client.client == nil {
+		client.client = http.DefaultClient
+	}
+
+	m, digest, err := fetchManifest(ctx, client)
+	if err != nil {
+		return "", fmt.Errorf("pulling %s: %w", ref, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	for _, layer := range m.Layers {
+		if err := pullLayer(ctx, client, layer, dir, opts.CacheDir); err != nil {
+			return "", fmt.Errorf("pulling %s: %w", ref, err)
+		}
+	}
+	return digest, nil
+}
+
+func fetchManifest(ctx context.Context, client *registryClient) (*manifest, string, error) {
+	resp, err := client.get(ctx, "manifests/"+client.ref.reference, mediaTypeManifest)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, "", fmt.Errorf("reading manifest: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(client.ref.reference, "sha256:") && client.ref.reference != digest {
+		return nil, "", fmt.Errorf("manifest digest mismatch: expected %s, got %s", client.ref.reference, digest)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, "", fmt.Errorf("decoding manifest: %w", err)
+	}
+	return &m, digest, nil
+}
+
+// pullLayer writes a layer into dir as a file, or a directory if the layer is
+// marked for unpacking.
+func pullLayer(ctx context.Context, client *registryClient, layer descriptor, dir, cacheDir string) error {
+	hexDigest, err := parseDigest(layer.Digest)
+	if err != nil {
+		return err
+	}
+	name := layer.Annotations[annotationTitle]
+	if name == "" {
+		name = hexDigest
+	}
+	dest, err := securePath(dir, name)
+	if err != nil {
+		return err
+	}
+
+	blob, cleanup, err := fetchBlob(ctx, client, layer.Digest, hexDigest, cacheDir)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	f, err := os.Open(blob)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if layer.Annotations[annotationUnpack] == "true" {
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+		return unpack(f, dest)
+	}
+	return writeFile(dest, f)
+}
+
+// fetchBlob downloads and verifies a blob, returning the path of a local copy
+// and a function to clean it up. Cached blobs are used without downloading.
+func fetchBlob(
+	ctx context.Context,
+	client *registryClient,
+	digest, hexDigest, cacheDir string,
+) (string, func(), error) {
+	noop := func() {}
+	var cached string
+	if cacheDir != "" {
+		cached = filepath.Join(cacheDir, "blobs", "sha256", hexDigest)
+		if _, err := os.Stat(cached); err == nil {
+			return cached, noop, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+			return "", noop, err
+		}
+	}
+
+	resp, err := client.get(ctx, "blobs/"+digest)
+	if err != nil {
+		return "", noop, err
+	}
+	defer resp.Body.Close()
+
+	// Download to a temporary file so that unverified content is never cached.
+	tmpDir := cacheDir
+	if tmpDir != "" {
+		tmpDir = filepath.Dir(cached)
+	}
+	tmp, err := ioutil.TempFile(tmpDir, "blob-")
+	if err != nil {
+		return "", noop, err
+	}
+	remove := func() { os.Remove(tmp.Name()) }
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		tmp.Close()
+		remove()
+		return "", noop, fmt.Errorf("downloading %s: %w", digest, err)
+	}
+	if err := tmp.Close(); err != nil {
+		remove()
+		return "", noop, err
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != hexDigest {
+		remove()
+		return "", noop, fmt.Errorf("blob digest mismatch: expected %s, got sha256:%s", digest, actual)
+	}
+
+	if cached == "" {
+		return tmp.Name(), remove, nil
+	}
+	if err := os.Rename(tmp.Name(), cached); err != nil {
+		remove()
+		return "", noop, err
+	}
+	return cached, noop, nil
+}
+
+// parseDigest returns the hex-encoded hash of a SHA-256 digest.
+func parseDigest(digest string) (string, error) {
+	hexDigest := strings.TrimPrefix(digest, "sha256:")
+	if hexDigest == digest || len(hexDigest) != sha256.Size*2 {
+		return "", fmt.Errorf("unsupported digest %q", digest)
+	}
+	if _, err := hex.DecodeString(hexDigest); err != nil {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return hexDigest, nil
+}
+
+// securePath joins a relative path from untrusted content to dir, rejecting
+// paths which would escape it.
+func securePath(dir, name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == "." || clean == ".." ||
+		strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path %q", name)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// unpack extracts a gzipped tarball into dir. Only regular files and
+// directories are extracted; links and special files are skipped.
+func unpack(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("unpacking: %w", err)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unpacking: %w", err)
+		}
+
+		if filepath.Clean(header.Name) == "." {
+			continue
+		}
+		path, err := securePath(dir, header.Name)
+		if err != nil {
+			return fmt.Errorf("unpacking: %w", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg:
+			err = writeFile(path, tr)
+		}
+		if err != nil {
+			return fmt.Errorf("unpacking: %w", err)
+		}
+	}
+}
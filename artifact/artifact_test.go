@@ -0,0 +1,195 @@
+package artifact
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime"
+)
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0755}))
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// registry serves an artifact's manifest and blobs behind token authentication.
+type registry struct {
+	t        *testing.T
+	manifest []byte
+	blobs    map[string][]byte
+
+	mu        sync.Mutex
+	blobPulls int
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.URL.Path == "/token":
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(r.t, "repository:org/dataset:pull", req.URL.Query().Get("scope"))
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+
+	case req.Header.Get("Authorization") != "Bearer secret":
+		w.Header().Set("WWW-Authenticate",
+			`Bearer realm="http://`+req.Host+`/token",service="test",scope="repository:org/dataset:pull"`)
+		w.WriteHeader(http.StatusUnauthorized)
+
+	case strings.HasPrefix(req.URL.Path, "/v2/org/dataset/manifests/"):
+		w.Header().Set("Content-Type", mediaTypeManifest)
+		_, _ = w.Write(r.manifest)
+
+	case strings.HasPrefix(req.URL.Path, "/v2/org/dataset/blobs/"):
+		blob, ok := r.blobs[strings.TrimPrefix(req.URL.Path, "/v2/org/dataset/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r.mu.Lock()
+		r.blobPulls++
+		r.mu.Unlock()
+		_, _ = w.Write(blob)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPull(t *testing.T) {
+	ctx := context.Background()
+
+	readme := []byte("hello\n")
+	data := tarball(t, map[string]string{"train/a.txt": "a", "test/b.txt": "b"})
+	m := manifest{Layers: []descriptor{
+		{
+			Digest:      digestOf(readme),
+			Size:        int64(len(readme)),
+			Annotations: map[string]string{annotationTitle: "README.md"},
+		},
+		{
+			Digest:      digestOf(data),
+			Size:        int64(len(data)),
+			Annotations: map[string]string{annotationTitle: "data", annotationUnpack: "true"},
+		},
+	}}
+	manifestBytes, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	reg := &registry{t: t, manifest: manifestBytes, blobs: map[string][]byte{
+		digestOf(readme): readme,
+		digestOf(data):   data,
+	}}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tmp, err := ioutil.TempDir("", "artifact")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmp)
+
+	opts := &Opts{
+		Auth:     &runtime.RegistryAuth{Username: "user", Password: "pass"},
+		CacheDir: filepath.Join(tmp, "cache"),
+	}
+
+	dest := filepath.Join(tmp, "dest")
+	digest, err := Pull(ctx, host+"/org/dataset:v1", dest, opts)
+	require.NoError(t, err)
+	assert.Equal(t, digestOf(manifestBytes), digest)
+
+	content, err := ioutil.ReadFile(filepath.Join(dest, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, readme, content)
+	content, err = ioutil.ReadFile(filepath.Join(dest, "data", "train", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(content))
+	assert.Equal(t, 2, reg.blobPulls)
+
+	t.Run("Cached", func(t *testing.T) {
+		_, err := Pull(ctx, host+"/org/dataset@"+digest, filepath.Join(tmp, "dest2"), opts)
+		require.NoError(t, err)
+		assert.Equal(t, 2, reg.blobPulls)
+	})
+
+	t.Run("ManifestMismatch", func(t *testing.T) {
+		_, err := Pull(ctx, host+"/org/dataset@"+digestOf(nil), filepath.Join(tmp, "dest3"), opts)
+		assert.Error(t, err)
+	})
+
+	t.Run("BlobMismatch", func(t *testing.T) {
+		reg.blobs[digestOf(readme)] = []byte("tampered")
+		_, err := Pull(ctx, host+"/org/dataset:v1", filepath.Join(tmp, "dest4"), &Opts{Auth: opts.Auth})
+		assert.Error(t, err)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		_, err := Pull(ctx, host+"/org/dataset:v1", filepath.Join(tmp, "dest5"), nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseReference(t *testing.T) {
+	ref, err := parseReference("ghcr.io/org/dataset")
+	require.NoError(t, err)
+	assert.Equal(t, reference{"ghcr.io", "org/dataset", "latest"}, ref)
+
+	ref, err = parseReference("localhost:5000/dataset:v1")
+	require.NoError(t, err)
+	assert.Equal(t, reference{"localhost:5000", "dataset", "v1"}, ref)
+
+	ref, err = parseReference("ghcr.io/dataset@sha256:abc")
+	require.NoError(t, err)
+	assert.Equal(t, reference{"ghcr.io", "dataset", "sha256:abc"}, ref)
+
+	_, err = parseReference("dataset")
+	assert.Error(t, err)
+}
+
+func TestSecurePath(t *testing.T) {
+	for _, name := range []string{"../etc/passwd", "/etc/passwd", "a/../../b", "."} {
+		_, err := securePath("/dest", name)
+		assert.Error(t, err, name)
+	}
+	path, err := securePath("/dest", "a/./b")
+	require.NoError(t, err)
+	assert.Equal(t, "/dest/a/b", path)
+}
@@ -0,0 +1,208 @@
+package artifact
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/beaker/runtime"
+)
+
+// reference identifies an artifact in a registry.
+type reference struct {
+	registry   string
+	repository string
+
+	// A tag or digest.
+	reference string
+}
+
+// parseReference parses a reference such as "ghcr.io/org/dataset:v1" or
+// "ghcr.io/org/dataset@sha256:...". Unlike image tags, artifact references
+// must name their registry and default to the "latest" tag.
+func parseReference(ref string) (reference, error) {
+	i := strings.IndexByte(ref, '/')
+	if i == -1 {
+		return reference{}, fmt.Errorf("artifact reference %q must include a registry", ref)
+	}
+	r := reference{registry: ref[:i], repository: ref[i+1:], reference: "latest"}
+
+	if j := strings.IndexByte(r.repository, '@'); j != -1 {
+		r.repository, r.reference = r.repository[:j], r.repository[j+1:]
+	} else if j := strings.LastIndexByte(r.repository, ':'); j != -1 {
+		r.repository, r.reference = r.repository[:j], r.repository[j+1:]
+	}
+	if r.repository == "" || r.reference == "" {
+		return reference{}, fmt.Errorf("invalid artifact reference %q", ref)
+	}
+	return r, nil
+}
+
+// registryClient makes authenticated requests to an OCI distribution API.
+type registryClient struct {
+	client *http.Client
+	ref    reference
+	auth   *runtime.RegistryAuth
+
+	// The authorization header for requests, set after the first challenge.
+	authorization string
+}
+
+// url returns the API URL of a path within the repository. Registries on
+// localhost are assumed to be plain HTTP, as with Docker.
+func (c *registryClient) url(path string) string {
+	scheme := "https"
+	host := c.ref.registry
+	if h := strings.Split(host, ":")[0]; h == "localhost" || h == "127.0.0.1" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, host, c.ref.repository, path)
+}
+
+// get requests a path within the repository, authenticating if challenged. The
+// caller must close the response's body.
+func (c *registryClient) get(ctx context.Context, path string, accept ...string) (*http.Response, error) {
+	resp, err := c.do(ctx, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && c.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, path, accept); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *registryClient) do(ctx context.Context, path string, accept []string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, mediaType := range accept {
+		req.Header.Add("Accept", mediaType)
+	}
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+	return c.client.Do(req)
+}
+
+// authenticate responds to a registry's challenge, as described by the Docker
+// token authentication specification.
+// https://docs.docker.com/registry/spec/auth/token/
+func (c *registryClient) authenticate(ctx context.Context, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.auth == nil {
+			return errors.New("registry requires credentials")
+		}
+		credentials := c.auth.Username + ":" + c.auth.Password
+		c.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+		return nil
+
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return fmt.Errorf("invalid authentication realm %q", params["realm"])
+		}
+		query := realm.Query()
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		scope := params["scope"]
+		if scope == "" {
+			scope = "repository:" + c.ref.repository + ":pull"
+		}
+		query.Set("scope", scope)
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return err
+		}
+		if c.auth != nil {
+			req.SetBasicAuth(c.auth.Username, c.auth.Password)
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("requesting registry token: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("requesting registry token: %s", resp.Status)
+		}
+
+		var body struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+			return fmt.Errorf("decoding registry token: %w", err)
+		}
+		token := body.Token
+		if token == "" {
+			token = body.AccessToken
+		}
+		c.authorization = "Bearer " + token
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported authentication scheme %q", scheme)
+	}
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// `Bearer realm="https://auth.example.com/token",service="registry"`.
+func parseChallenge(header string) (scheme string, params map[string]string) {
+	params = make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	scheme = parts[0]
+	if len(parts) == 1 {
+		return scheme, params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq == -1 {
+			break
+		}
+		key := strings.TrimSpace(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end == -1 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.IndexByte(rest, ','); comma != -1 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[strings.ToLower(key)] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return scheme, params
+}
@@ -0,0 +1,217 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Bandwidth is a network rate in bits per second. It may be written in
+// configuration as a number or a string such as "100M" or "1Gbit". See
+// ParseBandwidth for the accepted forms.
+type Bandwidth int64
+
+// Bandwidth units, which are decimal as is conventional for network rates.
+const (
+	Kbit Bandwidth = 1000
+	Mbit           = 1000 * Kbit
+	Gbit           = 1000 * Mbit
+	Tbit           = 1000 * Gbit
+)
+
+var bandwidthUnits = []struct {
+	suffix string
+	size   Bandwidth
+}{
+	{"T", Tbit},
+	{"G", Gbit},
+	{"M", Mbit},
+	{"k", Kbit},
+}
+
+// MinBandwidth is the smallest bandwidth limit runtimes accept.
+const MinBandwidth = Kbit
+
+// ParseBandwidth parses a rate in bits per second such as "100M", "1.5Gbit",
+// "500kbps", or "1000000". Units are decimal and case-insensitive, and may be
+// followed by "bit" or "bps".
+func ParseBandwidth(s string) (Bandwidth, error) {
+	s = strings.TrimSpace(s)
+	num := strings.ToLower(s)
+	for _, suffix := range []string{"bit", "bps"} {
+		if strings.HasSuffix(num, suffix) {
+			num = strings.TrimSuffix(num, suffix)
+			break
+		}
+	}
+
+	size := Bandwidth(1)
+	for _, u := range bandwidthUnits {
+		if strings.HasSuffix(num, strings.ToLower(u.suffix)) {
+			num, size = strings.TrimSuffix(num, strings.ToLower(u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("%q is not a valid bandwidth", s)
+	}
+	return Bandwidth(n * float64(size)), nil
+}
+
+// String formats the rate exactly, in the largest unit which divides it, e.g.
+// "100M" or "1500k". The form is also a valid Kubernetes quantity.
+func (b Bandwidth) String() string {
+	for _, u := range bandwidthUnits {
+		if b != 0 && b%u.size == 0 {
+			return strconv.FormatInt(int64(b/u.size), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
+}
+
+// MarshalText encodes the rate as its string form.
+func (b Bandwidth) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText decodes a rate with ParseBandwidth.
+func (b *Bandwidth) UnmarshalText(text []byte) error {
+	rate, err := ParseBandwidth(string(text))
+	if err != nil {
+		return err
+	}
+	*b = rate
+	return nil
+}
+
+// UnmarshalJSON decodes a rate from either a number of bits per second or a
+// string.
+func (b *Bandwidth) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = Bandwidth(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("bandwidth must be a number or string: %w", err)
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// IngressBandwidthLabel and EgressBandwidthLabel are set on containers created
+// with bandwidth limits on runtimes which apply them themselves. See
+// ContainerOpts.IngressBandwidth.
+const (
+	IngressBandwidthLabel = "beaker.org/ingress-bandwidth"
+	EgressBandwidthLabel  = "beaker.org/egress-bandwidth"
+)
+
+// ValidateBandwidth checks that bandwidth limits are well formed and can be
+// applied.
+func (o *ContainerOpts) ValidateBandwidth() error {
+	for _, limit := range []struct {
+		name string
+		rate Bandwidth
+	}{
+		{"ingress", o.IngressBandwidth},
+		{"egress", o.EgressBandwidth},
+	} {
+		if limit.rate != 0 && limit.rate < MinBandwidth {
+			return fmt.Errorf("%s bandwidth %s is below the minimum of %s", limit.name, limit.rate, MinBandwidth)
+		}
+	}
+	if o.NetworkFrom != "" && (o.IngressBandwidth != 0 || o.EgressBandwidth != 0) {
+		// The namespace's owner sets its limits.
+		return errors.New("bandwidth limits can't be set on a container which joins another's network namespace")
+	}
+	return nil
+}
+
+// LimitBandwidth shapes the traffic of every interface but loopback in a
+// process's network namespace with tc, so that the process's container can't
+// saturate the node's network. Egress is rate-limited with a token bucket and
+// ingress is policed, dropping excess packets. A zero rate leaves its direction
+// unlimited. Requires nsenter and tc on the host, and root privileges.
+func LimitBandwidth(ctx context.Context, pid int, ingress, egress Bandwidth) error {
+	ifaces, err := namespaceInterfaces(pid)
+	if err != nil {
+		return fmt.Errorf("limiting bandwidth: %w", err)
+	}
+	for _, iface := range ifaces {
+		for _, args := range bandwidthCommands(iface, ingress, egress) {
+			args = append([]string{"-t", strconv.Itoa(pid), "-n", "tc"}, args...)
+			if err := runCommand(ctx, nil, "", "nsenter", args...); err != nil {
+				return fmt.Errorf("limiting bandwidth of %s: %w", iface, err)
+			}
+		}
+	}
+	return nil
+}
+
+// namespaceInterfaces lists the interfaces in a process's network namespace,
+// excluding loopback.
+func namespaceInterfaces(pid int) ([]string, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/net/dev", pid))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// The first two lines are headers; each other begins "<name>:".
+	var ifaces []string
+	scanner := bufio.NewScanner(f)
+	for line := 0; scanner.Scan(); line++ {
+		if line < 2 {
+			continue
+		}
+		fields := strings.SplitN(scanner.Text(), ":", 2)
+		if name := strings.TrimSpace(fields[0]); len(fields) == 2 && name != "lo" {
+			ifaces = append(ifaces, name)
+		}
+	}
+	return ifaces, scanner.Err()
+}
+
+// bandwidthCommands returns the tc arguments which limit an interface.
+func bandwidthCommands(iface string, ingress, egress Bandwidth) [][]string {
+	var commands [][]string
+	if egress != 0 {
+		commands = append(commands, []string{
+			"qdisc", "add", "dev", iface, "root", "tbf",
+			"rate", tcRate(egress), "burst", tcBurst(egress), "latency", "25ms",
+		})
+	}
+	if ingress != 0 {
+		commands = append(commands,
+			[]string{"qdisc", "add", "dev", iface, "handle", "ffff:", "ingress"},
+			[]string{
+				"filter", "add", "dev", iface, "parent", "ffff:", "protocol", "all",
+				"u32", "match", "u32", "0", "0",
+				"police", "rate", tcRate(ingress), "burst", tcBurst(ingress), "drop",
+			},
+		)
+	}
+	return commands
+}
+
+func tcRate(b Bandwidth) string {
+	return strconv.FormatInt(int64(b), 10) + "bit"
+}
+
+// tcBurst sizes a token bucket to hold 100ms of traffic, and at least enough
+// for a few full-size packets.
+func tcBurst(b Bandwidth) string {
+	burst := int64(b) / 8 / 10
+	if burst < 16*1024 {
+		burst = 16 * 1024
+	}
+	return strconv.FormatInt(burst, 10) + "b"
+}
@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	tests := map[string]Bandwidth{
+		"1000000":  1e6,
+		"100M":     100 * Mbit,
+		"100m":     100 * Mbit,
+		"100Mbit":  100 * Mbit,
+		"100mbps":  100 * Mbit,
+		"1.5Gbit":  1500 * Mbit,
+		"500k":     500 * Kbit,
+		" 10 G ":   10 * Gbit,
+		"2T":       2 * Tbit,
+		"64000bit": 64 * Kbit,
+	}
+	for s, expected := range tests {
+		rate, err := ParseBandwidth(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, rate, s)
+		}
+	}
+
+	for _, invalid := range []string{"", "M", "-1M", "10X", "fast", "inf", "NaN"} {
+		_, err := ParseBandwidth(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBandwidthText(t *testing.T) {
+	for rate, expected := range map[Bandwidth]string{
+		0:             "0",
+		999:           "999",
+		100 * Mbit:    "100M",
+		1500 * Mbit:   "1500M",
+		1500*Kbit + 1: "1500001",
+	} {
+		text, err := rate.MarshalText()
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(text))
+
+		var decoded Bandwidth
+		require.NoError(t, decoded.UnmarshalText(text))
+		assert.Equal(t, rate, decoded)
+	}
+
+	var opts ContainerOpts
+	require.NoError(t, json.Unmarshal([]byte(`{"IngressBandwidth": "1G", "EgressBandwidth": 5000}`), &opts))
+	assert.Equal(t, Gbit, opts.IngressBandwidth)
+	assert.Equal(t, Bandwidth(5000), opts.EgressBandwidth)
+}
+
+func TestValidateBandwidth(t *testing.T) {
+	assert.NoError(t, (&ContainerOpts{}).ValidateBandwidth())
+	assert.NoError(t, (&ContainerOpts{IngressBandwidth: Gbit, EgressBandwidth: Kbit}).ValidateBandwidth())
+	assert.Error(t, (&ContainerOpts{EgressBandwidth: 999}).ValidateBandwidth())
+	assert.Error(t, (&ContainerOpts{IngressBandwidth: -Mbit}).ValidateBandwidth())
+	assert.Error(t, (&ContainerOpts{IngressBandwidth: Mbit, NetworkFrom: "a"}).ValidateBandwidth())
+}
+
+func TestBandwidthCommands(t *testing.T) {
+	assert.Empty(t, bandwidthCommands("eth0", 0, 0))
+	assert.Equal(t, [][]string{
+		{"qdisc", "add", "dev", "eth0", "root", "tbf", "rate", "1000000000bit", "burst", "12500000b", "latency", "25ms"},
+	}, bandwidthCommands("eth0", 0, Gbit))
+	assert.Equal(t, [][]string{
+		{"qdisc", "add", "dev", "eth0", "handle", "ffff:", "ingress"},
+		{
+			"filter", "add", "dev", "eth0", "parent", "ffff:", "protocol", "all", "u32", "match", "u32", "0", "0",
+			"police", "rate", "1000000bit", "burst", "16384b", "drop",
+		},
+	}, bandwidthCommands("eth0", Mbit, 0))
+}
+
+func TestNamespaceInterfaces(t *testing.T) {
+	if _, err := os.Stat("/proc/self/net/dev"); err != nil {
+		t.Skip("procfs is not available")
+	}
+	ifaces, err := namespaceInterfaces(os.Getpid())
+	require.NoError(t, err)
+	assert.NotContains(t, ifaces, "lo")
+}
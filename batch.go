@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StopAll stops containers concurrently as with Container.Stop. If any fail,
+// a *BatchError is returned.
+func StopAll(ctx context.Context, containers []Container, timeout *time.Duration) error {
+	return batch(containerNames(containers), func(i int) error {
+		return containers[i].Stop(ctx, timeout)
+	})
+}
+
+// RemoveAll removes containers concurrently as with Container.Remove. If any
+// fail, a *BatchError is returned.
+func RemoveAll(ctx context.Context, containers []Container, opts RemoveOpts) error {
+	return batch(containerNames(containers), func(i int) error {
+		return containers[i].Remove(ctx, opts)
+	})
+}
+
+// PullImages pulls images concurrently as with Runtime.PullImage. Images are
+// identified by tag. If any fail, a *BatchError is returned.
+func PullImages(ctx context.Context, rt Runtime, images []*DockerImage, policy PullPolicy, quiet bool) error {
+	tags := make([]string, len(images))
+	for i, image := range images {
+		tags[i] = image.Tag
+	}
+	return batch(tags, func(i int) error {
+		return rt.PullImage(ctx, images[i], policy, quiet)
+	})
+}
+
+func containerNames(containers []Container) []string {
+	names := make([]string, len(containers))
+	for i, c := range containers {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// batchConcurrency bounds the number of items of a batch processed at once,
+// so that large batches don't flood the runtime's API.
+const batchConcurrency = 8
+
+// batch runs fn concurrently for each named item and collects the results.
+func batch(names []string, fn func(i int) error) error {
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	result := &BatchError{}
+	for i, err := range errs {
+		if err != nil {
+			result.Failed = append(result.Failed, BatchFailure{Name: names[i], Err: err})
+		} else {
+			result.Succeeded = append(result.Succeeded, names[i])
+		}
+	}
+	if len(result.Failed) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedContainer is a fakeContainer with a name which fails to stop if err is set.
+type namedContainer struct {
+	fakeContainer
+	name string
+	err  error
+}
+
+func (c *namedContainer) Name() string { return c.name }
+
+func (c *namedContainer) Stop(ctx context.Context, timeout *time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.fakeContainer.Stop(ctx, timeout)
+}
+
+func TestStopAll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		containers := []Container{&namedContainer{name: "a"}, &namedContainer{name: "b"}}
+		assert.NoError(t, StopAll(ctx, containers, nil))
+	})
+
+	t.Run("PartialFailure", func(t *testing.T) {
+		containers := []Container{
+			&namedContainer{name: "a"},
+			&namedContainer{name: "b", err: ErrNotFound},
+			&namedContainer{name: "c"},
+			&namedContainer{name: "d", err: errors.New("oh no")},
+		}
+		err := StopAll(ctx, containers, nil)
+
+		var batchErr *BatchError
+		require.True(t, errors.As(err, &batchErr))
+		assert.Equal(t, []string{"a", "c"}, batchErr.Succeeded)
+		assert.Equal(t, []BatchFailure{{"b", ErrNotFound}, {"d", errors.New("oh no")}}, batchErr.Failed)
+		assert.EqualError(t, err, "2 of 4 failed: b: container not found; d: oh no")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("DuplicateNames", func(t *testing.T) {
+		containers := []Container{
+			&namedContainer{name: "a", err: ErrNotFound},
+			&namedContainer{name: "a", err: ErrClosed},
+		}
+		err := StopAll(ctx, containers, nil)
+		assert.EqualError(t, err, "2 of 2 failed: a: container not found; a: runtime is closed")
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		var mu sync.Mutex
+		var running, peak int
+		names := make([]string, 4*batchConcurrency)
+		assert.NoError(t, batch(names, func(i int) error {
+			mu.Lock()
+			if running++; running > peak {
+				peak = running
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		}))
+		assert.LessOrEqual(t, peak, batchConcurrency)
+	})
+}
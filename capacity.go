@@ -0,0 +1,117 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrCapacity indicates a node already has as many managed containers as it's
+// allowed. Errors matching it are of type *CapacityError.
+var ErrCapacity = errors.New("container limit reached")
+
+// CapacityError reports that a container wasn't created because the node is
+// at its container limit.
+type CapacityError struct {
+	Limit int
+	Count int
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("%v: node has %d of %d managed containers", ErrCapacity, e.Count, e.Limit)
+}
+
+// Is makes CapacityError match ErrCapacity.
+func (e *CapacityError) Is(target error) bool {
+	return target == ErrCapacity
+}
+
+// CappedRuntime is a view of a runtime which limits the number of managed
+// containers on its node, protecting the node and its container daemon from
+// schedulers which create containers faster than they remove them. Every
+// managed container counts, whether running or not, including those created
+// through other views or processes. The runtime must be able to list its
+// containers, which CRI can't.
+//
+// The view doesn't own the underlying runtime; closing it has no effect.
+type CappedRuntime struct {
+	Runtime
+	limit int
+
+	// Serializes creation so that concurrent creates can't exceed the limit
+	// together.
+	mu sync.Mutex
+}
+
+// WithContainerLimit caps the number of managed containers a runtime may
+// create.
+func WithContainerLimit(rt Runtime, limit int) (*CappedRuntime, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid container limit %d", limit)
+	}
+	return &CappedRuntime{Runtime: rt, limit: limit}, nil
+}
+
+// Limit returns the maximum number of managed containers.
+func (r *CappedRuntime) Limit() int {
+	return r.limit
+}
+
+// Close implements the io.Closer interface. The underlying runtime is shared,
+// so it's left open.
+func (r *CappedRuntime) Close() error {
+	return nil
+}
+
+// CreateContainer creates a container unless the node is at its limit, in
+// which case it returns a *CapacityError. Retries of an idempotent creation
+// are refused at the limit too, since the existing container can't be found
+// without creating one.
+func (r *CappedRuntime) CreateContainer(ctx context.Context, opts *ContainerOpts) (Container, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count, err := r.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if count >= r.limit {
+		return nil, &CapacityError{Limit: r.limit, Count: count}
+	}
+	return r.Runtime.CreateContainer(ctx, opts)
+}
+
+// Count returns the number of managed containers on the node.
+func (r *CappedRuntime) Count(ctx context.Context) (int, error) {
+	containers, err := r.Runtime.ListContainers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("counting containers: %w", err)
+	}
+	return len(containers), nil
+}
+
+// WriteMetrics writes the number of managed containers and the limit as gauges
+// in the Prometheus text exposition format.
+func (r *CappedRuntime) WriteMetrics(ctx context.Context, w io.Writer) error {
+	count, err := r.Count(ctx)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, metric := range []struct {
+		name, help string
+		value      int
+	}{
+		{"node_managed_containers", "Managed containers on the node.", count},
+		{"node_managed_containers_limit", "Maximum managed containers on the node.", r.limit},
+	} {
+		fmt.Fprintf(bw, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", metric.name)
+		fmt.Fprintf(bw, "%s %d\n", metric.name, metric.value)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,54 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCappedRuntime(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := WithContainerLimit(&memoryRuntime{}, 0)
+	assert.Error(t, err)
+
+	shared := &memoryRuntime{}
+	capped, err := WithContainerLimit(shared, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, capped.Limit())
+
+	// Containers created elsewhere count against the limit.
+	_, err = shared.CreateContainer(ctx, &ContainerOpts{Name: "a"})
+	require.NoError(t, err)
+	_, err = capped.CreateContainer(ctx, &ContainerOpts{Name: "b"})
+	require.NoError(t, err)
+
+	_, err = capped.CreateContainer(ctx, &ContainerOpts{Name: "c"})
+	assert.ErrorIs(t, err, ErrCapacity)
+	var capacityErr *CapacityError
+	require.True(t, errors.As(err, &capacityErr))
+	assert.Equal(t, &CapacityError{Limit: 2, Count: 2}, capacityErr)
+	assert.Len(t, shared.containers, 2)
+
+	count, err := capped.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	var b strings.Builder
+	require.NoError(t, capped.WriteMetrics(ctx, &b))
+	assert.Equal(t, `# HELP node_managed_containers Managed containers on the node.
+# TYPE node_managed_containers gauge
+node_managed_containers 2
+# HELP node_managed_containers_limit Maximum managed containers on the node.
+# TYPE node_managed_containers_limit gauge
+node_managed_containers_limit 2
+`, b.String())
+
+	// Closing the view leaves the shared runtime open.
+	require.NoError(t, capped.Close())
+	assert.False(t, shared.closed)
+}
@@ -0,0 +1,536 @@
+// Package cgroup collects container resource usage directly from cgroupfs. It
+// serves as a fallback for runtimes whose stats APIs are missing or unreliable.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/clock"
+)
+
+const (
+	defaultCgroupRoot = "/sys/fs/cgroup"
+	defaultProcRoot   = "/proc"
+
+	// Key for the unified hierarchy in cgroup v2. Version 1 paths are keyed by
+	// controller name instead.
+	unified = ""
+)
+
+// Collector samples a container's resource usage from its cgroup. CPU usage
+// is measured between consecutive samples, so the first call to Stats omits
+// it. A Collector is safe for concurrent use.
+type Collector struct {
+	cgroupRoot string
+	procRoot   string
+
+	// Cgroup paths keyed by controller, relative to the cgroupfs root. They're
+	// re-resolved if the process moves, so they're guarded by pathsMu.
+	pathsMu sync.RWMutex
+	paths   map[string]string
+
+	// PID of any process in the container. Used to read network usage.
+	pid int
+
+	mu       sync.Mutex
+	clock    clock.Clock
+	prevCPU  uint64 // In nanoseconds
+	prevTime time.Time
+}
+
+// NewCollector creates a collector for the cgroup containing a process. The PID
+// must be visible from the caller's PID namespace, typically the host's.
+func NewCollector(pid int) (*Collector, error) {
+	c := &Collector{cgroupRoot: defaultCgroupRoot, procRoot: defaultProcRoot, pid: pid}
+	if err := c.readPaths(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewCollectorForPath creates a collector for a cgroup path relative to the
+// cgroupfs root, e.g. "/docker/<id>". On cgroup v1 hosts the path is assumed to
+// be the same for every controller. Network usage is not collected.
+func NewCollectorForPath(path string) *Collector {
+	return &Collector{
+		cgroupRoot: defaultCgroupRoot,
+		procRoot:   defaultProcRoot,
+		paths:      map[string]string{unified: path},
+	}
+}
+
+// ProcessPath returns the path of the cgroup containing a process, relative to
+// the cgroupfs root. On cgroup v1 hosts the memory controller's path is used.
+func ProcessPath(pid int) (string, error) {
+	c := &Collector{cgroupRoot: defaultCgroupRoot, procRoot: defaultProcRoot, pid: pid}
+	if err := c.readPaths(); err != nil {
+		return "", err
+	}
+	if c.isUnified() {
+		return c.paths[unified], nil
+	}
+	if path, ok := c.paths["memory"]; ok {
+		return path, nil
+	}
+	return "", errors.New("cgroup: no memory cgroup found for process")
+}
+
+// UnifiedDir returns the absolute directory of a process's cgroup in the v2
+// hierarchy. On hybrid hosts, where the v2 hierarchy is mounted beside the v1
+// controllers, its directory is beneath <root>/unified.
+func UnifiedDir(pid int) (string, error) {
+	c := &Collector{cgroupRoot: defaultCgroupRoot, procRoot: defaultProcRoot, pid: pid}
+	if err := c.readPaths(); err != nil {
+		return "", err
+	}
+	return c.unifiedDir()
+}
+
+// unifiedDir returns the absolute directory of the collector's v2 cgroup.
+func (c *Collector) unifiedDir() (string, error) {
+	path, ok := c.paths[unified]
+	if !ok {
+		return "", errors.New("cgroup: process has no cgroup v2 cgroup")
+	}
+	if c.isUnified() {
+		return filepath.Join(c.cgroupRoot, path), nil
+	}
+	return filepath.Join(c.cgroupRoot, "unified", path), nil
+}
+
+// readPaths populates cgroup paths from /proc/<pid>/cgroup. Each line has the
+// form "hierarchy-ID:controller-list:cgroup-path".
+func (c *Collector) readPaths() error {
+	f, err := os.Open(filepath.Join(c.procRoot, strconv.Itoa(c.pid), "cgroup"))
+	if err != nil {
+		return fmt.Errorf("cgroup: %w", err)
+	}
+	defer f.Close()
+
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			paths[unified] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cgroup: %w", err)
+	}
+	if len(paths) == 0 {
+		return errors.New("cgroup: no cgroups found for process")
+	}
+
+	c.pathsMu.Lock()
+	c.paths = paths
+	c.pathsMu.Unlock()
+	return nil
+}
+
+// isUnified returns true if the host uses the cgroup v2 unified hierarchy.
+func (c *Collector) isUnified() bool {
+	_, err := os.Stat(filepath.Join(c.cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// file returns the full path to a file within a controller's cgroup.
+func (c *Collector) file(controller, name string) string {
+	c.pathsMu.RLock()
+	defer c.pathsMu.RUnlock()
+	if c.isUnified() {
+		return filepath.Join(c.cgroupRoot, c.paths[unified], name)
+	}
+	path, ok := c.paths[controller]
+	if !ok {
+		path = c.paths[unified]
+	}
+	return filepath.Join(c.cgroupRoot, controller, path, name)
+}
+
+// SetClock replaces the clock which timestamps samples and measures the time
+// between them, e.g. with a fake clock in tests.
+func (c *Collector) SetClock(clk clock.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = clk
+}
+
+// Stats samples the cgroup's current resource usage. If the cgroup is gone,
+// e.g. because the process was moved to another, a collector created with
+// NewCollector finds the process's cgroup again.
+func (c *Collector) Stats() (*runtime.ContainerStats, error) {
+	stats, err := c.sample()
+	if errors.Is(err, os.ErrNotExist) && c.pid != 0 {
+		if c.readPaths() == nil {
+			stats, err = c.sample()
+		}
+	}
+	return stats, err
+}
+
+func (c *Collector) sample() (*runtime.ContainerStats, error) {
+	c.mu.Lock()
+	now := clock.OrSystem(c.clock).Now()
+	c.mu.Unlock()
+	stats := make(map[runtime.StatType]float64)
+
+	var cpu, memUsage, memLimit uint64
+	var err error
+	v2 := c.isUnified()
+	if v2 {
+		cpu, err = c.readCPUv2()
+	} else {
+		cpu, err = readUint(c.file("cpuacct", "cpuacct.usage"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cgroup: reading cpu usage: %w", err)
+	}
+
+	if v2 {
+		memUsage, memLimit, err = c.readMemoryv2()
+	} else {
+		memUsage, memLimit, err = c.readMemoryv1()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cgroup: reading memory usage: %w", err)
+	}
+	if hostMem, err := c.readHostMemory(); err == nil && (memLimit == 0 || memLimit > hostMem) {
+		memLimit = hostMem
+	}
+	stats[runtime.MemoryUsageBytesStat] = float64(memUsage)
+	if memLimit != 0 {
+		stats[runtime.MemoryUsagePercentStat] = float64(memUsage) / float64(memLimit) * 100.0
+	}
+
+	var read, write uint64
+	if v2 {
+		read, write, err = c.readBlockIOv2()
+	} else {
+		read, write, err = c.readBlockIOv1()
+	}
+	if err == nil {
+		stats[runtime.BlockReadBytesStat] = float64(read)
+		stats[runtime.BlockWriteBytesStat] = float64(write)
+	}
+
+	if c.pid != 0 {
+		if rx, tx, err := c.readNetwork(); err == nil {
+			stats[runtime.NetworkRxBytesStat] = float64(rx)
+			stats[runtime.NetworkTxBytesStat] = float64(tx)
+		}
+	}
+
+	c.mu.Lock()
+	if !c.prevTime.IsZero() && cpu >= c.prevCPU {
+		elapsed := now.Sub(c.prevTime)
+		if elapsed > 0 {
+			// Percentages are omitted if the limit or host CPUs can't be read.
+			cores := float64(cpu-c.prevCPU) / float64(elapsed.Nanoseconds())
+			var limit float64
+			if v2 {
+				limit, _ = c.readCPULimitv2()
+			} else {
+				limit, _ = c.readCPULimitv1()
+			}
+			hostCPUs, _ := c.readHostCPUs()
+			runtime.SetCPUStats(stats, cores, limit, hostCPUs)
+		}
+	}
+	c.prevCPU, c.prevTime = cpu, now
+	c.mu.Unlock()
+
+	return &runtime.ContainerStats{Time: now, Stats: stats}, nil
+}
+
+// readCPUv2 reads total CPU usage in nanoseconds from cpu.stat.
+func (c *Collector) readCPUv2() (uint64, error) {
+	stat, err := readKeyValues(c.file("cpu", "cpu.stat"))
+	if err != nil {
+		return 0, err
+	}
+	usec, ok := stat["usage_usec"]
+	if !ok {
+		return 0, errors.New("missing usage_usec")
+	}
+	return usec * 1000, nil
+}
+
+// readCPULimitv1 reads the CFS quota in cores. Zero means no limit.
+func (c *Collector) readCPULimitv1() (float64, error) {
+	b, err := ioutil.ReadFile(c.file("cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, err
+	}
+	quota, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil || quota <= 0 {
+		return 0, err
+	}
+	period, err := readUint(c.file("cpu", "cpu.cfs_period_us"))
+	if err != nil || period == 0 {
+		return 0, err
+	}
+	return float64(quota) / float64(period), nil
+}
+
+// readCPULimitv2 reads the CFS quota in cores from cpu.max, which has the form
+// "quota period" or "max period". Zero means no limit.
+func (c *Collector) readCPULimitv2() (float64, error) {
+	b, err := ioutil.ReadFile(c.file("cpu", "cpu.max"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("unexpected cpu.max: %q", b)
+	}
+	if fields[0] == "max" {
+		return 0, nil
+	}
+	quota, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	period, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil || period == 0 {
+		return 0, err
+	}
+	return float64(quota) / float64(period), nil
+}
+
+// SetCPUBurst sets how much unused CPU quota the cgroup may bank and spend in
+// later periods. It fails if the kernel doesn't support CFS burst.
+func (c *Collector) SetCPUBurst(burst time.Duration) error {
+	name := "cpu.cfs_burst_us"
+	if c.isUnified() {
+		name = "cpu.max.burst"
+	}
+	path := c.file("cpu", name)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cgroup: CPU burst is not supported: %w", err)
+	}
+	value := strconv.FormatInt(burst.Microseconds(), 10)
+	if err := ioutil.WriteFile(path, []byte(value), 0); err != nil {
+		return fmt.Errorf("cgroup: %w", err)
+	}
+	return nil
+}
+
+// readHostCPUs counts the host's CPUs from the per-CPU lines of /proc/stat.
+func (c *Collector) readHostCPUs() (int, error) {
+	stat, err := ioutil.ReadFile(filepath.Join(c.procRoot, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for _, line := range strings.Split(string(stat), "\n") {
+		if len(line) > 3 && strings.HasPrefix(line, "cpu") && line[3] >= '0' && line[3] <= '9' {
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, errors.New("no CPUs listed")
+	}
+	return count, nil
+}
+
+// readMemoryv1 reads memory usage, excluding page cache, and the memory limit.
+func (c *Collector) readMemoryv1() (usage, limit uint64, err error) {
+	if usage, err = readUint(c.file("memory", "memory.usage_in_bytes")); err != nil {
+		return 0, 0, err
+	}
+	if limit, err = readUint(c.file("memory", "memory.limit_in_bytes")); err != nil {
+		return 0, 0, err
+	}
+	stat, err := readKeyValues(c.file("memory", "memory.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if cache := stat["cache"]; cache < usage {
+		usage -= cache
+	}
+	return usage, limit, nil
+}
+
+// readMemoryv2 reads memory usage, excluding page cache, and the memory limit.
+// A limit of zero indicates the cgroup is unlimited.
+func (c *Collector) readMemoryv2() (usage, limit uint64, err error) {
+	if usage, err = readUint(c.file("memory", "memory.current")); err != nil {
+		return 0, 0, err
+	}
+	max, err := ioutil.ReadFile(c.file("memory", "memory.max"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if s := strings.TrimSpace(string(max)); s != "max" {
+		if limit, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	stat, err := readKeyValues(c.file("memory", "memory.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if file := stat["file"]; file < usage {
+		usage -= file
+	}
+	return usage, limit, nil
+}
+
+// readHostMemory reads the total memory available to the host in bytes.
+func (c *Collector) readHostMemory() (uint64, error) {
+	meminfo, err := ioutil.ReadFile(filepath.Join(c.procRoot, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(meminfo), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return kb * 1024, nil
+	}
+	return 0, errors.New("missing MemTotal")
+}
+
+// readBlockIOv1 sums bytes read and written from blkio.throttle.io_service_bytes.
+// Each line has the form "major:minor operation bytes".
+func (c *Collector) readBlockIOv1() (read, write uint64, err error) {
+	b, err := ioutil.ReadFile(c.file("blkio", "blkio.throttle.io_service_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += v
+		case "Write":
+			write += v
+		}
+	}
+	return read, write, nil
+}
+
+// readBlockIOv2 sums bytes read and written from io.stat. Each line has the
+// form "major:minor rbytes=N wbytes=N ...".
+func (c *Collector) readBlockIOv2() (read, write uint64, err error) {
+	b, err := ioutil.ReadFile(c.file("io", "io.stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		for _, field := range strings.Fields(line) {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			v, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch parts[0] {
+			case "rbytes":
+				read += v
+			case "wbytes":
+				write += v
+			}
+		}
+	}
+	return read, write, nil
+}
+
+// readNetwork sums bytes received and sent on all non-loopback interfaces in
+// the process's network namespace.
+func (c *Collector) readNetwork() (rx, tx uint64, err error) {
+	b, err := ioutil.ReadFile(filepath.Join(c.procRoot, strconv.Itoa(c.pid), "net", "dev"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Skip the two header lines. Each following line has the form
+	// "iface: rx-bytes rx-packets ... (8 receive fields) tx-bytes ...".
+	lines := strings.Split(string(b), "\n")
+	if len(lines) < 2 {
+		return 0, 0, errors.New("invalid network stats")
+	}
+	for _, line := range lines[2:] {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "lo" {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 9 {
+			continue
+		}
+		r, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		t, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		rx += r
+		tx += t
+	}
+	return rx, tx, nil
+}
+
+// readUint reads a file containing a single unsigned integer.
+func readUint(path string) (uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// readKeyValues reads a flat keyed file, where each line has the form "key value".
+func readKeyValues(path string) (map[string]uint64, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]uint64)
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		result[fields[0]] = v
+	}
+	return result, nil
+}
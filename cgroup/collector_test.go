@@ -0,0 +1,203 @@
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime"
+)
+
+// writeFiles populates a directory with files, creating parents as needed.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:     100       1    0    0    0     0          0         0      100       1    0    0    0     0       0          0
+  eth0:    2000      20    0    0    0     0          0         0     3000      30    0    0    0     0       0          0
+`
+
+func TestCollectorV1(t *testing.T) {
+	cgroupRoot, procRoot := t.TempDir(), t.TempDir()
+	writeFiles(t, procRoot, map[string]string{
+		"meminfo": "MemTotal:       1024 kB\nMemFree:         512 kB\n",
+		"stat":    "cpu  10 0 10 100\ncpu0 5 0 5 50\ncpu1 5 0 5 50\nintr 1\n",
+		"42/cgroup": "12:blkio:/docker/abc\n" +
+			"4:memory:/docker/abc\n" +
+			"3:cpu,cpuacct:/docker/abc\n",
+		"42/net/dev": netDev,
+	})
+	writeFiles(t, cgroupRoot, map[string]string{
+		"cpuacct/docker/abc/cpuacct.usage":                 "1000\n",
+		"cpu/docker/abc/cpu.cfs_quota_us":                  "50000\n",
+		"cpu/docker/abc/cpu.cfs_period_us":                 "100000\n",
+		"memory/docker/abc/memory.usage_in_bytes":          "600\n",
+		"memory/docker/abc/memory.limit_in_bytes":          "9223372036854771712\n",
+		"memory/docker/abc/memory.stat":                    "cache 88\nrss 512\n",
+		"blkio/docker/abc/blkio.throttle.io_service_bytes": "8:0 Read 10\n8:0 Write 20\n8:16 Read 1\nTotal 31\n",
+	})
+
+	c := &Collector{cgroupRoot: cgroupRoot, procRoot: procRoot, pid: 42}
+	require.NoError(t, c.readPaths())
+
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, map[runtime.StatType]float64{
+		runtime.MemoryUsageBytesStat:   512,
+		runtime.MemoryUsagePercentStat: 512.0 / (1024 * 1024) * 100,
+		runtime.BlockReadBytesStat:     11,
+		runtime.BlockWriteBytesStat:    20,
+		runtime.NetworkRxBytesStat:     2000,
+		runtime.NetworkTxBytesStat:     3000,
+	}, stats.Stats)
+
+	writeFiles(t, cgroupRoot, map[string]string{"cpuacct/docker/abc/cpuacct.usage": "2000\n"})
+	stats, err = c.Stats()
+	require.NoError(t, err)
+	cores := stats.Stats[runtime.CPUUsageCoresStat]
+	assert.Greater(t, cores, 0.0)
+	assert.InDelta(t, cores*100, stats.Stats[runtime.CPUUsagePercentStat], 1e-9)
+	assert.InDelta(t, cores/0.5*100, stats.Stats[runtime.CPUUsageOfLimitPercentStat], 1e-9)
+	assert.InDelta(t, cores/2*100, stats.Stats[runtime.CPUUsageOfHostPercentStat], 1e-9)
+}
+
+func TestCPULimitV2(t *testing.T) {
+	cgroupRoot := t.TempDir()
+	writeFiles(t, cgroupRoot, map[string]string{"cgroup.controllers": "cpu\n"})
+	c := &Collector{cgroupRoot: cgroupRoot, paths: map[string]string{unified: "/abc"}}
+
+	writeFiles(t, cgroupRoot, map[string]string{"abc/cpu.max": "150000 100000\n"})
+	limit, err := c.readCPULimitv2()
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, limit)
+
+	writeFiles(t, cgroupRoot, map[string]string{"abc/cpu.max": "max 100000\n"})
+	limit, err = c.readCPULimitv2()
+	require.NoError(t, err)
+	assert.Zero(t, limit)
+}
+
+func TestSetCPUBurst(t *testing.T) {
+	cgroupRoot := t.TempDir()
+	c := &Collector{cgroupRoot: cgroupRoot, paths: map[string]string{"cpu": "/abc"}}
+	assert.Error(t, c.SetCPUBurst(50*time.Millisecond))
+
+	writeFiles(t, cgroupRoot, map[string]string{"cpu/abc/cpu.cfs_burst_us": "0\n"})
+	require.NoError(t, c.SetCPUBurst(50*time.Millisecond))
+	b, err := ioutil.ReadFile(filepath.Join(cgroupRoot, "cpu/abc/cpu.cfs_burst_us"))
+	require.NoError(t, err)
+	assert.Equal(t, "50000", string(b))
+
+	writeFiles(t, cgroupRoot, map[string]string{"cgroup.controllers": "cpu\n", "abc/cpu.max.burst": "0\n"})
+	c.paths = map[string]string{unified: "/abc"}
+	require.NoError(t, c.SetCPUBurst(20*time.Millisecond))
+	b, err = ioutil.ReadFile(filepath.Join(cgroupRoot, "abc/cpu.max.burst"))
+	require.NoError(t, err)
+	assert.Equal(t, "20000", string(b))
+}
+
+func TestCollectorV2(t *testing.T) {
+	cgroupRoot, procRoot := t.TempDir(), t.TempDir()
+	writeFiles(t, procRoot, map[string]string{
+		"meminfo":   "MemTotal:       1024 kB\n",
+		"7/cgroup":  "0::/kubepods/pod1/abc\n",
+		"7/net/dev": netDev,
+	})
+	writeFiles(t, cgroupRoot, map[string]string{
+		"cgroup.controllers":               "cpu io memory\n",
+		"kubepods/pod1/abc/cpu.stat":       "usage_usec 5\nuser_usec 3\nsystem_usec 2\n",
+		"kubepods/pod1/abc/memory.current": "4096\n",
+		"kubepods/pod1/abc/memory.max":     "8192\n",
+		"kubepods/pod1/abc/memory.stat":    "anon 2048\nfile 2048\n",
+		"kubepods/pod1/abc/io.stat":        "8:0 rbytes=100 wbytes=200 rios=1 wios=2 dbytes=0 dios=0\n",
+	})
+
+	c := &Collector{cgroupRoot: cgroupRoot, procRoot: procRoot, pid: 7}
+	require.NoError(t, c.readPaths())
+
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, map[runtime.StatType]float64{
+		runtime.MemoryUsageBytesStat:   2048,
+		runtime.MemoryUsagePercentStat: 25,
+		runtime.BlockReadBytesStat:     100,
+		runtime.BlockWriteBytesStat:    200,
+		runtime.NetworkRxBytesStat:     2000,
+		runtime.NetworkTxBytesStat:     3000,
+	}, stats.Stats)
+}
+
+func TestCollectorMissing(t *testing.T) {
+	c := &Collector{cgroupRoot: t.TempDir(), procRoot: t.TempDir(), paths: map[string]string{unified: "/gone"}}
+	_, err := c.Stats()
+	assert.Error(t, err)
+}
+
+func TestCollectorMoved(t *testing.T) {
+	cgroupRoot, procRoot := t.TempDir(), t.TempDir()
+	writeFiles(t, procRoot, map[string]string{"7/cgroup": "0::/old\n"})
+	writeFiles(t, cgroupRoot, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"old/cpu.stat":       "usage_usec 5\n",
+		"old/memory.current": "4096\n",
+		"old/memory.max":     "max\n",
+		"old/memory.stat":    "file 0\n",
+	})
+	c := &Collector{cgroupRoot: cgroupRoot, procRoot: procRoot, pid: 7}
+	require.NoError(t, c.readPaths())
+	_, err := c.Stats()
+	require.NoError(t, err)
+
+	// The process moves and its old cgroup is removed.
+	require.NoError(t, os.RemoveAll(filepath.Join(cgroupRoot, "old")))
+	writeFiles(t, procRoot, map[string]string{"7/cgroup": "0::/new\n"})
+	writeFiles(t, cgroupRoot, map[string]string{
+		"new/cpu.stat":       "usage_usec 7\n",
+		"new/memory.current": "8192\n",
+		"new/memory.max":     "max\n",
+		"new/memory.stat":    "file 0\n",
+	})
+	stats, err := c.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 8192.0, stats.Stats[runtime.MemoryUsageBytesStat])
+}
+
+func TestUnifiedDir(t *testing.T) {
+	procRoot := t.TempDir()
+	writeFiles(t, procRoot, map[string]string{
+		"1/cgroup": "4:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n0::/system.slice/docker-abc.scope\n",
+		"2/cgroup": "4:memory:/docker/abc\n",
+	})
+
+	// Hybrid hosts mount the v2 hierarchy beside the v1 controllers.
+	hybridRoot := t.TempDir()
+	c := &Collector{cgroupRoot: hybridRoot, procRoot: procRoot, pid: 1}
+	require.NoError(t, c.readPaths())
+	dir, err := c.unifiedDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(hybridRoot, "unified/system.slice/docker-abc.scope"), dir)
+
+	unifiedRoot := t.TempDir()
+	writeFiles(t, unifiedRoot, map[string]string{"cgroup.controllers": "cpu\n"})
+	c.cgroupRoot = unifiedRoot
+	dir, err = c.unifiedDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(unifiedRoot, "system.slice/docker-abc.scope"), dir)
+
+	// Hosts without a v2 hierarchy have no cgroup IDs.
+	c = &Collector{cgroupRoot: hybridRoot, procRoot: procRoot, pid: 2}
+	require.NoError(t, c.readPaths())
+	_, err = c.unifiedDir()
+	assert.Error(t, err)
+}
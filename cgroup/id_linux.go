@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// ID returns the kernel's identifier for the v2 cgroup containing a process.
+// This is the value reported by bpf_get_current_cgroup_id, so it can be used to
+// attribute events observed by eBPF probes to a container.
+//
+// Cgroup IDs are only defined for the v2 hierarchy, so on hybrid hosts the
+// process's v2 cgroup is used regardless of its v1 cgroups. See UnifiedDir.
+func ID(pid int) (uint64, error) {
+	dir, err := UnifiedDir(pid)
+	if err != nil {
+		return 0, err
+	}
+	return dirID(dir)
+}
+
+// dirID returns the cgroup ID of a directory in the v2 hierarchy, which is its
+// inode number.
+func dirID(dir string) (uint64, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, fmt.Errorf("cgroup: %w", err)
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("cgroup: unexpected file info for %s", dir)
+	}
+	return stat.Ino, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package cgroup
+
+import (
+	"github.com/beaker/runtime"
+)
+
+// ID returns the kernel's identifier for the v2 cgroup containing a process.
+// Cgroups are only supported on Linux.
+func ID(pid int) (uint64, error) {
+	return 0, runtime.ErrNotImplemented
+}
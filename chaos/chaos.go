@@ -0,0 +1,259 @@
+// Package chaos wraps runtimes to inject failures and latency, so that code
+// which must survive unreliable infrastructure can be exercised in tests
+// without it.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/clock"
+)
+
+// ErrInjected is returned by calls which fail by injection, unless their fault
+// sets another error.
+var ErrInjected = errors.New("chaos: injected failure")
+
+// Operation names a runtime or container method into which faults can be
+// injected.
+type Operation string
+
+const (
+	Ping   Operation = "ping"
+	Pull   Operation = "pull"
+	Create Operation = "create"
+	List   Operation = "list"
+	Start  Operation = "start"
+	Info   Operation = "info"
+	Logs   Operation = "logs"
+	Stats  Operation = "stats"
+	Stop   Operation = "stop"
+	Remove Operation = "remove"
+)
+
+// Fault describes how calls to an operation misbehave. The zero value injects
+// nothing.
+type Fault struct {
+	// (optional) Latency delays every call, e.g. to simulate a loaded daemon.
+	Latency time.Duration
+
+	// (optional) Jitter adds a random delay of up to this much to Latency.
+	Jitter time.Duration
+
+	// (optional) ErrorRate is the probability, from 0 to 1, that a call fails
+	// without reaching the underlying runtime.
+	ErrorRate float64
+
+	// (optional) Err is returned by failed calls. Defaults to ErrInjected.
+	Err error
+
+	// (optional) Hang makes failed calls block until their context is done
+	// and return its error, e.g. to simulate a pull which times out.
+	Hang bool
+}
+
+// isZero reports whether a fault is the zero value. Faults can't be compared
+// with == since their errors needn't be comparable.
+func (f Fault) isZero() bool {
+	return f.Latency == 0 && f.Jitter == 0 && f.ErrorRate == 0 && f.Err == nil && !f.Hang
+}
+
+// Truncation describes log streams which end early, as when a daemon drops a
+// connection.
+type Truncation struct {
+	// Rate is the probability, from 0 to 1, that a stream is truncated.
+	Rate float64
+
+	// After is the number of messages a truncated stream returns before
+	// failing with io.ErrUnexpectedEOF.
+	After int
+}
+
+// Config selects the faults a Runtime injects.
+type Config struct {
+	// Faults by operation. Operations without faults behave normally.
+	Faults map[Operation]Fault
+
+	// (optional) Truncation truncates log streams.
+	Truncation Truncation
+
+	// (optional) Seed makes injection deterministic. Zero seeds from the time.
+	Seed int64
+}
+
+// Runtime is a decorator which injects faults into an underlying runtime and
+// its containers. Delays are measured by the clock in each call's context; see
+// clock.NewContext. A Runtime is safe for concurrent use.
+//
+// Containers are wrapped, implementing the optional runtime.Signaler and
+// runtime.Execer interfaces only where the underlying container does.
+type Runtime struct {
+	runtime.Runtime
+
+	mu         sync.Mutex
+	faults     map[Operation]Fault
+	truncation Truncation
+	rand       *rand.Rand
+	injected   map[Operation]int
+}
+
+// Wrap injects faults into a runtime.
+func Wrap(rt runtime.Runtime, config Config) *Runtime {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	faults := make(map[Operation]Fault, len(config.Faults))
+	for op, f := range config.Faults {
+		faults[op] = f
+	}
+	return &Runtime{
+		Runtime:    rt,
+		faults:     faults,
+		truncation: config.Truncation,
+		rand:       rand.New(rand.NewSource(seed)),
+		injected:   make(map[Operation]int),
+	}
+}
+
+// SetFault replaces an operation's fault, e.g. to let a test recover after an
+// outage. The zero Fault removes it.
+func (r *Runtime) SetFault(op Operation, f Fault) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f.isZero() {
+		delete(r.faults, op)
+	} else {
+		r.faults[op] = f
+	}
+}
+
+// Injected returns the number of calls to an operation which failed by
+// injection. Truncated log streams count as failed calls to Logs.
+func (r *Runtime) Injected(op Operation) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.injected[op]
+}
+
+// inject delays a call as its operation's fault requires, then returns an
+// error if the call should fail.
+func (r *Runtime) inject(ctx context.Context, op Operation) error {
+	r.mu.Lock()
+	f, ok := r.faults[op]
+	var delay time.Duration
+	var fail bool
+	if ok {
+		delay = f.Latency
+		if f.Jitter > 0 {
+			delay += time.Duration(r.rand.Int63n(int64(f.Jitter)))
+		}
+		if fail = f.ErrorRate > 0 && r.rand.Float64() < f.ErrorRate; fail {
+			r.injected[op]++
+		}
+	}
+	r.mu.Unlock()
+
+	if delay > 0 {
+		timer := clock.FromContext(ctx).NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	if !fail {
+		return nil
+	}
+	if f.Hang {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if f.Err != nil {
+		return f.Err
+	}
+	return ErrInjected
+}
+
+// truncateAfter returns the number of messages after which a new log stream
+// is truncated, or -1 if it isn't.
+func (r *Runtime) truncateAfter() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncation.Rate <= 0 || r.rand.Float64() >= r.truncation.Rate {
+		return -1
+	}
+	r.injected[Logs]++
+	return r.truncation.After
+}
+
+// Ping implements runtime.Runtime.
+func (r *Runtime) Ping(ctx context.Context) error {
+	if err := r.inject(ctx, Ping); err != nil {
+		return err
+	}
+	return r.Runtime.Ping(ctx)
+}
+
+// PullImage implements runtime.Runtime.
+func (r *Runtime) PullImage(
+	ctx context.Context,
+	image *runtime.DockerImage,
+	policy runtime.PullPolicy,
+	quiet bool,
+) error {
+	if err := r.inject(ctx, Pull); err != nil {
+		return err
+	}
+	return r.Runtime.PullImage(ctx, image, policy, quiet)
+}
+
+// CreateContainer implements runtime.Runtime.
+func (r *Runtime) CreateContainer(ctx context.Context, opts *runtime.ContainerOpts) (runtime.Container, error) {
+	if err := r.inject(ctx, Create); err != nil {
+		return nil, err
+	}
+	c, err := r.Runtime.CreateContainer(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.Container(c), nil
+}
+
+// ListContainers implements runtime.Runtime.
+func (r *Runtime) ListContainers(ctx context.Context) ([]runtime.Container, error) {
+	if err := r.inject(ctx, List); err != nil {
+		return nil, err
+	}
+	containers, err := r.Runtime.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range containers {
+		containers[i] = r.Container(c)
+	}
+	return containers, nil
+}
+
+// Container wraps a container of the underlying runtime to inject faults. The
+// wrapper implements the same optional interfaces as the underlying container.
+func (r *Runtime) Container(c runtime.Container) runtime.Container {
+	wrapped := &Container{Container: c, r: r}
+	_, signals := c.(runtime.Signaler)
+	_, execs := c.(runtime.Execer)
+	switch {
+	case signals && execs:
+		return signalExecContainer{wrapped}
+	case signals:
+		return signalContainer{wrapped}
+	case execs:
+		return execContainer{wrapped}
+	default:
+		return wrapped
+	}
+}
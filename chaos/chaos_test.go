@@ -0,0 +1,165 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/clock"
+	"github.com/beaker/runtime/logging"
+)
+
+// stubContainer succeeds at everything, logging an endless stream.
+type stubContainer struct{}
+
+func (stubContainer) Name() string                    { return "stub" }
+func (stubContainer) Start(ctx context.Context) error { return nil }
+func (stubContainer) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
+	return &runtime.ContainerInfo{Status: runtime.StatusRunning}, nil
+}
+func (stubContainer) Logs(ctx context.Context, opts runtime.LogsOpts) (logging.LogReader, error) {
+	return endlessReader{}, nil
+}
+func (stubContainer) Stats(ctx context.Context) (*runtime.ContainerStats, error) {
+	return &runtime.ContainerStats{}, nil
+}
+func (stubContainer) Stop(ctx context.Context, timeout *time.Duration) error    { return nil }
+func (stubContainer) Remove(ctx context.Context, opts runtime.RemoveOpts) error { return nil }
+
+type endlessReader struct{}
+
+func (endlessReader) Close() error { return nil }
+func (endlessReader) ReadMessage() (*logging.Message, error) {
+	return &logging.Message{Text: "tick\n"}, nil
+}
+
+// stubRuntime creates stub containers.
+type stubRuntime struct{}
+
+func (stubRuntime) Close() error                   { return nil }
+func (stubRuntime) Ping(ctx context.Context) error { return nil }
+func (stubRuntime) PullImage(context.Context, *runtime.DockerImage, runtime.PullPolicy, bool) error {
+	return nil
+}
+func (stubRuntime) CreateContainer(context.Context, *runtime.ContainerOpts) (runtime.Container, error) {
+	return stubContainer{}, nil
+}
+func (stubRuntime) ListContainers(context.Context) ([]runtime.Container, error) {
+	return []runtime.Container{stubContainer{}}, nil
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	errStats := errors.New("stats unavailable")
+	rt := Wrap(stubRuntime{}, Config{
+		Faults: map[Operation]Fault{
+			Create: {ErrorRate: 1},
+			Stats:  {ErrorRate: 1, Err: errStats},
+			Info:   {ErrorRate: 0.5},
+		},
+		Seed: 1,
+	})
+
+	_, err := rt.CreateContainer(ctx, &runtime.ContainerOpts{})
+	assert.ErrorIs(t, err, ErrInjected)
+	assert.Equal(t, 1, rt.Injected(Create))
+	assert.NoError(t, rt.Ping(ctx))
+
+	containers, err := rt.ListContainers(ctx)
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+	c := containers[0]
+
+	_, err = c.Stats(ctx)
+	assert.Equal(t, errStats, err)
+
+	var failed int
+	for i := 0; i < 1000; i++ {
+		if _, err := c.Info(ctx); err != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 500, failed, 100)
+	assert.Equal(t, failed, rt.Injected(Info))
+
+	// Faults can be lifted.
+	rt.SetFault(Create, Fault{})
+	_, err = rt.CreateContainer(ctx, &runtime.ContainerOpts{})
+	assert.NoError(t, err)
+
+	// Faults with incomparable errors can be set and lifted.
+	rt.SetFault(Stats, Fault{ErrorRate: 1, Err: incomparableError{"a"}})
+	rt.SetFault(Stats, Fault{})
+	_, err = c.Stats(ctx)
+	assert.NoError(t, err)
+
+	// Only optional interfaces the underlying container implements are exposed.
+	_, ok := c.(runtime.Signaler)
+	assert.False(t, ok)
+	_, ok = c.(runtime.Execer)
+	assert.False(t, ok)
+	signaler, ok := rt.Container(signalingContainer{}).(runtime.Signaler)
+	require.True(t, ok)
+	assert.NoError(t, signaler.Signal(ctx, "SIGTERM"))
+	_, ok = rt.Container(signalingContainer{}).(runtime.Execer)
+	assert.False(t, ok)
+}
+
+// incomparableError can't be compared with ==.
+type incomparableError []string
+
+func (e incomparableError) Error() string { return e[0] }
+
+// signalingContainer is a stubContainer which implements runtime.Signaler.
+type signalingContainer struct{ stubContainer }
+
+func (signalingContainer) Signal(ctx context.Context, signal string) error { return nil }
+
+func TestLatency(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	ctx := clock.NewContext(context.Background(), fake)
+	rt := Wrap(stubRuntime{}, Config{Faults: map[Operation]Fault{Pull: {Latency: time.Minute}}})
+
+	done := make(chan error)
+	go func() { done <- rt.PullImage(ctx, &runtime.DockerImage{Tag: "busybox"}, runtime.PullAlways, true) }()
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("pull returned before its latency elapsed")
+	default:
+	}
+	fake.Advance(time.Minute)
+	assert.NoError(t, <-done)
+}
+
+func TestHang(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	rt := Wrap(stubRuntime{}, Config{Faults: map[Operation]Fault{Pull: {ErrorRate: 1, Hang: true}}})
+
+	err := rt.PullImage(ctx, &runtime.DockerImage{Tag: "busybox"}, runtime.PullAlways, true)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestTruncation(t *testing.T) {
+	ctx := context.Background()
+	rt := Wrap(stubRuntime{}, Config{Truncation: Truncation{Rate: 1, After: 2}})
+
+	r, err := rt.Container(stubContainer{}).Logs(ctx, runtime.LogsOpts{})
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := r.ReadMessage()
+		require.NoError(t, err)
+	}
+	_, err = r.ReadMessage()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 1, rt.Injected(Logs))
+}
@@ -0,0 +1,118 @@
+package chaos
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/logging"
+)
+
+// Container is a container into which a Runtime injects faults.
+type Container struct {
+	runtime.Container
+	r *Runtime
+}
+
+// Unwrap returns the underlying container.
+func (c *Container) Unwrap() runtime.Container {
+	return c.Container
+}
+
+// Start implements runtime.Container.
+func (c *Container) Start(ctx context.Context) error {
+	if err := c.r.inject(ctx, Start); err != nil {
+		return err
+	}
+	return c.Container.Start(ctx)
+}
+
+// Info implements runtime.Container.
+func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
+	if err := c.r.inject(ctx, Info); err != nil {
+		return nil, err
+	}
+	return c.Container.Info(ctx)
+}
+
+// Logs implements runtime.Container. Streams may be truncated as configured.
+func (c *Container) Logs(ctx context.Context, opts runtime.LogsOpts) (logging.LogReader, error) {
+	if err := c.r.inject(ctx, Logs); err != nil {
+		return nil, err
+	}
+	r, err := c.Container.Logs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if after := c.r.truncateAfter(); after >= 0 {
+		return &truncatedReader{LogReader: r, remaining: after}, nil
+	}
+	return r, nil
+}
+
+// Stats implements runtime.Container.
+func (c *Container) Stats(ctx context.Context) (*runtime.ContainerStats, error) {
+	if err := c.r.inject(ctx, Stats); err != nil {
+		return nil, err
+	}
+	return c.Container.Stats(ctx)
+}
+
+// Stop implements runtime.Container.
+func (c *Container) Stop(ctx context.Context, timeout *time.Duration) error {
+	if err := c.r.inject(ctx, Stop); err != nil {
+		return err
+	}
+	return c.Container.Stop(ctx, timeout)
+}
+
+// Remove implements runtime.Container.
+func (c *Container) Remove(ctx context.Context, opts runtime.RemoveOpts) error {
+	if err := c.r.inject(ctx, Remove); err != nil {
+		return err
+	}
+	return c.Container.Remove(ctx, opts)
+}
+
+// signalContainer, execContainer, and signalExecContainer extend a Container
+// with the optional interfaces its underlying container implements, so that
+// callers can detect support by type assertion as they would unwrapped.
+type (
+	signalContainer     struct{ *Container }
+	execContainer       struct{ *Container }
+	signalExecContainer struct{ *Container }
+)
+
+func (c signalContainer) Signal(ctx context.Context, signal string) error {
+	return c.Container.Container.(runtime.Signaler).Signal(ctx, signal)
+}
+
+func (c execContainer) ExecSync(ctx context.Context, cmd []string, timeout time.Duration) (*runtime.ExecResult, error) {
+	return c.Container.Container.(runtime.Execer).ExecSync(ctx, cmd, timeout)
+}
+
+func (c signalExecContainer) Signal(ctx context.Context, signal string) error {
+	return signalContainer(c).Signal(ctx, signal)
+}
+
+func (c signalExecContainer) ExecSync(ctx context.Context, cmd []string, timeout time.Duration) (*runtime.ExecResult, error) {
+	return execContainer(c).ExecSync(ctx, cmd, timeout)
+}
+
+// truncatedReader ends a log stream early.
+type truncatedReader struct {
+	logging.LogReader
+	remaining int
+}
+
+func (r *truncatedReader) ReadMessage() (*logging.Message, error) {
+	if r.remaining <= 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	msg, err := r.LogReader.ReadMessage()
+	if err == nil {
+		r.remaining--
+	}
+	return msg, err
+}
@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// cleanupTimeout bounds the time spent undoing a failed operation.
+const cleanupTimeout = time.Minute
+
+// CreateTokenLabel is set on containers to a token unique to the call which
+// created them. A container found by name after a canceled create is only
+// removed if it carries the call's token, so that an existing container of the
+// same name, whose conflict may have failed the create, is left alone.
+const CreateTokenLabel = "beaker.org/create-token"
+
+// NewCreateToken returns a random token for CreateTokenLabel.
+func NewCreateToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// CleanupAsync undoes part of a failed operation in the background, such as a
+// container whose creation was canceled after the request reached the runtime.
+// It must be called by an operation registered with the lifecycle, whose
+// shutdown then waits for the cleanup too. The cleanup runs with its own
+// context since the operation's context may be canceled. Failures are logged
+// since there's no caller left to report them to.
+func CleanupAsync(life *Lifecycle, description string, cleanup func(ctx context.Context) error) {
+	end := life.hold()
+	go func() {
+		defer end()
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+
+		if err := cleanup(ctx); err != nil {
+			log.WithError(err).Warnf("Failed to clean up %s", description)
+			return
+		}
+		log.Debugf("Cleaned up %s", description)
+	}()
+}
@@ -0,0 +1,76 @@
+// Package clock abstracts the passage of time so that code which timestamps,
+// polls, or expires things can be tested with a fake clock instead of by
+// sleeping.
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and creates timers against it.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer delivers the time once on C after its duration, as time.Timer does.
+type Timer interface {
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It returns false if the timer had
+	// already fired or been stopped.
+	Stop() bool
+}
+
+// Ticker delivers the time on C once per period, as time.Ticker does. Ticks
+// are dropped if the receiver falls behind.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// System is the real clock.
+var System Clock = system{}
+
+type system struct{}
+
+func (system) Now() time.Time                   { return time.Now() }
+func (system) NewTimer(d time.Duration) Timer   { return systemTimer{time.NewTimer(d)} }
+func (system) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+func (t systemTimer) Stop() bool          { return t.t.Stop() }
+
+type systemTicker struct{ t *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+func (t systemTicker) Stop()               { t.t.Stop() }
+
+type contextKey struct{}
+
+// NewContext returns a context which carries a clock, for functions which
+// take a context rather than a clock of their own.
+func NewContext(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the context's clock, or System if it has none.
+func FromContext(ctx context.Context) Clock {
+	if c, ok := ctx.Value(contextKey{}).(Clock); ok {
+		return c
+	}
+	return System
+}
+
+// OrSystem returns c, or System if c is nil. It lets types hold an optional
+// clock in a field whose zero value is usable.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
@@ -0,0 +1,90 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeTimer(t *testing.T) {
+	start := time.Unix(1000, 0)
+	f := NewFake(start)
+
+	timer := f.NewTimer(time.Minute)
+	assert.Equal(t, 1, f.Waiters())
+
+	f.Advance(59 * time.Second)
+	assertNotFired(t, timer.C())
+
+	f.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-timer.C())
+	assert.Equal(t, 0, f.Waiters())
+	assert.False(t, timer.Stop())
+
+	stopped := f.NewTimer(time.Second)
+	assert.True(t, stopped.Stop())
+	f.Advance(time.Minute)
+	assertNotFired(t, stopped.C())
+
+	// Timers which are already due fire without advancing.
+	assert.Equal(t, f.Now(), <-f.NewTimer(0).C())
+}
+
+func TestFakeTicker(t *testing.T) {
+	start := time.Unix(1000, 0)
+	f := NewFake(start)
+
+	ticker := f.NewTicker(time.Second)
+	f.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-ticker.C())
+
+	// Ticks are dropped when the receiver falls behind.
+	f.Advance(3 * time.Second)
+	assert.Equal(t, start.Add(2*time.Second), <-ticker.C())
+	assertNotFired(t, ticker.C())
+	assert.Equal(t, start.Add(4*time.Second), f.Now())
+
+	ticker.Stop()
+	f.Advance(time.Second)
+	assertNotFired(t, ticker.C())
+	assert.Equal(t, 0, f.Waiters())
+}
+
+func TestFakeOrder(t *testing.T) {
+	f := NewFake(time.Unix(1000, 0))
+	late := f.NewTimer(2 * time.Second)
+	early := f.NewTimer(time.Second)
+
+	f.Advance(time.Minute)
+	assert.Equal(t, time.Unix(1001, 0), <-early.C())
+	assert.Equal(t, time.Unix(1002, 0), <-late.C())
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, System, FromContext(ctx))
+
+	f := NewFake(time.Unix(1000, 0))
+	assert.Equal(t, f, FromContext(NewContext(ctx, f)))
+}
+
+func TestSystem(t *testing.T) {
+	timer := System.NewTimer(time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		require.Fail(t, "timer didn't fire")
+	}
+	assert.Equal(t, System, OrSystem(nil))
+}
+
+func assertNotFired(t *testing.T, c <-chan time.Time) {
+	select {
+	case v := <-c:
+		assert.Fail(t, "unexpected fire", "fired at %v", v)
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a clock which only moves when advanced. Timers and tickers fire as
+// the clock passes their deadlines, in order. A Fake is safe for concurrent
+// use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFake creates a fake clock set to a time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake's current time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer creates a timer which fires once the clock is advanced by d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0)
+}
+
+// NewTicker creates a ticker which fires each time the clock is advanced by
+// another d. It panics if d isn't positive, as time.NewTicker does.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return fakeTicker{f.add(d, d)}
+}
+
+// Advance moves the clock forward, firing timers and tickers which come due
+// in order of their deadlines.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target := f.now.Add(d)
+	for {
+		var next *fakeWaiter
+		for _, w := range f.waiters {
+			if !w.at.After(target) && (next == nil || w.at.Before(next.at)) {
+				next = w
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		f.now = next.at
+		select {
+		case next.c <- f.now:
+		default: // Dropped, as a real ticker does.
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			f.remove(next)
+		}
+	}
+	f.now = target
+}
+
+// Waiters returns the number of timers and tickers which haven't fired or
+// been stopped. Tests can wait for it to rise to know that the code under test
+// is waiting before advancing the clock.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+func (f *Fake) add(d, period time.Duration) *fakeWaiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &fakeWaiter{f: f, at: f.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if period == 0 && d <= 0 {
+		// Timers which are already due fire immediately.
+		w.c <- f.now
+		return w
+	}
+	f.waiters = append(f.waiters, w)
+	return w
+}
+
+// remove stops a waiter, returning false if it wasn't active. The caller must
+// hold the lock.
+func (f *Fake) remove(w *fakeWaiter) bool {
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeWaiter is a fake timer or, if it has a period, ticker.
+type fakeWaiter struct {
+	f      *Fake
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.f.mu.Lock()
+	defer w.f.mu.Unlock()
+	return w.f.remove(w)
+}
+
+// fakeTicker adapts a fake waiter to the Ticker interface.
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.w.C() }
+func (t fakeTicker) Stop()               { t.w.Stop() }
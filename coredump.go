@@ -0,0 +1,136 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corePatternFile holds the kernel's core dump destination. It's a variable so
+// tests can replace it.
+var corePatternFile = "/proc/sys/kernel/core_pattern"
+
+// CoreDumpPath returns the directory to which the kernel writes core dumps, as
+// configured by kernel.core_pattern. The pattern is global to the host but is
+// resolved in the crashing process's mount namespace, so mounting a host
+// directory at this path inside a container collects its core dumps.
+//
+// Patterns which pipe to a handler such as apport or systemd-coredump, or which
+// are relative to the crashing process's working directory, are unsupported.
+func CoreDumpPath() (string, error) {
+	b, err := ioutil.ReadFile(corePatternFile)
+	if err != nil {
+		return "", fmt.Errorf("reading core pattern: %w", err)
+	}
+	return parseCorePattern(string(b))
+}
+
+func parseCorePattern(pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	switch {
+	case strings.HasPrefix(pattern, "|"):
+		return "", fmt.Errorf("core dumps are piped to %q; set kernel.core_pattern to an absolute path such as /var/crash/core.%%e.%%p", pattern[1:])
+	case !filepath.IsAbs(pattern):
+		return "", fmt.Errorf("core pattern %q is relative; set kernel.core_pattern to an absolute path", pattern)
+	}
+	dir := filepath.Dir(pattern)
+	if strings.Contains(dir, "%") {
+		return "", fmt.Errorf("core pattern %q has a variable directory", pattern)
+	}
+	return dir, nil
+}
+
+// coreSignals are the signals whose default action dumps core, by number.
+var coreSignals = map[int]string{
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	11: "SIGSEGV",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+	31: "SIGSYS",
+}
+
+// CrashSignal returns the signal, e.g. "SIGSEGV", which killed a container's
+// main process if it's one which dumps core. Runtimes report death by signal N
+// as exit code 128+N.
+func CrashSignal(info *ContainerInfo) (string, bool) {
+	if info.Status != StatusExited || info.ExitCode == nil || *info.ExitCode <= 128 {
+		return "", false
+	}
+	signal, ok := coreSignals[*info.ExitCode-128]
+	return signal, ok
+}
+
+// CollectCoreDumps lists core dumps written to a container's CoreDumpDir since
+// it started, oldest first. Call it once the container exits, e.g. when
+// CrashSignal reports a crash. Core dumps may be written by any of the
+// container's processes, not only its main process.
+func CollectCoreDumps(dir string, info *ContainerInfo) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing core dumps: %w", err)
+	}
+
+	var dumps []os.FileInfo
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && !entry.ModTime().Before(info.StartedAt) {
+			dumps = append(dumps, entry)
+		}
+	}
+	sort.Slice(dumps, func(i, j int) bool { return dumps[i].ModTime().Before(dumps[j].ModTime()) })
+
+	paths := make([]string, len(dumps))
+	for i, dump := range dumps {
+		paths[i] = filepath.Join(dir, dump.Name())
+	}
+	return paths, nil
+}
+
+// CaptureStacks writes the stack traces of a running container's main process
+// to a file in dir, e.g. before stopping a container which appears hung. The
+// process is identified by its host PID; see ContainerInfo.PID.
+//
+// Python processes are captured with py-spy. Other processes, or Python
+// processes on hosts without py-spy, are captured with gdb. The tool must be
+// installed on the host and the caller must be allowed to trace the process.
+func CaptureStacks(ctx context.Context, pid int, dir string) (string, error) {
+	if pid == 0 {
+		return "", ErrNotStarted
+	}
+
+	tools := [][]string{
+		{"py-spy", "dump", "--native", "--pid", strconv.Itoa(pid)},
+		{"gdb", "-batch", "-p", strconv.Itoa(pid), "-ex", "thread apply all bt"},
+	}
+	var errs []string
+	for _, args := range tools {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v: %s", args[0], err, strings.TrimSpace(stderr.String())))
+			continue
+		}
+
+		name := fmt.Sprintf("stacks.%d.%s.%s.txt", pid, args[0], time.Now().UTC().Format("20060102T150405Z"))
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, stdout.Bytes(), 0644); err != nil {
+			return "", fmt.Errorf("writing stacks: %w", err)
+		}
+		return path, nil
+	}
+	return "", errors.New("capturing stacks failed; " + strings.Join(errs, "; "))
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCorePattern(t *testing.T) {
+	dir, err := parseCorePattern("/var/crash/core.%e.%p\n")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/crash", dir)
+
+	for _, pattern := range []string{
+		"|/usr/share/apport/apport %p %s %c %d %P %E",
+		"core",
+		"/var/crash/%e/core",
+	} {
+		_, err := parseCorePattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestCrashSignal(t *testing.T) {
+	exited := func(code int) *ContainerInfo {
+		return &ContainerInfo{Status: StatusExited, ExitCode: &code}
+	}
+
+	signal, ok := CrashSignal(exited(139))
+	assert.True(t, ok)
+	assert.Equal(t, "SIGSEGV", signal)
+
+	signal, ok = CrashSignal(exited(134))
+	assert.True(t, ok)
+	assert.Equal(t, "SIGABRT", signal)
+
+	// SIGKILL and SIGTERM don't dump core.
+	_, ok = CrashSignal(exited(137))
+	assert.False(t, ok)
+	_, ok = CrashSignal(exited(143))
+	assert.False(t, ok)
+	_, ok = CrashSignal(exited(1))
+	assert.False(t, ok)
+	_, ok = CrashSignal(&ContainerInfo{Status: StatusRunning})
+	assert.False(t, ok)
+}
+
+func TestCollectCoreDumps(t *testing.T) {
+	dir := t.TempDir()
+	started := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	write := func(name string, modified time.Time) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte("core"), 0644))
+		require.NoError(t, os.Chtimes(path, modified, modified))
+	}
+	write("core.stale.1", started.Add(-time.Hour))
+	write("core.python.20", started.Add(20*time.Second))
+	write("core.python.10", started.Add(10*time.Second))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0755))
+
+	dumps, err := CollectCoreDumps(dir, &ContainerInfo{StartedAt: started})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "core.python.10"),
+		filepath.Join(dir, "core.python.20"),
+	}, dumps)
+}
@@ -0,0 +1,69 @@
+package cri
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/beaker/runtime"
+)
+
+// backendName identifies CRI in API metrics.
+const backendName = "cri"
+
+// ObserveAPI registers a function to receive metrics for each request to the
+// CRI runtime. It must be called before the runtime is used.
+func (r *Runtime) ObserveAPI(fn func(runtime.APICall)) {
+	r.onCall = fn
+}
+
+// observeCall is a gRPC interceptor which reports each request. Operations are
+// named after their CRI methods, e.g. "StartContainer".
+func (r *Runtime) observeCall(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if r.onCall != nil {
+		r.onCall(runtime.APICall{
+			Backend:   backendName,
+			Operation: path.Base(method),
+			Duration:  time.Since(start),
+			Err:       err,
+			Class:     classifyErr(err),
+		})
+	}
+	return err
+}
+
+// classifyErr categorizes a gRPC error by its status code.
+func classifyErr(err error) runtime.APIErrorClass {
+	if err == nil {
+		return ""
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return runtime.ClassifyAPIError(err)
+	}
+	switch s.Code() {
+	case codes.NotFound:
+		return runtime.APIErrorNotFound
+	case codes.Canceled:
+		return runtime.APIErrorCanceled
+	case codes.DeadlineExceeded:
+		return runtime.APIErrorTimeout
+	case codes.Unavailable, codes.ResourceExhausted:
+		return runtime.APIErrorUnavailable
+	default:
+		return runtime.APIErrorOther
+	}
+}
@@ -3,12 +3,16 @@ package cri
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/cgroup"
 	"github.com/beaker/runtime/logging"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	cri "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -16,8 +20,14 @@ import (
 
 // Container wraps a CRI container.
 type Container struct {
-	client cri.RuntimeServiceClient
-	id     string
+	client  cri.RuntimeServiceClient
+	life    *runtime.Lifecycle
+	history *runtime.History
+	id      string
+
+	// Stats are read from cgroupfs since CRI's stats API is incomplete.
+	collectorLock sync.Mutex
+	collector     *cgroup.Collector
 }
 
 // Name returns the container's unique ID.
@@ -26,13 +36,52 @@ func (c *Container) Name() string {
 }
 
 // Start calls the entrypoint in a created container.
+//
+// CRI can't configure CFS burst, so a container's CPUBurst is written to its
+// cgroup once it's running. Nor does it have hooks, so a container's PostStart
+// hook is run here with exec.
 func (c *Container) Start(ctx context.Context) error {
-	_, err := c.client.StartContainer(ctx, &cri.StartContainerRequest{ContainerId: c.id})
-	return translateErr(err)
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	_, err = c.client.StartContainer(ctx, &cri.StartContainerRequest{ContainerId: c.id})
+	if err != nil {
+		return translateErr(err)
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("setting CPU burst: %w", err)
+	}
+	if info.CPUBurst != 0 && info.CgroupPath != "" {
+		if err := cgroup.NewCollectorForPath(info.CgroupPath).SetCPUBurst(info.CPUBurst); err != nil {
+			logrus.WithError(err).Warnf("Failed to set CPU burst of container %s; its CPU limit is strict", c.id)
+		}
+	}
+	if info.PostStart != nil && info.Status == runtime.StatusRunning {
+		if err := runtime.RunHook(ctx, c, info.PostStart); err != nil {
+			// As on Kubernetes, a container whose hook fails doesn't run.
+			_, stopErr := c.client.StopContainer(ctx, &cri.StopContainerRequest{ContainerId: c.id})
+			if stopErr != nil {
+				logrus.WithError(stopErr).Warnf("Failed to stop container %s after its post-start hook failed", c.id)
+			}
+			return fmt.Errorf("post-start hook: %w", err)
+		}
+	}
+	return nil
 }
 
 // Info returns a container's details.
 func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	resp, err := c.client.ContainerStatus(ctx, &cri.ContainerStatusRequest{
 		ContainerId: c.id,
 		Verbose:     true,
@@ -54,6 +103,26 @@ func containerInfo(
 ) (runtime.ContainerInfo, error) {
 	var result runtime.ContainerInfo
 	result.Labels = status.Labels
+	if hash, ok := result.Labels[runtime.ConfigHashLabel]; ok {
+		result.ConfigHash = hash
+		delete(result.Labels, runtime.ConfigHashLabel)
+	}
+	delete(result.Labels, runtime.CreateTokenLabel)
+	delete(result.Labels, runtime.AutoRemoveLabel)
+	if burst, ok := result.Labels[runtime.CPUBurstLabel]; ok {
+		var err error
+		if result.CPUBurst, err = time.ParseDuration(burst); err != nil {
+			return runtime.ContainerInfo{}, fmt.Errorf("CPU burst: %w", err)
+		}
+		delete(result.Labels, runtime.CPUBurstLabel)
+	}
+	if hooks, ok := result.Labels[runtime.HooksLabel]; ok {
+		var err error
+		if result.PostStart, result.PreStop, err = runtime.DecodeHooks(hooks); err != nil {
+			return runtime.ContainerInfo{}, err
+		}
+		delete(result.Labels, runtime.HooksLabel)
+	}
 	result.CreatedAt = time.Unix(0, status.CreatedAt)
 	if status.StartedAt != 0 {
 		result.CreatedAt = time.Unix(0, status.StartedAt)
@@ -73,28 +142,65 @@ func containerInfo(
 		*result.ExitCode = int(status.ExitCode)
 	}
 
-	if jsonConfig, ok := info["info"]; ok {
-		var jsonInfo struct {
-			SandboxID string              `json:"sandboxID"`
-			PID       int                 `json:"pid"`
-			Config    cri.ContainerConfig `json:"config"`
-		}
-
-		if err := json.Unmarshal([]byte(jsonConfig), &jsonInfo); err != nil {
-			return runtime.ContainerInfo{}, fmt.Errorf("cri: couldn't parse container config: %w", err)
+	jsonInfo, err := parseVerboseInfo(info)
+	if err != nil {
+		return runtime.ContainerInfo{}, err
+	}
+	if jsonInfo != nil {
+		if result.Status == runtime.StatusRunning && jsonInfo.PID != 0 {
+			result.PID = jsonInfo.PID
+			result.CgroupPath, _ = cgroup.ProcessPath(jsonInfo.PID) // Best effort.
 		}
 
 		res := jsonInfo.Config.Linux.GetResources()
 		result.Memory = res.GetMemoryLimitInBytes()
-		result.CPUCount = float64(res.GetCpuQuota()) / float64(res.GetCpuPeriod())
+		if period := res.GetCpuPeriod(); period != 0 {
+			result.CPUCount = float64(res.GetCpuQuota()) / float64(period)
+		}
 	}
 
 	return result, nil
 }
 
+// verboseInfo is the runtime-specific detail returned by a verbose container
+// status request. Its layout matches containerd's CRI plugin.
+type verboseInfo struct {
+	SandboxID string              `json:"sandboxID"`
+	PID       int                 `json:"pid"`
+	Config    cri.ContainerConfig `json:"config"`
+}
+
+// parseVerboseInfo parses verbose container status. It returns nil if the
+// runtime didn't include any details.
+func parseVerboseInfo(info map[string]string) (*verboseInfo, error) {
+	jsonConfig, ok := info["info"]
+	if !ok {
+		return nil, nil
+	}
+
+	var result verboseInfo
+	if err := json.Unmarshal([]byte(jsonConfig), &result); err != nil {
+		return nil, fmt.Errorf("cri: couldn't parse container config: %w", err)
+	}
+	return &result, nil
+}
+
 // Logs returns logging.LogReader which can be used to read log messages
-// starting at the given time (inclusive). Set time to zero to read the full log.
-func (c *Container) Logs(ctx context.Context, since time.Time) (logging.LogReader, error) {
+// starting at the given time (inclusive).
+func (c *Container) Logs(ctx context.Context, opts runtime.LogsOpts) (logging.LogReader, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	if opts.Follow {
+		return nil, fmt.Errorf("cri: following logs is not supported (%w)", runtime.ErrNotImplemented)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
 	resp, err := c.client.ContainerStatus(ctx, &cri.ContainerStatusRequest{ContainerId: c.id})
 	if err != nil {
 		return nil, translateErr(err)
@@ -106,29 +212,148 @@ func (c *Container) Logs(ctx context.Context, since time.Time) (logging.LogReade
 		return nil, fmt.Errorf("couldn't open log file %q: %w", logPath, err)
 	}
 
-	return NewLogReader(r, since), nil
+	return runtime.BufferLogs(NewLogReader(r, opts.Since, opts.ReaderOpts), opts), nil
 }
 
 // Stop sends a SIGTERM to a container to instruct it to exit. If a timeout is
 // provided and elapses, the container is forcibly stopped with SIGKILL.
 func (c *Container) Stop(ctx context.Context, timeout *time.Duration) error {
-	_, err := c.client.StopContainer(ctx, &cri.StopContainerRequest{
-		ContainerId: c.id,
-		Timeout:     int64(timeout.Seconds()),
-	})
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if timeout, err = c.preStop(ctx, timeout); err != nil {
+		return err
+	}
+	req := &cri.StopContainerRequest{ContainerId: c.id}
+	if timeout != nil {
+		req.Timeout = int64(timeout.Seconds())
+	}
+	_, err = c.client.StopContainer(ctx, req)
 	return translateErr(err)
 }
 
-// Remove kills and removes a container with no grace period.
-func (c *Container) Remove(ctx context.Context) error {
-	_, err := c.client.RemoveContainer(ctx, &cri.RemoveContainerRequest{ContainerId: c.id})
-	return translateErr(err)
+// preStop runs the container's pre-stop hook, if it has one and is running,
+// returning the grace period left for stopping it.
+func (c *Container) preStop(ctx context.Context, grace *time.Duration) (*time.Duration, error) {
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.PreStop == nil || info.Status != runtime.StatusRunning {
+		return grace, nil
+	}
+	grace, err = runtime.RunPreStop(ctx, c, info.PreStop, grace)
+	if err != nil {
+		logrus.WithError(err).Warnf("Stopping container %s despite its failed hook", c.id)
+	}
+	return grace, nil
+}
+
+// Remove removes a container. A running container is first given the grace
+// period to exit, then killed. CRI containers have no anonymous volumes.
+func (c *Container) Remove(ctx context.Context, opts runtime.RemoveOpts) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if opts.GracePeriod > 0 {
+		if err := c.Stop(ctx, &opts.GracePeriod); err != nil {
+			return err
+		}
+	}
+	if opts.LogArchiveDir != "" {
+		if _, err := runtime.ArchiveLogs(ctx, c, opts.LogArchiveDir, opts.LogArchiveOpts); err != nil {
+			return fmt.Errorf("cri: %w", err)
+		}
+	}
+
+	var record *runtime.HistoryRecord
+	if c.history != nil {
+		record = runtime.CaptureRecord(ctx, c)
+	}
+
+	if _, err := c.client.RemoveContainer(ctx, &cri.RemoveContainerRequest{ContainerId: c.id}); err != nil {
+		return translateErr(err)
+	}
+	if c.history != nil {
+		c.history.Add(record)
+	}
+	return nil
+}
+
+// ExecSync runs a command in the container and waits for it to exit. CRI
+// timeouts have a resolution of seconds, so the timeout is rounded up.
+func (c *Container) ExecSync(
+	ctx context.Context,
+	cmd []string,
+	timeout time.Duration,
+) (*runtime.ExecResult, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	resp, err := c.client.ExecSync(ctx, &cri.ExecSyncRequest{
+		ContainerId: c.id,
+		Cmd:         cmd,
+		Timeout:     int64((timeout + time.Second - 1) / time.Second),
+	})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	return &runtime.ExecResult{
+		ExitCode: int(resp.ExitCode),
+		Stdout:   resp.Stdout,
+		Stderr:   resp.Stderr,
+	}, nil
 }
 
 // Stats scrapes stats information about the container and returns it.
 // This includes information about memory, cpu, network and block IO.
+//
+// Stats are read directly from the container's cgroup, so the caller must share
+// the host's PID namespace and cgroupfs. CPU usage is omitted from the first
+// sample since it is measured between calls.
 func (c *Container) Stats(ctx context.Context) (*runtime.ContainerStats, error) {
-	return nil, runtime.ErrNotImplemented
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	c.collectorLock.Lock()
+	collector := c.collector
+	c.collectorLock.Unlock()
+
+	if collector == nil {
+		info, err := c.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case info.Status == runtime.StatusCreated:
+			return nil, runtime.ErrNotStarted
+		case info.Status != runtime.StatusRunning:
+			return nil, errors.New("cri: container is not running")
+		case info.PID == 0:
+			return nil, fmt.Errorf("cri: container process is unknown (%w)", runtime.ErrNotImplemented)
+		}
+		if collector, err = cgroup.NewCollector(info.PID); err != nil {
+			return nil, err
+		}
+
+		c.collectorLock.Lock()
+		c.collector = collector
+		c.collectorLock.Unlock()
+	}
+
+	return collector.Stats()
 }
 
 func translateErr(err error) error {
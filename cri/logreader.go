@@ -34,15 +34,18 @@ type LogReader struct {
 
 	buf   *bufio.Reader
 	parse parseFunc
+
+	opts     logging.ReaderOpts
+	lastTime time.Time
 }
 
 // NewLogReader wraps a streaming log reader. The provided reader must
-// include timestamps.
+// include timestamps. Messages are normalized according to opts.
 //
 // The reader introduces its own buffering and may read data from r beyond the
 // bytes requested by Read().
-func NewLogReader(r io.Reader, since time.Time) *LogReader {
-	lr := &LogReader{r: r, buf: bufio.NewReader(r), since: since}
+func NewLogReader(r io.Reader, since time.Time, opts logging.ReaderOpts) *LogReader {
+	lr := &LogReader{r: r, buf: bufio.NewReader(r), since: since, opts: opts}
 	return lr
 }
 
@@ -93,7 +96,9 @@ func (r *LogReader) ReadMessage() (*logging.Message, error) {
 		}
 	}
 
-	msg.Time = msg.Time.UTC() // TODO: Should we leave this to the caller?
+	msg.Time = r.opts.NormalizeTime(msg.Time, r.lastTime)
+	r.lastTime = msg.Time
+	msg.Text, msg.Encoding = r.opts.NormalizeText(msg.Text)
 	return msg, nil
 }
 
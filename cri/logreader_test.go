@@ -15,35 +15,35 @@ import (
 
 func TestLogReader(t *testing.T) {
 	t.Run("EmptyLog", func(t *testing.T) {
-		r := NewLogReader(strings.NewReader(""), time.Time{})
+		r := NewLogReader(strings.NewReader(""), time.Time{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Equal(t, io.EOF, err)
 		assert.Nil(t, m)
 	})
 
 	t.Run("UnexpectedEOF", func(t *testing.T) {
-		r := NewLogReader(strings.NewReader("no line ending!"), time.Time{})
+		r := NewLogReader(strings.NewReader("no line ending!"), time.Time{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Equal(t, io.ErrUnexpectedEOF, err)
 		assert.Nil(t, m)
 	})
 
 	t.Run("EmptyLog", func(t *testing.T) {
-		r := NewLogReader(strings.NewReader(""), time.Time{})
+		r := NewLogReader(strings.NewReader(""), time.Time{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Equal(t, io.EOF, err)
 		assert.Nil(t, m)
 	})
 
 	t.Run("InvalidFormat", func(t *testing.T) {
-		r := NewLogReader(strings.NewReader("foobar\n"), time.Time{})
+		r := NewLogReader(strings.NewReader("foobar\n"), time.Time{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.EqualError(t, err, `cri: unsupported log format: "foobar\n"`)
 		assert.Nil(t, m)
 	})
 
 	t.Run("ReadError", func(t *testing.T) {
-		r := NewLogReader(badReader{}, time.Time{})
+		r := NewLogReader(badReader{}, time.Time{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.EqualError(t, err, "cri: failed to read log: oh no")
 		assert.Nil(t, m)
@@ -57,7 +57,7 @@ func TestCRILogFormat(t *testing.T) {
 	logTime, _ := time.Parse(time.RFC3339Nano, logTimeStr)
 
 	t.Run("EmptyLine", func(t *testing.T) {
-		r := NewLogReader(strings.NewReader(logTimeStr+" stdout P \n"), time.Time{})
+		r := NewLogReader(strings.NewReader(logTimeStr+" stdout P \n"), time.Time{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		require.NoError(t, err)
 		assert.Equal(t, &logging.Message{Stream: logging.Stdout, Time: logTime.UTC(), Text: ""}, m)
@@ -68,7 +68,7 @@ func TestCRILogFormat(t *testing.T) {
 			logTimeStr+" stdout P First one thing...\n"+
 				logTimeStr+" stdout F  and then another\n"+
 				logTimeStr+" stderr F This is an error\n",
-		), time.Time{})
+		), time.Time{}, logging.ReaderOpts{})
 
 		m, err := r.ReadMessage()
 		require.NoError(t, err)
@@ -103,7 +103,7 @@ func TestCRILogFormat(t *testing.T) {
 		r := NewLogReader(strings.NewReader(
 			logTime.Add(-1).Format(time.RFC3339Nano)+" stdout F This should be skipped.\n"+
 				logTime.Format(time.RFC3339Nano)+" stdout F This is the first message.\n",
-		), logTime)
+		), logTime, logging.ReaderOpts{})
 
 		m, err := r.ReadMessage()
 		require.NoError(t, err)
@@ -127,7 +127,7 @@ func TestJSONLogFormat(t *testing.T) {
 	t.Run("EmptyLine", func(t *testing.T) {
 		r := NewLogReader(strings.NewReader(
 			`{"time":"`+logTimeStr+`"}`+"\n",
-		), time.Time{})
+		), time.Time{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		require.NoError(t, err)
 		assert.Equal(t, &logging.Message{Stream: logging.Stdout, Time: logTime.UTC(), Text: ""}, m)
@@ -138,7 +138,7 @@ func TestJSONLogFormat(t *testing.T) {
 			`{"time":"`+logTimeStr+`","stream":"stdout","log":"First one thing..."}`+"\n"+
 				`{"time":"`+logTimeStr+`","stream":"stdout","log":" and then another\n"}`+"\n"+
 				`{"time":"`+logTimeStr+`","stream":"stderr","log":"This is an error\n"}`+"\n",
-		), time.Time{})
+		), time.Time{}, logging.ReaderOpts{})
 
 		m, err := r.ReadMessage()
 		require.NoError(t, err)
@@ -169,11 +169,28 @@ func TestJSONLogFormat(t *testing.T) {
 		assert.Equal(t, io.EOF, err)
 	})
 
+	t.Run("ReaderOpts", func(t *testing.T) {
+		r := NewLogReader(strings.NewReader(
+			`{"time":"2021-08-01T12:00:00+02:00","stream":"stdout","log":"first\n"}`+"\n"+
+				`{"time":"2021-08-01T12:00:00+02:00","stream":"stdout","log":"second\n"}`+"\n",
+		), time.Time{}, logging.ReaderOpts{PreserveZone: true, Monotonic: true})
+		first, err := time.Parse(time.RFC3339Nano, "2021-08-01T12:00:00+02:00")
+		require.NoError(t, err)
+
+		m, err := r.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, first.String(), m.Time.String())
+
+		m, err = r.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, first.Add(time.Nanosecond).String(), m.Time.String())
+	})
+
 	t.Run("Since", func(t *testing.T) {
 		r := NewLogReader(strings.NewReader(
 			`{"time":"`+logTime.Add(-1).Format(time.RFC3339Nano)+`","stream":"stdout","log":"This should be skipped.\n"}`+"\n"+
 				`{"time":"`+logTimeStr+`","stream":"stdout","log":"This is the first message.\n"}`+"\n",
-		), logTime)
+		), logTime, logging.ReaderOpts{})
 
 		m, err := r.ReadMessage()
 		require.NoError(t, err)
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	cri "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -24,30 +26,78 @@ const (
 	// e.g. "0", "0,1", "all", "GPU-0a5c0cf4-eb7d-4fdd-40ea-4ac6803659ab".
 	visibleDevicesEnv = "NVIDIA_VISIBLE_DEVICES"
 	pathDneError      = "path does not exist"
+
+	// The CFS period applied when a container limits CPUs without specifying one.
+	defaultCPUPeriod = 100 * time.Millisecond
 )
 
 // Runtime wraps the Docker runtime in a common interface.
 type Runtime struct {
 	conn   *grpc.ClientConn
 	client cri.RuntimeServiceClient
+	life   *runtime.Lifecycle
+	onCall func(runtime.APICall)
+
+	// Containers being removed on exit. See ContainerOpts.AutoRemove.
+	removals *runtime.Removals
+
+	// Records of removed containers, if retained.
+	history *runtime.History
 }
 
 // NewRuntime creates a new cri-backed Runtime.
 func NewRuntime(ctx context.Context, address string) (*Runtime, error) {
-	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	r := &Runtime{life: runtime.NewLifecycle(), removals: runtime.NewRemovals()}
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithUnaryInterceptor(r.observeCall))
 	if err != nil {
 		return nil, fmt.Errorf("cri: couldn't connect to %q: %w", address, err)
 	}
 
-	return &Runtime{
-		conn:   conn,
-		client: cri.NewRuntimeServiceClient(conn),
-	}, nil
+	r.conn = conn
+	r.client = cri.NewRuntimeServiceClient(conn)
+	return r, nil
 }
 
-// Close implements the io.Closer interface.
+// Close implements the io.Closer interface. It shuts down the runtime, waiting
+// up to runtime.ShutdownTimeout for in-flight operations.
 func (r *Runtime) Close() error {
-	return r.conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), runtime.ShutdownTimeout)
+	defer cancel()
+	return r.Shutdown(ctx)
+}
+
+// Shutdown implements runtime.Shutdowner.
+func (r *Runtime) Shutdown(ctx context.Context) error {
+	err := r.life.Shutdown(ctx)
+	if closeErr := r.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Ping verifies that the CRI runtime is reachable and reports itself ready.
+func (r *Runtime) Ping(ctx context.Context) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if _, err := r.client.Version(ctx, &cri.VersionRequest{}); err != nil {
+		return fmt.Errorf("cri: getting version: %w", err)
+	}
+
+	resp, err := r.client.Status(ctx, &cri.StatusRequest{})
+	if err != nil {
+		return fmt.Errorf("cri: getting status: %w", err)
+	}
+	for _, cond := range resp.GetStatus().GetConditions() {
+		if cond.Type == cri.RuntimeReady && !cond.Status {
+			return fmt.Errorf("cri: runtime is not ready: %s", cond.Message)
+		}
+	}
+	return nil
 }
 
 // PullImage pulls a Docker image and prints progress to stdout unless quiet is set.
@@ -57,6 +107,12 @@ func (r *Runtime) PullImage(
 	policy runtime.PullPolicy,
 	quiet bool,
 ) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
 	return runtime.ErrNotImplemented
 }
 
@@ -65,6 +121,12 @@ func (r *Runtime) CreateContainer(
 	ctx context.Context,
 	opts *runtime.ContainerOpts,
 ) (runtime.Container, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	if opts.SharedMemory != 0 {
 		// There doesn't seem to be a way to set the size of /dev/shm (like we do in Docker) or
 		// mount an in-memory volume (like we do in K8s) in CRI.
@@ -78,20 +140,80 @@ func (r *Runtime) CreateContainer(
 	if _, ok := opts.Labels[managedLabel]; ok {
 		return nil, fmt.Errorf("forbidden label: %s", managedLabel)
 	}
+	for _, key := range []string{
+		runtime.ConfigHashLabel,
+		runtime.IdempotencyKeyLabel,
+		runtime.CreateTokenLabel,
+		runtime.AutoRemoveLabel,
+		runtime.CPUBurstLabel,
+		runtime.HooksLabel,
+	} {
+		if _, ok := opts.Labels[key]; ok {
+			return nil, fmt.Errorf("forbidden label: %s", key)
+		}
+	}
+	configHash, err := opts.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if opts.IdempotencyKey != "" {
+		existing, err := r.findByIdempotencyKey(ctx, opts.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if err := runtime.CheckIdempotent(ctx, existing, configHash); err != nil {
+				return nil, err
+			}
+			return existing, nil
+		}
+	}
+
+	if err := opts.ValidateSSHAgent(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateX11(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateCPUBurst(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateSensitiveEnv(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateHooks(); err != nil {
+		return nil, err
+	}
+	hooks, err := runtime.EncodeHooks(opts.PostStart, opts.PreStop)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Image.PullPolicy != "" {
+		// Images can't be pulled through CRI yet; see PullImage.
+		return nil, fmt.Errorf("image pull policies are not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+
+	entrypoint, args, err := opts.ResolveCommand()
+	if err != nil {
+		return nil, err
+	}
 
 	// TODO: Set UID and GID via LinuxContainerSecurityContext.
 	// TODO: Apply a namespace via LinuxContainerSecurityContext.
 	cconf := &cri.ContainerConfig{
 		Metadata:   &cri.ContainerMetadata{Name: opts.Name},
 		Image:      &cri.ImageSpec{Image: opts.Image.Tag},
-		Command:    opts.Command,
-		Args:       opts.Arguments,
+		Command:    entrypoint,
+		Args:       args,
 		WorkingDir: opts.WorkingDir,
 		Linux:      &cri.LinuxContainerConfig{},
 	}
 
-	// Generate a random name if none was provided.
-	if cconf.Metadata.Name == "" {
+	// Derive a name from the idempotency key, or generate a random one, if
+	// none was provided.
+	if cconf.Metadata.Name == "" && opts.IdempotencyKey != "" {
+		cconf.Metadata.Name = runtime.IdempotentName(opts.IdempotencyKey)
+	} else if cconf.Metadata.Name == "" {
 		cconf.Metadata.Name = unique.NewID().String()
 	}
 
@@ -100,13 +222,38 @@ func (r *Runtime) CreateContainer(
 		cconf.Tty = true
 	}
 
-	cconf.Labels = make(map[string]string, len(opts.Labels)+1)
+	if opts.Init {
+		// CRI has no equivalent of Docker's injected init process.
+		opts.Warn("init processes are not supported by CRI; " +
+			"use an image whose entrypoint is an init such as tini to reap zombie processes")
+	}
+
+	cconf.Labels = make(map[string]string, len(opts.Labels)+3)
 	cconf.Labels[managedLabel] = "true"
+	cconf.Labels[runtime.ConfigHashLabel] = configHash
+	token := runtime.NewCreateToken()
+	cconf.Labels[runtime.CreateTokenLabel] = token
+	if opts.IdempotencyKey != "" {
+		cconf.Labels[runtime.IdempotencyKeyLabel] = opts.IdempotencyKey
+	}
+	if opts.AutoRemove {
+		// CRI has no equivalent of Docker's auto-remove, so it's recorded for
+		// the runtime to remove it. See Runtime.Container.
+		cconf.Labels[runtime.AutoRemoveLabel] = "true"
+	}
+	if opts.CPUBurst != 0 {
+		// The burst is applied when the container starts. See Container.Start.
+		cconf.Labels[runtime.CPUBurstLabel] = opts.CPUBurst.String()
+	}
+	if hooks != "" {
+		// CRI has no hooks, so they're run through exec. See Container.Start.
+		cconf.Labels[runtime.HooksLabel] = hooks
+	}
 	for k, v := range opts.Labels {
 		cconf.Labels[k] = v
 	}
 
-	for k, v := range opts.Env {
+	for k, v := range opts.ResolveEnv() {
 		cconf.Envs = append(cconf.Envs, &cri.KeyValue{Key: k, Value: v})
 	}
 
@@ -122,22 +269,51 @@ func (r *Runtime) CreateContainer(
 			Readonly:      m.ReadOnly,
 		}
 	}
+	if opts.SSHAgent != "" {
+		source, err := filepath.Abs(opts.SSHAgent)
+		if err != nil {
+			return nil, fmt.Errorf("translating to absolute path: %w", err)
+		}
+		cconf.Mounts = append(cconf.Mounts, &cri.Mount{
+			HostPath:      source,
+			ContainerPath: runtime.SSHAgentPath,
+		})
+		cconf.Envs = append(cconf.Envs, &cri.KeyValue{Key: "SSH_AUTH_SOCK", Value: runtime.SSHAgentPath})
+	}
+	if opts.X11 != nil {
+		for _, m := range opts.X11.Mounts() {
+			cconf.Mounts = append(cconf.Mounts, &cri.Mount{
+				HostPath:      m.HostPath,
+				ContainerPath: m.ContainerPath,
+				Readonly:      m.ReadOnly,
+			})
+		}
+		for k, v := range opts.X11.Env() {
+			cconf.Envs = append(cconf.Envs, &cri.KeyValue{Key: k, Value: v})
+		}
+	}
 
 	// Set hardware limits.
 	cconf.Linux.Resources = &cri.LinuxContainerResources{}
 	if mem := opts.Memory; mem != 0 {
 		const minimum = 4 * 1024 * 1024
 		if mem < minimum {
+			opts.Warn("memory limit raised from %d to the minimum of %d bytes", mem, minimum)
 			mem = minimum
 		}
 		cconf.Linux.Resources.MemoryLimitInBytes = mem
 	}
 	if opts.CPUShares != 0 {
 		cconf.Linux.Resources.CpuShares = opts.CPUShares
-	} else if opts.CPUCount != 0 {
+	}
+	if opts.CPUCount != 0 {
 		// CPU period and quota are in microseconds.
-		cconf.Linux.Resources.CpuPeriod = 100000
-		cconf.Linux.Resources.CpuQuota = int64(opts.CPUCount * 100000)
+		period := defaultCPUPeriod
+		if opts.CPUPeriod != 0 {
+			period = opts.CPUPeriod
+		}
+		cconf.Linux.Resources.CpuPeriod = period.Microseconds()
+		cconf.Linux.Resources.CpuQuota = int64(opts.CPUCount * float64(period.Microseconds()))
 	}
 	if len(opts.GPUs) != 0 {
 		// TODO: Mount GPU device. Compare whatever GKE does under the hood.
@@ -152,8 +328,92 @@ func (r *Runtime) CreateContainer(
 		cconf.Linux.Resources.OomScoreAdj = 1000
 	}
 
+	namespaces := &cri.NamespaceOption{}
+	if opts.IPCMode != "" {
+		ipc, err := ipcNamespaceMode(opts.IPCMode)
+		if err != nil {
+			return nil, err
+		}
+		namespaces.Ipc = ipc
+	}
+	if opts.StopSignal != "" {
+		// CRI always stops containers with the image's stop signal.
+		return nil, fmt.Errorf("stop signals are not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if len(opts.Ports) != 0 {
+		// Port mappings are configured per pod sandbox, not per container.
+		return nil, fmt.Errorf("published ports are not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.CoreDumpDir != "" {
+		// CRI can't raise a container's core size limit.
+		return nil, fmt.Errorf("core dump collection is not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.MaxRuntime != 0 {
+		// CRI has no deadlines; Kubernetes enforces them per pod.
+		return nil, fmt.Errorf("max runtime is not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.Network != "" {
+		// Networks are configured per pod sandbox, not per container.
+		return nil, fmt.Errorf("user-defined networks are not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.IngressBandwidth != 0 || opts.EgressBandwidth != 0 {
+		// Bandwidth is limited per pod sandbox, not per container.
+		return nil, fmt.Errorf("bandwidth limits are not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.DNSPolicy != runtime.DNSDefault || opts.DNSConfig != nil {
+		// DNS is configured per pod sandbox, not per container.
+		return nil, fmt.Errorf("DNS options are not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.NetworkFrom != "" {
+		// CRI only allows targeting another container's PID namespace.
+		return nil, fmt.Errorf("joining a network namespace is not supported on CRI (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.PIDFrom != "" {
+		if err := r.checkManaged(ctx, opts.PIDFrom); err != nil {
+			return nil, fmt.Errorf("joining PID namespace: %w", err)
+		}
+		namespaces.Pid = cri.NamespaceMode_TARGET
+		namespaces.TargetId = opts.PIDFrom
+	}
+	var dependency runtime.Container
+	if s := opts.Schedule; s != nil {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		if s.After != "" {
+			if err := r.checkManaged(ctx, s.After); err != nil {
+				return nil, fmt.Errorf("scheduling start: %w", err)
+			}
+			dependency = r.Container(s.After)
+		}
+	}
+	if *namespaces != (cri.NamespaceOption{}) {
+		cconf.Linux.SecurityContext = &cri.LinuxContainerSecurityContext{NamespaceOptions: namespaces}
+	}
+
 	c, err := r.client.CreateContainer(ctx, &cri.CreateContainerRequest{Config: cconf})
 	if err != nil {
+		if ctx.Err() != nil && opts.IdempotencyKey == "" {
+			// The runtime may have created the container after the request was
+			// canceled. Find it by this call's token, so that a container of
+			// the same name which predates the call is left alone, and remove
+			// it. Containers with idempotency keys are kept for the caller's
+			// retry.
+			name := cconf.Metadata.Name
+			runtime.CleanupAsync(r.life, "container "+name, func(ctx context.Context) error {
+				return r.removeByToken(ctx, token)
+			})
+		}
+		if opts.IdempotencyKey != "" && ctx.Err() == nil {
+			// A concurrent request with the same key may have taken the name.
+			if existing, _ := r.findByIdempotencyKey(ctx, opts.IdempotencyKey); existing != nil {
+				if err := runtime.CheckIdempotent(ctx, existing, configHash); err != nil {
+					return nil, err
+				}
+				return existing, nil
+			}
+		}
+
 		msg := err.Error()
 		if i := strings.Index(msg, pathDneError); i != -1 {
 			// Sanitize mounting errors for cleaner presentation.
@@ -162,15 +422,197 @@ func (r *Runtime) CreateContainer(
 		return nil, err
 	}
 
-	return r.Container(c.ContainerId), nil
+	ctr := r.container(c.ContainerId)
+	if opts.AutoRemove {
+		r.removals.Arm(r.life, ctr)
+	}
+	if opts.Schedule != nil {
+		runtime.ScheduleStart(r.life.Context(), ctr, dependency, opts.Schedule)
+	}
+	return ctr, nil
+}
+
+// checkManaged returns an error unless the container exists and is managed by
+// this runtime.
+func (r *Runtime) checkManaged(ctx context.Context, id string) error {
+	resp, err := r.client.ContainerStatus(ctx, &cri.ContainerStatusRequest{ContainerId: id})
+	if err != nil {
+		return translateErr(err)
+	}
+	if _, ok := resp.GetStatus().GetLabels()[managedLabel]; !ok {
+		return fmt.Errorf("container %s is not managed by this runtime", id)
+	}
+	return nil
+}
+
+// ipcNamespaceMode translates an IPC mode to a CRI namespace mode. CRI only
+// supports sharing IPC namespaces within a pod, and has no IPC namespace of a
+// single container, so private namespaces aren't supported.
+func ipcNamespaceMode(mode runtime.IPCMode) (cri.NamespaceMode, error) {
+	if err := mode.Validate(); err != nil {
+		return 0, err
+	}
+	switch mode {
+	case runtime.IPCShareable:
+		return cri.NamespaceMode_POD, nil
+	case runtime.IPCHost:
+		return cri.NamespaceMode_NODE, nil
+	default:
+		return 0, fmt.Errorf("IPC mode %q is not supported on CRI (%w)", mode, runtime.ErrNotImplemented)
+	}
+}
+
+// findByIdempotencyKey returns the managed container created with the given
+// idempotency key, or nil if there is none.
+func (r *Runtime) findByIdempotencyKey(ctx context.Context, key string) (runtime.Container, error) {
+	resp, err := r.client.ListContainers(ctx, &cri.ListContainersRequest{
+		Filter: &cri.ContainerFilter{LabelSelector: map[string]string{
+			managedLabel:                "true",
+			runtime.IdempotencyKeyLabel: key,
+		}},
+	})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	if len(resp.Containers) == 0 {
+		return nil, nil
+	}
+	return r.Container(resp.Containers[0].Id), nil
+}
+
+// removeByToken removes managed containers stamped with a create token.
+func (r *Runtime) removeByToken(ctx context.Context, token string) error {
+	resp, err := r.client.ListContainers(ctx, &cri.ListContainersRequest{
+		Filter: &cri.ContainerFilter{LabelSelector: map[string]string{
+			managedLabel:             "true",
+			runtime.CreateTokenLabel: token,
+		}},
+	})
+	if err != nil {
+		return translateErr(err)
+	}
+	for _, c := range resp.Containers {
+		if err := r.container(c.Id).Remove(ctx, runtime.RemoveOpts{}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ListContainers enumerates all containers.
 func (r *Runtime) ListContainers(ctx context.Context) ([]runtime.Container, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	return nil, runtime.ErrNotImplemented
 }
 
-// Container creates an interface to an existing container.
+// RuntimeName returns the name the CRI runtime reports for itself, e.g.
+// "containerd", "cri-o", or "docker" for dockershim.
+func (r *Runtime) RuntimeName(ctx context.Context) (string, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer end()
+
+	resp, err := r.client.Version(ctx, &cri.VersionRequest{})
+	if err != nil {
+		return "", fmt.Errorf("cri: getting version: %w", err)
+	}
+	return resp.RuntimeName, nil
+}
+
+// Instance identifies one attempt at running a pod's container.
+type Instance struct {
+	ID string
+
+	// Attempt counts the times the container has been restarted in place.
+	Attempt   uint32
+	CreatedAt time.Time
+}
+
+// FindInstances lists containers, managed or not, with all of the given labels,
+// such as those kubelet sets on the containers it creates. Instances are
+// ordered from the latest attempt to the earliest.
+func (r *Runtime) FindInstances(ctx context.Context, labels map[string]string) ([]Instance, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	resp, err := r.client.ListContainers(ctx, &cri.ListContainersRequest{
+		Filter: &cri.ContainerFilter{LabelSelector: labels},
+	})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	instances := make([]Instance, len(resp.Containers))
+	for i, c := range resp.Containers {
+		instances[i] = Instance{
+			ID:        c.Id,
+			Attempt:   c.GetMetadata().GetAttempt(),
+			CreatedAt: time.Unix(0, c.CreatedAt),
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Attempt != instances[j].Attempt {
+			return instances[i].Attempt > instances[j].Attempt
+		}
+		return instances[i].CreatedAt.After(instances[j].CreatedAt)
+	})
+	return instances, nil
+}
+
+// RetainHistory records each container removed through the runtime in h, so
+// that it can be queried with History. It must be called before any containers
+// are created or listed.
+func (r *Runtime) RetainHistory(h *runtime.History) {
+	r.history = h
+}
+
+// History implements runtime.Historian. It fails unless RetainHistory was
+// called.
+func (r *Runtime) History(ctx context.Context, filter runtime.HistoryFilter) ([]runtime.HistoryRecord, error) {
+	_, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	if r.history == nil {
+		return nil, errors.New("cri: container history is not retained")
+	}
+	return r.history.Query(filter), nil
+}
+
+// Container creates an interface to an existing container. If the container
+// was created with AutoRemove, its removal on exit resumes in the background,
+// so that a new runtime removes it after its process restarts.
 func (r *Runtime) Container(id string) runtime.Container {
-	return &Container{r.client, id}
+	c := r.container(id)
+	r.armAutoRemoveAsync(c)
+	return c
+}
+
+func (r *Runtime) container(id string) *Container {
+	return &Container{client: r.client, life: r.life, history: r.history, id: id}
+}
+
+// armAutoRemoveAsync checks in the background whether a container was created
+// with AutoRemove and, if so, arms its removal unless it's already armed.
+func (r *Runtime) armAutoRemoveAsync(c *Container) {
+	if r.removals.IsArmed(c.id) {
+		return
+	}
+	r.life.Go(func(ctx context.Context) {
+		resp, err := r.client.ContainerStatus(ctx, &cri.ContainerStatusRequest{ContainerId: c.id})
+		if err == nil && resp.Status.GetLabels()[runtime.AutoRemoveLabel] != "" {
+			r.removals.Arm(r.life, c)
+		}
+	})
 }
@@ -5,10 +5,13 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/beaker/runtime"
 	"github.com/beaker/runtime/internal/test"
+	cri "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
 const testCRIKey = "TEST_CRI_ADDRESS"
@@ -25,5 +28,20 @@ func TestCRI(t *testing.T) {
 	rt, err := NewRuntime(context.Background(), address)
 	require.NoError(t, err)
 
-	suite.Run(t, test.NewRuntimeSuite(rt))
+	suite.Run(t, test.NewRuntimeSuite(rt).ReportTo("cri", os.Getenv(test.ReportEnv)))
+}
+
+func TestIPCNamespaceMode(t *testing.T) {
+	mode, err := ipcNamespaceMode(runtime.IPCShareable)
+	require.NoError(t, err)
+	assert.Equal(t, cri.NamespaceMode_POD, mode)
+	mode, err = ipcNamespaceMode(runtime.IPCHost)
+	require.NoError(t, err)
+	assert.Equal(t, cri.NamespaceMode_NODE, mode)
+
+	// CRI has no IPC namespace of a single container.
+	_, err = ipcNamespaceMode(runtime.IPCPrivate)
+	assert.ErrorIs(t, err, runtime.ErrNotImplemented)
+	_, err = ipcNamespaceMode(runtime.IPCContainer("other"))
+	assert.ErrorIs(t, err, runtime.ErrNotImplemented)
 }
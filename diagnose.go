@@ -0,0 +1,203 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/beaker/runtime/logging"
+)
+
+// DefaultDiagnosticImage is a small image with a shell, used by Diagnose
+// unless another is given.
+const DefaultDiagnosticImage = "busybox:latest"
+
+// diagnosticMarker is printed by the diagnostic container to verify logs.
+const diagnosticMarker = "beaker-runtime-diagnostic"
+
+// DiagnoseOpts configures Diagnose.
+type DiagnoseOpts struct {
+	// (optional) Image must include sh, echo, and sleep. Defaults to
+	// DefaultDiagnosticImage.
+	Image *DockerImage
+
+	// (optional) Timeout bounds the whole check. Defaults to one minute.
+	Timeout time.Duration
+}
+
+// DiagnosticStep is the outcome of one step of a diagnostic check.
+type DiagnosticStep struct {
+	Name     string
+	Duration time.Duration
+
+	// Err is set if the step failed.
+	Err error
+
+	// Skipped is set if the step wasn't attempted because an earlier one
+	// failed.
+	Skipped bool
+}
+
+// DiagnosticReport describes which of a runtime's capabilities work and how
+// long they took, e.g. to verify a new node or to attach to a support ticket.
+type DiagnosticReport struct {
+	Steps []DiagnosticStep
+
+	// Capabilities lists the optional interfaces implemented by the runtime
+	// and its containers, e.g. "Execer".
+	Capabilities []string
+}
+
+// OK returns true if every step succeeded.
+func (r *DiagnosticReport) OK() bool {
+	for _, step := range r.Steps {
+		if step.Err != nil || step.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the report as a table.
+func (r *DiagnosticReport) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tRESULT\tDURATION")
+	for _, step := range r.Steps {
+		result := "ok"
+		switch {
+		case step.Skipped:
+			result = "skipped"
+		case step.Err != nil:
+			result = "failed: " + step.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", step.Name, result, step.Duration.Round(time.Millisecond))
+	}
+	w.Flush()
+	fmt.Fprintf(&b, "Capabilities: %s\n", strings.Join(r.Capabilities, ", "))
+	return b.String()
+}
+
+// Diagnose runs a quick end-to-end check of a runtime: it pulls a small
+// image, then creates, starts, inspects, and removes a container which prints
+// to its logs. Each step is timed. Steps which depend on a failed step are
+// skipped, but a container is always removed once created.
+func Diagnose(ctx context.Context, rt Runtime, opts DiagnoseOpts) *DiagnosticReport {
+	image := opts.Image
+	if image == nil {
+		image = &DockerImage{Tag: DefaultDiagnosticImage}
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	report := &DiagnosticReport{}
+	failed := false
+	step := func(name string, fn func() error) {
+		if failed {
+			report.Steps = append(report.Steps, DiagnosticStep{Name: name, Skipped: true})
+			return
+		}
+		start := time.Now()
+		err := fn()
+		report.Steps = append(report.Steps, DiagnosticStep{Name: name, Duration: time.Since(start), Err: err})
+		failed = err != nil
+	}
+
+	if _, ok := rt.(Shutdowner); ok {
+		report.Capabilities = append(report.Capabilities, "Shutdowner")
+	}
+	if _, ok := rt.(Historian); ok {
+		report.Capabilities = append(report.Capabilities, "Historian")
+	}
+
+	var c Container
+	step("ping", func() error { return rt.Ping(ctx) })
+	step("pull", func() error { return rt.PullImage(ctx, image, PullIfMissing, true) })
+	step("create", func() (err error) {
+		c, err = rt.CreateContainer(ctx, &ContainerOpts{
+			Image:      image,
+			Entrypoint: []string{"sh", "-c", "echo " + diagnosticMarker + " && sleep 2"},
+		})
+		return err
+	})
+	if c != nil {
+		if _, ok := c.(Signaler); ok {
+			report.Capabilities = append(report.Capabilities, "Signaler")
+		}
+		if _, ok := c.(Execer); ok {
+			report.Capabilities = append(report.Capabilities, "Execer")
+		}
+	}
+	step("start", func() error { return c.Start(ctx) })
+	step("stats", func() error { return pollStats(ctx, c) })
+	step("wait", func() error {
+		info, err := WaitForExit(ctx, c)
+		if err != nil {
+			return err
+		}
+		if info.ExitCode == nil || *info.ExitCode != 0 {
+			return fmt.Errorf("container exited unsuccessfully: %s", ClassifyExit(info))
+		}
+		return nil
+	})
+	step("logs", func() error { return checkLogs(ctx, c) })
+
+	// Clean up regardless of earlier failures, even if the check timed out.
+	if c != nil {
+		failed = false
+	}
+	step("remove", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		return c.Remove(ctx, RemoveOpts{})
+	})
+	return report
+}
+
+// pollStats samples a container's stats, retrying while it starts up.
+func pollStats(ctx context.Context, c Container) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := c.Stats(ctx)
+		if err == nil || !errors.Is(err, ErrNotStarted) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkLogs verifies that the diagnostic container's output was logged.
+func checkLogs(ctx context.Context, c Container) error {
+	logs, err := c.Logs(ctx, LogsOpts{})
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	for {
+		msg, err := logs.ReadMessage()
+		if err == io.EOF {
+			return errors.New("expected output is missing from logs")
+		} else if err != nil {
+			return err
+		}
+		if msg.Stream == logging.Stdout && strings.Contains(msg.Text, diagnosticMarker) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime/logging"
+)
+
+// diagnosticContainer behaves like a container running the diagnostic command.
+type diagnosticContainer struct {
+	loggingContainer
+	removed bool
+}
+
+func (c *diagnosticContainer) Info(ctx context.Context) (*ContainerInfo, error) {
+	code := 0
+	return &ContainerInfo{Status: StatusExited, ExitCode: &code}, nil
+}
+
+func (c *diagnosticContainer) Stats(ctx context.Context) (*ContainerStats, error) {
+	return &ContainerStats{Time: time.Now()}, nil
+}
+
+func (c *diagnosticContainer) Remove(ctx context.Context, opts RemoveOpts) error {
+	c.removed = true
+	return nil
+}
+
+// diagnosticRuntime creates diagnosticContainers unless pulls fail.
+type diagnosticRuntime struct {
+	memoryRuntime
+	pullErr   error
+	container *diagnosticContainer
+}
+
+func (r *diagnosticRuntime) PullImage(ctx context.Context, image *DockerImage, policy PullPolicy, quiet bool) error {
+	return r.pullErr
+}
+
+func (r *diagnosticRuntime) CreateContainer(ctx context.Context, opts *ContainerOpts) (Container, error) {
+	r.container = &diagnosticContainer{loggingContainer: loggingContainer{logs: []logging.Message{
+		{Stream: logging.Stdout, Time: time.Now(), Text: diagnosticMarker + "\n"},
+	}}}
+	return r.container, nil
+}
+
+func TestDiagnose(t *testing.T) {
+	ctx := context.Background()
+	steps := func(report *DiagnosticReport) map[string]string {
+		results := make(map[string]string)
+		for _, step := range report.Steps {
+			switch {
+			case step.Skipped:
+				results[step.Name] = "skipped"
+			case step.Err != nil:
+				results[step.Name] = "failed"
+			default:
+				results[step.Name] = "ok"
+			}
+		}
+		return results
+	}
+
+	t.Run("Healthy", func(t *testing.T) {
+		rt := &diagnosticRuntime{}
+		report := Diagnose(ctx, rt, DiagnoseOpts{})
+		assert.True(t, report.OK(), report.String())
+		assert.Len(t, report.Steps, 8)
+		assert.True(t, rt.container.removed)
+	})
+
+	t.Run("PullFails", func(t *testing.T) {
+		rt := &diagnosticRuntime{pullErr: errors.New("registry unreachable")}
+		report := Diagnose(ctx, rt, DiagnoseOpts{})
+		require.False(t, report.OK())
+		assert.Equal(t, map[string]string{
+			"ping":   "ok",
+			"pull":   "failed",
+			"create": "skipped",
+			"start":  "skipped",
+			"stats":  "skipped",
+			"wait":   "skipped",
+			"logs":   "skipped",
+			"remove": "skipped",
+		}, steps(report))
+		assert.Contains(t, report.String(), "failed: registry unreachable")
+	})
+}
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ImageDigests records the local image ID each tag resolved to when it was
+// pulled, so containers created later can be checked against the image that
+// was validated. A background prune or another pull may replace a tag's image
+// in the meantime; see each runtime's VerifyImages.
+//
+// Digests may be persisted to a file so they survive restarts of the node's
+// agent. An ImageDigests is safe for concurrent use.
+type ImageDigests struct {
+	path string
+
+	mu      sync.Mutex
+	digests map[string]string
+}
+
+// NewImageDigests creates an image digest cache. If path is non-empty, digests
+// are loaded from and saved to that file; a missing file is treated as empty.
+func NewImageDigests(path string) (*ImageDigests, error) {
+	d := &ImageDigests{path: path, digests: make(map[string]string)}
+	if path == "" {
+		return d, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &d.digests); err != nil {
+		return nil, fmt.Errorf("reading image digests from %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Record sets the image ID a tag was pulled as, replacing any earlier record.
+func (d *ImageDigests) Record(tag, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.digests[tag] == id {
+		return nil
+	}
+	d.digests[tag] = id
+	return d.save()
+}
+
+// Forget removes a tag's record.
+func (d *ImageDigests) Forget(tag string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.digests[tag]; !ok {
+		return nil
+	}
+	delete(d.digests, tag)
+	return d.save()
+}
+
+// Lookup returns the image ID a tag was last pulled as, if it was recorded.
+func (d *ImageDigests) Lookup(tag string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	id, ok := d.digests[tag]
+	return id, ok
+}
+
+// Verify checks that a tag's local image ID matches the recorded one. Tags
+// without a record always pass. On mismatch, an error wrapping ErrImageChanged
+// is returned.
+func (d *ImageDigests) Verify(tag, id string) error {
+	want, ok := d.Lookup(tag)
+	if !ok || want == id {
+		return nil
+	}
+	return fmt.Errorf("image %s is %s but was pulled as %s (%w)", tag, id, want, ErrImageChanged)
+}
+
+// save writes digests to the backing file, if any. The file is replaced
+// atomically so a crash can't leave it partially written.
+func (d *ImageDigests) save() error {
+	if d.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(d.digests)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(d.path), ".digests-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
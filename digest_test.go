@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageDigests(t *testing.T) {
+	d, err := NewImageDigests("")
+	require.NoError(t, err)
+
+	// Unrecorded tags always pass.
+	assert.NoError(t, d.Verify("alpine", "sha256:aaa"))
+
+	require.NoError(t, d.Record("alpine", "sha256:aaa"))
+	id, ok := d.Lookup("alpine")
+	assert.True(t, ok)
+	assert.Equal(t, "sha256:aaa", id)
+	assert.NoError(t, d.Verify("alpine", "sha256:aaa"))
+	assert.ErrorIs(t, d.Verify("alpine", "sha256:bbb"), ErrImageChanged)
+
+	require.NoError(t, d.Forget("alpine"))
+	_, ok = d.Lookup("alpine")
+	assert.False(t, ok)
+	assert.NoError(t, d.Verify("alpine", "sha256:bbb"))
+}
+
+func TestImageDigestsPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "digests.json")
+
+	d, err := NewImageDigests(path)
+	require.NoError(t, err)
+	require.NoError(t, d.Record("alpine", "sha256:aaa"))
+	require.NoError(t, d.Record("ubuntu", "sha256:bbb"))
+	require.NoError(t, d.Forget("ubuntu"))
+
+	d, err = NewImageDigests(path)
+	require.NoError(t, err)
+	id, ok := d.Lookup("alpine")
+	assert.True(t, ok)
+	assert.Equal(t, "sha256:aaa", id)
+	_, ok = d.Lookup("ubuntu")
+	assert.False(t, ok)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0644))
+	_, err = NewImageDigests(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DNSPolicy selects the base of a container's resolver configuration, to which
+// its DNSConfig is added.
+type DNSPolicy string
+
+const (
+	// DNSDefault uses the runtime's default resolver configuration: cluster
+	// DNS on Kubernetes and the node's configuration elsewhere.
+	DNSDefault DNSPolicy = ""
+
+	// DNSHost uses the node's resolver configuration.
+	DNSHost DNSPolicy = "host"
+
+	// DNSNone uses only the container's DNSConfig, which must name at least
+	// one nameserver.
+	DNSNone DNSPolicy = "none"
+)
+
+// Validate returns an error if the policy is not recognized.
+func (p DNSPolicy) Validate() error {
+	switch p {
+	case DNSDefault, DNSHost, DNSNone:
+		return nil
+	default:
+		return fmt.Errorf("%q is not a valid DNS policy", p)
+	}
+}
+
+// DNSConfig overrides parts of a container's resolv.conf.
+type DNSConfig struct {
+	// (optional) Nameservers are IP addresses of DNS servers.
+	Nameservers []string
+
+	// (optional) Searches are domains searched for unqualified hostnames.
+	Searches []string
+
+	// (optional) Options are resolver options as written in resolv.conf,
+	// such as "ndots:1" or "edns0".
+	Options []string
+}
+
+// ParseDNSOption splits a resolver option such as "ndots:1" into its name and
+// value. Options without a value, such as "edns0", return ok false.
+func ParseDNSOption(option string) (name, value string, ok bool) {
+	if i := strings.IndexByte(option, ':'); i != -1 {
+		return option[:i], option[i+1:], true
+	}
+	return option, "", false
+}
+
+// ValidateDNS checks that DNS options are well formed and consistent.
+func (o *ContainerOpts) ValidateDNS() error {
+	if err := o.DNSPolicy.Validate(); err != nil {
+		return err
+	}
+	c := o.DNSConfig
+	if c == nil {
+		if o.DNSPolicy == DNSNone {
+			return errors.New("DNS policy none requires a nameserver")
+		}
+		return nil
+	}
+	if o.DNSPolicy == DNSNone && len(c.Nameservers) == 0 {
+		return errors.New("DNS policy none requires a nameserver")
+	}
+	for _, ns := range c.Nameservers {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("%q is not a valid nameserver address", ns)
+		}
+	}
+	for _, search := range c.Searches {
+		if search == "" || strings.ContainsAny(search, " \t\n") {
+			return fmt.Errorf("%q is not a valid search domain", search)
+		}
+	}
+	for _, option := range c.Options {
+		if name, _, _ := ParseDNSOption(option); name == "" || strings.ContainsAny(option, " \t\n") {
+			return fmt.Errorf("%q is not a valid resolver option", option)
+		}
+	}
+	return nil
+}
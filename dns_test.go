@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDNSOption(t *testing.T) {
+	name, value, ok := ParseDNSOption("ndots:1")
+	assert.Equal(t, "ndots", name)
+	assert.Equal(t, "1", value)
+	assert.True(t, ok)
+
+	name, value, ok = ParseDNSOption("edns0")
+	assert.Equal(t, "edns0", name)
+	assert.Empty(t, value)
+	assert.False(t, ok)
+}
+
+func TestValidateDNS(t *testing.T) {
+	for _, valid := range []ContainerOpts{
+		{},
+		{DNSPolicy: DNSHost},
+		{DNSConfig: &DNSConfig{Options: []string{"ndots:1", "edns0"}}},
+		{DNSPolicy: DNSNone, DNSConfig: &DNSConfig{
+			Nameservers: []string{"10.0.0.10", "fd00::10"},
+			Searches:    []string{"cluster.local"},
+		}},
+	} {
+		assert.NoError(t, valid.ValidateDNS(), valid)
+	}
+
+	for _, invalid := range []ContainerOpts{
+		{DNSPolicy: "cluster"},
+		{DNSPolicy: DNSNone},
+		{DNSPolicy: DNSNone, DNSConfig: &DNSConfig{Searches: []string{"cluster.local"}}},
+		{DNSConfig: &DNSConfig{Nameservers: []string{"dns.local"}}},
+		{DNSConfig: &DNSConfig{Searches: []string{""}}},
+		{DNSConfig: &DNSConfig{Options: []string{":1"}}},
+		{DNSConfig: &DNSConfig{Options: []string{"ndots 1"}}},
+	} {
+		assert.Error(t, invalid.ValidateDNS(), invalid)
+	}
+}
@@ -0,0 +1,174 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/beaker/runtime"
+)
+
+// backendName identifies Docker in API metrics.
+const backendName = "docker"
+
+// ObserveAPI registers a function to receive metrics for each request to
+// dockerd. It must be called before the runtime is used.
+func (r *Runtime) ObserveAPI(fn func(runtime.APICall)) {
+	r.client.onCall = fn
+}
+
+// apiClient is a Docker client which reports the requests the runtime makes.
+// Methods the runtime doesn't call, and ContainerWait, whose latency is that
+// of the container rather than dockerd, pass through unobserved.
+type apiClient struct {
+	*client.Client
+	onCall func(runtime.APICall)
+}
+
+func (c *apiClient) observe(operation string, start time.Time, err *error) {
+	if c.onCall == nil {
+		return
+	}
+	call := runtime.APICall{Backend: backendName, Operation: operation, Duration: time.Since(start), Err: *err}
+	switch {
+	case *err == nil:
+	case client.IsErrNotFound(*err):
+		call.Class = runtime.APIErrorNotFound
+	case client.IsErrConnectionFailed(*err):
+		call.Class = runtime.APIErrorUnavailable
+	default:
+		call.Class = runtime.ClassifyAPIError(*err)
+	}
+	c.onCall(call)
+}
+
+func (c *apiClient) Ping(ctx context.Context) (_ types.Ping, err error) {
+	defer c.observe("Ping", time.Now(), &err)
+	return c.Client.Ping(ctx)
+}
+
+func (c *apiClient) ImageInspectWithRaw(ctx context.Context, ref string) (_ types.ImageInspect, _ []byte, err error) {
+	defer c.observe("ImageInspect", time.Now(), &err)
+	return c.Client.ImageInspectWithRaw(ctx, ref)
+}
+
+func (c *apiClient) ImageHistory(ctx context.Context, ref string) (_ []image.HistoryResponseItem, err error) {
+	defer c.observe("ImageHistory", time.Now(), &err)
+	return c.Client.ImageHistory(ctx, ref)
+}
+
+// ImagePull measures the time to start a pull. The pull's total duration is
+// reported separately; see ObservePulls.
+func (c *apiClient) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (_ io.ReadCloser, err error) {
+	defer c.observe("ImagePull", time.Now(), &err)
+	return c.Client.ImagePull(ctx, ref, options)
+}
+
+func (c *apiClient) ImageRemove(ctx context.Context, ref string, options types.ImageRemoveOptions) (_ []types.ImageDeleteResponseItem, err error) {
+	defer c.observe("ImageRemove", time.Now(), &err)
+	return c.Client.ImageRemove(ctx, ref, options)
+}
+
+func (c *apiClient) ContainerCommit(ctx context.Context, id string, options types.ContainerCommitOptions) (_ types.IDResponse, err error) {
+	defer c.observe("ContainerCommit", time.Now(), &err)
+	return c.Client.ContainerCommit(ctx, id, options)
+}
+
+func (c *apiClient) ContainerCreate(
+	ctx context.Context,
+	config *container.Config,
+	hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig,
+	platform *specs.Platform,
+	name string,
+) (_ container.ContainerCreateCreatedBody, err error) {
+	defer c.observe("ContainerCreate", time.Now(), &err)
+	return c.Client.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, name)
+}
+
+func (c *apiClient) ContainerList(ctx context.Context, options types.ContainerListOptions) (_ []types.Container, err error) {
+	defer c.observe("ContainerList", time.Now(), &err)
+	return c.Client.ContainerList(ctx, options)
+}
+
+func (c *apiClient) ContainerInspect(ctx context.Context, id string) (_ types.ContainerJSON, err error) {
+	defer c.observe("ContainerInspect", time.Now(), &err)
+	return c.Client.ContainerInspect(ctx, id)
+}
+
+func (c *apiClient) ContainerStart(ctx context.Context, id string, options types.ContainerStartOptions) (err error) {
+	defer c.observe("ContainerStart", time.Now(), &err)
+	return c.Client.ContainerStart(ctx, id, options)
+}
+
+func (c *apiClient) ContainerStop(ctx context.Context, id string, timeout *time.Duration) (err error) {
+	defer c.observe("ContainerStop", time.Now(), &err)
+	return c.Client.ContainerStop(ctx, id, timeout)
+}
+
+func (c *apiClient) ContainerKill(ctx context.Context, id, signal string) (err error) {
+	defer c.observe("ContainerKill", time.Now(), &err)
+	return c.Client.ContainerKill(ctx, id, signal)
+}
+
+func (c *apiClient) ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) (err error) {
+	defer c.observe("ContainerRemove", time.Now(), &err)
+	return c.Client.ContainerRemove(ctx, id, options)
+}
+
+func (c *apiClient) ContainerStats(ctx context.Context, id string, stream bool) (_ types.ContainerStats, err error) {
+	defer c.observe("ContainerStats", time.Now(), &err)
+	return c.Client.ContainerStats(ctx, id, stream)
+}
+
+func (c *apiClient) ContainerLogs(ctx context.Context, id string, options types.ContainerLogsOptions) (_ io.ReadCloser, err error) {
+	defer c.observe("ContainerLogs", time.Now(), &err)
+	return c.Client.ContainerLogs(ctx, id, options)
+}
+
+func (c *apiClient) ContainerAttach(ctx context.Context, id string, options types.ContainerAttachOptions) (_ types.HijackedResponse, err error) {
+	defer c.observe("ContainerAttach", time.Now(), &err)
+	return c.Client.ContainerAttach(ctx, id, options)
+}
+
+func (c *apiClient) ContainerResize(ctx context.Context, id string, options types.ResizeOptions) (err error) {
+	defer c.observe("ContainerResize", time.Now(), &err)
+	return c.Client.ContainerResize(ctx, id, options)
+}
+
+func (c *apiClient) ContainerExecCreate(ctx context.Context, id string, config types.ExecConfig) (_ types.IDResponse, err error) {
+	defer c.observe("ContainerExecCreate", time.Now(), &err)
+	return c.Client.ContainerExecCreate(ctx, id, config)
+}
+
+func (c *apiClient) ContainerExecAttach(ctx context.Context, execID string, config types.ExecStartCheck) (_ types.HijackedResponse, err error) {
+	defer c.observe("ContainerExecAttach", time.Now(), &err)
+	return c.Client.ContainerExecAttach(ctx, execID, config)
+}
+
+func (c *apiClient) ContainerExecInspect(ctx context.Context, execID string) (_ types.ContainerExecInspect, err error) {
+	defer c.observe("ContainerExecInspect", time.Now(), &err)
+	return c.Client.ContainerExecInspect(ctx, execID)
+}
+
+func (c *apiClient) ContainerExecResize(ctx context.Context, execID string, options types.ResizeOptions) (err error) {
+	defer c.observe("ContainerExecResize", time.Now(), &err)
+	return c.Client.ContainerExecResize(ctx, execID, options)
+}
+
+func (c *apiClient) NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (_ types.NetworkCreateResponse, err error) {
+	defer c.observe("NetworkCreate", time.Now(), &err)
+	return c.Client.NetworkCreate(ctx, name, options)
+}
+
+func (c *apiClient) NetworkRemove(ctx context.Context, network string) (err error) {
+	defer c.observe("NetworkRemove", time.Now(), &err)
+	return c.Client.NetworkRemove(ctx, network)
+}
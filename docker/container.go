@@ -9,22 +9,36 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/pkg/stdcopy"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/term"
 
 	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/cgroup"
+	"github.com/beaker/runtime/ebpf"
 	"github.com/beaker/runtime/logging"
 )
 
 // Container wraps a Docker container in the common runtime interface.
 type Container struct {
-	client *client.Client
-	id     string
+	client  *apiClient
+	life    *runtime.Lifecycle
+	history *runtime.History
+	mps     *runtime.MPS
+	probes  *ebpf.Probes
+	id      string
+
+	deadlines *deadlines
+
+	// Fallback stats source used when Docker's stats API fails.
+	collectorLock sync.Mutex
+	collector     *cgroup.Collector
 }
 
 // Name returns the container's unique ID.
@@ -33,12 +47,73 @@ func (c *Container) Name() string {
 }
 
 // Start calls the entrypoint in a created container.
+//
+// Docker has no time limits of its own, so a container's MaxRuntime is
+// enforced by the runtime; see armMaxRuntime. Nor can it configure CFS burst,
+// so a container's CPUBurst is written to its cgroup once it's running, or limit
+// bandwidth, so a container's traffic is shaped with tc once it's running. Nor
+// does it have hooks, so a container's PostStart hook is run here with exec.
 func (c *Container) Start(ctx context.Context) error {
-	return c.client.ContainerStart(ctx, c.id, types.ContainerStartOptions{})
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	// GPUs are attached when the container starts, so problems with the
+	// node's GPU software surface here.
+	if err := c.client.ContainerStart(ctx, c.id, types.ContainerStartOptions{}); err != nil {
+		return runtime.TranslateGPUError(err)
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("enforcing max runtime: %w", err)
+	}
+	if info.CPUBurst != 0 && info.CgroupPath != "" {
+		if err := cgroup.NewCollectorForPath(info.CgroupPath).SetCPUBurst(info.CPUBurst); err != nil {
+			log.WithError(err).Warnf("Failed to set CPU burst of container %s; its CPU limit is strict", c.id)
+		}
+	}
+	if c.probes != nil && info.PID != 0 {
+		if err := c.probes.Watch(info.PID); err != nil {
+			log.WithError(err).Warnf("Failed to watch container %s with eBPF probes", c.id)
+		}
+	}
+	if (info.IngressBandwidth != 0 || info.EgressBandwidth != 0) && info.PID != 0 {
+		if err := runtime.LimitBandwidth(ctx, info.PID, info.IngressBandwidth, info.EgressBandwidth); err != nil {
+			// An unlimited container could saturate the node's network.
+			c.abortStart(ctx, "its bandwidth couldn't be limited")
+			return err
+		}
+	}
+	if info.PostStart != nil && info.Status == runtime.StatusRunning {
+		if err := runtime.RunHook(ctx, c, info.PostStart); err != nil {
+			// As on Kubernetes, a container whose hook fails doesn't run.
+			c.abortStart(ctx, "its post-start hook failed")
+			return fmt.Errorf("post-start hook: %w", err)
+		}
+	}
+	c.armMaxRuntime(info)
+	return nil
+}
+
+// abortStart kills a container which started but can't be allowed to run.
+func (c *Container) abortStart(ctx context.Context, reason string) {
+	var kill time.Duration
+	if err := c.client.ContainerStop(ctx, c.id, &kill); err != nil {
+		log.WithError(err).Warnf("Failed to stop container %s after %s", c.id, reason)
+	}
 }
 
 // Info returns a container's details.
 func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	body, err := c.client.ContainerInspect(ctx, c.id)
 	if err != nil {
 		return nil, translateErr(err)
@@ -49,11 +124,56 @@ func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
 
 	res := body.HostConfig.Resources
 	info := runtime.ContainerInfo{
-		Labels:   body.Config.Labels,
-		CPUCount: float64(res.NanoCPUs) / 1000000000,
-		Memory:   res.Memory,
+		Labels:       body.Config.Labels,
+		CPUCount:     cpuCount(res),
+		Memory:       res.Memory,
+		RestartCount: body.RestartCount,
+	}
+	if hash, ok := info.Labels[runtime.ConfigHashLabel]; ok {
+		info.ConfigHash = hash
+		delete(info.Labels, runtime.ConfigHashLabel)
+	}
+	delete(info.Labels, runtime.CreateTokenLabel)
+	delete(info.Labels, runtime.AutoRemoveLabel)
+	if maxRuntime, ok := info.Labels[runtime.MaxRuntimeLabel]; ok {
+		if info.MaxRuntime, err = time.ParseDuration(maxRuntime); err != nil {
+			return nil, fmt.Errorf("max runtime: %w", err)
+		}
+		delete(info.Labels, runtime.MaxRuntimeLabel)
+	}
+	if burst, ok := info.Labels[runtime.CPUBurstLabel]; ok {
+		if info.CPUBurst, err = time.ParseDuration(burst); err != nil {
+			return nil, fmt.Errorf("CPU burst: %w", err)
+		}
+		delete(info.Labels, runtime.CPUBurstLabel)
+	}
+	if rate, ok := info.Labels[runtime.IngressBandwidthLabel]; ok {
+		if info.IngressBandwidth, err = runtime.ParseBandwidth(rate); err != nil {
+			return nil, fmt.Errorf("ingress bandwidth: %w", err)
+		}
+		delete(info.Labels, runtime.IngressBandwidthLabel)
+	}
+	if rate, ok := info.Labels[runtime.EgressBandwidthLabel]; ok {
+		if info.EgressBandwidth, err = runtime.ParseBandwidth(rate); err != nil {
+			return nil, fmt.Errorf("egress bandwidth: %w", err)
+		}
+		delete(info.Labels, runtime.EgressBandwidthLabel)
+	}
+	if hooks, ok := info.Labels[runtime.HooksLabel]; ok {
+		if info.PostStart, info.PreStop, err = runtime.DecodeHooks(hooks); err != nil {
+			return nil, err
+		}
+		delete(info.Labels, runtime.HooksLabel)
+	}
+	if gpus, ok := info.Labels[runtime.GPUsLabel]; ok {
+		info.GPUs = strings.Split(gpus, ",")
+		delete(info.Labels, runtime.GPUsLabel)
+	} else {
+		// Containers created before GPUs were labeled have them as requested.
+		for _, req := range res.DeviceRequests {
+			info.GPUs = append(info.GPUs, req.DeviceIDs...)
+		}
 	}
-
 	if info.CreatedAt, err = parseTime(body.Created); err != nil {
 		return nil, fmt.Errorf("create time: %w", err)
 	}
@@ -64,6 +184,22 @@ func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
 		return nil, fmt.Errorf("end time: %w", err)
 	}
 
+	if body.NetworkSettings != nil {
+		for _, endpoint := range body.NetworkSettings.Networks {
+			if endpoint.IPAddress != "" {
+				info.IPAddresses = append(info.IPAddresses, endpoint.IPAddress)
+			}
+			if endpoint.GlobalIPv6Address != "" {
+				info.IPAddresses = append(info.IPAddresses, endpoint.GlobalIPv6Address)
+			}
+		}
+	}
+
+	if body.State.Pid != 0 {
+		info.PID = body.State.Pid
+		info.CgroupPath, _ = cgroup.ProcessPath(body.State.Pid) // Best effort.
+	}
+
 	// Translate container status. The logic here is based on Kubernetes.
 	// At time of writing: "k8s.io/kubernetes/pkg/kubelet/dockershim"
 	switch {
@@ -78,6 +214,10 @@ func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
 		if body.State.OOMKilled {
 			info.Message = addContext(info.Message, "out of memory")
 		}
+		if c.deadlines.exceeded(c.id) {
+			info.Message = addContext(info.Message, "deadline exceeded")
+			info.Interruption = &runtime.Interruption{Reason: runtime.InterruptionDeadlineExceeded}
+		}
 
 	case body.State.ExitCode != 0:
 		// Container failed to start. It's dead.
@@ -101,11 +241,21 @@ func addContext(message string, context string) string {
 }
 
 // Logs returns logging.LogReader which can be used to read log messages
-// starting at the given time (inclusive). Set time to zero to read the full log.
-func (c *Container) Logs(ctx context.Context, since time.Time) (logging.LogReader, error) {
+// starting at the given time (inclusive).
+func (c *Container) Logs(ctx context.Context, opts runtime.LogsOpts) (logging.LogReader, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
 	var sinceStr string
-	if !since.IsZero() {
-		sinceStr = since.Format(time.RFC3339Nano)
+	if !opts.Since.IsZero() {
+		sinceStr = opts.Since.Format(time.RFC3339Nano)
 	}
 
 	r, err := c.client.ContainerLogs(ctx, c.id, types.ContainerLogsOptions{
@@ -113,11 +263,12 @@ func (c *Container) Logs(ctx context.Context, since time.Time) (logging.LogReade
 		ShowStderr: true,
 		Since:      sinceStr,
 		Timestamps: true,
+		Follow:     opts.Follow,
 	})
 	if err != nil {
 		return nil, translateErr(err)
 	}
-	return NewLogReader(r), nil
+	return runtime.BufferLogs(NewLogReader(r, opts.ReaderOpts), opts), nil
 }
 
 func parseTime(s string) (time.Time, error) {
@@ -137,26 +288,135 @@ func translateErr(err error) error {
 // Stop sends a SIGTERM to a container to instruct it to exit. If a timeout is
 // provided and elapses, the container is forcibly stopped with SIGKILL.
 func (c *Container) Stop(ctx context.Context, timeout *time.Duration) error {
-	err := c.client.ContainerStop(ctx, c.id, timeout)
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if timeout, err = c.preStop(ctx, timeout); err != nil {
+		return err
+	}
+	err = c.client.ContainerStop(ctx, c.id, timeout)
 	return translateErr(err)
 }
 
-// Remove kills and removes a container with no grace period.
-func (c *Container) Remove(ctx context.Context) error {
-	err := c.client.ContainerRemove(ctx, c.id, types.ContainerRemoveOptions{Force: true})
+// preStop runs the container's pre-stop hook, if it has one and is running,
+// returning the grace period left for stopping it.
+func (c *Container) preStop(ctx context.Context, grace *time.Duration) (*time.Duration, error) {
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.PreStop == nil || info.Status != runtime.StatusRunning {
+		return grace, nil
+	}
+	grace, err = runtime.RunPreStop(ctx, c, info.PreStop, grace)
+	if err != nil {
+		log.WithError(err).Warnf("Stopping container %s despite its failed hook", c.id)
+	}
+	return grace, nil
+}
+
+// Signal sends a signal by name, e.g. "SIGUSR1", to the container's main process.
+func (c *Container) Signal(ctx context.Context, signal string) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	err = c.client.ContainerKill(ctx, c.id, signal)
 	return translateErr(err)
 }
 
+// Remove removes a container. A running container is first given the grace
+// period to exit, then killed.
+func (c *Container) Remove(ctx context.Context, opts runtime.RemoveOpts) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if opts.GracePeriod > 0 {
+		if err := c.Stop(ctx, &opts.GracePeriod); err != nil {
+			return err
+		}
+	}
+	if opts.LogArchiveDir != "" {
+		if _, err := runtime.ArchiveLogs(ctx, c, opts.LogArchiveDir, opts.LogArchiveOpts); err != nil {
+			return fmt.Errorf("docker: %w", err)
+		}
+	}
+
+	var record *runtime.HistoryRecord
+	if c.history != nil {
+		record = runtime.CaptureRecord(ctx, c)
+	}
+	mpsGPU, err := c.mpsGPU(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.ContainerRemove(ctx, c.id, types.ContainerRemoveOptions{
+		RemoveVolumes: opts.RemoveVolumes,
+		Force:         true,
+	})
+	if err != nil {
+		return translateErr(err)
+	}
+	c.deadlines.forget(c.id)
+	if c.history != nil {
+		c.history.Add(record)
+	}
+	if mpsGPU != "" {
+		if err := c.mps.Release(ctx, mpsGPU); err != nil {
+			log.WithError(err).WithField("container", c.id).Warn("Failed to release MPS daemon")
+		}
+	}
+	return nil
+}
+
+// mpsGPU returns the GPU the container shares through MPS, if any.
+func (c *Container) mpsGPU(ctx context.Context) (string, error) {
+	if c.mps == nil {
+		return "", nil
+	}
+	body, err := c.client.ContainerInspect(ctx, c.id)
+	if err != nil {
+		return "", translateErr(err)
+	}
+	labels := body.Config.Labels
+	if labels[runtime.GPUSharingLabel] != string(runtime.GPUSharingMPS) {
+		return "", nil
+	}
+	return labels[runtime.GPUsLabel], nil
+}
+
 // stats handling largely inspired by docker CLI's stats handler. see:
 // https://github.com/docker/cli/blob/968ce1ae4d45722c6ae70aa1dff6ee28d88e976a/cli/command/container/stats_helpers.go
 
 // Stats scrapes stats information about the container and returns it.
 // This includes information about memory, cpu, network and block IO.
+//
+// If Docker's stats API fails, e.g. because the daemon is under heavy load,
+// stats are read directly from the container's cgroup instead.
 func (c *Container) Stats(ctx context.Context) (*runtime.ContainerStats, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	response, err := c.client.ContainerStats(ctx, c.id, false)
 	if err != nil {
-		return nil, translateErr(err)
+		if err = translateErr(err); err == runtime.ErrNotFound || ctx.Err() != nil {
+			return nil, err
+		}
+		return c.fallbackStats(ctx, err)
 	}
+	defer response.Body.Close()
 
 	dec := json.NewDecoder(response.Body)
 	var stats *types.StatsJSON
@@ -173,7 +433,6 @@ func (c *Container) Stats(ctx context.Context) (*runtime.ContainerStats, error)
 	s := runtime.ContainerStats{
 		Time: time.Now(),
 		Stats: map[runtime.StatType]float64{
-			runtime.CPUUsagePercentStat:    calculateCPUPercentUnix(previousCPU, previousSystem, stats),
 			runtime.MemoryUsagePercentStat: calculateMemPercentUnixNoCache(memLimit, mem),
 			runtime.MemoryUsageBytesStat:   mem,
 			runtime.NetworkRxBytesStat:     netRx,
@@ -182,21 +441,105 @@ func (c *Container) Stats(ctx context.Context) (*runtime.ContainerStats, error)
 			runtime.BlockWriteBytesStat:    float64(blkWrite),
 		},
 	}
+
+	// The container is inspected once per sample for both its CPU limit,
+	// which "docker update" can change, and its PID for the probes. The limit
+	// is omitted from percentages if it can't be read.
+	var cpuLimit float64
+	var pid int
+	if body, err := c.client.ContainerInspect(ctx, c.id); err == nil {
+		if body.HostConfig != nil {
+			cpuLimit = cpuCount(body.HostConfig.Resources)
+		}
+		if body.State != nil {
+			pid = body.State.Pid
+		}
+	}
+	runtime.SetCPUStats(s.Stats, calculateCPUCoresUnix(previousCPU, previousSystem, stats), cpuLimit, onlineCPUs(stats))
+	c.addProbeStats(&s, pid)
 	return &s, nil
 }
 
-func calculateCPUPercentUnix(previousCPU, previousSystem uint64, v *types.StatsJSON) float64 {
-	cpuPercent := 0.0
+// addProbeStats adds statistics from eBPF probes for the container's main
+// process, if enabled. They're omitted if the container isn't running, i.e.
+// the PID is zero.
+func (c *Container) addProbeStats(s *runtime.ContainerStats, pid int) {
+	if c.probes == nil || pid == 0 {
+		return
+	}
+	probeStats, err := c.probes.Stats(pid)
+	if err != nil {
+		log.WithError(err).Debugf("Failed to read eBPF probe stats of container %s", c.id)
+		return
+	}
+	ebpf.AddStats(s, probeStats)
+}
+
+// cpuCount returns a container's CPU limit in cores, or zero if unlimited.
+func cpuCount(res container.Resources) float64 {
+	if res.CPUPeriod != 0 {
+		return float64(res.CPUQuota) / float64(res.CPUPeriod)
+	}
+	return float64(res.NanoCPUs) / 1000000000
+}
+
+// fallbackStats reads stats from the container's cgroup. The original error
+// from Docker is returned if the fallback is also unavailable.
+func (c *Container) fallbackStats(ctx context.Context, statsErr error) (*runtime.ContainerStats, error) {
+	c.collectorLock.Lock()
+	collector := c.collector
+	c.collectorLock.Unlock()
+
+	// The PID is needed for a new collector and for the probes.
+	var pid int
+	if collector == nil || c.probes != nil {
+		body, err := c.client.ContainerInspect(ctx, c.id)
+		if err == nil && body.State != nil {
+			pid = body.State.Pid
+		}
+	}
+	if collector == nil {
+		if pid == 0 {
+			return nil, statsErr
+		}
+		var err error
+		if collector, err = cgroup.NewCollector(pid); err != nil {
+			return nil, statsErr
+		}
+
+		c.collectorLock.Lock()
+		c.collector = collector
+		c.collectorLock.Unlock()
+	}
+
+	stats, err := collector.Stats()
+	if err != nil {
+		return nil, statsErr
+	}
+	c.addProbeStats(stats, pid)
+	return stats, nil
+}
+
+func calculateCPUCoresUnix(previousCPU, previousSystem uint64, v *types.StatsJSON) float64 {
+	cores := 0.0
 	// calculate the change for the cpu usage of the container in between readings
 	cpuDelta := float64(v.CPUStats.CPUUsage.TotalUsage) - float64(previousCPU)
 	// calculate the change for the entire system between readings
 	systemDelta := float64(v.CPUStats.SystemUsage) - float64(previousSystem)
 
-	onlineCPUs := float64(len(v.CPUStats.CPUUsage.PercpuUsage))
 	if systemDelta > 0.0 && cpuDelta > 0.0 {
-		cpuPercent = (cpuDelta / systemDelta) * onlineCPUs * 100.0
+		cores = (cpuDelta / systemDelta) * float64(onlineCPUs(v))
+	}
+	return cores
+}
+
+// onlineCPUs returns the number of CPUs on the host. Older daemons don't
+// report it, so it's inferred from per-CPU usage.
+func onlineCPUs(v *types.StatsJSON) int {
+	if v.CPUStats.OnlineCPUs != 0 {
+		return int(v.CPUStats.OnlineCPUs)
 	}
-	return cpuPercent
+	return len(v.CPUStats.CPUUsage.PercpuUsage)
 }
 
 func calculateBlockIO(blkio types.BlkioStats) (uint64, uint64) {
@@ -243,6 +586,12 @@ func calculateMemPercentUnixNoCache(limit float64, usedNoCache float64) float64
 // Attach hijacks the IO streams of a container.
 // This must be called before the container is started.
 func (c *Container) Attach(ctx context.Context) (types.HijackedResponse, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	defer end()
+
 	return c.client.ContainerAttach(ctx, c.id, types.ContainerAttachOptions{
 		Stream: true,
 		Stdin:  true,
@@ -251,19 +600,65 @@ func (c *Container) Attach(ctx context.Context) (types.HijackedResponse, error)
 	})
 }
 
+// StreamOpts directs the IO streams of an attached container.
+type StreamOpts struct {
+	// (optional) Stdin is copied to the container. Defaults to os.Stdin.
+	Stdin io.Reader
+
+	// (optional) Stdout receives the container's standard output. Defaults
+	// to os.Stdout. If the container has a TTY, it also receives standard
+	// error since a terminal merges the two.
+	Stdout io.Writer
+
+	// (optional) Stderr receives the container's standard error if it has no
+	// TTY. Defaults to os.Stderr.
+	Stderr io.Writer
+
+	// (optional) Recorder records the session's input and output, e.g. with
+	// logging.NewAsciicastRecorder. The caller must close it afterwards.
+	Recorder logging.Recorder
+}
+
+func (o StreamOpts) withDefaults() StreamOpts {
+	if o.Stdin == nil {
+		o.Stdin = os.Stdin
+	}
+	if o.Stdout == nil {
+		o.Stdout = os.Stdout
+	}
+	if o.Stderr == nil {
+		o.Stderr = os.Stderr
+	}
+	return o
+}
+
 // Stream connects to a container with an interactive prompt.
 // Use Attach to get the hijacked response.
 // This must be called after the container is started.
 // This is a borrowed and cleaned up version of the Docker CLI implementation.
-func (c *Container) Stream(ctx context.Context, resp types.HijackedResponse) error {
-	const tty = true // TODO: Detect or config param to set TTY.
+//
+// Output is written to the streams in opts. Containers without a TTY keep
+// standard output and error separate, so a transcript can preserve which
+// stream each line came from.
+func (c *Container) Stream(ctx context.Context, resp types.HijackedResponse, opts StreamOpts) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	body, err := c.client.ContainerInspect(ctx, c.id)
+	if err != nil {
+		return translateErr(err)
+	}
+	tty := body.Config.Tty
 
 	if tty {
 		c.monitorTTYSize(ctx, "")
 	}
 
 	resultC, errC := c.client.ContainerWait(ctx, c.id, "")
-	if err := streamIO(ctx, resp, tty); err != nil {
+	if err := streamIO(ctx, resp, tty, opts.withDefaults()); err != nil {
 		return err
 	}
 
@@ -289,6 +684,88 @@ func (c *Container) Stream(ctx context.Context, resp types.HijackedResponse) err
 	}
 }
 
+// AttachLogs attaches to a running container's output and returns it as a
+// LogReader, so that live output can be consumed like the container's logs.
+// Unlike Logs, only output emitted after attaching is read. The reader ends
+// when the container exits.
+func (c *Container) AttachLogs(ctx context.Context, opts logging.ReaderOpts) (logging.LogReader, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	body, err := c.client.ContainerInspect(ctx, c.id)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+
+	resp, err := c.client.ContainerAttach(ctx, c.id, types.ContainerAttachOptions{
+		Stream: true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+
+	r := logging.NewStreamReader(opts, resp.Conn)
+	c.streamOutput(r, resp, body.Config.Tty, nil)
+	return r, nil
+}
+
+// streamOutput copies a hijacked connection's output to a StreamReader in a
+// background task, then closes the reader with the first error from copying or
+// from finish, if given. The task outlives the call which attached, so it runs
+// under the runtime's lifecycle and closes the connection when shutdown begins.
+func (c *Container) streamOutput(
+	r *logging.StreamReader,
+	resp types.HijackedResponse,
+	tty bool,
+	finish func(ctx context.Context) error,
+) {
+	started := c.life.Go(func(ctx context.Context) {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				resp.Close()
+			case <-done:
+			}
+		}()
+
+		err := copyOutput(r, resp, tty)
+		if err == nil && finish != nil {
+			err = finish(ctx)
+		}
+		if err != nil && ctx.Err() != nil {
+			err = runtime.ErrClosed
+		}
+		r.CloseWithError(err)
+	})
+	if !started {
+		resp.Close()
+		r.CloseWithError(runtime.ErrClosed)
+	}
+}
+
+// copyOutput copies a hijacked connection's output to a StreamReader.
+func copyOutput(r *logging.StreamReader, resp types.HijackedResponse, tty bool) error {
+	var err error
+	if tty {
+		_, err = io.Copy(r.Writer(logging.Stdout), resp.Reader)
+	} else {
+		// Without a TTY, Docker multiplexes stdout and stderr.
+		_, err = stdcopy.StdCopy(r.Writer(logging.Stdout), r.Writer(logging.Stderr), resp.Reader)
+	}
+	if err == io.ErrClosedPipe {
+		// The reader was closed by its consumer.
+		return nil
+	}
+	return err
+}
+
 // monitorTTYSize monitors the outer shell and resizes the container's TTY to match.
 // https://github.com/docker/cli/blob/fff164c22e8dc904291fecb62307312fd4ca153e/cli/command/container/tty.go#L71
 // Optionally takes an execution ID to resize. If omitted, the root TTY is resized.
@@ -335,18 +812,30 @@ func (c *Container) resizeTTY(ctx context.Context, exec string) error {
 	}
 }
 
-// streamIO proxies STDIN/STDOUT for a hijacked TCP connection.
-func streamIO(ctx context.Context, resp types.HijackedResponse, tty bool) error {
+// streamIO proxies standard streams for a hijacked TCP connection.
+func streamIO(ctx context.Context, resp types.HijackedResponse, tty bool, opts StreamOpts) error {
 	// Set input terminal to raw mode so keystrokes are sent directly.
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
-	if err != nil {
-		return fmt.Errorf("unable to set up input stream: %w", err)
+	if f, ok := opts.Stdin.(*os.File); ok && tty {
+		oldState, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return fmt.Errorf("unable to set up input stream: %w", err)
+		}
+		defer term.Restore(int(f.Fd()), oldState)
+
+		// Ensure terminal output is cleared on restore.
+		defer func() { fmt.Fprintln(opts.Stdout) }()
+	}
+
+	// Record after the terminal is set up so that raw mode applies to stdin.
+	if opts.Recorder != nil {
+		opts.Stdin = logging.RecordReader(opts.Stdin, opts.Recorder)
+		opts.Stdout = logging.RecordWriter(opts.Stdout, opts.Recorder, logging.Stdout)
+		opts.Stderr = logging.RecordWriter(opts.Stderr, opts.Recorder, logging.Stderr)
 	}
-	defer term.Restore(int(os.Stdin.Fd()), oldState)
 
 	// Proxy input.
 	go func() {
-		io.Copy(resp.Conn, os.Stdin)
+		io.Copy(resp.Conn, opts.Stdin)
 		_ = resp.CloseWrite()
 	}()
 
@@ -354,17 +843,14 @@ func streamIO(ctx context.Context, resp types.HijackedResponse, tty bool) error
 	go func() {
 		var err error
 		if tty {
-			_, err = io.Copy(os.Stdout, resp.Reader)
+			_, err = io.Copy(opts.Stdout, resp.Reader)
 		} else {
-			// TODO: Is this needed?
-			_, err = stdcopy.StdCopy(os.Stdout, os.Stderr, resp.Reader)
+			// Without a TTY, Docker multiplexes stdout and stderr.
+			_, err = stdcopy.StdCopy(opts.Stdout, opts.Stderr, resp.Reader)
 		}
 		outputDone <- err
 	}()
 
-	// Ensure terminal output is cleared on restore.
-	defer func() { fmt.Println() }()
-
 	select {
 	case err := <-outputDone:
 		return err
@@ -387,9 +873,18 @@ type ExecOpts struct {
 	// (optional) WorkingDir where the command will be launched.
 	// Defaults to the container's working dir.
 	WorkingDir string
+
+	// (optional) Recorder records the session's input and output.
+	Recorder logging.Recorder
 }
 
 func (c *Container) Exec(ctx context.Context, opts *ExecOpts) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
 	const tty = true // TODO: Detect or config param to set TTY.
 
 	env := make([]string, 0, len(opts.Env))
@@ -423,7 +918,7 @@ func (c *Container) Exec(ctx context.Context, opts *ExecOpts) error {
 	go func() {
 		defer close(errCh)
 		errCh <- func() error {
-			return streamIO(ctx, resp, tty)
+			return streamIO(ctx, resp, tty, StreamOpts{Recorder: opts.Recorder}.withDefaults())
 		}()
 	}()
 
@@ -444,3 +939,54 @@ func (c *Container) Exec(ctx context.Context, opts *ExecOpts) error {
 	}
 	return nil
 }
+
+// ExecLogs runs a command in the container without a terminal and returns its
+// output as a LogReader, so that it can be archived or forwarded like the
+// container's logs. Standard input is not attached. Once the output ends, the
+// reader returns an error if the command exited with a non-zero code.
+func (c *Container) ExecLogs(
+	ctx context.Context,
+	opts *ExecOpts,
+	readerOpts logging.ReaderOpts,
+) (logging.LogReader, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	env := make([]string, 0, len(opts.Env))
+	for k, v := range opts.Env {
+		env = append(env, k+"="+v)
+	}
+
+	exec, err := c.client.ContainerExecCreate(ctx, c.id, types.ExecConfig{
+		User:         opts.User,
+		AttachStderr: true,
+		AttachStdout: true,
+		Env:          env,
+		WorkingDir:   opts.WorkingDir,
+		Cmd:          opts.Command,
+	})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+
+	resp, err := c.client.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{})
+	if err != nil {
+		return nil, translateErr(err)
+	}
+
+	r := logging.NewStreamReader(readerOpts, resp.Conn)
+	c.streamOutput(r, resp, false, func(ctx context.Context) error {
+		result, err := c.client.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return translateErr(err)
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("exited with code %d", result.ExitCode)
+		}
+		return nil
+	})
+	return r, nil
+}
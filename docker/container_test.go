@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime"
+)
+
+// fakeDaemon serves the Docker API for a single running container, recording
+// the requests it receives.
+func fakeDaemon(t *testing.T, state *types.ContainerJSON) (*Container, *[]string) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Paths are versioned, e.g. "/v1.41/containers/ctr/start".
+		path := r.URL.Path[strings.Index(r.URL.Path[1:], "/")+1:]
+		requests = append(requests, r.Method+" "+path)
+		switch path {
+		case "/containers/ctr/json":
+			_ = json.NewEncoder(w).Encode(state)
+		case "/containers/ctr/stats":
+			// One core in use of four on the host.
+			var stats types.StatsJSON
+			stats.CPUStats.CPUUsage.TotalUsage = 1000000000
+			stats.CPUStats.SystemUsage = 4000000000
+			stats.CPUStats.OnlineCPUs = 4
+			_ = json.NewEncoder(w).Encode(&stats)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://"+server.Listener.Addr().String()),
+		client.WithVersion("1.41"),
+	)
+	require.NoError(t, err)
+	return &Container{
+		client:    &apiClient{Client: cli},
+		life:      runtime.NewLifecycle(),
+		id:        "ctr",
+		deadlines: newDeadlines(),
+	}, &requests
+}
+
+func TestStartBandwidthFailure(t *testing.T) {
+	c, requests := fakeDaemon(t, &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			Created:    "2021-01-01T00:00:00Z",
+			State:      &types.ContainerState{Running: true, Pid: -1, StartedAt: "2021-01-01T00:00:01Z", FinishedAt: "0001-01-01T00:00:00Z"},
+			HostConfig: &container.HostConfig{},
+		},
+		Config: &container.Config{Labels: map[string]string{runtime.EgressBandwidthLabel: "10Mbit"}},
+	})
+
+	// The process doesn't exist, so its bandwidth can't be limited.
+	err := c.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "limiting bandwidth")
+
+	// A container which can't be limited is stopped rather than left running.
+	assert.Equal(t, []string{
+		"POST /containers/ctr/start",
+		"GET /containers/ctr/json",
+		"POST /containers/ctr/stop",
+	}, *requests)
+}
+
+func TestCPULimit(t *testing.T) {
+	state := &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			HostConfig: &container.HostConfig{Resources: container.Resources{NanoCPUs: 2000000000}},
+		},
+	}
+	c, requests := fakeDaemon(t, state)
+	stats, err := c.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, stats.Stats[runtime.CPUUsageOfLimitPercentStat])
+
+	// The container is inspected once per sample.
+	assert.Equal(t, []string{"GET /containers/ctr/stats", "GET /containers/ctr/json"}, *requests)
+
+	// Limits updated in place are seen by the next sample.
+	state.HostConfig.Resources = container.Resources{CPUQuota: 50000, CPUPeriod: 100000}
+	stats, err = c.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, stats.Stats[runtime.CPUUsageOfLimitPercentStat])
+}
@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/beaker/runtime"
+)
+
+// deadlines tracks the enforcement of containers' MaxRuntime, which Docker
+// can't enforce itself. A runtime and its containers share one.
+type deadlines struct {
+	mu sync.Mutex
+
+	// Containers whose deadlines are being enforced.
+	armed map[string]bool
+
+	// Containers stopped by the runtime for exceeding their deadlines. Docker
+	// labels can't change after creation, so the record is kept here.
+	stopped map[string]bool
+}
+
+func newDeadlines() *deadlines {
+	return &deadlines{armed: make(map[string]bool), stopped: make(map[string]bool)}
+}
+
+// arm returns true if a container's deadline wasn't already being enforced,
+// in which case the caller must enforce it and then call disarm.
+func (d *deadlines) arm(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.armed[id] {
+		return false
+	}
+	d.armed[id] = true
+	return true
+}
+
+func (d *deadlines) isArmed(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.armed[id]
+}
+
+func (d *deadlines) disarm(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.armed, id)
+}
+
+// markExceeded records that a container was stopped at its deadline.
+func (d *deadlines) markExceeded(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped[id] = true
+}
+
+// exceeded returns true if the runtime stopped a container at its deadline.
+func (d *deadlines) exceeded(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stopped[id]
+}
+
+// forget drops the records of a removed container.
+func (d *deadlines) forget(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.stopped, id)
+}
+
+// armMaxRuntime enforces a running container's MaxRuntime in the background,
+// unless it's already enforced. Deadlines are armed when containers are
+// started, listed, or looked up, so a new runtime resumes enforcing them after
+// its process restarts. A deadline which passed in the meantime is enforced
+// immediately.
+//
+// Enforcement stops when the runtime shuts down. Only the runtime which stops
+// a container at its deadline reports the container's interruption.
+func (c *Container) armMaxRuntime(info *runtime.ContainerInfo) {
+	if info.MaxRuntime == 0 || info.Status != runtime.StatusRunning || !c.deadlines.arm(c.id) {
+		return
+	}
+	deadline := info.StartedAt.Add(info.MaxRuntime)
+	c.life.Go(func(ctx context.Context) {
+		defer c.deadlines.disarm(c.id)
+		c.enforceMaxRuntime(ctx, deadline)
+	})
+}
+
+// armMaxRuntimeAsync inspects a container in the background to arm its
+// deadline, if it isn't already armed.
+func (c *Container) armMaxRuntimeAsync() {
+	if c.deadlines.isArmed(c.id) {
+		return
+	}
+	c.life.Go(func(ctx context.Context) {
+		if info, err := c.Info(ctx); err == nil {
+			c.armMaxRuntime(info)
+		}
+	})
+}
+
+// enforceMaxRuntime stops the container if it's still running at the deadline.
+// Enforcement ends early if the context is canceled.
+func (c *Container) enforceMaxRuntime(ctx context.Context, deadline time.Time) {
+	waitCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	waitC, errC := c.client.ContainerWait(waitCtx, c.id, container.WaitConditionNotRunning)
+	select {
+	case <-waitC:
+		return
+	case err := <-errC:
+		if ctx.Err() != nil {
+			return
+		}
+		if waitCtx.Err() == nil {
+			log.WithError(err).Warnf("Failed to wait for container %s; max runtime is not enforced", c.id)
+			return
+		}
+	}
+
+	// Only a container still running is recorded, so that one which exited on
+	// its own at the deadline isn't reported as interrupted.
+	body, err := c.client.ContainerInspect(ctx, c.id)
+	if err != nil || body.State == nil || !body.State.Running {
+		return
+	}
+	c.deadlines.markExceeded(c.id)
+	err = c.Stop(context.Background(), nil)
+	if err != nil && !errors.Is(err, runtime.ErrNotFound) && !errors.Is(err, runtime.ErrClosed) {
+		log.WithError(err).Warnf("Failed to stop container %s after max runtime", c.id)
+	}
+}
@@ -0,0 +1,26 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadlines(t *testing.T) {
+	d := newDeadlines()
+
+	// A deadline is enforced once, however many times it's armed.
+	assert.True(t, d.arm("a"))
+	assert.False(t, d.arm("a"))
+	assert.True(t, d.isArmed("a"))
+	d.disarm("a")
+	assert.True(t, d.arm("a"))
+
+	// Stops are recorded until the container is removed.
+	assert.False(t, d.exceeded("a"))
+	d.markExceeded("a")
+	assert.True(t, d.exceeded("a"))
+	assert.False(t, d.exceeded("b"))
+	d.forget("a")
+	assert.False(t, d.exceeded("a"))
+}
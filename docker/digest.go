@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/beaker/runtime"
+)
+
+// VerifyImages records the ID of each image pulled through the runtime in d
+// and checks it when a container is created from the same tag. If the image
+// was removed in the meantime, it's pulled again. If it was replaced, creation
+// fails with runtime.ErrImageChanged when strict is set, and otherwise warns.
+// It must be called before any images are pulled.
+func (r *Runtime) VerifyImages(d *runtime.ImageDigests, strict bool) {
+	r.digests = d
+	r.strictDigests = strict
+}
+
+// recordImage records the ID of a freshly pulled image.
+func (r *Runtime) recordImage(ctx context.Context, tag string) {
+	if r.digests == nil {
+		return
+	}
+	image, _, err := r.client.ImageInspectWithRaw(ctx, tag)
+	if err == nil {
+		err = r.digests.Record(tag, image.ID)
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Failed to record digest of image %s; it won't be verified", tag)
+	}
+}
+
+// verifyImage checks that a container's image is the one recorded when it was
+// pulled, re-pulling it if it has since been removed.
+func (r *Runtime) verifyImage(ctx context.Context, opts *runtime.ContainerOpts) error {
+	if r.digests == nil || opts.Image == nil {
+		return nil
+	}
+	tag := opts.Image.Tag
+	if _, ok := r.digests.Lookup(tag); !ok {
+		return nil
+	}
+
+	image, _, err := r.client.ImageInspectWithRaw(ctx, tag)
+	if client.IsErrNotFound(err) {
+		opts.Warn("image %s was removed after it was pulled; pulling it again", tag)
+		if err := r.repull(ctx, opts.Image); err != nil {
+			return err
+		}
+		image, _, err = r.client.ImageInspectWithRaw(ctx, tag)
+	}
+	if err != nil {
+		return fmt.Errorf("verifying image %s: %w", tag, err)
+	}
+
+	if err := r.digests.Verify(tag, image.ID); err != nil {
+		if r.strictDigests {
+			return err
+		}
+		opts.Warn("%v", err)
+	}
+	return nil
+}
+
+// repull pulls an image which was removed after it was recorded.
+func (r *Runtime) repull(ctx context.Context, image *runtime.DockerImage) error {
+	registryAuth, err := encodeRegistryAuth(image.Auth)
+	if err != nil {
+		return fmt.Errorf("encoding registry auth: %w", err)
+	}
+	if err := r.pull(ctx, image.Tag, registryAuth, true, newPullProgress()); err != nil {
+		return fmt.Errorf("pulling removed image %s: %w", image.Tag, err)
+	}
+	return nil
+}
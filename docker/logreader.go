@@ -16,15 +16,18 @@ import (
 type LogReader struct {
 	r     io.Reader
 	inBuf bytes.Buffer
+
+	opts     logging.ReaderOpts
+	lastTime time.Time
 }
 
 // NewLogReader wraps a streaming Docker log reader. The provided reader must
-// include timestamps.
+// include timestamps. Messages are normalized according to opts.
 //
 // The reader introduces its own buffering and may read data from r beyond the
 // bytes requested by Read().
-func NewLogReader(r io.Reader) *LogReader {
-	lr := &LogReader{r: r}
+func NewLogReader(r io.Reader, opts logging.ReaderOpts) *LogReader {
+	lr := &LogReader{r: r, opts: opts}
 	return lr
 }
 
@@ -73,7 +76,9 @@ func (r *LogReader) ReadMessage() (*logging.Message, error) {
 		return nil, fmt.Errorf("docker: invalid log time: %w", err)
 	}
 
-	return &logging.Message{Stream: stream, Time: t.UTC(), Text: r.inBuf.String()}, nil
+	r.lastTime = r.opts.NormalizeTime(t, r.lastTime)
+	text, encoding := r.opts.NormalizeText(r.inBuf.String())
+	return &logging.Message{Stream: stream, Time: r.lastTime, Text: text, Encoding: encoding}, nil
 }
 
 // Header layout is as follows. For more detail, see the Docker repository:
@@ -16,28 +16,28 @@ import (
 
 func TestLogHeader(t *testing.T) {
 	t.Run("EOF", func(t *testing.T) {
-		r := NewLogReader(bytes.NewReader(nil))
+		r := NewLogReader(bytes.NewReader(nil), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.Equal(t, io.EOF, err)
 	})
 
 	t.Run("Truncated", func(t *testing.T) {
-		r := NewLogReader(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00}))
+		r := NewLogReader(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00}), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.Equal(t, io.ErrUnexpectedEOF, err)
 	})
 
 	t.Run("ReadError", func(t *testing.T) {
-		r := NewLogReader(badReader{})
+		r := NewLogReader(badReader{}, logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.EqualError(t, err, "docker: error reading log header: oh no")
 	})
 
 	t.Run("InvalidStream", func(t *testing.T) {
-		r := NewLogReader(bytes.NewReader([]byte{0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0}))
+		r := NewLogReader(bytes.NewReader([]byte{0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0}), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.EqualError(t, err, "docker: unexpected log stream: 0x3")
@@ -50,7 +50,7 @@ func TestLogMessage(t *testing.T) {
 	logTime, _ := time.Parse(time.RFC3339Nano, logTimeStr)
 
 	t.Run("EOF", func(t *testing.T) {
-		r := NewLogReader(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05}))
+		r := NewLogReader(bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05}), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.Equal(t, io.ErrUnexpectedEOF, err)
@@ -59,28 +59,28 @@ func TestLogMessage(t *testing.T) {
 	t.Run("ReadError", func(t *testing.T) {
 		r := NewLogReader(io.MultiReader(
 			bytes.NewReader([]byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05}),
-			badReader{}))
+			badReader{}), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.EqualError(t, err, "docker: error reading log message: oh no")
 	})
 
 	t.Run("EmptyLine", func(t *testing.T) {
-		r := NewLogReader(bytes.NewReader(message(1, "")))
+		r := NewLogReader(bytes.NewReader(message(1, "")), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.EqualError(t, err, "docker: invalid log time: EOF")
 	})
 
 	t.Run("InvalidTimestamp", func(t *testing.T) {
-		r := NewLogReader(bytes.NewReader(message(1, "abcd efgh")))
+		r := NewLogReader(bytes.NewReader(message(1, "abcd efgh")), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		assert.Nil(t, m)
 		assert.EqualError(t, err, `docker: invalid log time: parsing time "abcd" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "abcd" as "2006"`)
 	})
 
 	t.Run("Minimal", func(t *testing.T) {
-		r := NewLogReader(bytes.NewReader(message(1, logTimeStr+" ")))
+		r := NewLogReader(bytes.NewReader(message(1, logTimeStr+" ")), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		require.NotNil(t, m)
 		require.NoError(t, err)
@@ -94,10 +94,17 @@ func TestLogMessage(t *testing.T) {
 		assert.Equal(t, io.EOF, err)
 	})
 
+	t.Run("Binary", func(t *testing.T) {
+		r := NewLogReader(bytes.NewReader(message(1, logTimeStr+" a\x00b\n")), logging.ReaderOpts{Binary: logging.BinaryEscape})
+		m, err := r.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, `a\x00b`+"\n", m.Text)
+	})
+
 	t.Run("Multiple", func(t *testing.T) {
 		m1 := message(1, logTimeStr+" First one thing...")
 		m2 := message(2, logTimeStr+" ... and then another.")
-		r := NewLogReader(bytes.NewReader(append(m1, m2...)))
+		r := NewLogReader(bytes.NewReader(append(m1, m2...)), logging.ReaderOpts{})
 
 		m, err := r.ReadMessage()
 		require.NotNil(t, m)
@@ -126,7 +133,7 @@ func TestLogMessage(t *testing.T) {
 			buf.Write([]byte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_"))
 		}
 
-		r := NewLogReader(bytes.NewReader(message(1, logTimeStr+" "+buf.String())))
+		r := NewLogReader(bytes.NewReader(message(1, logTimeStr+" "+buf.String())), logging.ReaderOpts{})
 		m, err := r.ReadMessage()
 		require.NotNil(t, m)
 		require.NoError(t, err)
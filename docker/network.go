@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+
+	"github.com/beaker/runtime"
+)
+
+// CreateNetwork implements runtime.NetworkCreator with a bridge network.
+func (r *Runtime) CreateNetwork(ctx context.Context, opts *runtime.NetworkOpts) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if _, ok := opts.Labels[managedLabel]; ok {
+		return fmt.Errorf("forbidden label: %s", managedLabel)
+	}
+
+	labels := make(map[string]string, len(opts.Labels)+1)
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	labels[managedLabel] = "true"
+
+	var ipam *network.IPAM
+	if len(opts.Subnets) != 0 {
+		ipam = &network.IPAM{Driver: "default"}
+		for _, subnet := range opts.Subnets {
+			ipam.Config = append(ipam.Config, network.IPAMConfig{Subnet: subnet})
+		}
+	}
+
+	_, err = r.client.NetworkCreate(ctx, opts.Name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+		EnableIPv6:     opts.EnableIPv6,
+		IPAM:           ipam,
+		Labels:         labels,
+	})
+	if err != nil {
+		return fmt.Errorf("creating network %s: %w", opts.Name, err)
+	}
+	return nil
+}
+
+// RemoveNetwork implements runtime.NetworkCreator. Networks with containers
+// attached can't be removed.
+func (r *Runtime) RemoveNetwork(ctx context.Context, name string) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	err = r.client.NetworkRemove(ctx, name)
+	if client.IsErrNotFound(err) {
+		return runtime.ErrNotFound
+	}
+	return err
+}
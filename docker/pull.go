@@ -0,0 +1,124 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+
+	"github.com/beaker/runtime"
+)
+
+// ObservePulls registers a function to receive metrics for each image pull.
+// It must be called before any images are pulled.
+func (r *Runtime) ObservePulls(fn func(runtime.PullMetrics)) {
+	r.onPull = fn
+}
+
+// pullProgress tracks an image's layers through the JSON message stream of a
+// pull. It never fails to write.
+type pullProgress struct {
+	buf []byte
+
+	// Layer IDs in the order they appear in the image's manifest.
+	layers []string
+	cached map[string]bool
+
+	// Compressed sizes of downloaded layers.
+	sizes map[string]int64
+
+	// The first error reported in the stream, if any.
+	err error
+}
+
+func newPullProgress() *pullProgress {
+	return &pullProgress{cached: make(map[string]bool), sizes: make(map[string]int64)}
+}
+
+// Write implements the io.Writer interface.
+func (p *pullProgress) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i == -1 {
+			return len(b), nil
+		}
+		p.handle(p.buf[:i])
+		p.buf = p.buf[i+1:]
+	}
+}
+
+func (p *pullProgress) handle(line []byte) {
+	var msg jsonmessage.JSONMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return
+	}
+	if msg.Error != nil && p.err == nil {
+		p.err = msg.Error
+	}
+
+	// Docker reports whether each layer is cached, in manifest order, before
+	// downloading any of them.
+	switch msg.Status {
+	case "Already exists":
+		p.layers = append(p.layers, msg.ID)
+		p.cached[msg.ID] = true
+	case "Pulling fs layer":
+		p.layers = append(p.layers, msg.ID)
+	case "Downloading":
+		if msg.Progress != nil && msg.Progress.Total > p.sizes[msg.ID] {
+			p.sizes[msg.ID] = msg.Progress.Total
+		}
+	}
+}
+
+// metrics summarizes the layers of a pull.
+func (p *pullProgress) metrics() runtime.PullMetrics {
+	var m runtime.PullMetrics
+	for _, id := range p.layers {
+		if p.cached[id] {
+			m.LayersCached++
+		} else {
+			m.LayersDownloaded++
+			m.BytesDownloaded += p.sizes[id]
+		}
+	}
+	return m
+}
+
+// measureCache fills in the cached layers of a successful pull. The stream
+// doesn't include the sizes of cached layers, so they're read from the image's
+// history, whose non-empty entries correspond to its layers. If the two can't
+// be matched up, cached bytes are left as zero.
+func (r *Runtime) measureCache(ctx context.Context, tag string, p *pullProgress, m *runtime.PullMetrics) {
+	image, _, err := r.client.ImageInspectWithRaw(ctx, tag)
+	if err != nil {
+		return
+	}
+	if len(p.layers) == 0 {
+		// The image was up to date, so every layer was cached.
+		m.LayersCached = len(image.RootFS.Layers)
+		m.BytesCached = image.Size
+		return
+	}
+
+	history, err := r.client.ImageHistory(ctx, tag)
+	if err != nil {
+		return
+	}
+	var sizes []int64
+	for i := len(history) - 1; i >= 0; i-- { // History is newest first.
+		if history[i].Size != 0 {
+			sizes = append(sizes, history[i].Size)
+		}
+	}
+	if len(sizes) != len(p.layers) {
+		return
+	}
+	for i, id := range p.layers {
+		if p.cached[id] {
+			m.BytesCached += sizes[i]
+		}
+	}
+}
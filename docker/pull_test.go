@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime"
+)
+
+func TestPullProgress(t *testing.T) {
+	const stream = `{"status":"Pulling from library/python","id":"3.9"}
+{"status":"Already exists","progressDetail":{},"id":"aaa"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"bbb"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"ccc"}
+{"status":"Downloading","progressDetail":{"current":100,"total":2000},"id":"bbb"}
+{"status":"Downloading","progressDetail":{"current":2000,"total":2000},"id":"bbb"}
+{"status":"Downloading","progressDetail":{"current":30,"total":300},"id":"ccc"}
+{"status":"Pull complete","progressDetail":{},"id":"bbb"}
+{"status":"Pull complete","progressDetail":{},"id":"ccc"}
+{"status":"Status: Downloaded newer image for python:3.9"}
+`
+	p := newPullProgress()
+
+	// Write in small chunks to split messages across writes.
+	_, err := io.CopyBuffer(p, strings.NewReader(stream), make([]byte, 7))
+	require.NoError(t, err)
+	assert.NoError(t, p.err)
+	assert.Equal(t, []string{"aaa", "bbb", "ccc"}, p.layers)
+	assert.Equal(t, runtime.PullMetrics{
+		LayersDownloaded: 2,
+		LayersCached:     1,
+		BytesDownloaded:  2300,
+	}, p.metrics())
+
+	t.Run("Error", func(t *testing.T) {
+		p := newPullProgress()
+		_, err := p.Write([]byte(`{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}` + "\n"))
+		require.NoError(t, err)
+		assert.EqualError(t, p.err, "manifest unknown")
+	})
+}
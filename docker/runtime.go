@@ -10,17 +10,25 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/beaker/unique"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/ebpf"
 )
 
 const (
@@ -37,7 +45,33 @@ const (
 
 // Runtime wraps the Docker runtime in a common interface.
 type Runtime struct {
-	client *client.Client
+	client *apiClient
+	life   *runtime.Lifecycle
+
+	// GPUs are listed on first use and cached since they rarely change.
+	gpuLock sync.Mutex
+	gpus    runtime.GPUInventory
+
+	onPull func(runtime.PullMetrics)
+
+	// Image IDs recorded at pull time, if verified.
+	digests       *runtime.ImageDigests
+	strictDigests bool
+
+	// Records of removed containers, if retained.
+	history *runtime.History
+
+	// Daemons for containers which share GPUs through MPS, if enabled.
+	mps *runtime.MPS
+
+	// Probes which add network and file IO stats, if enabled.
+	probes *ebpf.Probes
+
+	deadlines *deadlines
+
+	// Containers which the runtime removes on exit, rather than the daemon,
+	// so that they're recorded in the history.
+	removals *runtime.Removals
 }
 
 // NewRuntime creates a new Docker-backed Runtime.
@@ -46,12 +80,41 @@ func NewRuntime() (*Runtime, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Runtime{client}, nil
+	return &Runtime{
+		client:    &apiClient{Client: client},
+		life:      runtime.NewLifecycle(),
+		deadlines: newDeadlines(),
+		removals:  runtime.NewRemovals(),
+	}, nil
 }
 
-// Close implements the io.Closer interface.
+// Close implements the io.Closer interface. It shuts down the runtime, waiting
+// up to runtime.ShutdownTimeout for in-flight operations.
 func (r *Runtime) Close() error {
-	return r.client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), runtime.ShutdownTimeout)
+	defer cancel()
+	return r.Shutdown(ctx)
+}
+
+// Shutdown implements runtime.Shutdowner.
+func (r *Runtime) Shutdown(ctx context.Context) error {
+	err := r.life.Shutdown(ctx)
+	if closeErr := r.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Ping verifies that the Docker daemon is reachable.
+func (r *Runtime) Ping(ctx context.Context) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	_, err = r.client.Ping(ctx)
+	return err
 }
 
 // PullImage pulls a Docker image and prints progress to stdout unless quiet is set.
@@ -61,6 +124,12 @@ func (r *Runtime) PullImage(
 	policy runtime.PullPolicy,
 	quiet bool,
 ) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
 	switch policy {
 	case runtime.PullAlways:
 		// Nothing to do. Proceed to pulling the image.
@@ -83,22 +152,49 @@ func (r *Runtime) PullImage(
 		return fmt.Errorf("encoding registry auth: %w", err)
 	}
 
+	start := time.Now()
+	progress := newPullProgress()
+	err = r.pull(ctx, image.Tag, registryAuth, quiet, progress)
+
+	if r.onPull != nil {
+		m := progress.metrics()
+		m.Image = image.Tag
+		m.Registry = runtime.ImageRegistry(image.Tag)
+		m.Duration = time.Since(start)
+		m.Err = err
+		if err == nil {
+			r.measureCache(ctx, image.Tag, progress, &m)
+		}
+		r.onPull(m)
+	}
+	if err == nil {
+		r.recordImage(ctx, image.Tag)
+	}
+	return err
+}
+
+func (r *Runtime) pull(ctx context.Context, tag, registryAuth string, quiet bool, progress *pullProgress) error {
 	// Start the pull operation. The pull operation is not complete until the reader has been drained.
-	out, err := r.client.ImagePull(ctx, image.Tag, types.ImagePullOptions{RegistryAuth: registryAuth})
+	out, err := r.client.ImagePull(ctx, tag, types.ImagePullOptions{RegistryAuth: registryAuth})
 	if err != nil {
 		return err
 	}
+	stream := io.TeeReader(out, progress)
 
 	if quiet {
-		_, err = io.Copy(ioutil.Discard, out)
+		_, err = io.Copy(ioutil.Discard, stream)
+		if err == nil {
+			// Errors are reported in the stream, which is otherwise ignored.
+			err = progress.err
+		}
 	} else {
-		err = jsonmessage.DisplayJSONMessagesStream(out, os.Stdout, os.Stdout.Fd(), true, nil)
+		err = jsonmessage.DisplayJSONMessagesStream(stream, os.Stdout, os.Stdout.Fd(), true, nil)
 	}
 	if err != nil {
-		r.Close()
+		out.Close()
 		return err
 	}
-	return r.Close()
+	return out.Close()
 }
 
 // CreateContainer creates a new container. Call Start to run it.
@@ -106,6 +202,12 @@ func (r *Runtime) CreateContainer(
 	ctx context.Context,
 	opts *runtime.ContainerOpts,
 ) (runtime.Container, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	// Prevent collisions on protected variables and labels.
 	if _, ok := opts.Env[visibleDevicesEnv]; ok {
 		return nil, fmt.Errorf("forbidden environment variable: %s", visibleDevicesEnv)
@@ -113,15 +215,190 @@ func (r *Runtime) CreateContainer(
 	if _, ok := opts.Labels[managedLabel]; ok {
 		return nil, fmt.Errorf("forbidden label: %s", managedLabel)
 	}
+	for _, key := range []string{
+		runtime.ConfigHashLabel,
+		runtime.IdempotencyKeyLabel,
+		runtime.CreateTokenLabel,
+		runtime.AutoRemoveLabel,
+		runtime.GPUsLabel,
+		runtime.MaxRuntimeLabel,
+		runtime.CPUBurstLabel,
+		runtime.GPUSharingLabel,
+		runtime.IngressBandwidthLabel,
+		runtime.EgressBandwidthLabel,
+		runtime.HooksLabel,
+	} {
+		if _, ok := opts.Labels[key]; ok {
+			return nil, fmt.Errorf("forbidden label: %s", key)
+		}
+	}
+	configHash, err := opts.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if opts.IdempotencyKey != "" {
+		existing, err := r.findByIdempotencyKey(ctx, opts.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if err := runtime.CheckIdempotent(ctx, existing, configHash); err != nil {
+				return nil, err
+			}
+			return existing, nil
+		}
+	}
+	if err := opts.IPCMode.Validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateNetwork(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateGPUCapabilities(); err != nil {
+		return nil, err
+	}
+	if err := r.validateGPUSharing(opts); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateSSHAgent(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateX11(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateDNS(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateSensitiveEnv(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateBandwidth(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateHooks(); err != nil {
+		return nil, err
+	}
+	hooks, err := runtime.EncodeHooks(opts.PostStart, opts.PreStop)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxRuntime < 0 {
+		return nil, fmt.Errorf("invalid max runtime %s", opts.MaxRuntime)
+	}
+	if err := opts.ValidateCPUBurst(); err != nil {
+		return nil, err
+	}
+
+	if policy := opts.Image.PullPolicy; policy != "" {
+		if err := r.PullImage(ctx, opts.Image, policy, true); err != nil {
+			return nil, fmt.Errorf("pulling image: %w", err)
+		}
+	}
+	if err := r.verifyImage(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	entrypoint, args, err := opts.ResolveCommand()
+	if err != nil {
+		return nil, err
+	}
+	ports, err := opts.ResolvePorts()
+	if err != nil {
+		return nil, err
+	}
+	gpus, err := r.normalizeGPUs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(gpus) != 0 {
+		if err := r.checkGPUs(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
 
 	cconf := &container.Config{
 		Image:      opts.Image.Tag,
-		Entrypoint: opts.Command,
-		Cmd:        opts.Arguments,
+		Entrypoint: entrypoint,
+		Cmd:        args,
 		User:       opts.User,
 		WorkingDir: opts.WorkingDir,
+		MacAddress: opts.MACAddress,
+		StopSignal: opts.StopSignal,
+	}
+	hconf := &container.HostConfig{
+		// Docker's IPC modes use the same names, including "container:<name>".
+		IpcMode: container.IpcMode(opts.IPCMode),
+
+		// The daemon removes the container without going through Remove, so
+		// it isn't recorded in the history. If history is retained, the
+		// runtime removes the container itself instead.
+		AutoRemove: opts.AutoRemove && r.history == nil,
+	}
+	if c := opts.DNSConfig; c != nil {
+		// Docker's settings replace the node's rather than adding to them.
+		hconf.DNS, hconf.DNSSearch, hconf.DNSOptions = c.Nameservers, c.Searches, c.Options
+	}
+	if opts.DNSPolicy == runtime.DNSNone && len(hconf.DNSSearch) == 0 {
+		// Docker keeps the node's search domains unless given "." instead.
+		hconf.DNSSearch = []string{"."}
+	}
+	if opts.NetworkFrom != "" {
+		if err := r.checkManaged(ctx, opts.NetworkFrom); err != nil {
+			return nil, fmt.Errorf("joining network namespace: %w", err)
+		}
+		hconf.NetworkMode = container.NetworkMode("container:" + opts.NetworkFrom)
+	}
+	if opts.PIDFrom != "" {
+		if err := r.checkManaged(ctx, opts.PIDFrom); err != nil {
+			return nil, fmt.Errorf("joining PID namespace: %w", err)
+		}
+		hconf.PidMode = container.PidMode("container:" + opts.PIDFrom)
+	}
+	if name, ok := opts.IPCMode.Container(); ok {
+		if err := r.checkManaged(ctx, name); err != nil {
+			return nil, fmt.Errorf("joining IPC namespace: %w", err)
+		}
+	}
+	var dependency runtime.Container
+	if s := opts.Schedule; s != nil {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		if s.After != "" {
+			if err := r.checkManaged(ctx, s.After); err != nil {
+				return nil, fmt.Errorf("scheduling start: %w", err)
+			}
+			dependency = r.container(s.After)
+		}
+	}
+
+	var nconf *network.NetworkingConfig
+	if opts.Network != "" {
+		hconf.NetworkMode = container.NetworkMode(opts.Network)
+		endpoint := &network.EndpointSettings{}
+		if opts.IPAddress != "" || opts.IPv6Address != "" {
+			endpoint.IPAMConfig = &network.EndpointIPAMConfig{
+				IPv4Address: stripPrefix(opts.IPAddress),
+				IPv6Address: stripPrefix(opts.IPv6Address),
+			}
+		}
+		nconf = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{opts.Network: endpoint},
+		}
+	}
+
+	if len(ports) != 0 {
+		cconf.ExposedPorts = make(nat.PortSet, len(ports))
+		hconf.PortBindings = make(nat.PortMap, len(ports))
+		for _, p := range ports {
+			port := nat.Port(fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol))
+			cconf.ExposedPorts[port] = struct{}{}
+			hconf.PortBindings[port] = append(hconf.PortBindings[port], nat.PortBinding{
+				HostIP:   p.HostIP,
+				HostPort: strconv.Itoa(p.HostPort),
+			})
+		}
 	}
-	hconf := &container.HostConfig{}
 
 	if opts.Interactive {
 		cconf.OpenStdin = true
@@ -129,7 +406,9 @@ func (r *Runtime) CreateContainer(
 		cconf.AttachStdout = true
 		cconf.AttachStderr = true
 		cconf.Tty = true
+	}
 
+	if opts.Interactive || opts.Init {
 		// Init inserts a tiny init-process into the container as the main process
 		// and handles reaping of all processes when the container exits.
 		// Details here: https://docs.docker.com/config/containers/multi-service_container
@@ -137,14 +416,48 @@ func (r *Runtime) CreateContainer(
 		hconf.Init = &init
 	}
 
-	cconf.Labels = make(map[string]string, len(opts.Labels)+1)
+	cconf.Labels = make(map[string]string, len(opts.Labels)+5)
 	cconf.Labels[managedLabel] = "true"
+	cconf.Labels[runtime.ConfigHashLabel] = configHash
+	token := runtime.NewCreateToken()
+	cconf.Labels[runtime.CreateTokenLabel] = token
+	if opts.IdempotencyKey != "" {
+		cconf.Labels[runtime.IdempotencyKeyLabel] = opts.IdempotencyKey
+	}
+	if len(gpus) != 0 {
+		cconf.Labels[runtime.GPUsLabel] = strings.Join(gpus, ",")
+	}
+	if opts.MaxRuntime != 0 {
+		cconf.Labels[runtime.MaxRuntimeLabel] = opts.MaxRuntime.String()
+	}
+	if opts.AutoRemove && r.history != nil {
+		cconf.Labels[runtime.AutoRemoveLabel] = "true"
+	}
+	if opts.CPUBurst != 0 {
+		// The burst is applied when the container starts. See Container.Start.
+		cconf.Labels[runtime.CPUBurstLabel] = opts.CPUBurst.String()
+	}
+	if opts.IngressBandwidth != 0 {
+		// Docker can't limit bandwidth, so limits are applied on start.
+		cconf.Labels[runtime.IngressBandwidthLabel] = opts.IngressBandwidth.String()
+	}
+	if opts.EgressBandwidth != 0 {
+		cconf.Labels[runtime.EgressBandwidthLabel] = opts.EgressBandwidth.String()
+	}
+	if hooks != "" {
+		// Docker has no hooks, so they're run through exec. See Container.Start.
+		cconf.Labels[runtime.HooksLabel] = hooks
+	}
+	if opts.GPUSharing != runtime.GPUSharingDefault {
+		cconf.Labels[runtime.GPUSharingLabel] = string(opts.GPUSharing)
+	}
 	for k, v := range opts.Labels {
 		cconf.Labels[k] = v
 	}
 
-	cconf.Env = make([]string, 0, len(opts.Env))
-	for k, v := range opts.Env {
+	env := opts.ResolveEnv()
+	cconf.Env = make([]string, 0, len(env))
+	for k, v := range env {
 		cconf.Env = append(cconf.Env, k+"="+v)
 	}
 
@@ -161,11 +474,53 @@ func (r *Runtime) CreateContainer(
 			ReadOnly: m.ReadOnly,
 		}
 	}
+	if opts.CoreDumpDir != "" {
+		source, err := filepath.Abs(opts.CoreDumpDir)
+		if err != nil {
+			return nil, fmt.Errorf("translating to absolute path: %w", err)
+		}
+		target, err := runtime.CoreDumpPath()
+		if err != nil {
+			return nil, fmt.Errorf("collecting core dumps: %w", err)
+		}
+		hconf.Mounts = append(hconf.Mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: source,
+			Target: target,
+		})
+		hconf.Resources.Ulimits = []*units.Ulimit{{Name: "core", Soft: -1, Hard: -1}}
+	}
+	if opts.SSHAgent != "" {
+		source, err := filepath.Abs(opts.SSHAgent)
+		if err != nil {
+			return nil, fmt.Errorf("translating to absolute path: %w", err)
+		}
+		hconf.Mounts = append(hconf.Mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: source,
+			Target: runtime.SSHAgentPath,
+		})
+		cconf.Env = append(cconf.Env, "SSH_AUTH_SOCK="+runtime.SSHAgentPath)
+	}
+	if opts.X11 != nil {
+		for _, m := range opts.X11.Mounts() {
+			hconf.Mounts = append(hconf.Mounts, mount.Mount{
+				Type:     mount.TypeBind,
+				Source:   m.HostPath,
+				Target:   m.ContainerPath,
+				ReadOnly: m.ReadOnly,
+			})
+		}
+		for k, v := range opts.X11.Env() {
+			cconf.Env = append(cconf.Env, k+"="+v)
+		}
+	}
 
 	// Set hardware limits.
 	if mem := opts.Memory; mem != 0 {
 		const minimum = 4 * 1024 * 1024
 		if mem < minimum {
+			opts.Warn("memory limit raised from %d to the minimum of %d bytes", mem, minimum)
 			mem = minimum
 		}
 		hconf.Resources.Memory = mem
@@ -175,14 +530,27 @@ func (r *Runtime) CreateContainer(
 	}
 	if opts.CPUShares != 0 {
 		hconf.Resources.CPUShares = opts.CPUShares
-	} else if opts.CPUCount != 0 {
-		hconf.Resources.NanoCPUs = int64(opts.CPUCount * 1000000000)
 	}
-	if len(opts.GPUs) != 0 {
+	if opts.CPUCount != 0 {
+		if opts.CPUPeriod != 0 {
+			// CPU period and quota are in microseconds.
+			hconf.Resources.CPUPeriod = opts.CPUPeriod.Microseconds()
+			hconf.Resources.CPUQuota = int64(opts.CPUCount * float64(opts.CPUPeriod.Microseconds()))
+		} else {
+			hconf.Resources.NanoCPUs = int64(opts.CPUCount * 1000000000)
+		}
+	}
+	if len(gpus) != 0 {
+		// Docker translates capabilities other than "gpu" to driver
+		// capabilities for the NVIDIA container toolkit.
+		capabilities := []string{"gpu"}
+		for _, c := range opts.GPUCapabilities {
+			capabilities = append(capabilities, string(c))
+		}
 		hconf.Resources.DeviceRequests = []container.DeviceRequest{{
-			DeviceIDs:    opts.GPUs,
+			DeviceIDs:    gpus,
 			Driver:       "nvidia",
-			Capabilities: [][]string{{"gpu"}},
+			Capabilities: [][]string{capabilities},
 		}}
 	} else {
 		// If there aren't any GPUs requested, explicitly set NVIDIA_VISIBLE_DEVICES to none.
@@ -203,28 +571,204 @@ func (r *Runtime) CreateContainer(
 	if opts.IsEvictable() {
 		hconf.OomScoreAdj = 1000
 	}
+	if opts.GPUSharing == runtime.GPUSharingMPS {
+		// Clients reach the daemon through its pipes and shared memory.
+		hconf.IpcMode = container.IpcMode(runtime.IPCHost)
+		hconf.Mounts = append(hconf.Mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: r.mps.PipeDir(gpus[0]),
+			Target: runtime.MPSContainerPipeDir,
+		})
+		for k, v := range r.mps.ContainerEnv(opts) {
+			cconf.Env = append(cconf.Env, k+"="+v)
+		}
+		if err := r.mps.Acquire(ctx, gpus[0]); err != nil {
+			return nil, err
+		}
+	}
 
 	// Docker's auto-generated names frequently collide, so generate a random one.
 	name := opts.Name
-	if name == "" {
+	if name == "" && opts.IdempotencyKey != "" {
+		name = runtime.IdempotentName(opts.IdempotencyKey)
+	} else if name == "" {
 		name = unique.NewID().String()
 	}
 
-	c, err := r.client.ContainerCreate(ctx, cconf, hconf, nil, nil, name)
+	c, err := r.client.ContainerCreate(ctx, cconf, hconf, nconf, nil, name)
 	if err != nil {
+		if opts.GPUSharing == runtime.GPUSharingMPS {
+			if err := r.mps.Release(context.Background(), gpus[0]); err != nil {
+				log.WithError(err).Warn("Failed to release MPS daemon")
+			}
+		}
+		if ctx.Err() != nil && opts.IdempotencyKey == "" {
+			// The daemon may have created the container after the request was
+			// canceled. It's removed if it carries this call's token; a
+			// container of the same name without it predates the call.
+			// Containers with idempotency keys are kept for the caller's retry
+			// to find.
+			runtime.CleanupAsync(r.life, "container "+name, func(ctx context.Context) error {
+				body, err := r.client.ContainerInspect(ctx, name)
+				if client.IsErrNotFound(err) {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if body.Config == nil || body.Config.Labels[runtime.CreateTokenLabel] != token {
+					return nil
+				}
+				err = r.container(body.ID).Remove(ctx, runtime.RemoveOpts{})
+				if errors.Is(err, runtime.ErrNotFound) {
+					return nil
+				}
+				return err
+			})
+		}
+		if opts.IdempotencyKey != "" && ctx.Err() == nil {
+			// A concurrent request with the same key may have taken the name.
+			if existing, _ := r.findByIdempotencyKey(ctx, opts.IdempotencyKey); existing != nil {
+				if err := runtime.CheckIdempotent(ctx, existing, configHash); err != nil {
+					return nil, err
+				}
+				return existing, nil
+			}
+		}
+
 		msg := err.Error()
 		if i := strings.Index(msg, pathDneError); i != -1 {
 			// Sanitize mounting errors for cleaner presentation.
 			return nil, errors.New(msg[i:])
 		}
+		return nil, runtime.TranslateGPUError(err)
+	}
+	for _, w := range c.Warnings {
+		opts.Warn("%s", w)
+	}
+
+	ctr := r.container(c.ID)
+	if opts.AutoRemove && r.history != nil {
+		r.removals.Arm(r.life, ctr)
+	}
+	if opts.Schedule != nil {
+		runtime.ScheduleStart(r.life.Context(), ctr, dependency, opts.Schedule)
+	}
+	return ctr, nil
+}
+
+// normalizeGPUs resolves a container's GPU indices to UUIDs. If the node's GPUs
+// can't be listed, e.g. because nvidia-smi isn't installed, indices are passed
+// through with a warning.
+func (r *Runtime) normalizeGPUs(ctx context.Context, opts *runtime.ContainerOpts) ([]string, error) {
+	if !runtime.HasGPUIndices(opts.GPUs) {
+		return opts.GPUs, nil
+	}
+
+	r.gpuLock.Lock()
+	defer r.gpuLock.Unlock()
+	if r.gpus == nil {
+		gpus, err := runtime.QueryGPUs(ctx)
+		if err != nil {
+			opts.Warn("GPU indices can't be resolved to UUIDs: %v", err)
+			return opts.GPUs, nil
+		}
+		r.gpus = gpus
+	}
+	return r.gpus.Normalize(opts.GPUs)
+}
+
+// checkGPUs verifies that the node's NVIDIA driver supports the CUDA version
+// required by the container's image, so that an incompatibility is reported
+// clearly before the container starts. The check is skipped if the driver
+// version can't be determined, e.g. because nvidia-smi isn't installed.
+func (r *Runtime) checkGPUs(ctx context.Context, opts *runtime.ContainerOpts) error {
+	if opts.Env["NVIDIA_DISABLE_REQUIRE"] == "true" {
+		return nil
+	}
+
+	driver, err := runtime.QueryDriverVersion(ctx)
+	var gpuErr *runtime.GPUError
+	if errors.As(err, &gpuErr) {
+		return err
+	}
+	if err != nil {
+		opts.Warn("GPU compatibility can't be checked: %v", err)
+		return nil
+	}
+
+	// A missing image is reported when the container is created.
+	image, _, err := r.client.ImageInspectWithRaw(ctx, opts.Image.Tag)
+	if err != nil || image.Config == nil {
+		return nil
+	}
+	return runtime.CheckDriver(driver, runtime.RequiredCUDA(image.Config.Env))
+}
+
+// findByIdempotencyKey returns the managed container created with the given
+// idempotency key, or nil if there is none.
+func (r *Runtime) findByIdempotencyKey(ctx context.Context, key string) (runtime.Container, error) {
+	filters := filters.NewArgs()
+	filters.Add("label", managedLabel)
+	filters.Add("label", runtime.IdempotencyKeyLabel+"="+key)
+	body, err := r.client.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters,
+		All:     true,
+	})
+	if err != nil {
 		return nil, err
 	}
+	if len(body) == 0 {
+		return nil, nil
+	}
+	return r.Container(body[0].ID), nil
+}
 
-	return r.Container(c.ID), nil
+// checkManaged returns an error unless the named container exists and is
+// managed by this runtime.
+func (r *Runtime) checkManaged(ctx context.Context, name string) error {
+	body, err := r.client.ContainerInspect(ctx, name)
+	if err != nil {
+		return translateErr(err)
+	}
+	if _, ok := body.Config.Labels[managedLabel]; !ok {
+		return fmt.Errorf("container %s is not managed by this runtime", name)
+	}
+	return nil
 }
 
-// ListContainers enumerates all containers.
+// ListContainers enumerates all containers by creation time, then name.
 func (r *Runtime) ListContainers(ctx context.Context) ([]runtime.Container, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	entries, err := r.listEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.SortedContainers(entries), nil
+}
+
+// ListContainersPage implements runtime.Pager.
+func (r *Runtime) ListContainersPage(ctx context.Context, opts runtime.ListOpts) (*runtime.ContainerPage, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	entries, err := r.listEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.PaginateContainers(entries, opts)
+}
+
+// listEntries lists managed containers with their creation times. Docker
+// reports creation times to the second, so ties are common and ordered by ID.
+func (r *Runtime) listEntries(ctx context.Context) ([]runtime.ListEntry, error) {
 	filters := filters.NewArgs()
 	filters.Add("label", managedLabel)
 	body, err := r.client.ContainerList(ctx, types.ContainerListOptions{
@@ -235,16 +779,133 @@ func (r *Runtime) ListContainers(ctx context.Context) ([]runtime.Container, erro
 		return nil, err
 	}
 
-	containers := make([]runtime.Container, len(body))
+	entries := make([]runtime.ListEntry, len(body))
 	for i, c := range body {
-		containers[i] = r.Container(c.ID)
+		ctr := r.container(c.ID)
+		if _, ok := c.Labels[runtime.MaxRuntimeLabel]; ok && c.State == "running" {
+			ctr.armMaxRuntimeAsync()
+		}
+		if _, ok := c.Labels[runtime.AutoRemoveLabel]; ok {
+			r.removals.Arm(r.life, ctr)
+		}
+		entries[i] = runtime.ListEntry{Container: ctr, CreatedAt: time.Unix(c.Created, 0)}
 	}
-	return containers, nil
+	return entries, nil
 }
 
-// Container creates an interface to an existing container.
+// RetainHistory records each container removed through the runtime in h, so
+// that it can be queried with History. It must be called before any containers
+// are created or listed.
+func (r *Runtime) RetainHistory(h *runtime.History) {
+	r.history = h
+}
+
+// History implements runtime.Historian. It fails unless RetainHistory was
+// called.
+func (r *Runtime) History(ctx context.Context, filter runtime.HistoryFilter) ([]runtime.HistoryRecord, error) {
+	_, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	if r.history == nil {
+		return nil, errors.New("docker: container history is not retained")
+	}
+	return r.history.Query(filter), nil
+}
+
+// EnableMPS lets containers share GPUs through MPS, with m managing each GPU's
+// daemon. It must be called before any containers are created or listed. The
+// daemons' reference counts are restored from existing containers, so that
+// daemons in use before a restart are stopped once they're no longer needed.
+func (r *Runtime) EnableMPS(ctx context.Context, m *runtime.MPS) error {
+	filters := filters.NewArgs()
+	filters.Add("label", managedLabel)
+	filters.Add("label", runtime.GPUSharingLabel+"="+string(runtime.GPUSharingMPS))
+	body, err := r.client.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters,
+		All:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("listing MPS containers: %w", translateErr(err))
+	}
+
+	gpus := make([]string, 0, len(body))
+	for _, c := range body {
+		if gpu := c.Labels[runtime.GPUsLabel]; gpu != "" {
+			gpus = append(gpus, gpu)
+		}
+	}
+	if err := m.Restore(ctx, gpus); err != nil {
+		return err
+	}
+	r.mps = m
+	return nil
+}
+
+// EnableProbes adds the statistics collected by eBPF probes to the stats of
+// containers started after the call. The probes are shared, so closing the
+// runtime leaves them loaded.
+func (r *Runtime) EnableProbes(p *ebpf.Probes) {
+	r.probes = p
+}
+
+// validateGPUSharing checks that the runtime can share a container's GPUs as
+// requested.
+func (r *Runtime) validateGPUSharing(opts *runtime.ContainerOpts) error {
+	if err := opts.ValidateGPUSharing(); err != nil {
+		return err
+	}
+	if opts.GPUSharing != runtime.GPUSharingMPS {
+		return nil
+	}
+	if r.mps == nil {
+		return fmt.Errorf("MPS is not enabled (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.AutoRemove && r.history == nil {
+		// The daemon is released when the container is removed through the
+		// runtime, which a container removed by Docker never is.
+		return errors.New("MPS sharing is incompatible with auto-removal")
+	}
+	return nil
+}
+
+// Container creates an interface to an existing container. If the container
+// is running with a MaxRuntime, its enforcement resumes in the background, as
+// does its removal on exit if the runtime removes it. See
+// ContainerOpts.AutoRemove.
 func (r *Runtime) Container(id string) runtime.Container {
-	return &Container{r.client, id}
+	c := r.container(id)
+	c.armMaxRuntimeAsync()
+	r.armAutoRemoveAsync(c)
+	return c
+}
+
+// armAutoRemoveAsync checks in the background whether the runtime removes a
+// container on exit and, if so, arms its removal unless it's already armed.
+func (r *Runtime) armAutoRemoveAsync(c *Container) {
+	if r.removals.IsArmed(c.id) {
+		return
+	}
+	r.life.Go(func(ctx context.Context) {
+		body, err := r.client.ContainerInspect(ctx, c.id)
+		if err == nil && body.Config != nil && body.Config.Labels[runtime.AutoRemoveLabel] != "" {
+			r.removals.Arm(r.life, c)
+		}
+	})
+}
+
+func (r *Runtime) container(id string) *Container {
+	return &Container{
+		client:    r.client,
+		life:      r.life,
+		history:   r.history,
+		mps:       r.mps,
+		probes:    r.probes,
+		id:        id,
+		deadlines: r.deadlines,
+	}
 }
 
 func encodeRegistryAuth(auth *runtime.RegistryAuth) (string, error) {
@@ -262,3 +923,12 @@ func encodeRegistryAuth(auth *runtime.RegistryAuth) (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(authJSON), nil
 }
+
+// stripPrefix removes the prefix length from an address in CIDR notation.
+// Docker networks define their own prefix lengths.
+func stripPrefix(addr string) string {
+	if i := strings.IndexByte(addr, '/'); i != -1 {
+		return addr[:i]
+	}
+	return addr
+}
@@ -23,5 +23,5 @@ func TestDocker(t *testing.T) {
 	rt, err := NewRuntime()
 	require.NoError(t, err)
 
-	suite.Run(t, test.NewRuntimeSuite(rt))
+	suite.Run(t, test.NewRuntimeSuite(rt).ReportTo("docker", os.Getenv(test.ReportEnv)))
 }
@@ -0,0 +1,63 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"github.com/beaker/runtime"
+)
+
+// CommitSnapshot implements runtime.Snapshotter by committing a container to
+// an image. If a name is given, the image is tagged with it.
+func (r *Runtime) CommitSnapshot(ctx context.Context, c runtime.Container, name string) (*runtime.Snapshot, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	resp, err := r.client.ContainerCommit(ctx, c.Name(), types.ContainerCommitOptions{
+		Reference: name,
+		Comment:   "Snapshot of container " + c.Name(),
+		Pause:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("committing snapshot of %s: %w", c.Name(), translateErr(err))
+	}
+	return &runtime.Snapshot{ID: resp.ID, Name: name, Source: c.Name(), CreatedAt: time.Now()}, nil
+}
+
+// CreateContainerFromSnapshot implements runtime.Snapshotter. The container is
+// created from the snapshot's image, which must not be pulled.
+func (r *Runtime) CreateContainerFromSnapshot(
+	ctx context.Context,
+	snapshot *runtime.Snapshot,
+	opts *runtime.ContainerOpts,
+) (runtime.Container, error) {
+	if err := runtime.ValidateSnapshotOpts(snapshot, opts); err != nil {
+		return nil, err
+	}
+	fromSnapshot := *opts
+	fromSnapshot.Image = &runtime.DockerImage{Tag: snapshot.ID}
+	return r.CreateContainer(ctx, &fromSnapshot)
+}
+
+// RemoveSnapshot implements runtime.Snapshotter. The snapshot's image is
+// removed along with its tag, if any.
+func (r *Runtime) RemoveSnapshot(ctx context.Context, snapshot *runtime.Snapshot) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	_, err = r.client.ImageRemove(ctx, snapshot.ID, types.ImageRemoveOptions{Force: true, PruneChildren: true})
+	if client.IsErrNotFound(err) {
+		return runtime.ErrNotFound
+	}
+	return err
+}
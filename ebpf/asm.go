@@ -0,0 +1,201 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// This file is a minimal assembler for the handful of eBPF instructions the
+// probes need, so that they can be loaded without a compiler toolchain or a
+// BPF loader library.
+
+// register is an eBPF register. R0 holds return values, R1-R5 hold helper
+// arguments and are clobbered by calls, R6-R9 are preserved across calls, and
+// R10 is the read-only frame pointer.
+type register uint8
+
+const (
+	r0 register = iota
+	r1
+	r2
+	r3
+	r4
+	r5
+	r6
+	r7
+	r8
+	r9
+	r10
+)
+
+// Opcodes, combining an instruction class with its operation and source.
+const (
+	opLoadImm64    = 0x18 // BPF_LD | BPF_IMM | BPF_DW
+	opLoadWord     = 0x61 // BPF_LDX | BPF_MEM | BPF_W
+	opLoadDouble   = 0x79 // BPF_LDX | BPF_MEM | BPF_DW
+	opStoreDouble  = 0x7b // BPF_STX | BPF_MEM | BPF_DW
+	opAtomicDouble = 0xdb // BPF_STX | BPF_ATOMIC | BPF_DW
+	opAddImm       = 0x07 // BPF_ALU64 | BPF_ADD | BPF_K
+	opSubReg       = 0x1f // BPF_ALU64 | BPF_SUB | BPF_X
+	opOrImm        = 0x47 // BPF_ALU64 | BPF_OR | BPF_K
+	opMovImm       = 0xb7 // BPF_ALU64 | BPF_MOV | BPF_K
+	opMovReg       = 0xbf // BPF_ALU64 | BPF_MOV | BPF_X
+	opJump         = 0x05 // BPF_JMP | BPF_JA
+	opJumpEqImm    = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	opCall         = 0x85 // BPF_JMP | BPF_CALL
+	opExit         = 0x95 // BPF_JMP | BPF_EXIT
+)
+
+// pseudoMapFD marks a 64-bit immediate load as a map file descriptor, which the
+// kernel replaces with the map's address.
+const pseudoMapFD = 1
+
+// Helper functions callable from eBPF programs. See bpf-helpers(7).
+const (
+	helperMapLookup      = 1
+	helperMapUpdate      = 2
+	helperMapDelete      = 3
+	helperKtimeGetNS     = 5
+	helperGetPIDTGID     = 14
+	helperSockOpsCBFlags = 59
+	helperGetCgroupID    = 80
+)
+
+// mapUpdateAny lets helperMapUpdate create or replace an element.
+const mapUpdateAny = 0
+
+// instruction is an eBPF instruction, or a label marking the position of the
+// next one. Jumps name their target label rather than an offset.
+type instruction struct {
+	op       uint8
+	dst, src register
+	off      int16
+	imm      int64
+	target   string
+	label    string
+}
+
+func loadImm64(dst register, imm int64) instruction {
+	return instruction{op: opLoadImm64, dst: dst, imm: imm}
+}
+
+func loadMapFD(dst register, fd int) instruction {
+	return instruction{op: opLoadImm64, dst: dst, src: pseudoMapFD, imm: int64(fd)}
+}
+
+func loadWord(dst, src register, off int16) instruction {
+	return instruction{op: opLoadWord, dst: dst, src: src, off: off}
+}
+
+func loadDouble(dst, src register, off int16) instruction {
+	return instruction{op: opLoadDouble, dst: dst, src: src, off: off}
+}
+
+func storeDouble(dst register, off int16, src register) instruction {
+	return instruction{op: opStoreDouble, dst: dst, src: src, off: off}
+}
+
+// atomicAdd adds src to the double word at dst+off.
+func atomicAdd(dst register, off int16, src register) instruction {
+	return instruction{op: opAtomicDouble, dst: dst, src: src, off: off}
+}
+
+func addImm(dst register, imm int32) instruction {
+	return instruction{op: opAddImm, dst: dst, imm: int64(imm)}
+}
+
+func subReg(dst, src register) instruction {
+	return instruction{op: opSubReg, dst: dst, src: src}
+}
+
+func orImm(dst register, imm int32) instruction {
+	return instruction{op: opOrImm, dst: dst, imm: int64(imm)}
+}
+
+func movImm(dst register, imm int32) instruction {
+	return instruction{op: opMovImm, dst: dst, imm: int64(imm)}
+}
+
+func movReg(dst, src register) instruction {
+	return instruction{op: opMovReg, dst: dst, src: src}
+}
+
+func jump(target string) instruction {
+	return instruction{op: opJump, target: target}
+}
+
+func jumpEqImm(dst register, imm int32, target string) instruction {
+	return instruction{op: opJumpEqImm, dst: dst, imm: int64(imm), target: target}
+}
+
+func call(helper int32) instruction {
+	return instruction{op: opCall, imm: int64(helper)}
+}
+
+func exit() instruction {
+	return instruction{op: opExit}
+}
+
+func label(name string) instruction {
+	return instruction{label: name}
+}
+
+// slots returns the number of 8-byte slots an instruction occupies.
+func (i instruction) slots() int {
+	switch {
+	case i.label != "":
+		return 0
+	case i.op == opLoadImm64:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// assemble encodes a program, resolving jump targets. Instructions are encoded
+// little-endian, the byte order of every architecture the probes support.
+func assemble(program []instruction) ([]byte, error) {
+	labels := make(map[string]int)
+	var pc int
+	for _, i := range program {
+		if i.label != "" {
+			if _, ok := labels[i.label]; ok {
+				return nil, fmt.Errorf("duplicate label %q", i.label)
+			}
+			labels[i.label] = pc
+		}
+		pc += i.slots()
+	}
+
+	code := make([]byte, 0, pc*8)
+	pc = 0
+	for _, i := range program {
+		if i.label != "" {
+			continue
+		}
+		off := i.off
+		if i.target != "" {
+			target, ok := labels[i.target]
+			if !ok {
+				return nil, fmt.Errorf("undefined label %q", i.target)
+			}
+			// Offsets are relative to the next instruction.
+			off = int16(target - pc - 1)
+		}
+
+		var slot [8]byte
+		slot[0] = i.op
+		slot[1] = uint8(i.src)<<4 | uint8(i.dst)
+		binary.LittleEndian.PutUint16(slot[2:], uint16(off))
+		binary.LittleEndian.PutUint32(slot[4:], uint32(i.imm))
+		code = append(code, slot[:]...)
+		if i.op == opLoadImm64 {
+			// The upper half of the immediate occupies a second slot.
+			var next [8]byte
+			binary.LittleEndian.PutUint32(next[4:], uint32(uint64(i.imm)>>32))
+			code = append(code, next[:]...)
+		}
+		pc += i.slots()
+	}
+	return code, nil
+}
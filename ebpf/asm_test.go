@@ -0,0 +1,51 @@
+package ebpf
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssemble(t *testing.T) {
+	code, err := assemble([]instruction{
+		jumpEqImm(r1, 7, "out"),
+		loadImm64(r2, 0x1122334455667788),
+		loadMapFD(r3, 5),
+		label("out"),
+		movImm(r0, -1),
+		exit(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"1501040007000000"+ // Skips both wide loads, four slots.
+		"1802000088776655"+"0000000044332211"+
+		"1813000005000000"+"0000000000000000"+
+		"b7000000ffffffff"+
+		"9500000000000000",
+		hex.EncodeToString(code))
+
+	// Backward jumps have negative offsets.
+	code, err = assemble([]instruction{label("top"), movImm(r0, 0), jump("top")})
+	require.NoError(t, err)
+	assert.Equal(t, "b700000000000000"+"0500feff00000000", hex.EncodeToString(code))
+
+	_, err = assemble([]instruction{jump("nowhere")})
+	assert.EqualError(t, err, `undefined label "nowhere"`)
+	_, err = assemble([]instruction{label("a"), label("a")})
+	assert.EqualError(t, err, `duplicate label "a"`)
+}
+
+func TestPrograms(t *testing.T) {
+	// Programs must assemble and end by returning.
+	for name, program := range map[string][]instruction{
+		"sockOps": sockOpsProgram(3, 1<<40),
+		"ioEnter": ioEnterProgram(3, 4, []int{0, 1}),
+		"ioExit":  ioExitProgram(3, 4),
+	} {
+		code, err := assemble(program)
+		require.NoError(t, err, name)
+		assert.Equal(t, "9500000000000000", hex.EncodeToString(code[len(code)-8:]), name)
+	}
+}
@@ -0,0 +1,195 @@
+//go:build linux
+// +build linux
+
+package ebpf
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// This file wraps the bpf(2) system call. Attribute structs mirror the
+// corresponding members of union bpf_attr in <linux/bpf.h>.
+
+type mapCreateAttr struct {
+	MapType    uint32
+	KeySize    uint32
+	ValueSize  uint32
+	MaxEntries uint32
+	MapFlags   uint32
+}
+
+type mapElemAttr struct {
+	MapFD uint32
+	_     uint32
+	Key   uint64
+	Value uint64
+	Flags uint64
+}
+
+type progLoadAttr struct {
+	ProgType    uint32
+	InsnCnt     uint32
+	Insns       uint64
+	License     uint64
+	LogLevel    uint32
+	LogSize     uint32
+	LogBuf      uint64
+	KernVersion uint32
+	ProgFlags   uint32
+	ProgName    [16]byte
+}
+
+type progAttachAttr struct {
+	TargetFD    uint32
+	AttachBPFFD uint32
+	AttachType  uint32
+	AttachFlags uint32
+}
+
+type rawTracepointAttr struct {
+	Name   uint64
+	ProgFD uint32
+	_      uint32
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// createHashMap creates a hash map of 8-byte keys.
+func createHashMap(valueSize, maxEntries uint32) (int, error) {
+	attr := mapCreateAttr{
+		MapType:    unix.BPF_MAP_TYPE_HASH,
+		KeySize:    8,
+		ValueSize:  valueSize,
+		MaxEntries: maxEntries,
+	}
+	fd, err := bpf(unix.BPF_MAP_CREATE, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return -1, fmt.Errorf("creating map: %w", err)
+	}
+	return fd, nil
+}
+
+// lookupElem copies the value of a map's key into value, which must point to
+// memory of the map's value size.
+func lookupElem(mapFD int, key uint64, value unsafe.Pointer) error {
+	attr := mapElemAttr{
+		MapFD: uint32(mapFD),
+		Key:   uint64(uintptr(unsafe.Pointer(&key))),
+		Value: uint64(uintptr(value)),
+	}
+	_, err := bpf(unix.BPF_MAP_LOOKUP_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(&key)
+	return err
+}
+
+// updateElem sets the value of a map's key.
+func updateElem(mapFD int, key uint64, value unsafe.Pointer) error {
+	attr := mapElemAttr{
+		MapFD: uint32(mapFD),
+		Key:   uint64(uintptr(unsafe.Pointer(&key))),
+		Value: uint64(uintptr(value)),
+		Flags: unix.BPF_ANY,
+	}
+	_, err := bpf(unix.BPF_MAP_UPDATE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(&key)
+	return err
+}
+
+// deleteElem removes a map's key.
+func deleteElem(mapFD int, key uint64) error {
+	attr := mapElemAttr{
+		MapFD: uint32(mapFD),
+		Key:   uint64(uintptr(unsafe.Pointer(&key))),
+	}
+	_, err := bpf(unix.BPF_MAP_DELETE_ELEM, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(&key)
+	return err
+}
+
+// loadProgram assembles and loads a program. If the verifier rejects it, its
+// log is included in the error.
+func loadProgram(progType uint32, name string, program []instruction) (int, error) {
+	code, err := assemble(program)
+	if err != nil {
+		return -1, fmt.Errorf("assembling %s: %w", name, err)
+	}
+	license := []byte("Apache-2.0\x00")
+	attr := progLoadAttr{
+		ProgType: progType,
+		InsnCnt:  uint32(len(code) / 8),
+		Insns:    uint64(uintptr(unsafe.Pointer(&code[0]))),
+		License:  uint64(uintptr(unsafe.Pointer(&license[0]))),
+	}
+	copy(attr.ProgName[:len(attr.ProgName)-1], name)
+
+	fd, err := bpf(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		// Load again to collect the verifier's explanation.
+		log := make([]byte, 64*1024)
+		attr.LogLevel = 1
+		attr.LogSize = uint32(len(log))
+		attr.LogBuf = uint64(uintptr(unsafe.Pointer(&log[0])))
+		if _, retryErr := bpf(unix.BPF_PROG_LOAD, unsafe.Pointer(&attr), unsafe.Sizeof(attr)); retryErr != nil {
+			if n := bytes.IndexByte(log, 0); n > 0 {
+				err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(log[:n]))
+			}
+		}
+		runtime.KeepAlive(log)
+	}
+	runtime.KeepAlive(code)
+	runtime.KeepAlive(license)
+	if err != nil {
+		return -1, fmt.Errorf("loading %s: %w", name, err)
+	}
+	return fd, nil
+}
+
+// attachCgroup attaches a program to a cgroup alongside any others.
+func attachCgroup(cgroupFD, progFD int, attachType uint32) error {
+	attr := progAttachAttr{
+		TargetFD:    uint32(cgroupFD),
+		AttachBPFFD: uint32(progFD),
+		AttachType:  attachType,
+		AttachFlags: unix.BPF_F_ALLOW_MULTI,
+	}
+	_, err := bpf(unix.BPF_PROG_ATTACH, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+// detachCgroup detaches a program attached by attachCgroup.
+func detachCgroup(cgroupFD, progFD int, attachType uint32) error {
+	attr := progAttachAttr{
+		TargetFD:    uint32(cgroupFD),
+		AttachBPFFD: uint32(progFD),
+		AttachType:  attachType,
+	}
+	_, err := bpf(unix.BPF_PROG_DETACH, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	return err
+}
+
+// attachRawTracepoint attaches a program to a raw tracepoint. The program is
+// detached when the returned descriptor is closed.
+func attachRawTracepoint(name string, progFD int) (int, error) {
+	cname := append([]byte(name), 0)
+	attr := rawTracepointAttr{
+		Name:   uint64(uintptr(unsafe.Pointer(&cname[0]))),
+		ProgFD: uint32(progFD),
+	}
+	fd, err := bpf(unix.BPF_RAW_TRACEPOINT_OPEN, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(cname)
+	if err != nil {
+		return -1, fmt.Errorf("attaching to %s: %w", name, err)
+	}
+	return fd, nil
+}
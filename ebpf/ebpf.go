@@ -0,0 +1,38 @@
+// Package ebpf attributes network and file IO events to containers with eBPF
+// probes, collecting statistics which the usual stats APIs can't provide:
+// TCP retransmits, TCP connections, and file IO latency. Events are keyed by
+// the ID of the container's cgroup in the v2 hierarchy; see cgroup.ID.
+//
+// Probes are only available on Linux 4.18 or later with a v2 or hybrid cgroup
+// hierarchy, and loading them requires CAP_BPF and CAP_PERFMON, or root.
+package ebpf
+
+import (
+	"github.com/beaker/runtime"
+)
+
+// stats converts a cgroup's counters to container stats. File IO latency is
+// averaged over the calls made since the previous sample.
+func stats(current, previous counters) map[runtime.StatType]float64 {
+	result := map[runtime.StatType]float64{
+		runtime.TCPRetransmitsStat: float64(current.Retransmits),
+		runtime.TCPConnectionsStat: float64(current.Connections),
+		runtime.FileIOCallsStat:    float64(current.IOCalls),
+	}
+	if calls := current.IOCalls - previous.IOCalls; current.IOCalls > previous.IOCalls {
+		nanos := current.IONanos - previous.IONanos
+		result[runtime.FileIOLatencyStat] = float64(nanos) / float64(calls) / 1e9
+	}
+	return result
+}
+
+// AddStats merges a container's probe statistics into stats sampled from
+// elsewhere, such as a runtime's stats API.
+func AddStats(stats *runtime.ContainerStats, probes map[runtime.StatType]float64) {
+	if stats.Stats == nil {
+		stats.Stats = make(map[runtime.StatType]float64, len(probes))
+	}
+	for k, v := range probes {
+		stats.Stats[k] = v
+	}
+}
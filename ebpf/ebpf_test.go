@@ -0,0 +1,27 @@
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/beaker/runtime"
+)
+
+func TestStats(t *testing.T) {
+	previous := counters{Retransmits: 1, Connections: 2, IOCalls: 10, IONanos: 10000}
+	current := counters{Retransmits: 3, Connections: 4, IOCalls: 14, IONanos: 18000}
+	assert.Equal(t, map[runtime.StatType]float64{
+		runtime.TCPRetransmitsStat: 3,
+		runtime.TCPConnectionsStat: 4,
+		runtime.FileIOCallsStat:    14,
+		runtime.FileIOLatencyStat:  2e-6,
+	}, stats(current, previous))
+
+	// Latency is omitted without new calls.
+	assert.NotContains(t, stats(current, current), runtime.FileIOLatencyStat)
+
+	s := &runtime.ContainerStats{}
+	AddStats(s, stats(current, previous))
+	assert.Equal(t, 4.0, s.Stats[runtime.TCPConnectionsStat])
+}
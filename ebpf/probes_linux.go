@@ -0,0 +1,228 @@
+//go:build linux
+// +build linux
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/beaker/runtime"
+	"github.com/beaker/runtime/cgroup"
+)
+
+// ioSyscalls are the system calls whose latency is measured as file IO. They
+// include reads and writes of pipes and sockets as well as regular files.
+var ioSyscalls = []int{
+	unix.SYS_READ, unix.SYS_WRITE,
+	unix.SYS_PREAD64, unix.SYS_PWRITE64,
+	unix.SYS_READV, unix.SYS_WRITEV,
+}
+
+const (
+	// Maximum number of watched cgroups.
+	maxCgroups = 4096
+
+	// Maximum number of concurrent system calls timed. Calls beyond it aren't
+	// measured.
+	maxInFlight = 65536
+)
+
+// Probes attribute network and file IO events to watched containers. Load them
+// once per node and share them between runtimes. Probes are safe for
+// concurrent use.
+//
+// File IO probes run on every read and write system call on the node, adding
+// a small overhead to each, though only calls from watched cgroups are timed.
+type Probes struct {
+	counters int // Map of cgroup ID to counters.
+	starts   int // Map of PID and TGID to the start time of a call.
+	programs []int
+	links    []int
+
+	mu      sync.Mutex
+	watches map[uint64]*watch
+	closed  bool
+}
+
+// watch is a watched cgroup.
+type watch struct {
+	dir      string
+	cgroupFD int
+	program  int
+	previous counters
+}
+
+// Load loads and attaches the probes. It returns an error wrapping
+// runtime.ErrNotImplemented if the kernel doesn't support them.
+func Load() (*Probes, error) {
+	p := &Probes{counters: -1, starts: -1, watches: make(map[uint64]*watch)}
+	if err := p.load(); err != nil {
+		p.Close()
+		if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOSYS) {
+			return nil, fmt.Errorf("ebpf: %v (%w)", err, runtime.ErrNotImplemented)
+		}
+		return nil, fmt.Errorf("ebpf: %w", err)
+	}
+	return p, nil
+}
+
+func (p *Probes) load() error {
+	var err error
+	if p.counters, err = createHashMap(countersSize, maxCgroups); err != nil {
+		return err
+	}
+	if p.starts, err = createHashMap(8, maxInFlight); err != nil {
+		return err
+	}
+
+	for _, probe := range []struct {
+		tracepoint string
+		program    []instruction
+	}{
+		{"sys_enter", ioEnterProgram(p.counters, p.starts, ioSyscalls)},
+		{"sys_exit", ioExitProgram(p.counters, p.starts)},
+	} {
+		prog, err := loadProgram(unix.BPF_PROG_TYPE_RAW_TRACEPOINT, "io_"+probe.tracepoint, probe.program)
+		if err != nil {
+			return err
+		}
+		p.programs = append(p.programs, prog)
+
+		link, err := attachRawTracepoint(probe.tracepoint, prog)
+		if err != nil {
+			return err
+		}
+		p.links = append(p.links, link)
+	}
+	return nil
+}
+
+// Close detaches the probes from the kernel and every watched cgroup.
+func (p *Probes) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	for id := range p.watches {
+		p.unwatch(id)
+	}
+	for _, fd := range append(p.links, p.programs...) {
+		unix.Close(fd)
+	}
+	for _, fd := range []int{p.counters, p.starts} {
+		if fd >= 0 {
+			unix.Close(fd)
+		}
+	}
+	return nil
+}
+
+// Watch starts counting events in the cgroup of a container's process. Events
+// before the call aren't counted, so it should be called as soon as the
+// container starts. Watching a cgroup again has no effect.
+//
+// Cgroups are unwatched once they're removed, so watching needn't be undone.
+func (p *Probes) Watch(pid int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := p.watch(pid)
+	return err
+}
+
+// Stats returns the events counted in the cgroup of a container's process. The
+// cgroup is watched if it isn't already, in which case nothing has been
+// counted yet.
+func (p *Probes) Stats(pid int) (map[runtime.StatType]float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id, err := p.watch(pid)
+	if err != nil {
+		return nil, err
+	}
+	var current counters
+	if err := lookupElem(p.counters, id, unsafe.Pointer(&current)); err != nil {
+		return nil, fmt.Errorf("ebpf: reading counters: %w", err)
+	}
+	w := p.watches[id]
+	result := stats(current, w.previous)
+	w.previous = current
+	return result, nil
+}
+
+// watch watches a process's cgroup if it isn't already and returns its ID.
+// The caller must hold p.mu.
+func (p *Probes) watch(pid int) (uint64, error) {
+	if p.closed {
+		return 0, errors.New("ebpf: probes are closed")
+	}
+	id, err := cgroup.ID(pid)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.watches[id]; ok {
+		return id, nil
+	}
+	dir, err := cgroup.UnifiedDir(pid)
+	if err != nil {
+		return 0, err
+	}
+	p.prune()
+
+	// Counters must exist before the probes can add to them.
+	var zero counters
+	if err := updateElem(p.counters, id, unsafe.Pointer(&zero)); err != nil {
+		return 0, fmt.Errorf("ebpf: watching %s: %w", dir, err)
+	}
+	w := &watch{dir: dir, cgroupFD: -1, program: -1}
+	p.watches[id] = w
+
+	if w.cgroupFD, err = unix.Open(dir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0); err != nil {
+		p.unwatch(id)
+		return 0, fmt.Errorf("ebpf: watching %s: %w", dir, err)
+	}
+	if w.program, err = loadProgram(unix.BPF_PROG_TYPE_SOCK_OPS, "tcp", sockOpsProgram(p.counters, id)); err != nil {
+		p.unwatch(id)
+		return 0, fmt.Errorf("ebpf: watching %s: %w", dir, err)
+	}
+	if err := attachCgroup(w.cgroupFD, w.program, unix.BPF_CGROUP_SOCK_OPS); err != nil {
+		unix.Close(w.program)
+		w.program = -1
+		p.unwatch(id)
+		return 0, fmt.Errorf("ebpf: watching %s: %w", dir, err)
+	}
+	return id, nil
+}
+
+// prune unwatches cgroups which have been removed, releasing their resources.
+// The caller must hold p.mu.
+func (p *Probes) prune() {
+	for id, w := range p.watches {
+		if _, err := os.Stat(w.dir); os.IsNotExist(err) {
+			p.unwatch(id)
+		}
+	}
+}
+
+// unwatch stops counting a cgroup's events. The caller must hold p.mu.
+func (p *Probes) unwatch(id uint64) {
+	w := p.watches[id]
+	delete(p.watches, id)
+	if w.program >= 0 {
+		_ = detachCgroup(w.cgroupFD, w.program, unix.BPF_CGROUP_SOCK_OPS) // The cgroup may be gone.
+		unix.Close(w.program)
+	}
+	if w.cgroupFD >= 0 {
+		unix.Close(w.cgroupFD)
+	}
+	_ = deleteElem(p.counters, id)
+}
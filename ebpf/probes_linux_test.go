@@ -0,0 +1,121 @@
+//go:build linux
+// +build linux
+
+package ebpf
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime"
+)
+
+// TestProbes runs a process in its own cgroup and checks that its connections
+// and file IO are attributed to it. It requires root and a kernel which
+// supports the probes, so it's skipped elsewhere.
+func TestProbes(t *testing.T) {
+	p, err := Load()
+	if err != nil {
+		t.Skipf("Probes are unavailable: %v", err)
+	}
+	defer p.Close()
+
+	root := "/sys/fs/cgroup"
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		root = filepath.Join(root, "unified")
+	}
+	dir := filepath.Join(root, fmt.Sprintf("ebpf-test-%d", os.Getpid()))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Skipf("Can't create a cgroup: %v", err)
+	}
+	defer os.Remove(dir)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestProbeHelper")
+	cmd.Env = append(os.Environ(), "EBPF_TEST_CGROUP="+dir, "EBPF_TEST_ADDR="+listener.Addr().String())
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	defer cmd.Wait()
+	defer stdin.Close()
+	lines := bufio.NewScanner(stdout)
+
+	// Watch the helper once it has joined the cgroup, then let it work.
+	require.True(t, lines.Scan())
+	require.Equal(t, "joined", lines.Text())
+	require.NoError(t, p.Watch(cmd.Process.Pid))
+	_, err = stdin.Write([]byte("go\n"))
+	require.NoError(t, err)
+	require.True(t, lines.Scan())
+	require.Equal(t, "done", lines.Text())
+
+	stats, err := p.Stats(cmd.Process.Pid)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, stats[runtime.TCPConnectionsStat])
+	assert.Contains(t, stats, runtime.TCPRetransmitsStat)
+	assert.GreaterOrEqual(t, stats[runtime.FileIOCallsStat], 100.0)
+	assert.Greater(t, stats[runtime.FileIOLatencyStat], 0.0)
+
+	// This process's IO isn't attributed to the helper's cgroup.
+	_, err = p.Stats(os.Getpid())
+	require.NoError(t, err)
+	stats, err = p.Stats(cmd.Process.Pid)
+	require.NoError(t, err)
+	assert.NotContains(t, stats, runtime.FileIOLatencyStat)
+}
+
+// TestProbeHelper is run as a separate process by TestProbes. It joins a
+// cgroup, then opens a connection and writes a file when told to.
+func TestProbeHelper(t *testing.T) {
+	dir, addr := os.Getenv("EBPF_TEST_CGROUP"), os.Getenv("EBPF_TEST_ADDR")
+	if dir == "" {
+		t.Skip("Only run by TestProbes")
+	}
+	pid := []byte(strconv.Itoa(os.Getpid()))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cgroup.procs"), pid, 0))
+	fmt.Println("joined")
+
+	stdin := bufio.NewScanner(os.Stdin)
+	require.True(t, stdin.Scan())
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	conn.Close()
+
+	f, err := ioutil.TempFile("", "ebpf-test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	for i := 0; i < 100; i++ {
+		_, err := f.Write([]byte("data"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+	fmt.Println("done")
+
+	// Wait to be inspected.
+	stdin.Scan()
+}
@@ -0,0 +1,32 @@
+//go:build !linux
+// +build !linux
+
+package ebpf
+
+import (
+	"github.com/beaker/runtime"
+)
+
+// Probes attribute network and file IO events to watched containers. They're
+// only supported on Linux.
+type Probes struct{}
+
+// Load loads and attaches the probes. Probes are only supported on Linux.
+func Load() (*Probes, error) {
+	return nil, runtime.ErrNotImplemented
+}
+
+// Close detaches the probes.
+func (p *Probes) Close() error {
+	return nil
+}
+
+// Watch starts counting events in the cgroup of a container's process.
+func (p *Probes) Watch(pid int) error {
+	return runtime.ErrNotImplemented
+}
+
+// Stats returns the events counted in the cgroup of a container's process.
+func (p *Probes) Stats(pid int) (map[runtime.StatType]float64, error) {
+	return nil, runtime.ErrNotImplemented
+}
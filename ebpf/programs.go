@@ -0,0 +1,162 @@
+package ebpf
+
+// Counters are the values of the counters map, keyed by cgroup ID. Each field
+// is a uint64 at the given offset, updated atomically by the probes.
+type counters struct {
+	Retransmits uint64
+	Connections uint64
+	IOCalls     uint64
+	IONanos     uint64
+}
+
+const (
+	offRetransmits = 0
+	offConnections = 8
+	offIOCalls     = 16
+	offIONanos     = 24
+	countersSize   = 32
+)
+
+// Offsets within struct bpf_sock_ops, the context of a sock_ops program.
+const (
+	sockOpsOp      = 0
+	sockOpsCBFlags = 84
+)
+
+// Socket operations, from enum in <linux/bpf.h>.
+const (
+	sockOpsActiveEstablished  = 4
+	sockOpsPassiveEstablished = 5
+	sockOpsRetransmit         = 9
+
+	// Requests sockOpsRetransmit callbacks for a socket.
+	sockOpsRetransmitFlag = 2
+)
+
+// sockOpsProgram counts a cgroup's established TCP connections and retransmitted
+// segments. It's attached to the cgroup, so it sees only sockets created there,
+// and adds to the counters of the cgroup ID it's built with. Sockets created
+// before the program is attached aren't counted.
+//
+// Retransmits are reported in softirq context, where the current task's cgroup
+// is unrelated to the socket's, so attaching a program per cgroup is the only
+// reliable way to attribute them.
+func sockOpsProgram(countersFD int, cgroupID uint64) []instruction {
+	return []instruction{
+		movReg(r6, r1),
+		loadWord(r2, r6, sockOpsOp),
+		jumpEqImm(r2, sockOpsActiveEstablished, "established"),
+		jumpEqImm(r2, sockOpsPassiveEstablished, "established"),
+		jumpEqImm(r2, sockOpsRetransmit, "retransmit"),
+		jump("out"),
+
+		// Count the connection and ask to hear about its retransmits.
+		label("established"),
+		loadWord(r2, r6, sockOpsCBFlags),
+		orImm(r2, sockOpsRetransmitFlag),
+		movReg(r1, r6),
+		call(helperSockOpsCBFlags),
+		movImm(r7, offConnections),
+		jump("count"),
+
+		label("retransmit"),
+		movImm(r7, offRetransmits),
+
+		// Add one to the counter at offset r7.
+		label("count"),
+		loadImm64(r1, int64(cgroupID)),
+		storeDouble(r10, -8, r1),
+		loadMapFD(r1, countersFD),
+		movReg(r2, r10),
+		addImm(r2, -8),
+		call(helperMapLookup),
+		jumpEqImm(r0, 0, "out"),
+		movImm(r1, 1),
+		jumpEqImm(r7, offRetransmits, "add-retransmit"),
+		atomicAdd(r0, offConnections, r1),
+		jump("out"),
+		label("add-retransmit"),
+		atomicAdd(r0, offRetransmits, r1),
+
+		label("out"),
+		movImm(r0, 1),
+		exit(),
+	}
+}
+
+// ioEnterProgram records when a tracked cgroup's task enters a file IO system
+// call. It's attached to the raw sys_enter tracepoint, whose second argument is
+// the system call number. Start times are keyed by the task's PID and TGID.
+func ioEnterProgram(countersFD, startsFD int, syscalls []int) []instruction {
+	program := []instruction{loadDouble(r2, r1, 8)}
+	for _, nr := range syscalls {
+		program = append(program, jumpEqImm(r2, int32(nr), "io"))
+	}
+	return append(program,
+		jump("out"),
+
+		// Only cgroups with counters are tracked.
+		label("io"),
+		call(helperGetCgroupID),
+		storeDouble(r10, -8, r0),
+		loadMapFD(r1, countersFD),
+		movReg(r2, r10),
+		addImm(r2, -8),
+		call(helperMapLookup),
+		jumpEqImm(r0, 0, "out"),
+
+		call(helperGetPIDTGID),
+		storeDouble(r10, -8, r0),
+		call(helperKtimeGetNS),
+		storeDouble(r10, -16, r0),
+		loadMapFD(r1, startsFD),
+		movReg(r2, r10),
+		addImm(r2, -8),
+		movReg(r3, r10),
+		addImm(r3, -16),
+		movImm(r4, mapUpdateAny),
+		call(helperMapUpdate),
+
+		label("out"),
+		movImm(r0, 0),
+		exit(),
+	)
+}
+
+// ioExitProgram adds the latency of a system call recorded by ioEnterProgram to
+// its cgroup's counters. It's attached to the raw sys_exit tracepoint.
+func ioExitProgram(countersFD, startsFD int) []instruction {
+	return []instruction{
+		call(helperGetPIDTGID),
+		storeDouble(r10, -8, r0),
+		loadMapFD(r1, startsFD),
+		movReg(r2, r10),
+		addImm(r2, -8),
+		call(helperMapLookup),
+		jumpEqImm(r0, 0, "out"),
+		loadDouble(r6, r0, 0),
+		loadMapFD(r1, startsFD),
+		movReg(r2, r10),
+		addImm(r2, -8),
+		call(helperMapDelete),
+
+		call(helperKtimeGetNS),
+		subReg(r0, r6),
+		movReg(r7, r0),
+
+		call(helperGetCgroupID),
+		storeDouble(r10, -16, r0),
+		loadMapFD(r1, countersFD),
+		movReg(r2, r10),
+		addImm(r2, -16),
+		call(helperMapLookup),
+		jumpEqImm(r0, 0, "out"),
+		movImm(r1, 1),
+		atomicAdd(r0, offIOCalls, r1),
+		atomicAdd(r0, offIONanos, r7),
+
+		label("out"),
+		movImm(r0, 0),
+		exit(),
+	}
+}
@@ -2,6 +2,8 @@ package runtime
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 )
 
 var (
@@ -13,4 +15,71 @@ var (
 
 	// ErrNotImplemented indicates the underlying runtime hasn't implemented a function.
 	ErrNotImplemented = errors.New("not implemented")
+
+	// ErrOversubscribed indicates a reservation would exceed a node's capacity.
+	ErrOversubscribed = errors.New("insufficient resources")
+
+	// ErrClosed indicates an operation was attempted on a runtime, or one of
+	// its containers, after the runtime was closed.
+	ErrClosed = errors.New("runtime is closed")
+
+	// ErrImageChanged indicates a tag's local image differs from the one
+	// recorded when it was pulled.
+	ErrImageChanged = errors.New("image changed since it was pulled")
 )
+
+// BatchError reports the partial failure of a batch operation. Items are
+// identified by name, such as a container's name or an image's tag.
+type BatchError struct {
+	// Succeeded lists items for which the operation succeeded, in the order
+	// they were given.
+	Succeeded []string
+
+	// Failed lists items for which the operation failed, in the order they
+	// were given. Items may share a name, e.g. an image listed twice.
+	Failed []BatchFailure
+}
+
+// BatchFailure is the failure of one item of a batch operation.
+type BatchFailure struct {
+	Name string
+	Err  error
+}
+
+func (e *BatchError) Error() string {
+	errs := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Name + ": " + f.Err.Error()
+	}
+	return fmt.Sprintf("%d of %d failed: %s",
+		len(e.Failed), len(e.Failed)+len(e.Succeeded), strings.Join(errs, "; "))
+}
+
+// Unwrap returns the errors of the failed items, so that errors.Is and
+// errors.As match any of them.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// GPUError reports that a node's GPU software can't support a container.
+type GPUError struct {
+	Reason GPUIncompatibility
+
+	// Message describes the problem and how to fix it.
+	Message string
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+func (e *GPUError) Error() string {
+	return e.Message
+}
+
+func (e *GPUError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,219 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exporterTimeout bounds each scrape, and the shutdown of each exporter.
+const exporterTimeout = 10 * time.Second
+
+// exportedStat describes how a stat is exported to Prometheus.
+type exportedStat struct {
+	name, kind, help string
+}
+
+// exportedStats are the stats served by exporters, by type. Other stats are
+// omitted.
+var exportedStats = map[StatType]exportedStat{
+	CPUUsageCoresStat:          {"container_cpu_usage_cores", "gauge", "CPU usage in cores."},
+	CPUUsageOfLimitPercentStat: {"container_cpu_usage_of_limit_percent", "gauge", "CPU usage as a percentage of the container's limit."},
+	CPUUsageOfHostPercentStat:  {"container_cpu_usage_of_host_percent", "gauge", "CPU usage as a percentage of the host's CPUs."},
+	MemoryUsageBytesStat:       {"container_memory_usage_bytes", "gauge", "Memory usage in bytes."},
+	MemoryUsagePercentStat:     {"container_memory_usage_percent", "gauge", "Memory usage as a percentage of the container's limit."},
+	NetworkRxBytesStat:         {"container_network_receive_bytes_total", "counter", "Bytes received over the network."},
+	NetworkTxBytesStat:         {"container_network_transmit_bytes_total", "counter", "Bytes transmitted over the network."},
+	BlockReadBytesStat:         {"container_block_read_bytes_total", "counter", "Bytes read from block devices."},
+	BlockWriteBytesStat:        {"container_block_write_bytes_total", "counter", "Bytes written to block devices."},
+}
+
+// WriteMetrics writes a container's details and usage in the Prometheus text
+// exposition format. Each metric is labeled with the container's name and its
+// labels, whose keys are prefixed with "label_" and sanitized as Prometheus
+// requires, e.g. "beaker.org/job" becomes "label_beaker_org_job". Stats may be
+// nil if the container isn't running.
+func WriteMetrics(w io.Writer, name string, info *ContainerInfo, stats *ContainerStats) error {
+	labels := exportedLabels(name, info.Labels)
+	bw := bufio.NewWriter(w)
+	write := func(metric exportedStat, value float64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", metric.name, metric.kind)
+		fmt.Fprintf(bw, "%s%s %s\n", metric.name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+
+	var running float64
+	if info.Status == StatusRunning {
+		running = 1
+	}
+	write(exportedStat{"container_running", "gauge", "Whether the container is running."}, running)
+
+	if stats != nil {
+		types := make([]StatType, 0, len(stats.Stats))
+		for t := range stats.Stats {
+			if _, ok := exportedStats[t]; ok {
+				types = append(types, t)
+			}
+		}
+		sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+		for _, t := range types {
+			write(exportedStats[t], stats.Stats[t])
+		}
+	}
+	return bw.Flush()
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// exportedLabels formats a container's labels as a Prometheus label set.
+func exportedLabels(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(`{container="` + escapeLabelValue(name) + `"`)
+	seen := map[string]bool{}
+	for _, k := range keys {
+		key := "label_" + invalidLabelChars.ReplaceAllString(k, "_")
+		if seen[key] {
+			continue // Sanitized keys may collide; the first wins.
+		}
+		seen[key] = true
+		b.WriteString(`,` + key + `="` + escapeLabelValue(labels[k]) + `"`)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+// Exporters serves the usage of individual containers to Prometheus, each on
+// its own local address, so that every task has a metrics endpoint without a
+// central collector. Usage is sampled from the container on each scrape. An
+// exporter stops on its own once its container is removed.
+type Exporters struct {
+	mu      sync.Mutex
+	servers map[string]*http.Server
+	wg      sync.WaitGroup
+}
+
+// NewExporters creates an empty set of exporters.
+func NewExporters() *Exporters {
+	return &Exporters{servers: make(map[string]*http.Server)}
+}
+
+// Add starts serving a container's metrics at "/metrics" on a TCP address,
+// replacing any exporter it already has. The address may have port 0, in
+// which case a port is chosen; the address actually used is returned.
+func (e *Exporters) Add(c Container, addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("starting exporter for %s: %w", c.Name(), err)
+	}
+
+	server := &http.Server{ReadHeaderTimeout: exporterTimeout}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		e.serve(w, r, c, server)
+	})
+	server.Handler = mux
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if prev, ok := e.servers[c.Name()]; ok {
+		e.stop(prev)
+	}
+	e.servers[c.Name()] = server
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		_ = server.Serve(listener)
+	}()
+	return listener.Addr(), nil
+}
+
+// serve responds to a scrape.
+func (e *Exporters) serve(w http.ResponseWriter, r *http.Request, c Container, server *http.Server) {
+	ctx, cancel := context.WithTimeout(r.Context(), exporterTimeout)
+	defer cancel()
+
+	info, err := c.Info(ctx)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "container was removed", http.StatusGone)
+		go e.remove(c.Name(), server)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var stats *ContainerStats
+	if info.Status == StatusRunning {
+		if stats, err = c.Stats(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = WriteMetrics(w, c.Name(), info, stats)
+}
+
+// Remove stops serving a container's metrics.
+func (e *Exporters) Remove(name string) {
+	e.remove(name, nil)
+}
+
+// remove stops a container's exporter if it's the given server, or any server
+// if nil, so that an exporter which was replaced can't remove its successor.
+func (e *Exporters) remove(name string, server *http.Server) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if current, ok := e.servers[name]; ok && (server == nil || current == server) {
+		e.stop(current)
+		delete(e.servers, name)
+	}
+}
+
+// Close stops all exporters and waits for them to finish.
+func (e *Exporters) Close() error {
+	e.mu.Lock()
+	for name, server := range e.servers {
+		e.stop(server)
+		delete(e.servers, name)
+	}
+	e.mu.Unlock()
+
+	e.wg.Wait()
+	return nil
+}
+
+// stop shuts down a server in the background, letting scrapes in progress
+// finish.
+func (e *Exporters) stop(server *http.Server) {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), exporterTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+}
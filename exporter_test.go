@@ -0,0 +1,102 @@
+package runtime
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	info := &ContainerInfo{
+		Status: StatusRunning,
+		Labels: map[string]string{"beaker.org/job": "j1", "note": "say \"hi\"\n"},
+	}
+	stats := &ContainerStats{Stats: map[StatType]float64{
+		MemoryUsageBytesStat: 1 << 30,
+		CPUUsageCoresStat:    2.5,
+		CPUUsagePercentStat:  250, // Not exported
+	}}
+
+	var b strings.Builder
+	require.NoError(t, WriteMetrics(&b, "task", info, stats))
+	labels := `{container="task",label_beaker_org_job="j1",label_note="say \"hi\"\n"}`
+	assert.Equal(t, `# HELP container_running Whether the container is running.
+# TYPE container_running gauge
+container_running`+labels+` 1
+# HELP container_cpu_usage_cores CPU usage in cores.
+# TYPE container_cpu_usage_cores gauge
+container_cpu_usage_cores`+labels+` 2.5
+# HELP container_memory_usage_bytes Memory usage in bytes.
+# TYPE container_memory_usage_bytes gauge
+container_memory_usage_bytes`+labels+` 1.073741824e+09
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, WriteMetrics(&b, "task", &ContainerInfo{Status: StatusExited}, nil))
+	assert.Equal(t, `# HELP container_running Whether the container is running.
+# TYPE container_running gauge
+container_running{container="task"} 0
+`, b.String())
+}
+
+// exportedContainer reports fixed usage until it's removed.
+type exportedContainer struct {
+	fakeContainer
+
+	mu      sync.Mutex
+	removed bool
+}
+
+func (c *exportedContainer) Info(ctx context.Context) (*ContainerInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.removed {
+		return nil, ErrNotFound
+	}
+	return &ContainerInfo{Status: StatusRunning}, nil
+}
+
+func (c *exportedContainer) Stats(ctx context.Context) (*ContainerStats, error) {
+	return &ContainerStats{Stats: map[StatType]float64{CPUUsageCoresStat: 1}}, nil
+}
+
+func TestExporters(t *testing.T) {
+	exporters := NewExporters()
+	defer exporters.Close()
+
+	c := &exportedContainer{}
+	addr, err := exporters.Add(c, "127.0.0.1:0")
+	require.NoError(t, err)
+	url := "http://" + addr.String() + "/metrics"
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `container_cpu_usage_cores{container="fake"} 1`)
+
+	// The exporter stops once its container is removed.
+	c.mu.Lock()
+	c.removed = true
+	c.mu.Unlock()
+	resp, err = http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+	assert.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,249 @@
+// Package format renders container details and resource usage for people, in
+// tables for CLI output or one-line summaries for logs. Units are consistent
+// throughout: memory and I/O in binary bytes, CPU in cores, and durations in
+// their two most significant units.
+package format
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/beaker/runtime"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Bytes formats a byte count with binary units, e.g. "1.5GiB".
+func Bytes(n float64) string {
+	return units.BytesSize(n)
+}
+
+// Cores formats a CPU count with up to two decimal places, e.g. "2.5".
+func Cores(n float64) string {
+	return trimZeros(strconv.FormatFloat(n, 'f', 2, 64))
+}
+
+// Percent formats a percentage with one decimal place, e.g. "42.5%".
+func Percent(p float64) string {
+	return strconv.FormatFloat(p, 'f', 1, 64) + "%"
+}
+
+// Duration formats a duration in its two most significant units, e.g. "3d4h"
+// or "2m5s". Durations under a second are "0s".
+func Duration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	d = d.Truncate(time.Second)
+	if d == 0 {
+		return "0s"
+	}
+
+	parts := []struct {
+		unit time.Duration
+		name string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	}
+	var b strings.Builder
+	count := 0
+	for _, p := range parts {
+		n := d / p.unit
+		d -= n * p.unit
+		if n == 0 && count == 0 {
+			continue
+		}
+		if n != 0 {
+			fmt.Fprintf(&b, "%d%s", n, p.name)
+		}
+		if count++; count == 2 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// Status describes a container's state and how long it has been in it, e.g.
+// "running 5m", "exited (1) 2h ago", or "exited (137, evicted) 3m ago".
+func Status(info *runtime.ContainerInfo) string {
+	switch info.Status {
+	case runtime.StatusCreated:
+		return "created " + ago(info.CreatedAt)
+	case runtime.StatusRunning:
+		if info.StartedAt.IsZero() {
+			return "running"
+		}
+		return "running " + Duration(now().Sub(info.StartedAt))
+	case runtime.StatusExited:
+		var details []string
+		if info.ExitCode != nil {
+			details = append(details, strconv.Itoa(*info.ExitCode))
+		}
+		if info.Interruption != nil {
+			details = append(details, string(info.Interruption.Reason))
+		}
+		s := "exited"
+		if len(details) != 0 {
+			s += " (" + strings.Join(details, ", ") + ")"
+		}
+		if !info.EndedAt.IsZero() {
+			s += " " + ago(info.EndedAt)
+		}
+		return s
+	default:
+		return "unknown"
+	}
+}
+
+// Limits describes a container's resource limits, e.g. "2 CPUs, 4GiB, 1 GPU".
+// Containers without limits are "unlimited".
+func Limits(info *runtime.ContainerInfo) string {
+	var limits []string
+	if info.CPUCount != 0 {
+		limits = append(limits, plural(Cores(info.CPUCount), "CPU", info.CPUCount))
+	}
+	if info.Memory != 0 {
+		limits = append(limits, Bytes(float64(info.Memory)))
+	}
+	if n := len(info.GPUs); n != 0 {
+		limits = append(limits, plural(strconv.Itoa(n), "GPU", float64(n)))
+	}
+	if info.MaxRuntime != 0 {
+		limits = append(limits, Duration(info.MaxRuntime)+" max")
+	}
+	if len(limits) == 0 {
+		return "unlimited"
+	}
+	return strings.Join(limits, ", ")
+}
+
+// CPU describes CPU usage in cores and, where known, as a percentage of the
+// container's limit, e.g. "1.5 (75.0%)". It's "-" if usage is unknown.
+func CPU(stats *runtime.ContainerStats) string {
+	if stats == nil {
+		return "-"
+	}
+	cores, ok := stats.Stats[runtime.CPUUsageCoresStat]
+	if !ok {
+		percent, ok := stats.Stats[runtime.CPUUsagePercentStat]
+		if !ok {
+			return "-"
+		}
+		cores = percent / 100
+	}
+	s := Cores(cores)
+	if percent, ok := stats.Stats[runtime.CPUUsageOfLimitPercentStat]; ok {
+		s += " (" + Percent(percent) + ")"
+	}
+	return s
+}
+
+// Memory describes memory usage in bytes and as a percentage of the
+// container's limit, e.g. "1.2GiB (30.0%)". It's "-" if usage is unknown.
+func Memory(stats *runtime.ContainerStats) string {
+	if stats == nil {
+		return "-"
+	}
+	bytes, ok := stats.Stats[runtime.MemoryUsageBytesStat]
+	if !ok {
+		return "-"
+	}
+	s := Bytes(bytes)
+	if percent, ok := stats.Stats[runtime.MemoryUsagePercentStat]; ok {
+		s += " (" + Percent(percent) + ")"
+	}
+	return s
+}
+
+// IO describes total network and block I/O, e.g. "net 1MiB/2MiB, disk
+// 0B/10MiB" as received/sent and read/written. Absent stats are omitted.
+func IO(stats *runtime.ContainerStats) string {
+	if stats == nil {
+		return "-"
+	}
+	var parts []string
+	if s := pair(stats, runtime.NetworkRxBytesStat, runtime.NetworkTxBytesStat); s != "" {
+		parts = append(parts, "net "+s)
+	}
+	if s := pair(stats, runtime.BlockReadBytesStat, runtime.BlockWriteBytesStat); s != "" {
+		parts = append(parts, "disk "+s)
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Summary describes a container on one line, suitable for logs, e.g.
+// "abc: running 5m; limits 2 CPUs, 4GiB; CPU 1.5 (75.0%); memory 1GiB (25.0%)".
+// Stats may be nil.
+func Summary(name string, info *runtime.ContainerInfo, stats *runtime.ContainerStats) string {
+	s := fmt.Sprintf("%s: %s; limits %s", name, Status(info), Limits(info))
+	if stats != nil {
+		s += fmt.Sprintf("; CPU %s; memory %s", CPU(stats), Memory(stats))
+		if usage := IO(stats); usage != "-" {
+			s += "; " + usage
+		}
+	}
+	return s
+}
+
+// Row is a container to be formatted as part of a table.
+type Row struct {
+	Name string
+	Info *runtime.ContainerInfo
+
+	// (optional) Stats are the container's latest usage, if sampled.
+	Stats *runtime.ContainerStats
+}
+
+// Table writes containers as an aligned table with a header.
+func Table(w io.Writer, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSTATUS\tAGE\tLIMITS\tCPU\tMEMORY")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Name, Status(r.Info), Duration(now().Sub(r.Info.CreatedAt)),
+			Limits(r.Info), CPU(r.Stats), Memory(r.Stats))
+	}
+	return tw.Flush()
+}
+
+func ago(t time.Time) string {
+	return Duration(now().Sub(t)) + " ago"
+}
+
+func plural(count, noun string, n float64) string {
+	if n == 1 {
+		return count + " " + noun
+	}
+	return count + " " + noun + "s"
+}
+
+func pair(stats *runtime.ContainerStats, in, out runtime.StatType) string {
+	a, okA := stats.Stats[in]
+	b, okB := stats.Stats[out]
+	if !okA && !okB {
+		return ""
+	}
+	return Bytes(a) + "/" + Bytes(b)
+}
+
+// trimZeros removes trailing zeros after a decimal point, and the point itself
+// if nothing follows it.
+func trimZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
@@ -0,0 +1,147 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime"
+)
+
+var testNow = time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func init() {
+	now = func() time.Time { return testNow }
+}
+
+func TestUnits(t *testing.T) {
+	assert.Equal(t, "1.5GiB", Bytes(1.5*1024*1024*1024))
+	assert.Equal(t, "0B", Bytes(0))
+	assert.Equal(t, "2", Cores(2))
+	assert.Equal(t, "2.5", Cores(2.5))
+	assert.Equal(t, "0.33", Cores(1.0/3))
+	assert.Equal(t, "42.5%", Percent(42.5))
+}
+
+func TestDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                             "0s",
+		500 * time.Millisecond:        "0s",
+		45 * time.Second:              "45s",
+		2*time.Minute + 5*time.Second: "2m5s",
+		time.Hour + 5*time.Second:     "1h",
+		3*24*time.Hour + 4*time.Hour:  "3d4h",
+		26*time.Hour + 30*time.Minute: "1d2h",
+		-(90 * time.Second):           "1m30s",
+	}
+	for d, expected := range tests {
+		assert.Equal(t, expected, Duration(d), d.String())
+	}
+}
+
+func TestStatus(t *testing.T) {
+	code := 137
+	tests := map[string]struct {
+		Info     runtime.ContainerInfo
+		Expected string
+	}{
+		"Created": {runtime.ContainerInfo{
+			Status:    runtime.StatusCreated,
+			CreatedAt: testNow.Add(-time.Minute),
+		}, "created 1m ago"},
+		"Running": {runtime.ContainerInfo{
+			Status:    runtime.StatusRunning,
+			StartedAt: testNow.Add(-5 * time.Minute),
+		}, "running 5m"},
+		"Exited": {runtime.ContainerInfo{
+			Status:       runtime.StatusExited,
+			ExitCode:     &code,
+			Interruption: &runtime.Interruption{Reason: runtime.InterruptionEvicted},
+			EndedAt:      testNow.Add(-3 * time.Minute),
+		}, "exited (137, evicted) 3m ago"},
+		"ExitedUnknown": {runtime.ContainerInfo{Status: runtime.StatusExited}, "exited"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, Status(&test.Info))
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	assert.Equal(t, "unlimited", Limits(&runtime.ContainerInfo{}))
+	assert.Equal(t, "1 CPU, 1GiB", Limits(&runtime.ContainerInfo{CPUCount: 1, Memory: 1 << 30}))
+	assert.Equal(t, "2.5 CPUs, 2 GPUs, 1h max", Limits(&runtime.ContainerInfo{
+		CPUCount:   2.5,
+		GPUs:       []string{"0", "1"},
+		MaxRuntime: time.Hour,
+	}))
+}
+
+func TestUsage(t *testing.T) {
+	assert.Equal(t, "-", CPU(nil))
+	assert.Equal(t, "-", Memory(nil))
+	assert.Equal(t, "-", IO(&runtime.ContainerStats{}))
+
+	stats := &runtime.ContainerStats{Stats: map[runtime.StatType]float64{
+		runtime.CPUUsageCoresStat:          1.5,
+		runtime.CPUUsageOfLimitPercentStat: 75,
+		runtime.MemoryUsageBytesStat:       1 << 30,
+		runtime.MemoryUsagePercentStat:     25,
+		runtime.NetworkRxBytesStat:         1 << 20,
+		runtime.NetworkTxBytesStat:         2 << 20,
+	}}
+	assert.Equal(t, "1.5 (75.0%)", CPU(stats))
+	assert.Equal(t, "1GiB (25.0%)", Memory(stats))
+	assert.Equal(t, "net 1MiB/2MiB", IO(stats))
+
+	// Legacy stats are converted from percent of a single CPU.
+	legacy := &runtime.ContainerStats{Stats: map[runtime.StatType]float64{runtime.CPUUsagePercentStat: 250}}
+	assert.Equal(t, "2.5", CPU(legacy))
+}
+
+func TestSummary(t *testing.T) {
+	info := &runtime.ContainerInfo{
+		Status:    runtime.StatusRunning,
+		StartedAt: testNow.Add(-5 * time.Minute),
+		CPUCount:  2,
+		Memory:    4 << 30,
+	}
+	assert.Equal(t, "abc: running 5m; limits 2 CPUs, 4GiB", Summary("abc", info, nil))
+
+	stats := &runtime.ContainerStats{Stats: map[runtime.StatType]float64{
+		runtime.CPUUsageCoresStat:    1,
+		runtime.MemoryUsageBytesStat: 1 << 30,
+		runtime.BlockReadBytesStat:   0,
+		runtime.BlockWriteBytesStat:  10 << 20,
+	}}
+	assert.Equal(t, "abc: running 5m; limits 2 CPUs, 4GiB; CPU 1; memory 1GiB; disk 0B/10MiB",
+		Summary("abc", info, stats))
+}
+
+func TestTable(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, Table(&b, []Row{
+		{Name: "a", Info: &runtime.ContainerInfo{
+			Status:    runtime.StatusRunning,
+			CreatedAt: testNow.Add(-time.Hour),
+			StartedAt: testNow.Add(-time.Minute),
+			CPUCount:  1,
+		}, Stats: &runtime.ContainerStats{Stats: map[runtime.StatType]float64{
+			runtime.CPUUsageCoresStat: 0.5,
+		}}},
+		{Name: "bb", Info: &runtime.ContainerInfo{
+			Status:    runtime.StatusCreated,
+			CreatedAt: testNow.Add(-time.Minute),
+		}},
+	}))
+	assert.Equal(t, strings.Join([]string{
+		"NAME  STATUS          AGE  LIMITS     CPU  MEMORY",
+		"a     running 1m      1h   1 CPU      0.5  -",
+		"bb    created 1m ago  1m   unlimited  -    -",
+		"",
+	}, "\n"), b.String())
+}
@@ -0,0 +1,359 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// GPUsLabel is set on containers with GPUs to their comma-separated UUIDs.
+// See ContainerInfo.GPUs.
+const GPUsLabel = "beaker.org/gpus"
+
+// GPUInventory maps the indices of a node's GPUs to their UUIDs, e.g. "0" to
+// "GPU-0a5c0cf4-eb7d-4fdd-40ea-4ac6803659ab".
+type GPUInventory map[string]string
+
+// QueryGPUs lists the node's NVIDIA GPUs with nvidia-smi.
+func QueryGPUs(ctx context.Context) (GPUInventory, error) {
+	cmd := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=index,uuid", "--format=csv,noheader")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing GPUs: %w", err)
+	}
+	return parseGPUInventory(out)
+}
+
+// parseGPUInventory parses the output of nvidia-smi, e.g. "0, GPU-abc".
+func parseGPUInventory(out []byte) (GPUInventory, error) {
+	inventory := GPUInventory{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected GPU listing: %q", line)
+		}
+		inventory[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+	return inventory, scanner.Err()
+}
+
+// GPUUtilization maps GPU UUIDs to their utilization as a percentage: the
+// fraction of the driver's last sample period during which a kernel ran.
+type GPUUtilization map[string]float64
+
+// QueryGPUUtilization samples the utilization of the node's NVIDIA GPUs with
+// nvidia-smi. GPUs which don't report utilization, such as those partitioned
+// with MIG, are omitted.
+func QueryGPUUtilization(ctx context.Context) (GPUUtilization, error) {
+	cmd := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=uuid,utilization.gpu", "--format=csv,noheader,nounits")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("querying GPU utilization: %w", err)
+	}
+	return parseGPUUtilization(out)
+}
+
+// parseGPUUtilization parses the output of nvidia-smi, e.g. "GPU-abc, 87".
+func parseGPUUtilization(out []byte) (GPUUtilization, error) {
+	utilization := GPUUtilization{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected GPU utilization: %q", line)
+		}
+		value := strings.TrimSpace(fields[1])
+		if value == "[N/A]" || value == "[Not Supported]" {
+			continue
+		}
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected GPU utilization: %q", line)
+		}
+		utilization[strings.TrimSpace(fields[0])] = percent
+	}
+	return utilization, scanner.Err()
+}
+
+// Normalize resolves GPU indices to UUIDs so that a container's GPUs are
+// identified the same way regardless of how they were requested. UUIDs are
+// passed through. An error is returned if a GPU doesn't exist.
+func (inv GPUInventory) Normalize(gpus []string) ([]string, error) {
+	uuids := make(map[string]bool, len(inv))
+	for _, uuid := range inv {
+		uuids[uuid] = true
+	}
+
+	normalized := make([]string, len(gpus))
+	for i, gpu := range gpus {
+		switch {
+		case uuids[gpu]:
+			normalized[i] = gpu
+		case inv[gpu] != "":
+			normalized[i] = inv[gpu]
+		default:
+			return nil, fmt.Errorf("GPU %q doesn't exist", gpu)
+		}
+	}
+	return normalized, nil
+}
+
+// GPUCapability is an NVIDIA driver capability which determines the driver
+// libraries and utilities mounted into a container with GPUs.
+type GPUCapability string
+
+const (
+	// GPUCompute provides CUDA and OpenCL.
+	GPUCompute GPUCapability = "compute"
+
+	// GPUUtility provides nvidia-smi and NVML.
+	GPUUtility GPUCapability = "utility"
+
+	// GPUVideo provides the Video Codec SDK for hardware encoding and decoding.
+	GPUVideo GPUCapability = "video"
+
+	// GPUGraphics provides OpenGL and Vulkan.
+	GPUGraphics GPUCapability = "graphics"
+
+	// GPUDisplay provides X11 display output.
+	GPUDisplay GPUCapability = "display"
+
+	// GPUCompat32 provides 32-bit libraries.
+	GPUCompat32 GPUCapability = "compat32"
+)
+
+// DriverCapabilitiesEnv selects a container's NVIDIA driver capabilities as a
+// comma-separated list.
+const DriverCapabilitiesEnv = "NVIDIA_DRIVER_CAPABILITIES"
+
+// ValidateGPUCapabilities checks that the container's GPU capabilities are
+// recognized and that they aren't also set in the environment.
+func (o *ContainerOpts) ValidateGPUCapabilities() error {
+	if len(o.GPUCapabilities) == 0 {
+		return nil
+	}
+	if len(o.GPUs) == 0 {
+		return errors.New("GPU capabilities require GPUs")
+	}
+	if _, ok := o.Env[DriverCapabilitiesEnv]; ok {
+		return fmt.Errorf("GPU capabilities conflict with environment variable %s", DriverCapabilitiesEnv)
+	}
+	for _, c := range o.GPUCapabilities {
+		switch c {
+		case GPUCompute, GPUUtility, GPUVideo, GPUGraphics, GPUDisplay, GPUCompat32:
+		default:
+			return fmt.Errorf("%q is not a valid GPU capability", c)
+		}
+	}
+	return nil
+}
+
+// GPUCapabilitiesEnv returns the value of DriverCapabilitiesEnv which grants
+// the capabilities, e.g. "compute,utility,video".
+func GPUCapabilitiesEnv(caps []GPUCapability) string {
+	names := make([]string, len(caps))
+	for i, c := range caps {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ",")
+}
+
+// HasGPUIndices returns true if any of the GPUs are identified by index
+// rather than UUID.
+func HasGPUIndices(gpus []string) bool {
+	for _, gpu := range gpus {
+		if !strings.HasPrefix(gpu, "GPU-") && !strings.HasPrefix(gpu, "MIG-") {
+			return true
+		}
+	}
+	return false
+}
+
+// GPUIncompatibility classifies problems with a node's GPU software.
+type GPUIncompatibility string
+
+const (
+	// GPUDriverMissing indicates the NVIDIA driver isn't installed or loaded.
+	GPUDriverMissing GPUIncompatibility = "driver-missing"
+
+	// GPUDriverTooOld indicates the NVIDIA driver doesn't support the CUDA
+	// version required by a container's image.
+	GPUDriverTooOld GPUIncompatibility = "driver-too-old"
+
+	// GPUToolkitMissing indicates the NVIDIA container toolkit isn't installed,
+	// so the container runtime can't expose GPUs to containers.
+	GPUToolkitMissing GPUIncompatibility = "toolkit-missing"
+)
+
+// Explanations for GPUErrors.
+const (
+	driverMissingMessage  = "the NVIDIA driver isn't loaded; install it or check the node's GPUs with nvidia-smi"
+	toolkitMissingMessage = "the NVIDIA container toolkit isn't installed; install it and restart the container runtime"
+	driverTooOldAdvice    = "upgrade the driver or use an image built for an older CUDA version"
+)
+
+// QueryDriverVersion returns the version of the node's NVIDIA driver, e.g.
+// "470.57.02". If nvidia-smi is installed but the driver can't be reached, a
+// *GPUError is returned.
+func QueryDriverVersion(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "nvidia-smi", "--query-gpu=driver_version", "--format=csv,noheader")
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", &GPUError{
+			Reason:  GPUDriverMissing,
+			Message: driverMissingMessage,
+			Err:     err,
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("querying NVIDIA driver: %w", err)
+	}
+
+	// Each GPU reports the same version.
+	version := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	if version == "" {
+		return "", errors.New("querying NVIDIA driver: no GPUs found")
+	}
+	return version, nil
+}
+
+// minimumDrivers lists the minimum Linux driver version for each CUDA release.
+// https://docs.nvidia.com/cuda/cuda-toolkit-release-notes/index.html#cuda-major-component-versions
+var minimumDrivers = []struct{ cuda, driver string }{
+	{"11.4", "470.42.01"},
+	{"11.3", "465.19.01"},
+	{"11.2", "460.27.03"},
+	{"11.1", "455.23"},
+	{"11.0", "450.36.06"},
+	{"10.2", "440.33"},
+	{"10.1", "418.39"},
+	{"10.0", "410.48"},
+	{"9.2", "396.26"},
+	{"9.1", "390.46"},
+	{"9.0", "384.81"},
+	{"8.0", "375.26"},
+}
+
+// RequiredCUDA returns the CUDA version an image requires from its environment,
+// e.g. "11.4", or an empty string if the image doesn't use CUDA. Images built
+// from NVIDIA's CUDA images declare the version in NVIDIA_REQUIRE_CUDA or
+// CUDA_VERSION.
+func RequiredCUDA(env []string) string {
+	var version string
+	for _, kv := range env {
+		switch {
+		case strings.HasPrefix(kv, "NVIDIA_REQUIRE_CUDA="):
+			// e.g. "cuda>=11.4 brand=tesla,driver>=418,driver<419"
+			for _, cond := range strings.Fields(strings.TrimPrefix(kv, "NVIDIA_REQUIRE_CUDA=")) {
+				if strings.HasPrefix(cond, "cuda>=") {
+					return majorMinor(strings.TrimPrefix(cond, "cuda>="))
+				}
+			}
+		case strings.HasPrefix(kv, "CUDA_VERSION="):
+			version = majorMinor(strings.TrimPrefix(kv, "CUDA_VERSION="))
+		}
+	}
+	return version
+}
+
+// CheckDriver verifies that a driver version supports a CUDA version. Unknown
+// CUDA versions newer than any listed require at least the newest driver.
+func CheckDriver(driver, cuda string) error {
+	if cuda == "" {
+		return nil
+	}
+	for _, v := range minimumDrivers {
+		if compareVersions(cuda, v.cuda) < 0 {
+			continue
+		}
+		if compareVersions(driver, v.driver) < 0 {
+			return &GPUError{
+				Reason: GPUDriverTooOld,
+				Message: fmt.Sprintf("the image requires CUDA %s, which needs NVIDIA driver %s or newer, "+
+					"but the node has %s; "+driverTooOldAdvice,
+					cuda, v.driver, driver),
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// TranslateGPUError converts errors the container runtime raises for missing
+// or incompatible GPU software to a *GPUError. Other errors are returned as-is.
+func TranslateGPUError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, `could not select device driver "nvidia"`),
+		strings.Contains(msg, "nvidia-container-cli: not found"),
+		strings.Contains(msg, "nvidia-container-runtime-hook: no such file"):
+		return &GPUError{
+			Reason:  GPUToolkitMissing,
+			Message: toolkitMissingMessage,
+			Err:     err,
+		}
+	case strings.Contains(msg, "unsatisfied condition: cuda>="):
+		return &GPUError{
+			Reason:  GPUDriverTooOld,
+			Message: "the NVIDIA driver is too old for the image's CUDA version; " + driverTooOldAdvice,
+			Err:     err,
+		}
+	case strings.Contains(msg, "driver error: failed to process request"),
+		strings.Contains(msg, "nvml error: driver not loaded"):
+		return &GPUError{
+			Reason:  GPUDriverMissing,
+			Message: driverMissingMessage,
+			Err:     err,
+		}
+	}
+	return err
+}
+
+// majorMinor truncates a version such as "11.4.1" to "11.4".
+func majorMinor(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// compareVersions compares dotted numeric versions, returning -1, 0, or 1.
+// Missing components are treated as zero.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
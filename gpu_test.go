@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGPUInventory(t *testing.T) {
+	inventory, err := parseGPUInventory([]byte("0, GPU-aaa\n1, GPU-bbb\n"))
+	require.NoError(t, err)
+	assert.Equal(t, GPUInventory{"0": "GPU-aaa", "1": "GPU-bbb"}, inventory)
+
+	gpus, err := inventory.Normalize([]string{"1", "GPU-aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GPU-bbb", "GPU-aaa"}, gpus)
+
+	_, err = inventory.Normalize([]string{"2"})
+	assert.Error(t, err)
+	_, err = inventory.Normalize([]string{"GPU-ccc"})
+	assert.Error(t, err)
+
+	_, err = parseGPUInventory([]byte("0 GPU-aaa\n"))
+	assert.Error(t, err)
+}
+
+func TestGPUUtilization(t *testing.T) {
+	utilization, err := parseGPUUtilization([]byte("GPU-aaa, 87\nGPU-bbb, 0\nMIG-ccc, [N/A]\n"))
+	require.NoError(t, err)
+	assert.Equal(t, GPUUtilization{"GPU-aaa": 87, "GPU-bbb": 0}, utilization)
+
+	stats := map[StatType]float64{}
+	SetGPUStats(stats, []string{"GPU-aaa", "GPU-bbb"}, utilization)
+	assert.Equal(t, map[StatType]float64{GPUUtilizationPercentStat: 43.5}, stats)
+
+	// Utilization is omitted unless every GPU was sampled.
+	stats = map[StatType]float64{}
+	SetGPUStats(stats, []string{"GPU-aaa", "MIG-ccc"}, utilization)
+	SetGPUStats(stats, nil, utilization)
+	assert.Empty(t, stats)
+
+	_, err = parseGPUUtilization([]byte("GPU-aaa, high\n"))
+	assert.Error(t, err)
+}
+
+func TestHasGPUIndices(t *testing.T) {
+	assert.False(t, HasGPUIndices(nil))
+	assert.False(t, HasGPUIndices([]string{"GPU-aaa", "MIG-bbb"}))
+	assert.True(t, HasGPUIndices([]string{"GPU-aaa", "0"}))
+}
+
+func TestRequiredCUDA(t *testing.T) {
+	assert.Equal(t, "", RequiredCUDA([]string{"PATH=/usr/bin"}))
+	assert.Equal(t, "11.4", RequiredCUDA([]string{"CUDA_VERSION=11.4.1"}))
+	assert.Equal(t, "11.2", RequiredCUDA([]string{
+		"CUDA_VERSION=11.4.1",
+		"NVIDIA_REQUIRE_CUDA=cuda>=11.2 brand=tesla,driver>=418,driver<419",
+	}))
+}
+
+func TestCheckDriver(t *testing.T) {
+	assert.NoError(t, CheckDriver("470.57.02", ""))
+	assert.NoError(t, CheckDriver("470.57.02", "11.4"))
+	assert.NoError(t, CheckDriver("460.27.03", "11.2"))
+	assert.NoError(t, CheckDriver("375.26", "7.5"))
+
+	var gpuErr *GPUError
+	require.True(t, errors.As(CheckDriver("460.91.03", "11.4"), &gpuErr))
+	assert.Equal(t, GPUDriverTooOld, gpuErr.Reason)
+	assert.Contains(t, gpuErr.Message, "470.42.01")
+
+	// Newer CUDA versions require at least the newest known driver.
+	assert.Error(t, CheckDriver("460.91.03", "12.0"))
+}
+
+func TestTranslateGPUError(t *testing.T) {
+	assert.NoError(t, TranslateGPUError(nil))
+
+	other := errors.New("something else")
+	assert.Equal(t, other, TranslateGPUError(other))
+
+	tests := map[string]GPUIncompatibility{
+		`could not select device driver "nvidia" with capabilities: [[gpu]]`:         GPUToolkitMissing,
+		"nvidia-container-cli: requirement error: unsatisfied condition: cuda>=11.4": GPUDriverTooOld,
+		"nvidia-container-cli: initialization error: nvml error: driver not loaded":  GPUDriverMissing,
+	}
+	for msg, reason := range tests {
+		cause := errors.New(msg)
+		err := TranslateGPUError(cause)
+
+		var gpuErr *GPUError
+		require.True(t, errors.As(err, &gpuErr), msg)
+		assert.Equal(t, reason, gpuErr.Reason)
+		assert.ErrorIs(t, err, cause)
+	}
+}
+
+func TestValidateGPUCapabilities(t *testing.T) {
+	gpus := []string{"GPU-0"}
+	tests := map[string]struct {
+		Opts  ContainerOpts
+		Valid bool
+	}{
+		"Default":   {ContainerOpts{GPUs: gpus}, true},
+		"Video":     {ContainerOpts{GPUs: gpus, GPUCapabilities: []GPUCapability{GPUCompute, GPUVideo}}, true},
+		"NoGPUs":    {ContainerOpts{GPUCapabilities: []GPUCapability{GPUVideo}}, false},
+		"Invalid":   {ContainerOpts{GPUs: gpus, GPUCapabilities: []GPUCapability{"all"}}, false},
+		"EnvAndCap": {ContainerOpts{GPUs: gpus, GPUCapabilities: []GPUCapability{GPUVideo}, Env: map[string]string{DriverCapabilitiesEnv: "all"}}, false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.Opts.ValidateGPUCapabilities()
+			if test.Valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	assert.Equal(t, "compute,utility,video", GPUCapabilitiesEnv([]GPUCapability{GPUCompute, GPUUtility, GPUVideo}))
+}
@@ -0,0 +1,186 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// hashVersion identifies the layout of hashedOpts. It's incremented whenever a
+// field is added, removed, or redefined, so that a change in hash is never
+// mistaken for a change in configuration by a reconciler which doesn't know
+// about the new field.
+const hashVersion = 1
+
+// hashedOpts is the configuration covered by ContainerOpts.Hash. Fields are
+// listed explicitly rather than derived from ContainerOpts so that adding an
+// option never silently changes the hash of existing containers.
+type hashedOpts struct {
+	Version          int               `json:"version"`
+	Image            string            `json:"image,omitempty"`
+	Entrypoint       []string          `json:"entrypoint,omitempty"`
+	Args             []string          `json:"args,omitempty"`
+	Env              map[string]string `json:"env,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Mounts           []hashedMount     `json:"mounts,omitempty"`
+	Interactive      bool              `json:"interactive,omitempty"`
+	SSHAgent         string            `json:"sshAgent,omitempty"`
+	X11              *hashedX11        `json:"x11,omitempty"`
+	Init             bool              `json:"init,omitempty"`
+	StopSignal       string            `json:"stopSignal,omitempty"`
+	AutoRemove       bool              `json:"autoRemove,omitempty"`
+	CoreDumpDir      string            `json:"coreDumpDir,omitempty"`
+	MaxRuntime       time.Duration     `json:"maxRuntime,omitempty"`
+	Schedule         *hashedSchedule   `json:"schedule,omitempty"`
+	Memory           int64             `json:"memory,omitempty"`
+	SharedMemory     int64             `json:"sharedMemory,omitempty"`
+	CPUCount         float64           `json:"cpuCount,omitempty"`
+	CPUShares        int64             `json:"cpuShares,omitempty"`
+	CPUPeriod        time.Duration     `json:"cpuPeriod,omitempty"`
+	CPUBurst         time.Duration     `json:"cpuBurst,omitempty"`
+	GPUs             []string          `json:"gpus,omitempty"`
+	GPUCapabilities  []GPUCapability   `json:"gpuCapabilities,omitempty"`
+	GPUSharing       GPUSharing        `json:"gpuSharing,omitempty"`
+	MPSThreadPercent int               `json:"mpsThreadPercent,omitempty"`
+	User             string            `json:"user,omitempty"`
+	WorkingDir       string            `json:"workingDir,omitempty"`
+	IPCMode          IPCMode           `json:"ipcMode,omitempty"`
+	NetworkFrom      string            `json:"networkFrom,omitempty"`
+	PIDFrom          string            `json:"pidFrom,omitempty"`
+	Network          string            `json:"network,omitempty"`
+	IPAddress        string            `json:"ipAddress,omitempty"`
+	IPv6Address      string            `json:"ipv6Address,omitempty"`
+	MACAddress       string            `json:"macAddress,omitempty"`
+	DNSPolicy        DNSPolicy         `json:"dnsPolicy,omitempty"`
+	DNSConfig        *hashedDNSConfig  `json:"dnsConfig,omitempty"`
+	Ports            []hashedPort      `json:"ports,omitempty"`
+	IngressBandwidth Bandwidth         `json:"ingressBandwidth,omitempty"`
+	EgressBandwidth  Bandwidth         `json:"egressBandwidth,omitempty"`
+	PostStart        *hashedHook       `json:"postStart,omitempty"`
+	PreStop          *hashedHook       `json:"preStop,omitempty"`
+}
+
+type hashedMount struct {
+	HostPath      string `json:"hostPath"`
+	ContainerPath string `json:"containerPath"`
+	ReadOnly      bool   `json:"readOnly,omitempty"`
+}
+
+type hashedX11 struct {
+	Display    string `json:"display"`
+	XAuthority string `json:"xAuthority,omitempty"`
+}
+
+type hashedSchedule struct {
+	At        *time.Time     `json:"at,omitempty"`
+	After     string         `json:"after,omitempty"`
+	Condition StartCondition `json:"condition,omitempty"`
+}
+
+type hashedDNSConfig struct {
+	Nameservers []string `json:"nameservers,omitempty"`
+	Searches    []string `json:"searches,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type hashedPort struct {
+	ContainerPort int    `json:"containerPort"`
+	HostPort      int    `json:"hostPort,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+	HostIP        string `json:"hostIP,omitempty"`
+}
+
+type hashedHook struct {
+	Command []string      `json:"command"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// Hash returns a deterministic hash of the container's effective
+// configuration. Two sets of options with the same hash create equivalent
+// containers, so reconcilers can compare a container's ConfigHash against the
+// desired options without inspecting each field.
+//
+// The container's identity (Name and IdempotencyKey), registry credentials and
+// pull policy, callbacks, and SensitiveEnv are excluded from the hash. Commands
+// and environment are hashed as resolved, so templated and literal options
+// which resolve alike hash alike.
+func (o *ContainerOpts) Hash() (string, error) {
+	entrypoint, args, err := o.ResolveCommand()
+	if err != nil {
+		return "", err
+	}
+
+	h := hashedOpts{
+		Version:          hashVersion,
+		Entrypoint:       entrypoint,
+		Args:             args,
+		Env:              o.ResolveEnv(),
+		Labels:           o.Labels,
+		Interactive:      o.Interactive,
+		SSHAgent:         o.SSHAgent,
+		Init:             o.Init,
+		StopSignal:       o.StopSignal,
+		AutoRemove:       o.AutoRemove,
+		CoreDumpDir:      o.CoreDumpDir,
+		MaxRuntime:       o.MaxRuntime,
+		Memory:           o.Memory,
+		SharedMemory:     o.SharedMemory,
+		CPUCount:         o.CPUCount,
+		CPUShares:        o.CPUShares,
+		CPUPeriod:        o.CPUPeriod,
+		CPUBurst:         o.CPUBurst,
+		GPUs:             o.GPUs,
+		GPUCapabilities:  o.GPUCapabilities,
+		GPUSharing:       o.GPUSharing,
+		MPSThreadPercent: o.MPSThreadPercent,
+		User:             o.User,
+		WorkingDir:       o.WorkingDir,
+		IPCMode:          o.IPCMode,
+		NetworkFrom:      o.NetworkFrom,
+		PIDFrom:          o.PIDFrom,
+		Network:          o.Network,
+		IPAddress:        o.IPAddress,
+		IPv6Address:      o.IPv6Address,
+		MACAddress:       o.MACAddress,
+		DNSPolicy:        o.DNSPolicy,
+		IngressBandwidth: o.IngressBandwidth,
+		EgressBandwidth:  o.EgressBandwidth,
+		PostStart:        hashHook(o.PostStart),
+		PreStop:          hashHook(o.PreStop),
+	}
+	if o.Image != nil {
+		h.Image = o.Image.Tag
+	}
+	for _, m := range o.Mounts {
+		h.Mounts = append(h.Mounts, hashedMount{HostPath: m.HostPath, ContainerPath: m.ContainerPath, ReadOnly: m.ReadOnly})
+	}
+	if o.X11 != nil {
+		h.X11 = &hashedX11{Display: o.X11.Display, XAuthority: o.X11.XAuthority}
+	}
+	if s := o.Schedule; s != nil {
+		h.Schedule = &hashedSchedule{At: timeOrNil(s.At), After: s.After, Condition: s.Condition}
+	}
+	if d := o.DNSConfig; d != nil {
+		h.DNSConfig = &hashedDNSConfig{Nameservers: d.Nameservers, Searches: d.Searches, Options: d.Options}
+	}
+	for _, p := range o.Ports {
+		h.Ports = append(h.Ports, hashedPort{ContainerPort: p.ContainerPort, HostPort: p.HostPort, Protocol: p.Protocol, HostIP: p.HostIP})
+	}
+
+	// Map keys are sorted when encoded, so the encoding is deterministic.
+	b, err := json.Marshal(&h)
+	if err != nil {
+		return "", fmt.Errorf("hashing container options: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func hashHook(h *Hook) *hashedHook {
+	if h == nil {
+		return nil
+	}
+	return &hashedHook{Command: h.Command, Timeout: h.Timeout}
+}
@@ -0,0 +1,220 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beaker/runtime/clock"
+)
+
+// ExitClass summarizes how a container ended.
+type ExitClass string
+
+const (
+	// ExitSucceeded indicates the container exited with code 0.
+	ExitSucceeded ExitClass = "succeeded"
+
+	// ExitFailed indicates the container exited with a non-zero code.
+	ExitFailed ExitClass = "failed"
+
+	// ExitCrashed indicates the container's main process was killed by a
+	// signal which dumps core, such as SIGSEGV. See CrashSignal.
+	ExitCrashed ExitClass = "crashed"
+
+	// ExitOutOfMemory indicates the container was killed for exceeding its
+	// memory limit.
+	ExitOutOfMemory ExitClass = "out-of-memory"
+
+	// ExitInterrupted indicates the container was stopped by its
+	// infrastructure. See ContainerInfo.Interruption.
+	ExitInterrupted ExitClass = "interrupted"
+
+	// ExitNotExited indicates the container was removed before it exited.
+	ExitNotExited ExitClass = "not-exited"
+)
+
+// ClassifyExit classifies a container's final details. Infrastructure
+// interruptions take precedence over the exit code they cause.
+func ClassifyExit(info *ContainerInfo) ExitClass {
+	switch {
+	case info.Status != StatusExited:
+		return ExitNotExited
+	case info.Interruption != nil:
+		return ExitInterrupted
+	case strings.Contains(info.Message, "out of memory") || strings.Contains(info.Message, "OOMKilled"):
+		return ExitOutOfMemory
+	case info.ExitCode != nil && *info.ExitCode == 0:
+		return ExitSucceeded
+	}
+	if _, ok := CrashSignal(info); ok {
+		return ExitCrashed
+	}
+	return ExitFailed
+}
+
+// Historian is implemented by runtimes which retain records of containers
+// after they're removed.
+type Historian interface {
+	// History lists records of removed containers which match the filter,
+	// most recently removed first.
+	History(ctx context.Context, filter HistoryFilter) ([]HistoryRecord, error)
+}
+
+// HistoryRecord describes a container which has been removed.
+type HistoryRecord struct {
+	Name      string
+	RemovedAt time.Time
+
+	// Info is the container's final details.
+	Info ContainerInfo
+	Exit ExitClass
+
+	// Usage is the container's last resource usage, sampled just before it was
+	// removed. It's nil if the container had already exited.
+	Usage *ContainerStats
+}
+
+// Duration returns how long the container ran, through its removal if it
+// hadn't exited.
+func (r *HistoryRecord) Duration() time.Duration {
+	switch {
+	case r.Info.StartedAt.IsZero():
+		return 0
+	case r.Info.EndedAt.IsZero():
+		return r.RemovedAt.Sub(r.Info.StartedAt)
+	default:
+		return r.Info.EndedAt.Sub(r.Info.StartedAt)
+	}
+}
+
+// HistoryFilter selects history records. The zero value selects all records.
+type HistoryFilter struct {
+	// (optional) Name selects the record of a single container.
+	Name string
+
+	// (optional) Labels selects containers with all of the given labels.
+	Labels map[string]string
+
+	// (optional) Exit selects containers which ended the given way.
+	Exit ExitClass
+
+	// (optional) Since selects containers removed at or after a time.
+	Since time.Time
+
+	// (optional) Limit caps the number of records returned.
+	Limit int
+}
+
+func (f *HistoryFilter) matches(r *HistoryRecord) bool {
+	if f.Name != "" && r.Name != f.Name {
+		return false
+	}
+	if f.Exit != "" && r.Exit != f.Exit {
+		return false
+	}
+	if r.RemovedAt.Before(f.Since) {
+		return false
+	}
+	for k, v := range f.Labels {
+		if label, ok := r.Info.Labels[k]; !ok || label != v {
+			return false
+		}
+	}
+	return true
+}
+
+// History retains records of removed containers in memory, bounded by count
+// and age. Runtimes add records as they remove containers; see each runtime's
+// RetainHistory. A History is safe for concurrent use.
+type History struct {
+	maxRecords int
+	maxAge     time.Duration
+
+	mu      sync.Mutex
+	clock   clock.Clock
+	records []HistoryRecord // Oldest first
+}
+
+// NewHistory creates a history which retains up to maxRecords records, each
+// for up to maxAge. A zero maxAge retains records until they're displaced.
+func NewHistory(maxRecords int, maxAge time.Duration) *History {
+	return &History{maxRecords: maxRecords, maxAge: maxAge}
+}
+
+// SetClock replaces the clock used to timestamp and expire records, e.g. with
+// a fake clock in tests.
+func (h *History) SetClock(c clock.Clock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock = c
+}
+
+// CaptureRecord reads a container's final details before it's removed. It
+// returns nil if they can't be read, e.g. because the container is already
+// gone, since there's nothing to record.
+func CaptureRecord(ctx context.Context, c Container) *HistoryRecord {
+	info, err := c.Info(ctx)
+	if err != nil {
+		return nil
+	}
+	r := &HistoryRecord{Name: c.Name(), Info: *info, Exit: ClassifyExit(info)}
+	if info.Status == StatusRunning {
+		if stats, err := c.Stats(ctx); err == nil {
+			r.Usage = stats
+		}
+	}
+	return r
+}
+
+// Add records a container's removal. Nil records are ignored.
+func (h *History) Add(r *HistoryRecord) {
+	if r == nil {
+		return
+	}
+	record := *r
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if record.RemovedAt.IsZero() {
+		record.RemovedAt = clock.OrSystem(h.clock).Now()
+	}
+	h.records = append(h.records, record)
+	h.expire(record.RemovedAt)
+}
+
+// expire discards records which are too old or too many. Records are ordered
+// by removal, so the oldest are discarded first.
+func (h *History) expire(now time.Time) {
+	var drop int
+	if h.maxRecords > 0 && len(h.records) > h.maxRecords {
+		drop = len(h.records) - h.maxRecords
+	}
+	if h.maxAge > 0 {
+		for drop < len(h.records) && now.Sub(h.records[drop].RemovedAt) > h.maxAge {
+			drop++
+		}
+	}
+	if drop != 0 {
+		h.records = append([]HistoryRecord(nil), h.records[drop:]...)
+	}
+}
+
+// Query lists the records which match a filter, most recently removed first.
+func (h *History) Query(filter HistoryFilter) []HistoryRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expire(clock.OrSystem(h.clock).Now())
+
+	var result []HistoryRecord
+	for i := len(h.records) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+		if filter.matches(&h.records[i]) {
+			result = append(result, h.records[i])
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/beaker/runtime/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyExit(t *testing.T) {
+	exited := func(code int, message string) *ContainerInfo {
+		return &ContainerInfo{Status: StatusExited, ExitCode: &code, Message: message}
+	}
+	interrupted := exited(143, "")
+	interrupted.Interruption = &Interruption{Reason: InterruptionPreempted}
+
+	assert.Equal(t, ExitSucceeded, ClassifyExit(exited(0, "")))
+	assert.Equal(t, ExitFailed, ClassifyExit(exited(1, "")))
+	assert.Equal(t, ExitCrashed, ClassifyExit(exited(139, "")))
+	assert.Equal(t, ExitOutOfMemory, ClassifyExit(exited(137, "out of memory")))
+	assert.Equal(t, ExitOutOfMemory, ClassifyExit(exited(137, "OOMKilled")))
+	assert.Equal(t, ExitInterrupted, ClassifyExit(interrupted))
+	assert.Equal(t, ExitNotExited, ClassifyExit(&ContainerInfo{Status: StatusRunning}))
+}
+
+func TestHistory(t *testing.T) {
+	now := time.Now()
+	h := NewHistory(3, time.Hour)
+	add := func(name string, removed time.Time, exit ExitClass, labels map[string]string) {
+		h.Add(&HistoryRecord{Name: name, RemovedAt: removed, Exit: exit, Info: ContainerInfo{Labels: labels}})
+	}
+	add("expired", now.Add(-2*time.Hour), ExitSucceeded, nil)
+	add("a", now.Add(-3*time.Minute), ExitSucceeded, map[string]string{"job": "1"})
+	add("b", now.Add(-2*time.Minute), ExitFailed, map[string]string{"job": "2"})
+	add("c", now.Add(-time.Minute), ExitFailed, map[string]string{"job": "1"})
+	h.Add(nil)
+
+	names := func(records []HistoryRecord) []string {
+		var names []string
+		for _, r := range records {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"c", "b", "a"}, names(h.Query(HistoryFilter{})))
+	assert.Equal(t, []string{"c", "b"}, names(h.Query(HistoryFilter{Exit: ExitFailed})))
+	assert.Equal(t, []string{"c", "a"}, names(h.Query(HistoryFilter{Labels: map[string]string{"job": "1"}})))
+	assert.Equal(t, []string{"c", "b"}, names(h.Query(HistoryFilter{Since: now.Add(-150 * time.Second)})))
+	assert.Equal(t, []string{"b"}, names(h.Query(HistoryFilter{Name: "b"})))
+	assert.Equal(t, []string{"c"}, names(h.Query(HistoryFilter{Limit: 1})))
+
+	// The oldest record is displaced once the history is full.
+	add("d", now, ExitSucceeded, nil)
+	assert.Equal(t, []string{"d", "c", "b"}, names(h.Query(HistoryFilter{})))
+}
+
+func TestHistoryExpiry(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	h := NewHistory(0, time.Hour)
+	h.SetClock(clk)
+
+	h.Add(&HistoryRecord{Name: "a"})
+	clk.Advance(30 * time.Minute)
+	h.Add(&HistoryRecord{Name: "b"})
+
+	records := h.Query(HistoryFilter{})
+	require.Len(t, records, 2)
+	assert.Equal(t, time.Unix(1000, 0), records[1].RemovedAt)
+
+	clk.Advance(31 * time.Minute)
+	records = h.Query(HistoryFilter{})
+	require.Len(t, records, 1)
+	assert.Equal(t, "b", records[0].Name)
+}
+
+func TestCaptureRecord(t *testing.T) {
+	code := 0
+	started := time.Now().Add(-time.Hour)
+	c := &staticContainer{info: ContainerInfo{
+		Status:    StatusExited,
+		ExitCode:  &code,
+		StartedAt: started,
+		EndedAt:   started.Add(time.Minute),
+	}}
+
+	r := CaptureRecord(context.Background(), c)
+	require.NotNil(t, r)
+	assert.Equal(t, "fake", r.Name)
+	assert.Equal(t, ExitSucceeded, r.Exit)
+	assert.Nil(t, r.Usage)
+	assert.Equal(t, time.Minute, r.Duration())
+}
@@ -0,0 +1,143 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout bounds hooks which don't set their own timeout.
+const DefaultHookTimeout = 30 * time.Second
+
+// Hook is a command run inside a container at a point in its lifecycle, such
+// as a warmup or graceful shutdown script, so that the image's entrypoint
+// needn't be wrapped to run it. Hooks require a runtime whose containers
+// implement Execer, except on Kubernetes, where they're native.
+type Hook struct {
+	Command []string `json:"command"`
+
+	// (optional) Timeout bounds the command. Defaults to DefaultHookTimeout.
+	// Kubernetes doesn't bound hooks other than by the grace period, so it's
+	// ignored there.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// Validate checks that a hook is well formed.
+func (h *Hook) Validate() error {
+	if len(h.Command) == 0 {
+		return errors.New("hook must have a command")
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("invalid hook timeout %s", h.Timeout)
+	}
+	return nil
+}
+
+// HooksLabel is set on containers created with hooks on runtimes which run
+// them themselves. Its value is the hooks encoded as JSON.
+const HooksLabel = "beaker.org/hooks"
+
+// hooks is the encoding of HooksLabel.
+type hooks struct {
+	PostStart *Hook `json:"postStart,omitempty"`
+	PreStop   *Hook `json:"preStop,omitempty"`
+}
+
+// ValidateHooks checks that the container's hooks are well formed.
+func (o *ContainerOpts) ValidateHooks() error {
+	if o.PostStart != nil {
+		if err := o.PostStart.Validate(); err != nil {
+			return fmt.Errorf("post-start %w", err)
+		}
+	}
+	if o.PreStop != nil {
+		if err := o.PreStop.Validate(); err != nil {
+			return fmt.Errorf("pre-stop %w", err)
+		}
+	}
+	return nil
+}
+
+// EncodeHooks encodes a container's hooks as the value of HooksLabel. It
+// returns an empty string if the container has none.
+func EncodeHooks(postStart, preStop *Hook) (string, error) {
+	if postStart == nil && preStop == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(hooks{PostStart: postStart, PreStop: preStop})
+	if err != nil {
+		return "", fmt.Errorf("encoding hooks: %w", err)
+	}
+	return string(b), nil
+}
+
+// DecodeHooks decodes the value of HooksLabel.
+func DecodeHooks(label string) (postStart, preStop *Hook, err error) {
+	var h hooks
+	if err := json.Unmarshal([]byte(label), &h); err != nil {
+		return nil, nil, fmt.Errorf("decoding hooks: %w", err)
+	}
+	return h.PostStart, h.PreStop, nil
+}
+
+// RunHook runs a hook in a container. It fails if the container can't run
+// commands, or if the hook times out or exits with a nonzero code.
+func RunHook(ctx context.Context, c Container, hook *Hook) error {
+	execer, ok := c.(Execer)
+	if !ok {
+		return fmt.Errorf("container can't run hooks (%w)", ErrNotImplemented)
+	}
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = DefaultHookTimeout
+	}
+	result, err := execer.ExecSync(ctx, hook.Command, timeout)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		if stderr := strings.TrimSpace(string(result.Stderr)); stderr != "" {
+			return fmt.Errorf("hook exited with code %d: %s", result.ExitCode, stderr)
+		}
+		return fmt.Errorf("hook exited with code %d", result.ExitCode)
+	}
+	return nil
+}
+
+// RunPreStop runs a container's pre-stop hook before it's stopped with a grace
+// period, which the hook's run time counts against, as on Kubernetes. It
+// returns the grace period remaining for the stop itself, and any error
+// running the hook, which shouldn't prevent the stop. A nil grace period
+// leaves the hook bounded only by its own timeout.
+func RunPreStop(ctx context.Context, c Container, hook *Hook, grace *time.Duration) (*time.Duration, error) {
+	if hook == nil {
+		return grace, nil
+	}
+
+	bounded := *hook
+	if grace != nil {
+		if *grace <= 0 {
+			return grace, nil // No time for the hook.
+		}
+		if bounded.Timeout == 0 || bounded.Timeout > *grace {
+			bounded.Timeout = *grace
+		}
+	}
+
+	start := time.Now()
+	err := RunHook(ctx, c, &bounded)
+	if grace != nil {
+		remaining := *grace - time.Since(start)
+		if remaining < 0 {
+			remaining = 0
+		}
+		grace = &remaining
+	}
+	if err != nil {
+		return grace, fmt.Errorf("pre-stop hook: %w", err)
+	}
+	return grace, nil
+}
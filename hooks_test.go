@@ -0,0 +1,121 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookedContainer records the commands it runs and their timeouts, taking
+// delay to run each.
+type hookedContainer struct {
+	fakeContainer
+	result ExecResult
+	delay  time.Duration
+
+	commands [][]string
+	timeouts []time.Duration
+}
+
+func (c *hookedContainer) ExecSync(
+	ctx context.Context,
+	cmd []string,
+	timeout time.Duration,
+) (*ExecResult, error) {
+	c.commands = append(c.commands, cmd)
+	c.timeouts = append(c.timeouts, timeout)
+	time.Sleep(c.delay)
+	result := c.result
+	return &result, nil
+}
+
+func TestValidateHooks(t *testing.T) {
+	assert.NoError(t, (&ContainerOpts{}).ValidateHooks())
+	assert.NoError(t, (&ContainerOpts{
+		PostStart: &Hook{Command: []string{"warmup"}},
+		PreStop:   &Hook{Command: []string{"drain"}, Timeout: time.Minute},
+	}).ValidateHooks())
+	assert.Error(t, (&ContainerOpts{PostStart: &Hook{}}).ValidateHooks())
+	assert.Error(t, (&ContainerOpts{PreStop: &Hook{Command: []string{"drain"}, Timeout: -1}}).ValidateHooks())
+}
+
+func TestEncodeHooks(t *testing.T) {
+	label, err := EncodeHooks(nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, label)
+
+	preStop := &Hook{Command: []string{"sh", "-c", "drain"}, Timeout: time.Minute}
+	label, err = EncodeHooks(nil, preStop)
+	require.NoError(t, err)
+	postStart, decoded, err := DecodeHooks(label)
+	require.NoError(t, err)
+	assert.Nil(t, postStart)
+	assert.Equal(t, preStop, decoded)
+
+	_, _, err = DecodeHooks("{")
+	assert.Error(t, err)
+}
+
+func TestRunHook(t *testing.T) {
+	ctx := context.Background()
+
+	c := &hookedContainer{}
+	require.NoError(t, RunHook(ctx, c, &Hook{Command: []string{"warmup"}}))
+	assert.Equal(t, [][]string{{"warmup"}}, c.commands)
+	assert.Equal(t, []time.Duration{DefaultHookTimeout}, c.timeouts)
+
+	c = &hookedContainer{result: ExecResult{ExitCode: 3, Stderr: []byte("not ready\n")}}
+	err := RunHook(ctx, c, &Hook{Command: []string{"warmup"}, Timeout: time.Second})
+	assert.EqualError(t, err, "hook exited with code 3: not ready")
+	assert.Equal(t, []time.Duration{time.Second}, c.timeouts)
+
+	err = RunHook(ctx, &fakeContainer{}, &Hook{Command: []string{"warmup"}})
+	assert.ErrorIs(t, err, ErrNotImplemented)
+}
+
+func TestRunPreStop(t *testing.T) {
+	ctx := context.Background()
+	hook := &Hook{Command: []string{"drain"}, Timeout: time.Minute}
+	duration := func(d time.Duration) *time.Duration { return &d }
+
+	t.Run("None", func(t *testing.T) {
+		grace, err := RunPreStop(ctx, &hookedContainer{}, nil, duration(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, time.Second, *grace)
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		c := &hookedContainer{delay: 20 * time.Millisecond}
+		grace, err := RunPreStop(ctx, c, hook, duration(10*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{10 * time.Second}, c.timeouts)
+		assert.Less(t, *grace, 10*time.Second-20*time.Millisecond+time.Millisecond)
+		assert.Greater(t, *grace, 9*time.Second)
+	})
+
+	t.Run("Unbounded", func(t *testing.T) {
+		c := &hookedContainer{}
+		grace, err := RunPreStop(ctx, c, hook, nil)
+		require.NoError(t, err)
+		assert.Nil(t, grace)
+		assert.Equal(t, []time.Duration{time.Minute}, c.timeouts)
+	})
+
+	t.Run("Kill", func(t *testing.T) {
+		c := &hookedContainer{}
+		grace, err := RunPreStop(ctx, c, hook, duration(0))
+		require.NoError(t, err)
+		assert.Zero(t, *grace)
+		assert.Empty(t, c.commands)
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		c := &hookedContainer{result: ExecResult{ExitCode: 1}}
+		grace, err := RunPreStop(ctx, c, hook, duration(time.Second))
+		assert.Error(t, err)
+		assert.NotNil(t, grace)
+	})
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/beaker/runtime"
+)
+
+// ReportEnv names an environment variable which, if set, is a path to which
+// backend tests write a conformance report, e.g.
+//
+//   TEST_DOCKER=1 TEST_CONFORMANCE_REPORT=docker.json go test ./docker
+const ReportEnv = "TEST_CONFORMANCE_REPORT"
+
+// FeatureStatus is the outcome of a conformance test.
+type FeatureStatus string
+
+const (
+	// FeatureSupported indicates the backend passed the test.
+	FeatureSupported FeatureStatus = "supported"
+
+	// FeatureUnsupported indicates the backend doesn't implement the feature,
+	// e.g. because it lacks an optional interface or reported
+	// runtime.ErrNotImplemented.
+	FeatureUnsupported FeatureStatus = "unsupported"
+
+	// FeatureFailed indicates the backend implements the feature incorrectly.
+	FeatureFailed FeatureStatus = "failed"
+
+	// FeatureSkipped indicates the test was skipped for another reason.
+	FeatureSkipped FeatureStatus = "skipped"
+)
+
+// FeatureResult is the outcome of one conformance test.
+type FeatureResult struct {
+	// Name is the name of the test without its "Test" prefix, e.g. "Ping".
+	Name string `json:"name"`
+
+	Status   FeatureStatus    `json:"status"`
+	Duration runtime.Duration `json:"duration"`
+
+	// Reason explains why an unsupported feature isn't supported.
+	Reason string `json:"reason,omitempty"`
+
+	// Deviations are ways in which the backend's behavior differs from the
+	// reference without failing the test, e.g. falling back to a slower path.
+	Deviations []string `json:"deviations,omitempty"`
+}
+
+// Report is a machine-readable summary of a conformance run against one
+// backend, suitable for publishing with each release.
+type Report struct {
+	Backend  string           `json:"backend"`
+	Started  time.Time        `json:"started"`
+	Duration runtime.Duration `json:"duration"`
+	Features []FeatureResult  `json:"features"`
+}
+
+// ReportTo configures the suite to write a report for the named backend to a
+// path once it finishes. An empty path writes nothing, so callers may pass
+// os.Getenv(ReportEnv) directly.
+func (s *RuntimeSuite) ReportTo(backend, path string) *RuntimeSuite {
+	s.reportPath = path
+	s.report = &Report{Backend: backend}
+	return s
+}
+
+// SetupSuite implements suite.SetupAllSuite.
+func (s *RuntimeSuite) SetupSuite() {
+	if s.report != nil {
+		s.report.Started = time.Now()
+	}
+}
+
+// BeforeTest implements suite.BeforeTest.
+func (s *RuntimeSuite) BeforeTest(suiteName, testName string) {
+	s.current = FeatureResult{Name: strings.TrimPrefix(testName, "Test")}
+	s.testStart = time.Now()
+}
+
+// AfterTest implements suite.AfterTest.
+func (s *RuntimeSuite) AfterTest(suiteName, testName string) {
+	if s.report == nil {
+		return
+	}
+	result := s.current
+	result.Duration = runtime.Duration(time.Since(s.testStart))
+	switch t := s.T(); {
+	case t.Failed():
+		result.Status = FeatureFailed
+	case result.Status == FeatureUnsupported:
+	case t.Skipped():
+		result.Status = FeatureSkipped
+	default:
+		result.Status = FeatureSupported
+	}
+	s.report.Features = append(s.report.Features, result)
+}
+
+// TearDownSuite implements suite.TearDownAllSuite.
+func (s *RuntimeSuite) TearDownSuite() {
+	if s.report == nil || s.reportPath == "" {
+		return
+	}
+	s.report.Duration = runtime.Duration(time.Since(s.report.Started))
+	b, err := json.MarshalIndent(s.report, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(s.reportPath, append(b, '\n'), 0644)
+	}
+	if err != nil {
+		s.T().Errorf("writing conformance report: %v", err)
+	}
+}
+
+// unsupported marks the current test's feature as unsupported and skips it.
+func (s *RuntimeSuite) unsupported(format string, args ...interface{}) {
+	s.current.Status = FeatureUnsupported
+	s.current.Reason = fmt.Sprintf(format, args...)
+	s.T().Skip(s.current.Reason)
+}
+
+// deviate notes a way in which the backend differs from the reference.
+func (s *RuntimeSuite) deviate(format string, args ...interface{}) {
+	deviation := fmt.Sprintf(format, args...)
+	s.current.Deviations = append(s.current.Deviations, deviation)
+	s.T().Log(deviation)
+}
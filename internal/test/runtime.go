@@ -2,6 +2,8 @@ package test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -53,6 +55,12 @@ type RuntimeSuite struct {
 
 	ctx context.Context
 	rt  runtime.Runtime
+
+	// Conformance report, if requested, and the result of the current test.
+	report     *Report
+	reportPath string
+	current    FeatureResult
+	testStart  time.Time
 }
 
 // NewRuntimeSuite creates a test suite for a specific runtime.
@@ -63,6 +71,11 @@ func NewRuntimeSuite(rt runtime.Runtime) *RuntimeSuite {
 	}
 }
 
+// TestPing validates the runtime's health check.
+func (s *RuntimeSuite) TestPing() {
+	assert.NoError(s.T(), s.rt.Ping(s.ctx))
+}
+
 // TestCreateInspect tests container creation and introspection.
 func (s *RuntimeSuite) TestCreateInspect() {
 	t, ctx := s.T(), s.ctx
@@ -71,13 +84,14 @@ func (s *RuntimeSuite) TestCreateInspect() {
 		require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
 		ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 
 		assert.NotZero(t, ctr.Name())
 
 		info, err := ctr.Info(ctx)
 		require.NoError(t, err)
 		assert.Equal(t, map[string]string{"beaker.org/managed": "true"}, info.Labels)
+		assert.NotEmpty(t, info.ConfigHash)
 		assert.NotZero(t, info.CreatedAt)
 		assert.Zero(t, info.StartedAt)
 		assert.Zero(t, info.EndedAt)
@@ -88,6 +102,21 @@ func (s *RuntimeSuite) TestCreateInspect() {
 		assert.Equal(t, 0.0, info.CPUCount)
 	})
 
+	t.Run("PullPolicy", func(t *testing.T) {
+		image := *busybox
+		image.PullPolicy = runtime.PullIfMissing
+		ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: &image})
+		if errors.Is(err, runtime.ErrNotImplemented) {
+			t.Skipf("Pull policies are not supported: %v", err)
+		}
+		require.NoError(t, err)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
+
+		image.PullPolicy = "sometimes"
+		_, err = s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: &image})
+		assert.Error(t, err)
+	})
+
 	t.Run("Full", func(t *testing.T) {
 		require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
 		ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
@@ -106,7 +135,7 @@ func (s *RuntimeSuite) TestCreateInspect() {
 			CPUCount: 2,
 		})
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 
 		assert.NotZero(t, ctr.Name())
 
@@ -130,7 +159,7 @@ func (s *RuntimeSuite) TestCreateInspect() {
 		require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
 		ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 
 		require.NoError(t, ctr.Start(ctx))
 
@@ -150,7 +179,7 @@ func (s *RuntimeSuite) TestCreateInspect() {
 			Command: []string{"/bin/sh", "-c", "exit 1"},
 		})
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 
 		require.NoError(t, ctr.Start(ctx))
 
@@ -167,12 +196,34 @@ func (s *RuntimeSuite) TestCreateInspect() {
 		require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
 		ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		require.NoError(t, ctr.Remove(ctx))
+		require.NoError(t, ctr.Remove(ctx, runtime.RemoveOpts{}))
 		_, err = ctr.Info(ctx)
 		assert.Equal(t, runtime.ErrNotFound, err)
 	})
 }
 
+// TestIdempotentCreate validates that retried creations return the original container.
+func (s *RuntimeSuite) TestIdempotentCreate() {
+	t, ctx := s.T(), s.ctx
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	opts := &runtime.ContainerOpts{Image: busybox, IdempotencyKey: "TestIdempotentCreate"}
+	ctr1, err := s.rt.CreateContainer(ctx, opts)
+	require.NoError(t, err)
+	defer ctr1.Remove(ctx, runtime.RemoveOpts{})
+
+	ctr2, err := s.rt.CreateContainer(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, ctr1.Name(), ctr2.Name())
+
+	_, err = s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
+		Image:          busybox,
+		IdempotencyKey: opts.IdempotencyKey,
+		Env:            map[string]string{"DIFFERENT": "true"},
+	})
+	assert.Error(t, err)
+}
+
 // TestListContainers validates container enumeration.
 func (s *RuntimeSuite) TestListContainers() {
 	t, ctx := s.T(), s.ctx
@@ -187,13 +238,13 @@ func (s *RuntimeSuite) TestListContainers() {
 		require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
 		ctr1, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		defer ctr1.Remove(ctx)
+		defer ctr1.Remove(ctx, runtime.RemoveOpts{})
 		ctr2, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		defer ctr2.Remove(ctx)
+		defer ctr2.Remove(ctx, runtime.RemoveOpts{})
 		ctr3, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		defer ctr3.Remove(ctx)
+		defer ctr3.Remove(ctx, runtime.RemoveOpts{})
 
 		list, err := s.rt.ListContainers(ctx)
 		require.NoError(t, err)
@@ -207,10 +258,10 @@ func (s *RuntimeSuite) TestListContainers() {
 		require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
 		ctr1, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		ctr1.Remove(ctx)
+		ctr1.Remove(ctx, runtime.RemoveOpts{})
 		ctr2, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		defer ctr2.Remove(ctx)
+		defer ctr2.Remove(ctx, runtime.RemoveOpts{})
 
 		list, err := s.rt.ListContainers(ctx)
 		require.NoError(t, err)
@@ -227,12 +278,12 @@ func (s *RuntimeSuite) TestContainerLogs() {
 		require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
 		ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 		require.NoError(t, ctr.Start(ctx))
 		_, err = awaitExit(ctr)
 		require.NoError(t, err)
 
-		r, err := ctr.Logs(ctx, time.Time{})
+		r, err := ctr.Logs(ctx, runtime.LogsOpts{})
 		require.NoError(t, err)
 		defer r.Close()
 
@@ -249,12 +300,12 @@ func (s *RuntimeSuite) TestContainerLogs() {
 			Arguments: []string{"echo Foo; echo -n Bar"},
 		})
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 		require.NoError(t, ctr.Start(ctx))
 		_, err = awaitExit(ctr)
 		require.NoError(t, err)
 
-		r, err := ctr.Logs(ctx, time.Time{})
+		r, err := ctr.Logs(ctx, runtime.LogsOpts{})
 		require.NoError(t, err)
 		defer r.Close()
 
@@ -280,12 +331,12 @@ func (s *RuntimeSuite) TestContainerLogs() {
 			Arguments: []string{">&2 echo Error!"},
 		})
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 		require.NoError(t, ctr.Start(ctx))
 		_, err = awaitExit(ctr)
 		require.NoError(t, err)
 
-		r, err := ctr.Logs(ctx, time.Time{})
+		r, err := ctr.Logs(ctx, runtime.LogsOpts{})
 		require.NoError(t, err)
 		defer r.Close()
 
@@ -303,6 +354,262 @@ func (s *RuntimeSuite) TestContainerLogs() {
 	})
 }
 
+// TestLogThroughput measures the sustained rate at which the runtime delivers
+// a container's logs. Logs are followed while the container writes them on
+// runtimes which can follow, or read once it exits otherwise.
+func (s *RuntimeSuite) TestLogThroughput() {
+	t, ctx := s.T(), s.ctx
+	const lines = 100000
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
+		Image:     busybox,
+		Command:   []string{"sh", "-c"},
+		Arguments: []string{fmt.Sprintf("seq 1 %d", lines)},
+	})
+	require.NoError(t, err)
+	defer ctr.Remove(ctx, runtime.RemoveOpts{})
+	require.NoError(t, ctr.Start(ctx))
+
+	opts := runtime.LogsOpts{Follow: true, Buffer: &logging.BufferOpts{}}
+	r, err := ctr.Logs(ctx, opts)
+	if errors.Is(err, runtime.ErrNotImplemented) {
+		s.deviate("Following logs is not supported; measured reading logs after exit.")
+		_, err = awaitExit(ctr)
+		require.NoError(t, err)
+		opts.Follow = false
+		r, err = ctr.Logs(ctx, opts)
+	}
+	require.NoError(t, err)
+	defer r.Close()
+
+	tp, err := logging.MeasureThroughput(r, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, lines, tp.Messages)
+	t.Logf("Read %d messages in %v (follow=%t): %.0f messages/s, %.0f bytes/s",
+		tp.Messages, tp.Elapsed, opts.Follow, tp.MessagesPerSecond(), tp.BytesPerSecond())
+}
+
+// TestSignal validates sending signals to a container's main process.
+func (s *RuntimeSuite) TestSignal() {
+	t, ctx := s.T(), s.ctx
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
+		Image:     busybox,
+		Command:   []string{"sh", "-c"},
+		Arguments: []string{"trap 'exit 42' USR1; while true; do sleep 0.1; done"},
+	})
+	require.NoError(t, err)
+	defer ctr.Remove(ctx, runtime.RemoveOpts{})
+
+	signaler, ok := ctr.(runtime.Signaler)
+	if !ok {
+		s.unsupported("Containers don't implement runtime.Signaler.")
+	}
+	require.NoError(t, ctr.Start(ctx))
+
+	err = signaler.Signal(ctx, "SIGUSR1")
+	if errors.Is(err, runtime.ErrNotImplemented) {
+		s.unsupported("Signal: %v", err)
+	}
+	require.NoError(t, err)
+
+	info, err := awaitExit(ctr)
+	require.NoError(t, err)
+	require.NotNil(t, info.ExitCode)
+	assert.Equal(t, 42, *info.ExitCode)
+}
+
+// TestExecSync validates running commands inside a container.
+func (s *RuntimeSuite) TestExecSync() {
+	t, ctx := s.T(), s.ctx
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
+		Image:     busybox,
+		Command:   []string{"sleep"},
+		Arguments: []string{"60"},
+	})
+	require.NoError(t, err)
+	defer ctr.Remove(ctx, runtime.RemoveOpts{})
+
+	execer, ok := ctr.(runtime.Execer)
+	if !ok {
+		s.unsupported("Containers don't implement runtime.Execer.")
+	}
+	require.NoError(t, ctr.Start(ctx))
+
+	result, err := execer.ExecSync(ctx, []string{"sh", "-c", "echo out; echo err >&2; exit 3"}, 10*time.Second)
+	if errors.Is(err, runtime.ErrNotImplemented) {
+		s.unsupported("ExecSync: %v", err)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.Equal(t, "out\n", string(result.Stdout))
+	assert.Equal(t, "err\n", string(result.Stderr))
+}
+
+// TestHooks validates that post-start hooks run inside started containers.
+func (s *RuntimeSuite) TestHooks() {
+	t, ctx := s.T(), s.ctx
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
+		Image:     busybox,
+		Command:   []string{"sleep"},
+		Arguments: []string{"60"},
+		PostStart: &runtime.Hook{Command: []string{"touch", "/tmp/started"}},
+		PreStop:   &runtime.Hook{Command: []string{"true"}, Timeout: 5 * time.Second},
+	})
+	if errors.Is(err, runtime.ErrNotImplemented) {
+		s.unsupported("CreateContainer: %v", err)
+	}
+	require.NoError(t, err)
+	defer ctr.Remove(ctx, runtime.RemoveOpts{})
+
+	info, err := ctr.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"touch", "/tmp/started"}, info.PostStart.Command)
+	assert.Equal(t, []string{"true"}, info.PreStop.Command)
+
+	execer, ok := ctr.(runtime.Execer)
+	if !ok {
+		s.unsupported("Containers don't implement runtime.Execer.")
+	}
+	require.NoError(t, ctr.Start(ctx))
+
+	result, err := execer.ExecSync(ctx, []string{"test", "-f", "/tmp/started"}, 10*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode, "post-start hook didn't run")
+
+	grace := 5 * time.Second
+	require.NoError(t, ctr.Stop(ctx, &grace))
+}
+
+// TestListContainersPage validates paginated listing.
+func (s *RuntimeSuite) TestListContainersPage() {
+	t, ctx := s.T(), s.ctx
+
+	pager, ok := s.rt.(runtime.Pager)
+	if !ok {
+		s.unsupported("The runtime doesn't implement runtime.Pager.")
+	}
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	var created []string
+	for i := 0; i < 3; i++ {
+		ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{Image: busybox})
+		require.NoError(t, err)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
+		created = append(created, ctr.Name())
+	}
+
+	var listed []string
+	opts := runtime.ListOpts{Limit: 1}
+	for {
+		page, err := pager.ListContainersPage(ctx, opts)
+		if errors.Is(err, runtime.ErrNotImplemented) {
+			s.unsupported("ListContainersPage: %v", err)
+		}
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page.Containers), 1)
+		for _, c := range page.Containers {
+			listed = append(listed, c.Name())
+		}
+		if page.Next == "" {
+			break
+		}
+		opts.Cursor = page.Next
+	}
+	for _, name := range created {
+		assert.Contains(t, listed, name)
+	}
+}
+
+// TestSnapshot validates creating containers from a committed snapshot.
+func (s *RuntimeSuite) TestSnapshot() {
+	t, ctx := s.T(), s.ctx
+
+	snapshotter, ok := s.rt.(runtime.Snapshotter)
+	if !ok {
+		s.unsupported("The runtime doesn't implement runtime.Snapshotter.")
+	}
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	source, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
+		Image:     busybox,
+		Command:   []string{"sh", "-c"},
+		Arguments: []string{"echo warm > /cache"},
+	})
+	require.NoError(t, err)
+	defer source.Remove(ctx, runtime.RemoveOpts{})
+	require.NoError(t, source.Start(ctx))
+	_, err = awaitExit(source)
+	require.NoError(t, err)
+
+	snapshot, err := snapshotter.CommitSnapshot(ctx, source, "")
+	if errors.Is(err, runtime.ErrNotImplemented) {
+		s.unsupported("CommitSnapshot: %v", err)
+	}
+	require.NoError(t, err)
+	defer snapshotter.RemoveSnapshot(ctx, snapshot)
+
+	ctr, err := snapshotter.CreateContainerFromSnapshot(ctx, snapshot, &runtime.ContainerOpts{
+		Command:   []string{"cat"},
+		Arguments: []string{"/cache"},
+	})
+	require.NoError(t, err)
+	defer ctr.Remove(ctx, runtime.RemoveOpts{})
+	require.NoError(t, ctr.Start(ctx))
+	_, err = awaitExit(ctr)
+	require.NoError(t, err)
+
+	r, err := ctr.Logs(ctx, runtime.LogsOpts{})
+	require.NoError(t, err)
+	defer r.Close()
+	line, err := r.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "warm\n", line.Text)
+}
+
+// TestDualStackNetwork validates that containers on an IPv6-enabled network
+// are assigned static addresses of both families.
+func (s *RuntimeSuite) TestDualStackNetwork() {
+	t, ctx := s.T(), s.ctx
+
+	creator, ok := s.rt.(runtime.NetworkCreator)
+	if !ok {
+		s.unsupported("The runtime doesn't implement runtime.NetworkCreator.")
+	}
+
+	name := fmt.Sprintf("runtime-test-%d", time.Now().UnixNano())
+	err := creator.CreateNetwork(ctx, &runtime.NetworkOpts{
+		Name:       name,
+		EnableIPv6: true,
+		Subnets:    []string{"172.30.99.0/24", "fd00:beac::/64"},
+	})
+	require.NoError(t, err)
+	defer creator.RemoveNetwork(ctx, name)
+
+	require.NoError(t, s.rt.PullImage(ctx, busybox, runtime.PullIfMissing, true))
+	ctr, err := s.rt.CreateContainer(ctx, &runtime.ContainerOpts{
+		Image:       busybox,
+		Command:     []string{"sleep"},
+		Arguments:   []string{"60"},
+		Network:     name,
+		IPAddress:   "172.30.99.10",
+		IPv6Address: "fd00:beac::10",
+	})
+	require.NoError(t, err)
+	defer ctr.Remove(ctx, runtime.RemoveOpts{})
+	require.NoError(t, ctr.Start(ctx))
+
+	info, err := ctr.Info(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"172.30.99.10", "fd00:beac::10"}, info.IPAddresses)
+}
+
 // TestContainerStop validates different ways of terminating a container.
 func (s *RuntimeSuite) TestContainerStop() {
 	t, ctx := s.T(), s.ctx
@@ -317,7 +624,7 @@ func (s *RuntimeSuite) TestContainerStop() {
 		var zero time.Duration
 		ctr, err := s.rt.CreateContainer(ctx, spinForever)
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 		require.NoError(t, ctr.Start(ctx))
 
 		start := time.Now()
@@ -333,7 +640,7 @@ func (s *RuntimeSuite) TestContainerStop() {
 		delay := 5 * time.Second // This is really long for a test, but Docker is slow.
 		ctr, err := s.rt.CreateContainer(ctx, spinForever)
 		require.NoError(t, err)
-		defer ctr.Remove(ctx)
+		defer ctr.Remove(ctx, runtime.RemoveOpts{})
 		require.NoError(t, ctr.Start(ctx))
 
 		start := time.Now()
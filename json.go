@@ -0,0 +1,250 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion identifies the JSON representation of ContainerInfo,
+// ContainerStats, and Event, which is included in each encoded object as
+// "schemaVersion". Fields may be added without changing the version, but
+// removing or redefining a field increments it.
+//
+// Field names are camelCase, times are RFC 3339 strings which are omitted when
+// unset, durations are fractional seconds, and network rates are bits per
+// second. For example:
+//
+//	{"schemaVersion":1,"status":"exited","exitCode":0,"createdAt":"2021-08-01T00:00:00Z",...}
+const SchemaVersion = 1
+
+// jsonContainerInfo is the stable JSON representation of a ContainerInfo.
+type jsonContainerInfo struct {
+	SchemaVersion        int               `json:"schemaVersion"`
+	Status               ContainerStatus   `json:"status"`
+	Message              string            `json:"message,omitempty"`
+	ExitCode             *int              `json:"exitCode,omitempty"`
+	CreatedAt            *time.Time        `json:"createdAt,omitempty"`
+	StartedAt            *time.Time        `json:"startedAt,omitempty"`
+	EndedAt              *time.Time        `json:"endedAt,omitempty"`
+	RestartCount         int               `json:"restartCount"`
+	Interruption         *jsonInterruption `json:"interruption,omitempty"`
+	Labels               map[string]string `json:"labels,omitempty"`
+	PID                  int               `json:"pid,omitempty"`
+	IPAddresses          []string          `json:"ipAddresses,omitempty"`
+	CgroupPath           string            `json:"cgroupPath,omitempty"`
+	ConfigHash           string            `json:"configHash,omitempty"`
+	MemoryBytes          int64             `json:"memoryBytes,omitempty"`
+	CPUCount             float64           `json:"cpuCount,omitempty"`
+	GPUs                 []string          `json:"gpus,omitempty"`
+	MaxRuntimeSeconds    float64           `json:"maxRuntimeSeconds,omitempty"`
+	CPUBurstSeconds      float64           `json:"cpuBurstSeconds,omitempty"`
+	IngressBitsPerSecond int64             `json:"ingressBitsPerSecond,omitempty"`
+	EgressBitsPerSecond  int64             `json:"egressBitsPerSecond,omitempty"`
+	PostStart            *jsonHook         `json:"postStart,omitempty"`
+	PreStop              *jsonHook         `json:"preStop,omitempty"`
+}
+
+type jsonInterruption struct {
+	Reason   InterruptionReason `json:"reason"`
+	Resource string             `json:"resource,omitempty"`
+}
+
+type jsonHook struct {
+	Command        []string `json:"command"`
+	TimeoutSeconds float64  `json:"timeoutSeconds,omitempty"`
+}
+
+func hookToJSON(h *Hook) *jsonHook {
+	if h == nil {
+		return nil
+	}
+	return &jsonHook{Command: h.Command, TimeoutSeconds: h.Timeout.Seconds()}
+}
+
+func hookFromJSON(h *jsonHook) *Hook {
+	if h == nil {
+		return nil
+	}
+	return &Hook{Command: h.Command, Timeout: time.Duration(h.TimeoutSeconds * float64(time.Second))}
+}
+
+// MarshalJSON encodes a container's details in the stable format described by
+// SchemaVersion.
+func (i ContainerInfo) MarshalJSON() ([]byte, error) {
+	v := jsonContainerInfo{
+		SchemaVersion:        SchemaVersion,
+		Status:               i.Status,
+		Message:              i.Message,
+		ExitCode:             i.ExitCode,
+		CreatedAt:            timeOrNil(i.CreatedAt),
+		StartedAt:            timeOrNil(i.StartedAt),
+		EndedAt:              timeOrNil(i.EndedAt),
+		RestartCount:         i.RestartCount,
+		Labels:               i.Labels,
+		PID:                  i.PID,
+		IPAddresses:          i.IPAddresses,
+		CgroupPath:           i.CgroupPath,
+		ConfigHash:           i.ConfigHash,
+		MemoryBytes:          i.Memory,
+		CPUCount:             i.CPUCount,
+		GPUs:                 i.GPUs,
+		MaxRuntimeSeconds:    i.MaxRuntime.Seconds(),
+		CPUBurstSeconds:      i.CPUBurst.Seconds(),
+		IngressBitsPerSecond: int64(i.IngressBandwidth),
+		EgressBitsPerSecond:  int64(i.EgressBandwidth),
+		PostStart:            hookToJSON(i.PostStart),
+		PreStop:              hookToJSON(i.PreStop),
+	}
+	if i.Interruption != nil {
+		v.Interruption = &jsonInterruption{Reason: i.Interruption.Reason, Resource: i.Interruption.Resource}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a container's details encoded by MarshalJSON.
+func (i *ContainerInfo) UnmarshalJSON(b []byte) error {
+	var v jsonContainerInfo
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(v.SchemaVersion); err != nil {
+		return err
+	}
+
+	*i = ContainerInfo{
+		Labels:           v.Labels,
+		CreatedAt:        timeOrZero(v.CreatedAt),
+		StartedAt:        timeOrZero(v.StartedAt),
+		EndedAt:          timeOrZero(v.EndedAt),
+		Status:           v.Status,
+		Message:          v.Message,
+		ExitCode:         v.ExitCode,
+		RestartCount:     v.RestartCount,
+		PID:              v.PID,
+		IPAddresses:      v.IPAddresses,
+		CgroupPath:       v.CgroupPath,
+		ConfigHash:       v.ConfigHash,
+		Memory:           v.MemoryBytes,
+		CPUCount:         v.CPUCount,
+		GPUs:             v.GPUs,
+		MaxRuntime:       time.Duration(v.MaxRuntimeSeconds * float64(time.Second)),
+		CPUBurst:         time.Duration(v.CPUBurstSeconds * float64(time.Second)),
+		IngressBandwidth: Bandwidth(v.IngressBitsPerSecond),
+		EgressBandwidth:  Bandwidth(v.EgressBitsPerSecond),
+		PostStart:        hookFromJSON(v.PostStart),
+		PreStop:          hookFromJSON(v.PreStop),
+	}
+	if v.Interruption != nil {
+		i.Interruption = &Interruption{Reason: v.Interruption.Reason, Resource: v.Interruption.Resource}
+	}
+	return nil
+}
+
+// jsonContainerStats is the stable JSON representation of ContainerStats.
+// Statistics are keyed by their StatType, e.g. "CPUUsagePercent".
+type jsonContainerStats struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	Time          time.Time            `json:"time"`
+	Stats         map[StatType]float64 `json:"stats"`
+}
+
+// MarshalJSON encodes stats in the stable format described by SchemaVersion.
+func (s ContainerStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonContainerStats{
+		SchemaVersion: SchemaVersion,
+		Time:          s.Time,
+		Stats:         s.Stats,
+	})
+}
+
+// UnmarshalJSON decodes stats encoded by MarshalJSON.
+func (s *ContainerStats) UnmarshalJSON(b []byte) error {
+	var v jsonContainerStats
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(v.SchemaVersion); err != nil {
+		return err
+	}
+	*s = ContainerStats{Time: v.Time, Stats: v.Stats}
+	return nil
+}
+
+// jsonEvent is the stable JSON representation of an Event.
+type jsonEvent struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Time          time.Time `json:"time"`
+	Warning       bool      `json:"warning"`
+	Reason        string    `json:"reason"`
+	Message       string    `json:"message"`
+	Count         int       `json:"count"`
+}
+
+// MarshalJSON encodes an event in the stable format described by SchemaVersion.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonEvent{
+		SchemaVersion: SchemaVersion,
+		Time:          e.Time,
+		Warning:       e.Warning,
+		Reason:        e.Reason,
+		Message:       e.Message,
+		Count:         e.Count,
+	})
+}
+
+// UnmarshalJSON decodes an event encoded by MarshalJSON.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var v jsonEvent
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(v.SchemaVersion); err != nil {
+		return err
+	}
+	*e = Event{Time: v.Time, Warning: v.Warning, Reason: v.Reason, Message: v.Message, Count: v.Count}
+	return nil
+}
+
+// MarshalText encodes a status as its string form, e.g. "running".
+func (s ContainerStatus) MarshalText() ([]byte, error) {
+	switch s {
+	case StatusCreated, StatusRunning, StatusExited:
+		return []byte(s.String()), nil
+	}
+	return nil, fmt.Errorf("invalid container status %d", int(s))
+}
+
+// UnmarshalText decodes a status encoded by MarshalText.
+func (s *ContainerStatus) UnmarshalText(b []byte) error {
+	for _, status := range []ContainerStatus{StatusCreated, StatusRunning, StatusExited} {
+		if string(b) == status.String() {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid container status %q", b)
+}
+
+// checkSchemaVersion rejects objects encoded with an incompatible schema.
+// Objects without a version predate versioning and are assumed compatible.
+func checkSchemaVersion(version int) error {
+	if version > SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d; expected at most %d", version, SchemaVersion)
+	}
+	return nil
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
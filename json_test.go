@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerInfoJSON(t *testing.T) {
+	exitCode := 137
+	info := ContainerInfo{
+		Labels:           map[string]string{"app": "test"},
+		CreatedAt:        time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC),
+		StartedAt:        time.Date(2021, 8, 1, 0, 0, 1, 0, time.UTC),
+		EndedAt:          time.Date(2021, 8, 1, 1, 0, 1, 0, time.UTC),
+		Status:           StatusExited,
+		Message:          "deadline exceeded",
+		ExitCode:         &exitCode,
+		Interruption:     &Interruption{Reason: InterruptionDeadlineExceeded},
+		IPAddresses:      []string{"10.0.0.2"},
+		Memory:           1 << 30,
+		CPUCount:         1.5,
+		GPUs:             []string{"GPU-0"},
+		MaxRuntime:       time.Hour,
+		CPUBurst:         500 * time.Millisecond,
+		IngressBandwidth: 100 * Mbit,
+		EgressBandwidth:  10 * Mbit,
+		PostStart:        &Hook{Command: []string{"warm-up"}},
+		PreStop:          &Hook{Command: []string{"drain", "--quiet"}, Timeout: 30 * time.Second},
+	}
+
+	b, err := json.Marshal(info)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"status": "exited",
+		"message": "deadline exceeded",
+		"exitCode": 137,
+		"createdAt": "2021-08-01T00:00:00Z",
+		"startedAt": "2021-08-01T00:00:01Z",
+		"endedAt": "2021-08-01T01:00:01Z",
+		"restartCount": 0,
+		"interruption": {"reason": "deadline-exceeded"},
+		"labels": {"app": "test"},
+		"ipAddresses": ["10.0.0.2"],
+		"memoryBytes": 1073741824,
+		"cpuCount": 1.5,
+		"gpus": ["GPU-0"],
+		"maxRuntimeSeconds": 3600,
+		"cpuBurstSeconds": 0.5,
+		"ingressBitsPerSecond": 100000000,
+		"egressBitsPerSecond": 10000000,
+		"postStart": {"command": ["warm-up"]},
+		"preStop": {"command": ["drain", "--quiet"], "timeoutSeconds": 30}
+	}`, string(b))
+
+	var decoded ContainerInfo
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, info, decoded)
+
+	// Unset times are omitted.
+	b, err = json.Marshal(&ContainerInfo{Status: StatusCreated})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"schemaVersion": 1, "status": "created", "restartCount": 0}`, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"schemaVersion": 2, "status": "running"}`), &decoded))
+	assert.Error(t, json.Unmarshal([]byte(`{"schemaVersion": 1, "status": "paused"}`), &decoded))
+}
+
+func TestContainerStatsJSON(t *testing.T) {
+	stats := ContainerStats{
+		Time:  time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC),
+		Stats: map[StatType]float64{CPUUsagePercentStat: 50, MemoryUsageBytesStat: 1024},
+	}
+
+	b, err := json.Marshal(&stats)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"time": "2021-08-01T00:00:00Z",
+		"stats": {"CPUUsagePercent": 50, "MemoryUsageBytes": 1024}
+	}`, string(b))
+
+	var decoded ContainerStats
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, stats, decoded)
+}
+
+func TestEventJSON(t *testing.T) {
+	event := Event{
+		Time:    time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC),
+		Warning: true,
+		Reason:  "MemoryHigh",
+		Message: "MemoryUsagePercent is above 90 (currently 95)",
+		Count:   2,
+	}
+
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"time": "2021-08-01T00:00:00Z",
+		"warning": true,
+		"reason": "MemoryHigh",
+		"message": "MemoryUsagePercent is above 90 (currently 95)",
+		"count": 2
+	}`, string(b))
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, event, decoded)
+}
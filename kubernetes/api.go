@@ -0,0 +1,119 @@
+package kubernetes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/beaker/runtime"
+)
+
+// backendName identifies the Kubernetes API server in API metrics.
+const backendName = "kubernetes"
+
+// apiObserver is implemented by runtimes which report their API calls.
+type apiObserver interface {
+	ObserveAPI(fn func(runtime.APICall))
+}
+
+// ObserveAPI registers a function to receive metrics for each request to the
+// API server and to the node's container runtime. It must be called before the
+// runtime is used.
+func (r *Runtime) ObserveAPI(fn func(runtime.APICall)) {
+	r.onCall = fn
+	if observer, ok := r.runtime.(apiObserver); ok {
+		observer.ObserveAPI(fn)
+	}
+}
+
+// apiTransport reports each request made through it to its runtime.
+type apiTransport struct {
+	base http.RoundTripper
+	r    *Runtime
+}
+
+// RoundTrip implements the http.RoundTripper interface. Requests which stream
+// responses, such as watches, are measured up to the response's headers.
+func (t *apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if t.r.onCall == nil {
+		return resp, err
+	}
+
+	call := runtime.APICall{Backend: backendName, Operation: apiOperation(req), Duration: time.Since(start)}
+	if err != nil {
+		call.Err, call.Class = err, runtime.ClassifyAPIError(err)
+	} else if resp.StatusCode >= 400 {
+		call.Err, call.Class = errors.New(resp.Status), classifyStatus(resp.StatusCode)
+	}
+	t.r.onCall(call)
+	return resp, err
+}
+
+// apiOperation names a request by its verb and resource, as in the API
+// server's audit logs, e.g. "get pods" or "create pods/eviction".
+func apiOperation(req *http.Request) string {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		return req.Method + " " + req.URL.Path
+	}
+	if len(parts) > 2 && parts[0] == "namespaces" {
+		parts = parts[2:]
+	}
+	if len(parts) == 0 {
+		return req.Method + " " + req.URL.Path
+	}
+
+	resource, named := parts[0], len(parts) > 1
+	if len(parts) > 2 {
+		resource += "/" + parts[2]
+	}
+
+	var verb string
+	switch req.Method {
+	case http.MethodGet:
+		switch {
+		case named:
+			verb = "get"
+		case req.URL.Query().Get("watch") == "true":
+			verb = "watch"
+		default:
+			verb = "list"
+		}
+	case http.MethodPost:
+		verb = "create"
+	case http.MethodPut:
+		verb = "update"
+	case http.MethodPatch:
+		verb = "patch"
+	case http.MethodDelete:
+		verb = "delete"
+		if !named {
+			verb = "deletecollection"
+		}
+	default:
+		verb = strings.ToLower(req.Method)
+	}
+	return verb + " " + resource
+}
+
+// classifyStatus categorizes an HTTP error status.
+func classifyStatus(code int) runtime.APIErrorClass {
+	switch code {
+	case http.StatusNotFound:
+		return runtime.APIErrorNotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return runtime.APIErrorTimeout
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return runtime.APIErrorUnavailable
+	default:
+		return runtime.APIErrorOther
+	}
+}
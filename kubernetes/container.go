@@ -2,7 +2,11 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,6 +15,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/beaker/runtime"
@@ -21,7 +27,9 @@ import (
 // Note that standalone containers do not exist in Kubernetes; all containers
 // are wrapped in a pod.
 type Container struct {
-	client *kubernetes.Clientset
+	client  *kubernetes.Clientset
+	life    *runtime.Lifecycle
+	history *runtime.History
 
 	namespace     string
 	podName       string
@@ -30,6 +38,7 @@ type Container struct {
 	// Underlying runtime and container
 	runtimeLock sync.Mutex
 	runtime     runtime.Runtime
+	resolver    ContainerResolver
 	container   runtime.Container
 }
 
@@ -38,13 +47,91 @@ func (c *Container) Name() string {
 	return c.podName
 }
 
-// Start does nothing on Kubernetes since containers are automatically started on creation.
+// Start binds the pod to its node, allowing it to run. A container's
+// MaxRuntime is applied here so that time spent waiting to start doesn't count
+// against it.
+//
+// Start returns once the pod is bound. The kubelet then admits the pod and
+// starts its containers asynchronously, typically within a few seconds, after
+// pulling images if needed. The kubelet may also reject the pod if the node
+// lacks the resources it requests, in which case Info reports it as exited.
+// Pods aren't bound to unschedulable nodes; see Runtime.SetSchedulable.
 func (c *Container) Start(ctx context.Context) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	pod, err := c.client.CoreV1().Pods(c.namespace).Get(ctx, c.podName, metav1.GetOptions{})
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return runtime.ErrNotFound
+		}
+		return fmt.Errorf("getting pod: %w", err)
+	}
+	if !isGated(pod) {
+		return nil
+	}
+	if err := checkSchedulable(ctx, c.client, pod.Labels[nodeLabel]); err != nil {
+		return err
+	}
+
+	if value, ok := pod.Annotations[runtime.MaxRuntimeLabel]; ok {
+		maxRuntime, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("max runtime: %w", err)
+		}
+
+		// Kubernetes measures deadlines from when the node accepts the pod,
+		// which is after it's bound.
+		patch, err := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"activeDeadlineSeconds": int64(math.Ceil(maxRuntime.Seconds())),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = c.client.CoreV1().Pods(c.namespace).Patch(ctx, c.podName, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			if k8serror.IsNotFound(err) {
+				return runtime.ErrNotFound
+			}
+			return fmt.Errorf("patching pod: %w", err)
+		}
+	}
+
+	binding := &corev1.Binding{
+		ObjectMeta: metav1.ObjectMeta{Name: c.podName},
+		Target:     corev1.ObjectReference{Kind: "Node", Name: pod.Labels[nodeLabel]},
+	}
+	if err := c.client.CoreV1().Pods(c.namespace).Bind(ctx, binding, metav1.CreateOptions{}); err != nil {
+		switch {
+		case k8serror.IsNotFound(err):
+			return runtime.ErrNotFound
+		case k8serror.IsConflict(err):
+			// The pod was bound by a concurrent call.
+			return nil
+		}
+		return fmt.Errorf("binding pod: %w", err)
+	}
 	return nil
 }
 
+// isGated returns true if a pod is held until Start binds it to its node.
+func isGated(pod *corev1.Pod) bool {
+	return pod.Spec.NodeName == "" && pod.Spec.SchedulerName == gateSchedulerName
+}
+
 // Info returns a container's details.
 func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	pod, err := c.client.CoreV1().Pods(c.namespace).Get(ctx, c.podName, metav1.GetOptions{})
 	if err != nil {
 		if k8serror.IsNotFound(err) {
@@ -54,16 +141,56 @@ func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
 	}
 
 	var state corev1.ContainerState
+	var restarts int32
 	for _, status := range pod.Status.ContainerStatuses {
 		if status.Name == c.containerName {
 			state = status.State
+			restarts = status.RestartCount
 			break
 		}
 	}
 
 	info := &runtime.ContainerInfo{
-		Labels:    pod.Annotations,
-		CreatedAt: pod.CreationTimestamp.Time,
+		Labels:       make(map[string]string, len(pod.Annotations)),
+		CreatedAt:    pod.CreationTimestamp.Time,
+		RestartCount: int(restarts),
+	}
+	for k, v := range pod.Annotations {
+		switch k {
+		case networksAnnotation, runtime.CreateTokenLabel, runtime.AutoRemoveLabel:
+			// Internal annotations are not labels.
+		case runtime.ConfigHashLabel:
+			info.ConfigHash = v
+		case runtime.MaxRuntimeLabel:
+			if info.MaxRuntime, err = time.ParseDuration(v); err != nil {
+				return nil, fmt.Errorf("max runtime: %w", err)
+			}
+		case ingressBandwidthAnnotation:
+			if info.IngressBandwidth, err = runtime.ParseBandwidth(v); err != nil {
+				return nil, fmt.Errorf("ingress bandwidth: %w", err)
+			}
+		case egressBandwidthAnnotation:
+			if info.EgressBandwidth, err = runtime.ParseBandwidth(v); err != nil {
+				return nil, fmt.Errorf("egress bandwidth: %w", err)
+			}
+		default:
+			info.Labels[k] = v
+		}
+	}
+	for _, spec := range pod.Spec.Containers {
+		if spec.Name == c.containerName {
+			info.PostStart, info.PreStop = lifecycleHooks(spec.Lifecycle)
+			break
+		}
+	}
+
+	// PodIPs holds one address per IP family on dual-stack clusters. Older
+	// clusters only populate PodIP.
+	for _, ip := range pod.Status.PodIPs {
+		info.IPAddresses = append(info.IPAddresses, ip.IP)
+	}
+	if len(info.IPAddresses) == 0 && pod.Status.PodIP != "" {
+		info.IPAddresses = []string{pod.Status.PodIP}
 	}
 
 	for _, ctr := range pod.Spec.Containers {
@@ -76,6 +203,9 @@ func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
 	}
 
 	switch {
+	case isGated(pod) && pod.Status.Phase != corev1.PodFailed:
+		// The pod is held until Start is called.
+		info.Status = runtime.StatusCreated
 	case state.Waiting != nil:
 		info.Status = runtime.StatusRunning
 		info.Message = state.Waiting.Reason
@@ -86,6 +216,14 @@ func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
 		info.StartedAt = state.Running.StartedAt.Time
 
 		info.Status = runtime.StatusRunning
+
+		// Process details are only available from the underlying runtime.
+		if err := c.resolveContainer(ctx); err == nil {
+			if inner, err := c.container.Info(ctx); err == nil {
+				info.PID = inner.PID
+				info.CgroupPath = inner.CgroupPath
+			}
+		}
 	case state.Terminated != nil:
 		info.StartedAt = state.Terminated.StartedAt.Time
 		info.EndedAt = state.Terminated.FinishedAt.Time
@@ -118,52 +256,346 @@ func (c *Container) Info(ctx context.Context) (*runtime.ContainerInfo, error) {
 			"reason":     pod.Status.Reason,
 		}).Debug("No container state found; assumed 'running'")
 	}
+
+	info.Interruption = podInterruption(pod)
 	return info, nil
 }
 
+// Eviction messages name the resource under pressure, e.g. "The node was low
+// on resource: memory. Container task was using 10Gi, which exceeds..."
+var evictionResourceRegex = regexp.MustCompile(`low on resource: ([a-zA-Z0-9./-]+[a-zA-Z0-9])`)
+
+// podInterruption determines whether a pod failed due to its infrastructure.
+// Kubernetes reports these failures as pod reasons and conditions rather than
+// container states.
+func podInterruption(pod *corev1.Pod) *runtime.Interruption {
+	switch reason := pod.Status.Reason; {
+	case reason == "Evicted":
+		interruption := &runtime.Interruption{Reason: runtime.InterruptionEvicted}
+		if m := evictionResourceRegex.FindStringSubmatch(pod.Status.Message); m != nil {
+			interruption.Resource = m[1]
+		}
+		return interruption
+	case reason == "Preempting":
+		return &runtime.Interruption{Reason: runtime.InterruptionPreempted}
+	case reason == "Shutdown" || reason == "Terminated":
+		return &runtime.Interruption{Reason: runtime.InterruptionNodeShutdown}
+	case reason == "NodeLost":
+		return &runtime.Interruption{Reason: runtime.InterruptionNodeLost}
+	case reason == "DeadlineExceeded":
+		return &runtime.Interruption{Reason: runtime.InterruptionDeadlineExceeded}
+	case strings.HasPrefix(reason, "OutOf") || reason == "UnexpectedAdmissionError":
+		return &runtime.Interruption{Reason: runtime.InterruptionAdmission}
+	}
+
+	// Newer clusters mark pods about to be disrupted with a condition.
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type != "DisruptionTarget" || cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Reason {
+		case "PreemptionByScheduler", "PreemptionByKubeScheduler":
+			return &runtime.Interruption{Reason: runtime.InterruptionPreempted}
+		case "TerminationByKubelet":
+			return &runtime.Interruption{Reason: runtime.InterruptionEvicted}
+		}
+	}
+	return nil
+}
+
+// Events returns recent events for the container's pod in chronological
+// order. These explain why a container isn't starting, e.g. FailedScheduling,
+// ImagePullBackOff, or FailedMount.
+func (c *Container) Events(ctx context.Context) ([]runtime.Event, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	selector := fields.Set{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": c.podName,
+	}
+	list, err := c.client.CoreV1().Events(c.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.AsSelector().String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing events: %w", err)
+	}
+
+	events := make([]runtime.Event, len(list.Items))
+	for i, e := range list.Items {
+		events[i] = runtime.Event{
+			Time:    eventTime(&e),
+			Warning: e.Type == corev1.EventTypeWarning,
+			Reason:  e.Reason,
+			Message: e.Message,
+			Count:   int(e.Count),
+		}
+		if events[i].Count == 0 {
+			events[i].Count = 1
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
+}
+
+// eventTime returns the last time an event was observed. Events may be
+// recorded with any of several timestamps depending on the reporter.
+func eventTime(e *corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case e.Series != nil && !e.Series.LastObservedTime.IsZero():
+		return e.Series.LastObservedTime.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	default:
+		return e.FirstTimestamp.Time
+	}
+}
+
 // Logs returns logging.LogReader which can be used to read log messages
-// starting at the given time (inclusive). Set time to zero to read the full log.
-func (c *Container) Logs(ctx context.Context, since time.Time) (logging.LogReader, error) {
+// starting at the given time (inclusive).
+func (c *Container) Logs(ctx context.Context, opts runtime.LogsOpts) (logging.LogReader, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	// It's more efficient and reliable to pull logs from CRI than to use the
 	// k8s API. This is possible because we can guarantee we're on the same host.
 	if err := c.resolveContainer(ctx); err != nil {
 		return nil, err
 	}
-	return c.container.Logs(ctx, since)
+	return c.container.Logs(ctx, opts)
 }
 
 // Stop sends a SIGTERM to a container to instruct it to exit. If a timeout is
 // provided and elapses, the container is forcibly stopped with SIGKILL.
 func (c *Container) Stop(ctx context.Context, timeout *time.Duration) error {
-	// The k8s API offers no way to stop a container or pod without removal. Use CRI.
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	// The k8s API offers no way to stop a container or pod without removal. Use
+	// CRI, running the pre-stop hook since kubelet only does so on removal.
 	if err := c.resolveContainer(ctx); err != nil {
 		return err
 	}
+	info, err := c.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if info.PreStop != nil && info.Status == runtime.StatusRunning {
+		if timeout, err = runtime.RunPreStop(ctx, c.container, info.PreStop, timeout); err != nil {
+			log.WithError(err).Warnf("Stopping pod %s despite its failed hook", c.podName)
+		}
+	}
 	return c.container.Stop(ctx, timeout)
 }
 
-// Remove removes a pod with no grace period.
-func (c *Container) Remove(ctx context.Context) error {
-	var zero int64
-	opts := metav1.DeleteOptions{GracePeriodSeconds: &zero}
-	if err := c.client.CoreV1().Pods(c.namespace).Delete(ctx, c.podName, opts); err != nil {
+// Signal sends a signal to the container's main process if the underlying
+// runtime supports it.
+func (c *Container) Signal(ctx context.Context, signal string) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if err := c.resolveContainer(ctx); err != nil {
+		return err
+	}
+	signaler, ok := c.container.(runtime.Signaler)
+	if !ok {
+		return fmt.Errorf("underlying runtime doesn't support signals (%w)", runtime.ErrNotImplemented)
+	}
+	return signaler.Signal(ctx, signal)
+}
+
+// ExecSync runs a command in the container and waits for it to exit if the
+// underlying runtime supports it.
+func (c *Container) ExecSync(
+	ctx context.Context,
+	cmd []string,
+	timeout time.Duration,
+) (*runtime.ExecResult, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	if err := c.resolveContainer(ctx); err != nil {
+		return nil, err
+	}
+	execer, ok := c.container.(runtime.Execer)
+	if !ok {
+		return nil, fmt.Errorf("underlying runtime doesn't support exec (%w)", runtime.ErrNotImplemented)
+	}
+	return execer.ExecSync(ctx, cmd, timeout)
+}
+
+// Remove removes a pod, allowing it the grace period to exit. Volumes are
+// always removed along with the pod.
+func (c *Container) Remove(ctx context.Context, opts runtime.RemoveOpts) error {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	if opts.LogArchiveDir != "" {
+		// Logs are lost as soon as the pod is deleted, so stop the container
+		// first to capture its final output.
+		if opts.GracePeriod > 0 {
+			if err := c.Stop(ctx, &opts.GracePeriod); err != nil {
+				return err
+			}
+		}
+		if _, err := runtime.ArchiveLogs(ctx, c, opts.LogArchiveDir, opts.LogArchiveOpts); err != nil {
+			return err
+		}
+	}
+
+	var record *runtime.HistoryRecord
+	if c.history != nil {
+		record = runtime.CaptureRecord(ctx, c)
+	}
+
+	// Round up so that a sub-second grace period isn't treated as immediate.
+	grace := int64((opts.GracePeriod + time.Second - 1) / time.Second)
+	deleteOpts := metav1.DeleteOptions{GracePeriodSeconds: &grace}
+	if err := c.client.CoreV1().Pods(c.namespace).Delete(ctx, c.podName, deleteOpts); err != nil {
 		if k8serror.IsNotFound(err) {
 			return runtime.ErrNotFound
 		}
 		return fmt.Errorf("deleting pod: %w", err)
 	}
 
+	// The pod is removed even if cleaning up after it fails.
+	if c.history != nil {
+		c.history.Add(record)
+	}
+
+	// The budget may already have been garbage collected with the pod.
 	pdbs := c.client.PolicyV1beta1().PodDisruptionBudgets(c.namespace)
-	if err := pdbs.Delete(ctx, c.podName, metav1.DeleteOptions{}); err != nil {
+	if err := pdbs.Delete(ctx, c.podName, metav1.DeleteOptions{}); err != nil && !k8serror.IsNotFound(err) {
 		return fmt.Errorf("deleting pod disruption budget: %w", err)
 	}
 
+	if opts.WaitTimeout > 0 {
+		if err := c.waitForDeletion(ctx, opts); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// deletionPollInterval controls how often a deleted pod is checked while
+// waiting for it to disappear.
+const deletionPollInterval = time.Second
+
+// waitForDeletion polls a deleted pod until it's gone, reporting progress.
+// If the pod remains at the deadline, its finalizers are removed if requested.
+func (c *Container) waitForDeletion(ctx context.Context, opts runtime.RemoveOpts) error {
+	waitCtx, cancel := context.WithTimeout(ctx, opts.WaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(deletionPollInterval)
+	defer ticker.Stop()
+
+	pods := c.client.CoreV1().Pods(c.namespace)
+	var status string
+	for {
+		pod, err := pods.Get(waitCtx, c.podName, metav1.GetOptions{})
+		if k8serror.IsNotFound(err) {
+			return nil
+		}
+		if err != nil && waitCtx.Err() == nil {
+			return fmt.Errorf("waiting for pod deletion: %w", err)
+		}
+		if pod != nil && err == nil {
+			if s := deletionProgress(pod); s != status {
+				status = s
+				if opts.OnProgress != nil {
+					opts.OnProgress(status)
+				}
+			}
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !opts.ForceFinalize {
+				return fmt.Errorf("pod %s is still terminating after %s: %s", c.podName, opts.WaitTimeout, status)
+			}
+			return c.forceFinalize(ctx, opts.OnProgress)
+		case <-ticker.C:
+		}
+	}
+}
+
+// forceFinalize removes a terminating pod's finalizers so that the API server
+// can complete its deletion.
+func (c *Container) forceFinalize(ctx context.Context, onProgress func(string)) error {
+	if onProgress != nil {
+		onProgress("removing finalizers")
+	}
+	log.Warnf("Removing finalizers of pod %s, which is stuck terminating", c.podName)
+
+	patch := []byte(`{"metadata":{"finalizers":null}}`)
+	_, err := c.client.CoreV1().Pods(c.namespace).Patch(ctx, c.podName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !k8serror.IsNotFound(err) {
+		return fmt.Errorf("removing pod finalizers: %w", err)
+	}
+	return nil
+}
+
+// deletionProgress describes what's holding up a terminating pod's deletion.
+func deletionProgress(pod *corev1.Pod) string {
+	var running []string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated == nil {
+			running = append(running, status.Name)
+		}
+	}
+
+	var waiting []string
+	if len(running) != 0 {
+		waiting = append(waiting, "containers stopping: "+strings.Join(running, ", "))
+	} else if pod.Spec.NodeName != "" && pod.DeletionTimestamp != nil {
+		// Once containers stop, the kubelet tears down volumes and the
+		// sandbox before confirming deletion.
+		waiting = append(waiting, "kubelet cleaning up volumes and sandbox")
+	}
+	if len(pod.Finalizers) != 0 {
+		waiting = append(waiting, "finalizers: "+strings.Join(pod.Finalizers, ", "))
+	}
+	if len(waiting) == 0 {
+		return "terminating"
+	}
+	return "terminating; " + strings.Join(waiting, "; ")
+}
+
 // Stats scrapes stats information about the container and returns it.
 // This includes information about memory, cpu, network and block IO.
 func (c *Container) Stats(ctx context.Context) (*runtime.ContainerStats, error) {
+	ctx, end, err := c.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	if err := c.resolveContainer(ctx); err != nil {
 		return nil, err
 	}
@@ -200,12 +632,9 @@ func (c *Container) resolveContainer(ctx context.Context) error {
 	}
 
 	// Find the underlying runtime's container ID for the pod's main task.
-	var containerID string
-	for _, status := range pod.Status.ContainerStatuses {
-		if status.Name == c.containerName {
-			containerID = status.ContainerID
-			break
-		}
+	containerID, err := c.resolver.ResolveContainer(ctx, pod, c.containerName, false)
+	if err != nil {
+		return fmt.Errorf("resolving container: %w", err)
 	}
 
 	log := log.WithFields(log.Fields{
@@ -219,20 +648,25 @@ func (c *Container) resolveContainer(ctx context.Context) error {
 		return runtime.ErrNotStarted
 	}
 
-	// Strip the name's prefix if it has one.
-	if parts := strings.SplitN(containerID, "://", 2); len(parts) == 2 {
-		containerID = parts[1] // URI form "containerd://<id>"
-	}
-
-	wrapper, ok := c.runtime.(containerWrapper)
-	if !ok {
-		return fmt.Errorf("underlying runtime doesn't support direct container access (%w)", runtime.ErrNotImplemented)
+	container, err = c.wrapContainer(containerID)
+	if err != nil {
+		return err
 	}
 
 	c.runtimeLock.Lock()
-	c.container = wrapper.Container(containerID)
+	c.container = container
 	c.runtimeLock.Unlock()
 
 	log.Debugf("Resolved underlying container")
 	return nil
 }
+
+// wrapContainer accesses a container in the underlying runtime by the ID its
+// resolver found.
+func (c *Container) wrapContainer(containerID string) (runtime.Container, error) {
+	wrapper, ok := c.runtime.(containerWrapper)
+	if !ok {
+		return nil, fmt.Errorf("underlying runtime doesn't support direct container access (%w)", runtime.ErrNotImplemented)
+	}
+	return wrapper.Container(containerID), nil
+}
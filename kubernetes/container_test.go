@@ -0,0 +1,83 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/beaker/runtime"
+)
+
+func TestPodInterruption(t *testing.T) {
+	tests := map[string]struct {
+		Status   corev1.PodStatus
+		Expected *runtime.Interruption
+	}{
+		"Running": {corev1.PodStatus{Phase: corev1.PodRunning}, nil},
+		"Failed":  {corev1.PodStatus{Phase: corev1.PodFailed}, nil},
+		"Evicted": {
+			corev1.PodStatus{
+				Phase:   corev1.PodFailed,
+				Reason:  "Evicted",
+				Message: "The node was low on resource: ephemeral-storage. Container task was using 10Gi, which exceeds its request of 0. ",
+			},
+			&runtime.Interruption{Reason: runtime.InterruptionEvicted, Resource: "ephemeral-storage"},
+		},
+		"EvictedUnknown": {
+			corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"},
+			&runtime.Interruption{Reason: runtime.InterruptionEvicted},
+		},
+		"Preempting": {
+			corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Preempting"},
+			&runtime.Interruption{Reason: runtime.InterruptionPreempted},
+		},
+		"PreemptionCondition": {
+			corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{{
+				Type:   "DisruptionTarget",
+				Status: corev1.ConditionTrue,
+				Reason: "PreemptionByScheduler",
+			}}},
+			&runtime.Interruption{Reason: runtime.InterruptionPreempted},
+		},
+		"Shutdown": {
+			corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Shutdown"},
+			&runtime.Interruption{Reason: runtime.InterruptionNodeShutdown},
+		},
+		"DeadlineExceeded": {
+			corev1.PodStatus{Phase: corev1.PodFailed, Reason: "DeadlineExceeded"},
+			&runtime.Interruption{Reason: runtime.InterruptionDeadlineExceeded},
+		},
+		"OutOfMemory": {
+			corev1.PodStatus{Phase: corev1.PodFailed, Reason: "OutOfmemory"},
+			&runtime.Interruption{Reason: runtime.InterruptionAdmission},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, podInterruption(&corev1.Pod{Status: test.Status}))
+		})
+	}
+}
+
+func TestDeletionProgress(t *testing.T) {
+	now := metav1.Now()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now, Finalizers: []string{"example.com/cleanup"}},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+			{Name: "task", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			{Name: "sidecar", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+		}},
+	}
+	assert.Equal(t, "terminating; containers stopping: task; finalizers: example.com/cleanup", deletionProgress(pod))
+
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	pod.Finalizers = nil
+	assert.Equal(t, "terminating; kubelet cleaning up volumes and sandbox", deletionProgress(pod))
+
+	pod.Spec.NodeName = ""
+	assert.Equal(t, "terminating", deletionProgress(pod))
+}
@@ -0,0 +1,148 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/beaker/runtime/cri"
+)
+
+// ContainerResolver finds the node runtime's ID for a pod's container, so that
+// logs, stats, and exec can go directly to the node's container runtime.
+// Container runtimes identify containers differently, so the resolver is
+// selected to match the node; see Runtime.ResolveContainersWith.
+type ContainerResolver interface {
+	// ResolveContainer returns the ID of the named container's current
+	// instance in the pod, or of the instance which ran before its last
+	// restart if previous is set. It returns an empty ID if there's no such
+	// instance, e.g. because the container hasn't been created yet.
+	ResolveContainer(ctx context.Context, pod *corev1.Pod, name string, previous bool) (string, error)
+}
+
+// Labels kubelet sets on the containers it creates through CRI.
+const (
+	podUIDLabel        = "io.kubernetes.pod.uid"
+	containerNameLabel = "io.kubernetes.container.name"
+)
+
+// StatusResolver finds containers by the IDs kubelet reports in pod status.
+// It works on any runtime, but only once kubelet has reported the container,
+// which may lag its creation.
+type StatusResolver struct{}
+
+// ResolveContainer implements ContainerResolver.
+func (StatusResolver) ResolveContainer(ctx context.Context, pod *corev1.Pod, name string, previous bool) (string, error) {
+	status := containerStatus(pod, name)
+	if status == nil {
+		return "", nil
+	}
+	id := status.ContainerID
+	if previous {
+		id = ""
+		if last := status.LastTerminationState.Terminated; last != nil {
+			id = last.ContainerID
+		}
+	}
+
+	// Strip the ID's prefix if it has one.
+	if parts := strings.SplitN(id, "://", 2); len(parts) == 2 {
+		id = parts[1] // URI form "containerd://<id>"
+	}
+	return id, nil
+}
+
+// DockershimResolver finds containers by the names dockershim gives them,
+// "k8s_<container>_<pod>_<namespace>_<pod UID>_<attempt>", which Docker accepts
+// in place of IDs.
+type DockershimResolver struct{}
+
+// ResolveContainer implements ContainerResolver.
+func (DockershimResolver) ResolveContainer(ctx context.Context, pod *corev1.Pod, name string, previous bool) (string, error) {
+	status := containerStatus(pod, name)
+	if status == nil {
+		return "", nil
+	}
+	attempt := int(status.RestartCount)
+	if previous {
+		attempt--
+	} else if status.State.Waiting != nil && attempt == 0 {
+		return "", nil // Not yet created.
+	}
+	if attempt < 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("k8s_%s_%s_%s_%s_%d", name, pod.Name, pod.Namespace, pod.UID, attempt), nil
+}
+
+// InstanceFinder lists containers by label. It's implemented by cri.Runtime.
+type InstanceFinder interface {
+	FindInstances(ctx context.Context, labels map[string]string) ([]cri.Instance, error)
+}
+
+// LabelResolver finds containers by the pod UID and container name kubelet
+// labels them with, as on containerd and CRI-O.
+type LabelResolver struct {
+	Finder InstanceFinder
+}
+
+// ResolveContainer implements ContainerResolver.
+func (r LabelResolver) ResolveContainer(ctx context.Context, pod *corev1.Pod, name string, previous bool) (string, error) {
+	instances, err := r.Finder.FindInstances(ctx, map[string]string{
+		podUIDLabel:        string(pod.UID),
+		containerNameLabel: name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("finding container instances: %w", err)
+	}
+
+	// Instances are ordered from the latest attempt, which is current.
+	i := 0
+	if previous {
+		i = 1
+	}
+	if i >= len(instances) {
+		return "", nil
+	}
+	return instances[i].ID, nil
+}
+
+// resolverChain tries each resolver in turn until one finds the container.
+type resolverChain []ContainerResolver
+
+// ResolveContainer implements ContainerResolver.
+func (chain resolverChain) ResolveContainer(ctx context.Context, pod *corev1.Pod, name string, previous bool) (string, error) {
+	for _, r := range chain {
+		id, err := r.ResolveContainer(ctx, pod, name, previous)
+		if err != nil || id != "" {
+			return id, err
+		}
+	}
+	return "", nil
+}
+
+// resolverFor selects a resolver for a CRI runtime by the name it reports. Pod
+// status is preferred where available, since it's authoritative.
+func resolverFor(runtimeName string, finder InstanceFinder) ContainerResolver {
+	switch runtimeName {
+	case "docker":
+		return resolverChain{StatusResolver{}, DockershimResolver{}}
+	case "containerd", "cri-o":
+		return resolverChain{StatusResolver{}, LabelResolver{Finder: finder}}
+	default:
+		return StatusResolver{}
+	}
+}
+
+// containerStatus returns the status of a pod's container, or nil if kubelet
+// hasn't reported one.
+func containerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == name {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/beaker/runtime/cri"
+)
+
+// staticFinder finds the same instances for any labels, recording the labels.
+type staticFinder struct {
+	instances []cri.Instance
+	labels    map[string]string
+}
+
+func (f *staticFinder) FindInstances(ctx context.Context, labels map[string]string) ([]cri.Instance, error) {
+	f.labels = labels
+	return f.instances, nil
+}
+
+func testPod(statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "beaker", UID: "d7d1b8a0"},
+		Status:     corev1.PodStatus{ContainerStatuses: statuses},
+	}
+}
+
+func TestResolveContainer(t *testing.T) {
+	ctx := context.Background()
+	restarted := corev1.ContainerStatus{
+		Name:         containerName,
+		ContainerID:  "containerd://current",
+		RestartCount: 2,
+		LastTerminationState: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ContainerID: "containerd://previous"},
+		},
+	}
+	creating := corev1.ContainerStatus{
+		Name:  containerName,
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+	}
+
+	resolve := func(r ContainerResolver, pod *corev1.Pod, previous bool) string {
+		id, err := r.ResolveContainer(ctx, pod, containerName, previous)
+		require.NoError(t, err)
+		return id
+	}
+
+	t.Run("Status", func(t *testing.T) {
+		r := StatusResolver{}
+		assert.Equal(t, "current", resolve(r, testPod(restarted), false))
+		assert.Equal(t, "previous", resolve(r, testPod(restarted), true))
+		assert.Empty(t, resolve(r, testPod(creating), false))
+		assert.Empty(t, resolve(r, testPod(creating), true))
+		assert.Empty(t, resolve(r, testPod(), false))
+	})
+
+	t.Run("Dockershim", func(t *testing.T) {
+		r := DockershimResolver{}
+		assert.Equal(t, "k8s_task_job_beaker_d7d1b8a0_2", resolve(r, testPod(restarted), false))
+		assert.Equal(t, "k8s_task_job_beaker_d7d1b8a0_1", resolve(r, testPod(restarted), true))
+		assert.Empty(t, resolve(r, testPod(creating), false))
+		assert.Empty(t, resolve(r, testPod(creating), true))
+		assert.Empty(t, resolve(r, testPod(), false))
+	})
+
+	t.Run("Labels", func(t *testing.T) {
+		finder := &staticFinder{instances: []cri.Instance{{ID: "b", Attempt: 1}, {ID: "a", Attempt: 0}}}
+		r := LabelResolver{Finder: finder}
+		assert.Equal(t, "b", resolve(r, testPod(creating), false))
+		assert.Equal(t, map[string]string{podUIDLabel: "d7d1b8a0", containerNameLabel: containerName}, finder.labels)
+		assert.Equal(t, "a", resolve(r, testPod(creating), true))
+
+		finder.instances = finder.instances[1:]
+		assert.Empty(t, resolve(r, testPod(creating), true))
+	})
+
+	t.Run("Detected", func(t *testing.T) {
+		finder := &staticFinder{instances: []cri.Instance{{ID: "labeled"}}}
+
+		// Pod status is preferred, with a fallback before it's reported.
+		r := resolverFor("containerd", finder)
+		assert.Equal(t, "current", resolve(r, testPod(restarted), false))
+		assert.Equal(t, "labeled", resolve(r, testPod(creating), false))
+
+		r = resolverFor("docker", finder)
+		assert.Equal(t, "k8s_task_job_beaker_d7d1b8a0_0", resolve(r, testPod(corev1.ContainerStatus{Name: containerName}), false))
+
+		assert.Equal(t, StatusResolver{}, resolverFor("unknown", finder))
+	})
+}
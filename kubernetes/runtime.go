@@ -2,15 +2,22 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
+	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8serror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp" // Google Cloud Platform auth plugin for out of cluster authentication.
@@ -27,8 +34,33 @@ const (
 	sharedMemoryMountPath = "/dev/shm"
 )
 
+// Pods are created unbound, with a scheduler name no scheduler serves, so that
+// they're held until Container.Start binds them to the node. This matches the
+// create-then-start semantics of other runtimes without pulling an image or
+// running anything on the node until Start. Binding requires permission to
+// create the pods/binding subresource.
+const gateSchedulerName = "beaker.org/start-gate"
+
 const gpuResource = corev1.ResourceName("nvidia.com/gpu")
 
+// This annotation selects additional CNI networks for a pod. Its format is
+// defined by the Network Plumbing Working Group and implemented by Multus.
+const networksAnnotation = "k8s.v1.cni.cncf.io/networks"
+
+// These annotations limit a pod's bandwidth. They're implemented by the CNI
+// bandwidth plugin, which must be in the node's plugin chain.
+const (
+	ingressBandwidthAnnotation = "kubernetes.io/ingress-bandwidth"
+	egressBandwidthAnnotation  = "kubernetes.io/egress-bandwidth"
+)
+
+// networkSelection selects a network to attach in the networks annotation.
+type networkSelection struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips,omitempty"`
+	MAC  string   `json:"mac,omitempty"`
+}
+
 // Valid label values must be 63 characters or less and must be empty or begin
 // and end with an alphanumeric character ([a-z0-9A-Z]) with dashes (-),
 // underscores (_), dots (.), and alphanumerics between.
@@ -42,8 +74,17 @@ var labelRegex = regexp.MustCompile("^([a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-
 type Runtime struct {
 	client    *kubernetes.Clientset
 	runtime   runtime.Runtime
+	resolver  ContainerResolver
+	life      *runtime.Lifecycle
 	namespace string
 	node      string
+	onCall    func(runtime.APICall)
+
+	// Pods being removed on exit. See ContainerOpts.AutoRemove.
+	removals *runtime.Removals
+
+	// Records of removed containers, if retained.
+	history *runtime.History
 }
 
 // NewInClusterRuntime creates a new Kubernetes-backed Runtime from a process running
@@ -55,6 +96,16 @@ func NewInClusterRuntime(ctx context.Context, namespace string, node string) (*R
 	}
 	restConfig.Timeout = 60 * time.Second
 
+	r := &Runtime{
+		life:      runtime.NewLifecycle(),
+		removals:  runtime.NewRemovals(),
+		namespace: namespace,
+		node:      node,
+	}
+	restConfig.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return &apiTransport{base: rt, r: r}
+	}
+
 	client, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		return nil, fmt.Errorf("creating kubernetes client: %w", err)
@@ -71,17 +122,62 @@ func NewInClusterRuntime(ctx context.Context, namespace string, node string) (*R
 		return nil, err
 	}
 
-	return &Runtime{
-		client:    client,
-		runtime:   criRuntime,
-		namespace: namespace,
-		node:      node,
-	}, nil
+	r.client = client
+	r.runtime = criRuntime
+	r.resolver = StatusResolver{}
+	if name, err := criRuntime.RuntimeName(ctx); err != nil {
+		log.WithError(err).Warn("Container runtime is unknown; resolving containers by pod status alone")
+	} else {
+		r.resolver = resolverFor(name, criRuntime)
+	}
+	return r, nil
+}
+
+// ResolveContainersWith replaces the resolver selected for the node's container
+// runtime. It must be called before the runtime is used.
+func (r *Runtime) ResolveContainersWith(resolver ContainerResolver) {
+	r.resolver = resolver
 }
 
-// Close implements the io.Closer interface.
+// Close implements the io.Closer interface. It shuts down the runtime, waiting
+// up to runtime.ShutdownTimeout for in-flight operations.
 func (r *Runtime) Close() error {
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), runtime.ShutdownTimeout)
+	defer cancel()
+	return r.Shutdown(ctx)
+}
+
+// Shutdown implements runtime.Shutdowner. The node's underlying container
+// runtime is shut down once in-flight operations finish.
+func (r *Runtime) Shutdown(ctx context.Context) error {
+	err := r.life.Shutdown(ctx)
+
+	var closeErr error
+	if s, ok := r.runtime.(runtime.Shutdowner); ok {
+		closeErr = s.Shutdown(ctx)
+	} else {
+		closeErr = r.runtime.Close()
+	}
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Ping verifies that both the Kubernetes API server and the node's underlying
+// container runtime are healthy.
+func (r *Runtime) Ping(ctx context.Context) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	result := r.client.Discovery().RESTClient().Get().AbsPath("/healthz").Do(ctx)
+	if err := result.Error(); err != nil {
+		return fmt.Errorf("checking api server health: %w", err)
+	}
+	return r.runtime.Ping(ctx)
 }
 
 // PullImage is a no-op on Kubernetes; images are pulled implicitly on container creation.
@@ -91,14 +187,26 @@ func (r *Runtime) PullImage(
 	policy runtime.PullPolicy,
 	quiet bool,
 ) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
 	return nil
 }
 
-// CreateContainer creates a new container. The container is started implicitly.
+// CreateContainer creates a new container. Call Start to run it.
 func (r *Runtime) CreateContainer(
 	ctx context.Context,
 	opts *runtime.ContainerOpts,
 ) (runtime.Container, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
 	if opts.Interactive {
 		return nil, errors.New("interactive shells are not implemented for Kubernetes")
 	}
@@ -108,30 +216,193 @@ func (r *Runtime) CreateContainer(
 	if opts.WorkingDir != "" {
 		return nil, errors.New("working directory configuration is not implemented for Kubernetes")
 	}
+	if opts.StopSignal != "" {
+		return nil, fmt.Errorf("stop signal configuration is not implemented for Kubernetes (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.CoreDumpDir != "" {
+		// Pods can't set resource limits such as the core size.
+		return nil, fmt.Errorf("core dump collection is not implemented for Kubernetes (%w)", runtime.ErrNotImplemented)
+	}
+	if opts.CPUBurst != 0 {
+		return nil, fmt.Errorf("CPU burst is not implemented for Kubernetes (%w)", runtime.ErrNotImplemented)
+	}
+	if err := opts.IPCMode.Validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateNetwork(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateGPUCapabilities(); err != nil {
+		return nil, err
+	}
+	if opts.GPUSharing != runtime.GPUSharingDefault {
+		return nil, fmt.Errorf("GPU sharing is not supported on Kubernetes (%w)", runtime.ErrNotImplemented)
+	}
+	if err := opts.ValidateX11(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateDNS(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateSensitiveEnv(); err != nil {
+		return nil, err
+	}
+	if policy := opts.Image.PullPolicy; policy != "" {
+		if err := policy.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := opts.ValidateHooks(); err != nil {
+		return nil, err
+	}
+	if err := opts.ValidateBandwidth(); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{
+		networksAnnotation,
+		ingressBandwidthAnnotation,
+		egressBandwidthAnnotation,
+		runtime.ConfigHashLabel,
+		runtime.IdempotencyKeyLabel,
+		runtime.CreateTokenLabel,
+		runtime.AutoRemoveLabel,
+		runtime.MaxRuntimeLabel,
+	} {
+		if _, ok := opts.Labels[key]; ok {
+			return nil, fmt.Errorf("forbidden label: %s", key)
+		}
+	}
+	configHash, err := opts.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if opts.IdempotencyKey != "" {
+		existing, err := r.findByIdempotencyKey(ctx, opts.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if err := runtime.CheckIdempotent(ctx, existing, configHash); err != nil {
+				return nil, err
+			}
+			return existing, nil
+		}
+	}
+	if opts.NetworkFrom != "" || opts.PIDFrom != "" {
+		return nil, fmt.Errorf("joining another container's namespaces is not implemented for Kubernetes (%w)", runtime.ErrNotImplemented)
+	}
+	if _, ok := opts.IPCMode.Container(); ok {
+		// Containers can only share an IPC namespace within a pod.
+		return nil, fmt.Errorf("joining another container's IPC namespace is not implemented for Kubernetes (%w)", runtime.ErrNotImplemented)
+	}
+
+	if opts.Init {
+		// Kubernetes has no equivalent of Docker's injected init process.
+		opts.Warn("init processes are not supported by Kubernetes; " +
+			"use an image whose entrypoint is an init such as tini to reap zombie processes")
+	}
+
+	entrypoint, args, err := opts.ResolveCommand()
+	if err != nil {
+		return nil, err
+	}
+	ports, err := opts.ResolvePorts()
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxRuntime < 0 {
+		return nil, fmt.Errorf("invalid max runtime %s", opts.MaxRuntime)
+	}
+
+	var dependency runtime.Container
+	if s := opts.Schedule; s != nil {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		if s.After != "" {
+			if err := r.checkManaged(ctx, s.After); err != nil {
+				return nil, fmt.Errorf("scheduling start: %w", err)
+			}
+			dependency = r.container(s.After)
+		}
+	}
 
-	labels := map[string]string{nodeLabel: r.node}
-	annos := make(map[string]string, len(opts.Labels))
+	podLabels := map[string]string{nodeLabel: r.node}
+	annos := make(map[string]string, len(opts.Labels)+3)
+	annos[runtime.ConfigHashLabel] = configHash
+	token := runtime.NewCreateToken()
+	annos[runtime.CreateTokenLabel] = token
+	if opts.MaxRuntime != 0 {
+		// The deadline is applied when the container starts. See Container.Start.
+		annos[runtime.MaxRuntimeLabel] = opts.MaxRuntime.String()
+	}
+	if opts.IdempotencyKey != "" {
+		annos[runtime.IdempotencyKeyLabel] = opts.IdempotencyKey
+	}
+	if opts.AutoRemove {
+		// Removal is re-armed when pods are listed. See listEntries.
+		annos[runtime.AutoRemoveLabel] = "true"
+	}
+	if opts.IngressBandwidth != 0 {
+		annos[ingressBandwidthAnnotation] = opts.IngressBandwidth.String()
+	}
+	if opts.EgressBandwidth != 0 {
+		annos[egressBandwidthAnnotation] = opts.EgressBandwidth.String()
+	}
 	for k, v := range opts.Labels {
 		annos[k] = v
 
 		// We copy annotations to labels for convenience. Labels can be  used as
 		// query filters in kubectl while annotations can't.
 		if k != nodeLabel && labelRegex.Match([]byte(v)) {
-			labels[k] = v
+			podLabels[k] = v
+		}
+	}
+
+	if opts.Network != "" {
+		networks, err := json.Marshal([]networkSelection{selectNetwork(opts)})
+		if err != nil {
+			return nil, fmt.Errorf("encoding network selection: %w", err)
 		}
+		annos[networksAnnotation] = string(networks)
 	}
 
 	var env []corev1.EnvVar
-	for name, value := range opts.Env {
+	for name, value := range opts.ResolveEnv() {
 		env = append(env, corev1.EnvVar{
 			Name:  name,
 			Value: value,
 		})
 	}
+	if len(opts.GPUCapabilities) != 0 {
+		// The NVIDIA device plugin leaves driver capabilities to the image.
+		env = append(env, corev1.EnvVar{
+			Name:  runtime.DriverCapabilitiesEnv,
+			Value: runtime.GPUCapabilitiesEnv(opts.GPUCapabilities),
+		})
+	}
+	mounts := opts.Mounts
+	if opts.X11 != nil {
+		// The display is served by the node which runs the pod.
+		for name, value := range opts.X11.Env() {
+			env = append(env, corev1.EnvVar{Name: name, Value: value})
+		}
+		mounts = append(mounts[:len(mounts):len(mounts)], opts.X11.Mounts()...)
+	}
+
+	var containerPorts []corev1.ContainerPort
+	for _, p := range ports {
+		containerPorts = append(containerPorts, corev1.ContainerPort{
+			ContainerPort: int32(p.ContainerPort),
+			HostPort:      int32(p.HostPort),
+			HostIP:        p.HostIP,
+			Protocol:      corev1.Protocol(strings.ToUpper(p.Protocol)),
+		})
+	}
 
 	var volumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
-	for i, mount := range opts.Mounts {
+	for i, mount := range mounts {
 		name := fmt.Sprintf("volume-%d", i)
 		volumes = append(volumes, corev1.Volume{
 			Name: name,
@@ -202,11 +473,15 @@ func (r *Runtime) CreateContainer(
 		}
 	}
 
+	podName := opts.Name
+	if podName == "" && opts.IdempotencyKey != "" {
+		podName = runtime.IdempotentName(opts.IdempotencyKey)
+	}
 	podSpec := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      labels,
+			Labels:      podLabels,
 			Annotations: annos,
-			Name:        opts.Name,
+			Name:        podName,
 		},
 		Spec: corev1.PodSpec{
 			Containers: []corev1.Container{
@@ -218,55 +493,213 @@ func (r *Runtime) CreateContainer(
 					Name:  "pause",
 				},
 				{
-					Command:      opts.Command,
-					Args:         opts.Arguments,
-					Env:          env,
-					Image:        opts.Image.Tag,
-					Name:         containerName,
-					VolumeMounts: volumeMounts,
-					Resources:    corev1.ResourceRequirements{Requests: requests, Limits: limits},
+					Command:         entrypoint,
+					Args:            args,
+					Env:             env,
+					Image:           opts.Image.Tag,
+					ImagePullPolicy: podPullPolicy(opts.Image.PullPolicy),
+					Lifecycle:       podLifecycle(opts.PostStart, opts.PreStop),
+					Name:            containerName,
+					Ports:           containerPorts,
+					VolumeMounts:    volumeMounts,
+					Resources:       corev1.ResourceRequirements{Requests: requests, Limits: limits},
 				},
 			},
-			NodeName:      r.node,
+			// Containers in a pod always share an IPC namespace, so private
+			// and shareable modes are equivalent here.
+			HostIPC:       opts.IPCMode == runtime.IPCHost,
+			SchedulerName: gateSchedulerName,
 			RestartPolicy: "Never",
 			Volumes:       volumes,
+			DNSPolicy:     podDNSPolicy(opts.DNSPolicy),
+			DNSConfig:     podDNSConfig(opts.DNSConfig),
 		},
 	}
 
+	if err := checkSchedulable(ctx, r.client, r.node); err != nil {
+		return nil, err
+	}
 	pod, err := r.client.CoreV1().Pods(r.namespace).Create(ctx, podSpec, metav1.CreateOptions{})
 	if err != nil {
+		if ctx.Err() != nil && podSpec.Name != "" && opts.IdempotencyKey == "" {
+			// The API server may have created the pod after the request was
+			// canceled. It's removed only if it carries this call's token,
+			// so a pod of the same name which predates the call is left
+			// alone. Pods with idempotency keys are kept for the caller's
+			// retry to find.
+			r.cleanupPod(podSpec.Name, token)
+		}
+		if opts.IdempotencyKey != "" && k8serror.IsAlreadyExists(err) {
+			// A concurrent request with the same key may have taken the name.
+			if existing, _ := r.findByIdempotencyKey(ctx, opts.IdempotencyKey); existing != nil {
+				if err := runtime.CheckIdempotent(ctx, existing, configHash); err != nil {
+					return nil, err
+				}
+				return existing, nil
+			}
+		}
 		return nil, fmt.Errorf("creating pod: %w", err)
 	}
 
-	minAvailable := intstr.FromInt(1)
-	pdbSpec := &policyv1beta1.PodDisruptionBudget{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: pod.ObjectMeta.Name,
-		},
-		Spec: policyv1beta1.PodDisruptionBudgetSpec{
-			MinAvailable: &minAvailable,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: pod.ObjectMeta.Labels,
-			},
-		},
-	}
-
 	pdbs := r.client.PolicyV1beta1().PodDisruptionBudgets(r.namespace)
-	if _, err = pdbs.Create(ctx, pdbSpec, metav1.CreateOptions{}); err != nil {
+	if _, err = pdbs.Create(ctx, podDisruptionBudget(pod), metav1.CreateOptions{}); err != nil {
+		// A pod without a disruption budget is unprotected, so remove both.
+		r.cleanupPod(pod.Name, token)
 		return nil, fmt.Errorf("creating pod disruption budget: %w", err)
 	}
 
+	ctr := r.container(pod.Name)
+	if opts.AutoRemove {
+		// Pods are kept alive after exit by the pause container, so remove
+		// them ourselves.
+		r.removals.Arm(r.life, ctr)
+	}
+	if opts.Schedule != nil {
+		runtime.ScheduleStart(r.life.Context(), ctr, dependency, opts.Schedule)
+	}
+	return ctr, nil
+}
+
+// checkManaged returns an error unless the pod exists on the runtime's node.
+func (r *Runtime) checkManaged(ctx context.Context, name string) error {
+	pod, err := r.client.CoreV1().Pods(r.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if k8serror.IsNotFound(err) {
+			return runtime.ErrNotFound
+		}
+		return fmt.Errorf("getting pod: %w", err)
+	}
+	if pod.Labels[nodeLabel] != r.node {
+		return fmt.Errorf("container %s is not managed by this runtime", name)
+	}
+	return nil
+}
+
+// cleanupPod removes a partially created pod and its disruption budget in the
+// background. Nothing is removed unless the pod carries the given create token.
+func (r *Runtime) cleanupPod(name, token string) {
+	runtime.CleanupAsync(r.life, "pod "+name, func(ctx context.Context) error {
+		pods := r.client.CoreV1().Pods(r.namespace)
+		pod, err := pods.Get(ctx, name, metav1.GetOptions{})
+		if k8serror.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("getting pod: %w", err)
+		}
+		if pod.Annotations[runtime.CreateTokenLabel] != token {
+			return nil
+		}
+
+		// Guard against the pod being replaced since it was read.
+		var zero int64
+		err = pods.Delete(ctx, name, metav1.DeleteOptions{
+			GracePeriodSeconds: &zero,
+			Preconditions:      &metav1.Preconditions{UID: &pod.UID},
+		})
+		if err != nil && !k8serror.IsNotFound(err) {
+			return fmt.Errorf("deleting pod: %w", err)
+		}
+
+		pdbs := r.client.PolicyV1beta1().PodDisruptionBudgets(r.namespace)
+		err = pdbs.Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !k8serror.IsNotFound(err) {
+			return fmt.Errorf("deleting pod disruption budget: %w", err)
+		}
+		return nil
+	})
+}
+
+// findByIdempotencyKey returns the container on the node created with the
+// given idempotency key, or nil if there is none. Keys are stored as
+// annotations since they may not be valid label values.
+func (r *Runtime) findByIdempotencyKey(ctx context.Context, key string) (runtime.Container, error) {
+	pods, err := r.client.CoreV1().Pods(r.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", nodeLabel, r.node),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Annotations[runtime.IdempotencyKeyLabel] != key {
+			continue
+		}
+		return r.container(pod.Name), nil
+	}
+	return nil, nil
+}
+
+// RetainHistory records each container removed through the runtime in h, so
+// that it can be queried with History. It must be called before any containers
+// are created or listed.
+func (r *Runtime) RetainHistory(h *runtime.History) {
+	r.history = h
+}
+
+// History implements runtime.Historian. It fails unless RetainHistory was
+// called.
+func (r *Runtime) History(ctx context.Context, filter runtime.HistoryFilter) ([]runtime.HistoryRecord, error) {
+	_, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	if r.history == nil {
+		return nil, errors.New("container history is not retained")
+	}
+	return r.history.Query(filter), nil
+}
+
+// container creates an interface to an existing pod's task container.
+func (r *Runtime) container(podName string) *Container {
 	return &Container{
 		client:        r.client,
 		runtime:       r.runtime,
+		resolver:      r.resolver,
+		life:          r.life,
+		history:       r.history,
 		namespace:     r.namespace,
-		podName:       pod.Name,
+		podName:       podName,
 		containerName: containerName,
-	}, nil
+	}
 }
 
-// ListContainers enumerates all containers.
+// ListContainers enumerates all containers by creation time, then name.
 func (r *Runtime) ListContainers(ctx context.Context) ([]runtime.Container, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	entries, err := r.listEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.SortedContainers(entries), nil
+}
+
+// ListContainersPage implements runtime.Pager.
+func (r *Runtime) ListContainersPage(ctx context.Context, opts runtime.ListOpts) (*runtime.ContainerPage, error) {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+
+	entries, err := r.listEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return runtime.PaginateContainers(entries, opts)
+}
+
+// listEntries lists the node's pods with their creation times. Kubernetes
+// reports creation times to the second, so ties are ordered by name. Pods
+// created with AutoRemove have their removal re-armed, so that a new runtime
+// removes them after its process restarts.
+func (r *Runtime) listEntries(ctx context.Context) ([]runtime.ListEntry, error) {
 	pods, err := r.client.CoreV1().Pods(r.namespace).List(ctx, metav1.ListOptions{
 		LabelSelector: fmt.Sprintf("%s=%s", nodeLabel, r.node),
 	})
@@ -274,15 +707,223 @@ func (r *Runtime) ListContainers(ctx context.Context) ([]runtime.Container, erro
 		return nil, fmt.Errorf("listing pods: %w", err)
 	}
 
-	var containers []runtime.Container
-	for _, pod := range pods.Items {
-		containers = append(containers, &Container{
-			client:        r.client,
-			runtime:       r.runtime,
-			namespace:     r.namespace,
-			podName:       pod.Name,
-			containerName: containerName,
-		})
+	entries := make([]runtime.ListEntry, len(pods.Items))
+	for i, pod := range pods.Items {
+		ctr := r.container(pod.Name)
+		if pod.Annotations[runtime.AutoRemoveLabel] != "" && pod.DeletionTimestamp == nil {
+			r.removals.Arm(r.life, ctr)
+		}
+		entries[i] = runtime.ListEntry{Container: ctr, CreatedAt: pod.CreationTimestamp.Time}
+	}
+	return entries, nil
+}
+
+// RemoveContainers removes all pods on the node whose labels match the given
+// set in a single API call, rather than one call per pod. Pods are deleted in
+// the foreground after the grace period elapses, or immediately if nil. Their
+// disruption budgets are garbage collected with them. If history is retained,
+// the pods are recorded in it.
+//
+// Only labels which are valid Kubernetes label values are copied to pods, so
+// other labels can't be used for selection.
+func (r *Runtime) RemoveContainers(
+	ctx context.Context,
+	selector map[string]string,
+	gracePeriod *time.Duration,
+) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	set := make(labels.Set, len(selector)+1)
+	for k, v := range selector {
+		set[k] = v
+	}
+	set[nodeLabel] = r.node
+
+	var seconds int64
+	if gracePeriod != nil {
+		seconds = int64(gracePeriod.Seconds())
+	}
+	propagation := metav1.DeletePropagationForeground
+	deleteOpts := metav1.DeleteOptions{GracePeriodSeconds: &seconds, PropagationPolicy: &propagation}
+	listOpts := metav1.ListOptions{LabelSelector: labels.SelectorFromSet(set).String()}
+
+	// Records are captured before the pods are deleted, so a pod created in
+	// between is removed without one.
+	var records []*runtime.HistoryRecord
+	if r.history != nil {
+		pods, err := r.client.CoreV1().Pods(r.namespace).List(ctx, listOpts)
+		if err != nil {
+			return fmt.Errorf("listing pods: %w", err)
+		}
+		for _, pod := range pods.Items {
+			records = append(records, runtime.CaptureRecord(ctx, r.container(pod.Name)))
+		}
+	}
+
+	// Each pod owns its disruption budget, which is garbage collected with it.
+	if err := r.client.CoreV1().Pods(r.namespace).DeleteCollection(ctx, deleteOpts, listOpts); err != nil {
+		return fmt.Errorf("deleting pods: %w", err)
+	}
+	for _, record := range records {
+		r.history.Add(record)
+	}
+	return nil
+}
+
+// SetSchedulable cordons or uncordons the node the runtime is scoped to. The
+// runtime binds pods to the node itself rather than through the scheduler,
+// which is what respects cordons, so it checks the node instead: containers
+// can't be created or started on an unschedulable node. Pods already running
+// on it are unaffected. Use RemoveContainers to drain them.
+func (r *Runtime) SetSchedulable(ctx context.Context, schedulable bool) error {
+	ctx, end, err := r.life.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"unschedulable": !schedulable},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.CoreV1().Nodes().Patch(ctx, r.node, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("patching node %s: %w", r.node, err)
+	}
+	return nil
+}
+
+// checkSchedulable returns an error if a node is cordoned. See SetSchedulable.
+func checkSchedulable(ctx context.Context, client *kubernetes.Clientset, name string) error {
+	node, err := client.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting node %s: %w", name, err)
+	}
+	if node.Spec.Unschedulable {
+		return fmt.Errorf("node %s is unschedulable", name)
+	}
+	return nil
+}
+
+// podDisruptionBudget protects a pod from voluntary disruption, e.g. draining.
+// The budget is owned by the pod, so it's garbage collected once the pod is
+// deleted even if deleting it by name fails.
+func podDisruptionBudget(pod *corev1.Pod) *policyv1beta1.PodDisruptionBudget {
+	minAvailable := intstr.FromInt(1)
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: pod.Labels,
+			Name:   pod.Name,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       pod.Name,
+				UID:        pod.UID,
+			}},
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: pod.Labels,
+			},
+		},
+	}
+}
+
+// selectNetwork selects a container's network. Static addresses are passed
+// through as given, including any prefix length, which static IPAM requires.
+func selectNetwork(opts *runtime.ContainerOpts) networkSelection {
+	selection := networkSelection{Name: opts.Network, MAC: opts.MACAddress}
+	if opts.IPAddress != "" {
+		selection.IPs = append(selection.IPs, opts.IPAddress)
+	}
+	if opts.IPv6Address != "" {
+		selection.IPs = append(selection.IPs, opts.IPv6Address)
+	}
+	return selection
+}
+
+// podDNSPolicy translates a DNS policy to a pod's. Kubernetes' confusingly
+// named "Default" policy inherits the node's configuration.
+func podDNSPolicy(policy runtime.DNSPolicy) corev1.DNSPolicy {
+	switch policy {
+	case runtime.DNSHost:
+		return corev1.DNSDefault
+	case runtime.DNSNone:
+		return corev1.DNSNone
+	default:
+		return corev1.DNSClusterFirst
+	}
+}
+
+// podPullPolicy translates an image pull policy to a container's. An empty
+// policy leaves Kubernetes' default, which pulls only images tagged "latest"
+// or untagged.
+func podPullPolicy(policy runtime.PullPolicy) corev1.PullPolicy {
+	switch policy {
+	case runtime.PullAlways:
+		return corev1.PullAlways
+	case runtime.PullIfMissing:
+		return corev1.PullIfNotPresent
+	case runtime.PullNever:
+		return corev1.PullNever
+	default:
+		return ""
+	}
+}
+
+// podLifecycle translates hooks to a container's lifecycle handlers, or nil if
+// there are none. Kubernetes doesn't bound exec handlers, so hook timeouts are
+// dropped.
+func podLifecycle(postStart, preStop *runtime.Hook) *corev1.Lifecycle {
+	if postStart == nil && preStop == nil {
+		return nil
+	}
+	handler := func(hook *runtime.Hook) *corev1.Handler {
+		if hook == nil {
+			return nil
+		}
+		return &corev1.Handler{Exec: &corev1.ExecAction{Command: hook.Command}}
+	}
+	return &corev1.Lifecycle{PostStart: handler(postStart), PreStop: handler(preStop)}
+}
+
+// lifecycleHooks translates a container's exec lifecycle handlers to hooks.
+// Other handlers are omitted.
+func lifecycleHooks(lifecycle *corev1.Lifecycle) (postStart, preStop *runtime.Hook) {
+	if lifecycle == nil {
+		return nil, nil
+	}
+	hook := func(handler *corev1.Handler) *runtime.Hook {
+		if handler == nil || handler.Exec == nil {
+			return nil
+		}
+		return &runtime.Hook{Command: handler.Exec.Command}
+	}
+	return hook(lifecycle.PostStart), hook(lifecycle.PreStop)
+}
+
+// podDNSConfig translates DNS settings to a pod's, which Kubernetes merges
+// with those of the pod's policy.
+func podDNSConfig(c *runtime.DNSConfig) *corev1.PodDNSConfig {
+	if c == nil {
+		return nil
+	}
+	config := &corev1.PodDNSConfig{Nameservers: c.Nameservers, Searches: c.Searches}
+	for _, option := range c.Options {
+		name, value, ok := runtime.ParseDNSOption(option)
+		podOption := corev1.PodDNSConfigOption{Name: name}
+		if ok {
+			podOption.Value = &value
+		}
+		config.Options = append(config.Options, podOption)
 	}
-	return containers, nil
+	return config
 }
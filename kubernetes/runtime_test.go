@@ -2,13 +2,19 @@ package kubernetes
 
 import (
 	"context"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/beaker/runtime"
 	"github.com/beaker/runtime/internal/test"
 )
 
@@ -34,6 +40,102 @@ func TestLabelRegex(t *testing.T) {
 	}
 }
 
+func TestAPIOperation(t *testing.T) {
+	tests := map[string]string{
+		"GET /api/v1/namespaces/beaker/pods":                                  "list pods",
+		"GET /api/v1/namespaces/beaker/pods?watch=true":                       "watch pods",
+		"GET /api/v1/namespaces/beaker/pods/task-1":                           "get pods",
+		"GET /api/v1/namespaces/beaker/pods/task-1/log":                       "get pods/log",
+		"POST /api/v1/namespaces/beaker/pods":                                 "create pods",
+		"POST /api/v1/namespaces/beaker/pods/task-1/eviction":                 "create pods/eviction",
+		"DELETE /api/v1/namespaces/beaker/pods/task-1":                        "delete pods",
+		"DELETE /api/v1/namespaces/beaker/pods":                               "deletecollection pods",
+		"GET /api/v1/namespaces/beaker":                                       "get namespaces",
+		"PATCH /apis/policy/v1beta1/namespaces/beaker/poddisruptionbudgets/a": "patch poddisruptionbudgets",
+		"GET /api/v1/nodes/node-1":                                            "get nodes",
+		"GET /version":                                                        "GET /version",
+	}
+	for request, expected := range tests {
+		parts := strings.SplitN(request, " ", 2)
+		req, err := http.NewRequest(parts[0], "https://apiserver"+parts[1], nil)
+		require.NoError(t, err)
+		assert.Equal(t, expected, apiOperation(req), request)
+	}
+
+	assert.Equal(t, runtime.APIErrorNotFound, classifyStatus(http.StatusNotFound))
+	assert.Equal(t, runtime.APIErrorUnavailable, classifyStatus(http.StatusTooManyRequests))
+	assert.Equal(t, runtime.APIErrorOther, classifyStatus(http.StatusForbidden))
+}
+
+func TestPodDisruptionBudget(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:   "task",
+		UID:    "1234",
+		Labels: map[string]string{nodeLabel: "node"},
+	}}
+	pdb := podDisruptionBudget(pod)
+	assert.Equal(t, "task", pdb.Name)
+	assert.Equal(t, []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "task", UID: "1234"}}, pdb.OwnerReferences)
+	assert.Equal(t, pod.Labels, pdb.Spec.Selector.MatchLabels)
+	assert.Equal(t, 1, pdb.Spec.MinAvailable.IntValue())
+}
+
+func TestSelectNetwork(t *testing.T) {
+	opts := &runtime.ContainerOpts{
+		Network:     "macvlan",
+		IPAddress:   "10.1.2.3/24",
+		IPv6Address: "fd00::3/64",
+		MACAddress:  "02:42:ac:11:00:02",
+	}
+	require.NoError(t, opts.ValidateNetwork())
+	assert.Equal(t, networkSelection{
+		Name: "macvlan",
+		IPs:  []string{"10.1.2.3/24", "fd00::3/64"},
+		MAC:  "02:42:ac:11:00:02",
+	}, selectNetwork(opts))
+
+	assert.Equal(t, networkSelection{Name: "macvlan"}, selectNetwork(&runtime.ContainerOpts{Network: "macvlan"}))
+}
+
+func TestPodDNS(t *testing.T) {
+	assert.Equal(t, corev1.DNSClusterFirst, podDNSPolicy(runtime.DNSDefault))
+	assert.Equal(t, corev1.DNSDefault, podDNSPolicy(runtime.DNSHost))
+	assert.Equal(t, corev1.DNSNone, podDNSPolicy(runtime.DNSNone))
+
+	assert.Nil(t, podDNSConfig(nil))
+	one := "1"
+	assert.Equal(t, &corev1.PodDNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Searches:    []string{"cluster.local"},
+		Options:     []corev1.PodDNSConfigOption{{Name: "ndots", Value: &one}, {Name: "edns0"}},
+	}, podDNSConfig(&runtime.DNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Searches:    []string{"cluster.local"},
+		Options:     []string{"ndots:1", "edns0"},
+	}))
+}
+
+func TestPodPullPolicy(t *testing.T) {
+	assert.Equal(t, corev1.PullPolicy(""), podPullPolicy(""))
+	assert.Equal(t, corev1.PullAlways, podPullPolicy(runtime.PullAlways))
+	assert.Equal(t, corev1.PullIfNotPresent, podPullPolicy(runtime.PullIfMissing))
+	assert.Equal(t, corev1.PullNever, podPullPolicy(runtime.PullNever))
+}
+
+func TestPodLifecycle(t *testing.T) {
+	assert.Nil(t, podLifecycle(nil, nil))
+
+	preStop := &runtime.Hook{Command: []string{"drain"}}
+	lifecycle := podLifecycle(nil, &runtime.Hook{Command: []string{"drain"}, Timeout: time.Minute})
+	assert.Equal(t, &corev1.Lifecycle{
+		PreStop: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"drain"}}},
+	}, lifecycle)
+
+	postStart, decoded := lifecycleHooks(lifecycle)
+	assert.Nil(t, postStart)
+	assert.Equal(t, preStop, decoded)
+}
+
 const testKubernetesKey = "TEST_KUBERNETES"
 
 func TestKubernetes(t *testing.T) {
@@ -48,5 +150,5 @@ func TestKubernetes(t *testing.T) {
 	rt, err := NewInClusterRuntime(context.Background(), "beaker-test", node)
 	require.NoError(t, err)
 
-	suite.Run(t, test.NewRuntimeSuite(rt))
+	suite.Run(t, test.NewRuntimeSuite(rt).ReportTo("kubernetes", os.Getenv(test.ReportEnv)))
 }
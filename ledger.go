@@ -0,0 +1,193 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Resources is a quantity of node resources which can be committed to
+// containers.
+type Resources struct {
+	CPUCount float64
+	Memory   int64 // In bytes
+	GPUs     []string
+
+	// GPUSharing is how the GPUs are shared with other containers. GPUs shared
+	// through MPS may be reserved by any number of containers which also share
+	// them through MPS.
+	GPUSharing GPUSharing
+}
+
+// Resources returns the resources a container created with the options
+// commits. Containers without limits commit nothing.
+func (o *ContainerOpts) Resources() Resources {
+	return Resources{CPUCount: o.CPUCount, Memory: o.Memory, GPUs: o.GPUs, GPUSharing: o.GPUSharing}
+}
+
+// Ledger tracks the resources committed to containers on a node and rejects
+// reservations which would oversubscribe it. GPUs are reserved exclusively
+// unless shared through MPS.
+//
+// Runtimes don't consult a ledger. Callers which admit containers to a node
+// reserve their resources before creating them and release them once they're
+// removed. Reservations are identified by a caller-chosen key, typically the
+// container's name. A Ledger is safe for concurrent use.
+type Ledger struct {
+	capacity Resources
+
+	// inventory identifies GPUs by UUID, if set.
+	inventory GPUInventory
+
+	mu           sync.Mutex
+	reservations map[string]Resources
+}
+
+// NewLedger creates a ledger for a node with the given capacity. A zero CPU
+// count or memory is unlimited. If GPUs are omitted, any GPU may be reserved
+// but each by only one container at a time.
+func NewLedger(capacity Resources) *Ledger {
+	return &Ledger{capacity: capacity, reservations: make(map[string]Resources)}
+}
+
+// NewLedgerWithInventory creates a ledger for a node whose GPUs are listed in
+// an inventory. GPUs may then be given by index or UUID, e.g. "0" or
+// "GPU-0a5c0cf4-...", and are reported by UUID. The capacity's GPUs default to
+// the whole inventory.
+func NewLedgerWithInventory(capacity Resources, inv GPUInventory) (*Ledger, error) {
+	if capacity.GPUs == nil {
+		for _, uuid := range inv {
+			capacity.GPUs = append(capacity.GPUs, uuid)
+		}
+		sort.Strings(capacity.GPUs)
+	}
+	gpus, err := inv.Normalize(capacity.GPUs)
+	if err != nil {
+		return nil, err
+	}
+	capacity.GPUs = gpus
+
+	l := NewLedger(capacity)
+	l.inventory = inv
+	return l, nil
+}
+
+// Reserve commits resources under a key, replacing any reservation the key
+// already holds. If the node can't accommodate the reservation, an error
+// wrapping ErrOversubscribed is returned and the ledger is unchanged.
+func (l *Ledger) Reserve(key string, r Resources) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inventory != nil {
+		gpus, err := l.inventory.Normalize(r.GPUs)
+		if err != nil {
+			return fmt.Errorf("%v (%w)", err, ErrOversubscribed)
+		}
+		r.GPUs = gpus
+	}
+
+	type assignment struct {
+		owner  string
+		shared bool
+	}
+	var cpus float64
+	var memory int64
+	assigned := make(map[string]assignment)
+	for k, res := range l.reservations {
+		if k == key {
+			continue
+		}
+		cpus += res.CPUCount
+		memory += res.Memory
+		for _, gpu := range res.GPUs {
+			if a, ok := assigned[gpu]; !ok || a.shared {
+				assigned[gpu] = assignment{owner: k, shared: res.GPUSharing == GPUSharingMPS}
+			}
+		}
+	}
+
+	if l.capacity.CPUCount != 0 && cpus+r.CPUCount > l.capacity.CPUCount {
+		return fmt.Errorf("requested %g CPUs with %g of %g available (%w)",
+			r.CPUCount, l.capacity.CPUCount-cpus, l.capacity.CPUCount, ErrOversubscribed)
+	}
+	if l.capacity.Memory != 0 && memory+r.Memory > l.capacity.Memory {
+		return fmt.Errorf("requested %d bytes of memory with %d of %d available (%w)",
+			r.Memory, l.capacity.Memory-memory, l.capacity.Memory, ErrOversubscribed)
+	}
+
+	requested := make(map[string]bool, len(r.GPUs))
+	for _, gpu := range r.GPUs {
+		if requested[gpu] {
+			return fmt.Errorf("GPU %s requested more than once", gpu)
+		}
+		requested[gpu] = true
+
+		if l.capacity.GPUs != nil && !contains(l.capacity.GPUs, gpu) {
+			return fmt.Errorf("GPU %s doesn't exist (%w)", gpu, ErrOversubscribed)
+		}
+		if a, ok := assigned[gpu]; ok && !(a.shared && r.GPUSharing == GPUSharingMPS) {
+			return fmt.Errorf("GPU %s is reserved by %s (%w)", gpu, a.owner, ErrOversubscribed)
+		}
+	}
+
+	r.GPUs = append([]string(nil), r.GPUs...)
+	l.reservations[key] = r
+	return nil
+}
+
+// Release frees the resources reserved under a key, if any.
+func (l *Ledger) Release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.reservations, key)
+}
+
+// Reserved returns the total resources committed. GPUs are sorted, and those
+// shared by several reservations are listed once.
+func (l *Ledger) Reserved() Resources {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var total Resources
+	for _, r := range l.reservations {
+		total.CPUCount += r.CPUCount
+		total.Memory += r.Memory
+		for _, gpu := range r.GPUs {
+			if !contains(total.GPUs, gpu) {
+				total.GPUs = append(total.GPUs, gpu)
+			}
+		}
+	}
+	sort.Strings(total.GPUs)
+	return total
+}
+
+// Available returns the uncommitted capacity. Unlimited CPU and memory are
+// reported as zero, and GPUs are omitted if the capacity didn't list them.
+func (l *Ledger) Available() Resources {
+	reserved := l.Reserved()
+
+	var available Resources
+	if l.capacity.CPUCount != 0 {
+		available.CPUCount = l.capacity.CPUCount - reserved.CPUCount
+	}
+	if l.capacity.Memory != 0 {
+		available.Memory = l.capacity.Memory - reserved.Memory
+	}
+	for _, gpu := range l.capacity.GPUs {
+		if !contains(reserved.GPUs, gpu) {
+			available.GPUs = append(available.GPUs, gpu)
+		}
+	}
+	return available
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
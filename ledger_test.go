@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger(t *testing.T) {
+	l := NewLedger(Resources{CPUCount: 8, Memory: 1 << 30, GPUs: []string{"0", "1"}})
+
+	require.NoError(t, l.Reserve("a", Resources{CPUCount: 4, Memory: 1 << 29, GPUs: []string{"0"}}))
+	require.NoError(t, l.Reserve("b", Resources{CPUCount: 2}))
+	assert.Equal(t, Resources{CPUCount: 2, Memory: 1 << 29, GPUs: []string{"1"}}, l.Available())
+
+	tests := map[string]Resources{
+		"CPU":        {CPUCount: 3},
+		"Memory":     {Memory: 1<<29 + 1},
+		"GPUTaken":   {GPUs: []string{"0"}},
+		"GPUMissing": {GPUs: []string{"2"}},
+	}
+	for name, r := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, l.Reserve("c", r), ErrOversubscribed)
+		})
+	}
+	assert.Error(t, l.Reserve("c", Resources{GPUs: []string{"1", "1"}}))
+
+	// Replacing a reservation doesn't count its previous resources.
+	require.NoError(t, l.Reserve("a", Resources{CPUCount: 6, GPUs: []string{"0"}}))
+	assert.Equal(t, Resources{CPUCount: 8, GPUs: []string{"0"}}, l.Reserved())
+
+	l.Release("a")
+	l.Release("b")
+	assert.Equal(t, Resources{CPUCount: 8, Memory: 1 << 30, GPUs: []string{"0", "1"}}, l.Available())
+}
+
+func TestLedgerInventory(t *testing.T) {
+	inv := GPUInventory{"0": "GPU-a", "1": "GPU-b"}
+	l, err := NewLedgerWithInventory(Resources{}, inv)
+	require.NoError(t, err)
+
+	// GPUs are the same whether given by index or UUID.
+	require.NoError(t, l.Reserve("a", Resources{GPUs: []string{"0"}}))
+	assert.ErrorIs(t, l.Reserve("b", Resources{GPUs: []string{"GPU-a"}}), ErrOversubscribed)
+	assert.ErrorIs(t, l.Reserve("b", Resources{GPUs: []string{"2"}}), ErrOversubscribed)
+	assert.Equal(t, Resources{GPUs: []string{"GPU-b"}}, l.Available())
+
+	_, err = NewLedgerWithInventory(Resources{GPUs: []string{"2"}}, inv)
+	assert.Error(t, err)
+}
+
+func TestLedgerMPS(t *testing.T) {
+	l := NewLedger(Resources{GPUs: []string{"GPU-a", "GPU-b"}})
+	mps := Resources{GPUs: []string{"GPU-a"}, GPUSharing: GPUSharingMPS}
+
+	// Containers sharing a GPU through MPS may reserve it together, but not
+	// alongside one which uses it exclusively.
+	require.NoError(t, l.Reserve("a", mps))
+	require.NoError(t, l.Reserve("b", mps))
+	assert.ErrorIs(t, l.Reserve("c", Resources{GPUs: []string{"GPU-a"}}), ErrOversubscribed)
+	assert.Equal(t, Resources{GPUs: []string{"GPU-a"}}, l.Reserved())
+
+	require.NoError(t, l.Reserve("c", Resources{GPUs: []string{"GPU-b"}}))
+	assert.ErrorIs(t, l.Reserve("d", Resources{GPUs: []string{"GPU-b"}, GPUSharing: GPUSharingMPS}), ErrOversubscribed)
+}
+
+func TestLedgerUnlimited(t *testing.T) {
+	l := NewLedger(Resources{})
+	require.NoError(t, l.Reserve("a", (&ContainerOpts{CPUCount: 64, GPUs: []string{"GPU-1"}}).Resources()))
+	assert.ErrorIs(t, l.Reserve("b", Resources{GPUs: []string{"GPU-1"}}), ErrOversubscribed)
+	assert.Equal(t, Resources{}, l.Available())
+}
@@ -0,0 +1,145 @@
+package runtime
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pager is implemented by runtimes which can list containers a page at a time.
+type Pager interface {
+	ListContainersPage(ctx context.Context, opts ListOpts) (*ContainerPage, error)
+}
+
+// ListOpts selects a page of containers.
+type ListOpts struct {
+	// (optional) Limit caps the number of containers in the page. Zero means no
+	// limit.
+	Limit int
+
+	// (optional) Cursor resumes listing after the last container of a previous
+	// page. See ContainerPage.Next.
+	Cursor string
+}
+
+// ContainerPage is one page of a container listing.
+type ContainerPage struct {
+	Containers []Container
+
+	// Next is the cursor of the following page, or empty if this is the last.
+	// Cursors remain valid as containers are created and removed: a page
+	// continues after the last container of the previous one, even if that
+	// container has since been removed.
+	Next string
+}
+
+// ListEntry is a container with the time it was created, which runtimes read
+// when listing to order containers without inspecting each.
+type ListEntry struct {
+	Container Container
+	CreatedAt time.Time
+}
+
+// SortListEntries orders containers by creation time, then name.
+func SortListEntries(entries []ListEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entryBefore(entries[i].CreatedAt, entries[i].Container.Name(),
+			entries[j].CreatedAt, entries[j].Container.Name())
+	})
+}
+
+func entryBefore(t1 time.Time, name1 string, t2 time.Time, name2 string) bool {
+	if !t1.Equal(t2) {
+		return t1.Before(t2)
+	}
+	return name1 < name2
+}
+
+// SortedContainers sorts entries and returns their containers.
+func SortedContainers(entries []ListEntry) []Container {
+	SortListEntries(entries)
+	containers := make([]Container, len(entries))
+	for i, e := range entries {
+		containers[i] = e.Container
+	}
+	return containers
+}
+
+// PaginateContainers sorts entries and selects the page described by opts.
+func PaginateContainers(entries []ListEntry, opts ListOpts) (*ContainerPage, error) {
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d", opts.Limit)
+	}
+	SortListEntries(entries)
+
+	start := 0
+	if opts.Cursor != "" {
+		after, name, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		start = sort.Search(len(entries), func(i int) bool {
+			return entryBefore(after, name, entries[i].CreatedAt, entries[i].Container.Name())
+		})
+	}
+	entries = entries[start:]
+
+	page := &ContainerPage{}
+	if opts.Limit != 0 && len(entries) > opts.Limit {
+		entries = entries[:opts.Limit]
+		last := entries[len(entries)-1]
+		page.Next = encodeCursor(last.CreatedAt, last.Container.Name())
+	}
+	page.Containers = make([]Container, len(entries))
+	for i, e := range entries {
+		page.Containers[i] = e.Container
+	}
+	return page, nil
+}
+
+// Cursors encode the creation time and name of the last container of a page.
+// They're opaque to callers.
+func encodeCursor(createdAt time.Time, name string) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + "/" + name
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	invalid := errors.New("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", invalid
+	}
+	parts := strings.SplitN(string(raw), "/", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", invalid
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", invalid
+	}
+	return time.Unix(0, nanos), parts[1], nil
+}
+
+// SortContainers orders containers by creation time, then name, reading each
+// container's creation time from its details. Containers removed while being
+// sorted are dropped. Runtimes sort their own listings; this is for
+// combinations of them.
+func SortContainers(ctx context.Context, containers []Container) ([]Container, error) {
+	entries := make([]ListEntry, 0, len(containers))
+	for _, c := range containers {
+		info, err := c.Info(ctx)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		entries = append(entries, ListEntry{Container: c, CreatedAt: info.CreatedAt})
+	}
+	return SortedContainers(entries), nil
+}
@@ -0,0 +1,69 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func names(containers []Container) []string {
+	result := make([]string, len(containers))
+	for i, c := range containers {
+		result[i] = c.Name()
+	}
+	return result
+}
+
+func listEntries(specs ...interface{}) []ListEntry {
+	var entries []ListEntry
+	for i := 0; i < len(specs); i += 2 {
+		entries = append(entries, ListEntry{
+			Container: &memoryContainer{name: specs[i].(string)},
+			CreatedAt: specs[i+1].(time.Time),
+		})
+	}
+	return entries
+}
+
+func TestSortedContainers(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	entries := listEntries("c", t0.Add(time.Second), "b", t0, "a", t0.Add(time.Second), "d", t0)
+	assert.Equal(t, []string{"b", "d", "a", "c"}, names(SortedContainers(entries)))
+}
+
+func TestPaginateContainers(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	all := func() []ListEntry {
+		return listEntries("a", t0, "b", t0, "c", t0.Add(time.Second), "d", t0.Add(2*time.Second), "e", t0.Add(3*time.Second))
+	}
+
+	page, err := PaginateContainers(all(), ListOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names(page.Containers))
+	assert.Empty(t, page.Next)
+
+	page, err = PaginateContainers(all(), ListOpts{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names(page.Containers))
+	require.NotEmpty(t, page.Next)
+
+	// Removing the last listed container and adding a new one doesn't disturb
+	// the next page.
+	changed := append(all()[:1], all()[2:]...)
+	changed = append(changed, listEntries("f", t0.Add(4*time.Second))...)
+	page, err = PaginateContainers(changed, ListOpts{Limit: 2, Cursor: page.Next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d"}, names(page.Containers))
+
+	page, err = PaginateContainers(changed, ListOpts{Limit: 2, Cursor: page.Next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e", "f"}, names(page.Containers))
+	assert.Empty(t, page.Next)
+
+	_, err = PaginateContainers(all(), ListOpts{Cursor: "not a cursor"})
+	assert.Error(t, err)
+	_, err = PaginateContainers(all(), ListOpts{Limit: -1})
+	assert.Error(t, err)
+}
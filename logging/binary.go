@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TextEncoding identifies how a message's text is encoded.
+type TextEncoding string
+
+// TextEncoding definitions
+const (
+	// TextPlain is text as the container wrote it, possibly escaped.
+	TextPlain TextEncoding = ""
+
+	// TextBase64 is the container's output encoded as standard base64.
+	TextBase64 TextEncoding = "base64"
+)
+
+// BinaryMode controls how log readers present text which isn't valid UTF-8 or
+// contains NUL bytes. Such text breaks many JSON consumers.
+type BinaryMode int
+
+// BinaryMode definitions
+const (
+	// BinaryRaw passes text through unmodified.
+	BinaryRaw BinaryMode = iota
+
+	// BinaryEscape replaces invalid UTF-8 and NUL bytes with escape sequences
+	// of the form \xNN. This is lossy since backslashes are not escaped.
+	BinaryEscape
+
+	// BinaryBase64 encodes binary text as standard base64, marking the message
+	// with TextBase64. Valid text is passed through unmodified.
+	BinaryBase64
+)
+
+// IsBinary returns true if text isn't valid UTF-8 or contains NUL bytes.
+func IsBinary(text string) bool {
+	return !utf8.ValidString(text) || strings.IndexByte(text, 0) >= 0
+}
+
+// Sanitize presents text according to the mode, returning it along with its
+// encoding.
+func (m BinaryMode) Sanitize(text string) (string, TextEncoding) {
+	if m == BinaryRaw || !IsBinary(text) {
+		return text, TextPlain
+	}
+
+	switch m {
+	case BinaryEscape:
+		return escapeBinary(text), TextPlain
+	case BinaryBase64:
+		return base64.StdEncoding.EncodeToString([]byte(text)), TextBase64
+	default:
+		return text, TextPlain
+	}
+}
+
+func escapeBinary(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == 0 || (r == utf8.RuneError && size == 1) {
+			fmt.Fprintf(&b, `\x%02x`, text[i])
+		} else {
+			b.WriteString(text[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
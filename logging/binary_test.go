@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryMode(t *testing.T) {
+	tests := map[string]struct {
+		mode     BinaryMode
+		text     string
+		expected string
+		encoding TextEncoding
+	}{
+		"RawText":      {BinaryRaw, "héllo\n", "héllo\n", TextPlain},
+		"RawBinary":    {BinaryRaw, "a\x00b\xff", "a\x00b\xff", TextPlain},
+		"EscapeText":   {BinaryEscape, "héllo\n", "héllo\n", TextPlain},
+		"EscapeBinary": {BinaryEscape, "a\x00b\xffé\n", `a\x00b\xff` + "é\n", TextPlain},
+		"EscapeSplit":  {BinaryEscape, "é"[:1], `\xc3`, TextPlain},
+		"Base64Text":   {BinaryBase64, "héllo\n", "héllo\n", TextPlain},
+		"Base64Binary": {BinaryBase64, "a\x00b\xff\n", "YQBi/wo=", TextBase64},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			text, encoding := tt.mode.Sanitize(tt.text)
+			assert.Equal(t, tt.expected, text)
+			assert.Equal(t, tt.encoding, encoding)
+		})
+	}
+}
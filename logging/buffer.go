@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Backpressure determines what a BufferedReader does when its buffer is full.
+type Backpressure string
+
+const (
+	// BackpressureBlock stops reading from the source until the consumer
+	// catches up. Nothing is lost, but a source which can't wait, such as a
+	// followed log, may buffer on its own side instead.
+	BackpressureBlock Backpressure = "block"
+
+	// BackpressureDropOldest discards the oldest buffered messages to make
+	// room. Each run of dropped messages is replaced with a single marker on
+	// stderr noting how many were dropped, e.g.
+	// "[12 messages dropped by backpressure]".
+	BackpressureDropOldest Backpressure = "drop-oldest"
+)
+
+// defaultBufferMessages is the buffer size used if none is given.
+const defaultBufferMessages = 1024
+
+// BufferOpts bounds a BufferedReader's buffer.
+type BufferOpts struct {
+	// (optional) Messages is the maximum number of buffered messages. Defaults
+	// to 1024.
+	Messages int
+
+	// (optional) Bytes is the maximum total length of buffered text. A single
+	// message longer than the limit is buffered alone. Zero is unlimited.
+	Bytes int
+
+	// (optional) Backpressure is applied when the buffer is full. Defaults to
+	// BackpressureBlock.
+	Backpressure Backpressure
+}
+
+// Validate checks that the options are well formed.
+func (o BufferOpts) Validate() error {
+	if o.Messages < 0 {
+		return fmt.Errorf("invalid buffer size %d", o.Messages)
+	}
+	if o.Bytes < 0 {
+		return fmt.Errorf("invalid buffer byte limit %d", o.Bytes)
+	}
+	switch o.Backpressure {
+	case "", BackpressureBlock, BackpressureDropOldest:
+		return nil
+	default:
+		return fmt.Errorf("invalid backpressure policy %q", o.Backpressure)
+	}
+}
+
+// BufferedReader reads ahead of its consumer into a bounded buffer, so that a
+// bursty source such as a followed log is drained promptly without buffering
+// without limit. What happens when the buffer fills is set by its Backpressure
+// policy.
+type BufferedReader struct {
+	r    LogReader
+	opts BufferOpts
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*Message
+	bytes   int
+	dropped int       // Dropped since the last marker
+	last    time.Time // Time of the most recently dropped message
+	total   int
+	err     error
+	closed  bool
+}
+
+// NewBufferedReader wraps a log reader with a buffer and starts reading from
+// it in the background. The options must be valid.
+func NewBufferedReader(r LogReader, opts BufferOpts) *BufferedReader {
+	if opts.Messages == 0 {
+		opts.Messages = defaultBufferMessages
+	}
+	if opts.Backpressure == "" {
+		opts.Backpressure = BackpressureBlock
+	}
+	b := &BufferedReader{r: r, opts: opts}
+	b.cond = sync.NewCond(&b.mu)
+	go b.fill()
+	return b
+}
+
+// Close implements the io.Closer interface. It closes the source, which
+// should interrupt any read in progress.
+func (b *BufferedReader) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+	return b.r.Close()
+}
+
+// Dropped returns the number of messages dropped so far.
+func (b *BufferedReader) Dropped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
+// ReadMessage implements the LogReader interface. Once the source ends,
+// buffered messages are read before its error is returned.
+func (b *BufferedReader) ReadMessage() (*Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.queue) == 0 && b.dropped == 0 && b.err == nil && !b.closed {
+		b.cond.Wait()
+	}
+	switch {
+	case b.closed:
+		return nil, io.EOF
+	case b.dropped != 0:
+		return b.droppedMarker(), nil
+	case len(b.queue) != 0:
+		msg := b.queue[0]
+		b.queue[0] = nil
+		b.queue = b.queue[1:]
+		b.bytes -= len(msg.Text)
+		b.cond.Broadcast()
+		return msg, nil
+	default:
+		return nil, b.err
+	}
+}
+
+// fill reads from the source until it ends or the reader is closed.
+func (b *BufferedReader) fill() {
+	for {
+		msg, err := b.r.ReadMessage()
+
+		b.mu.Lock()
+		if b.closed {
+			b.mu.Unlock()
+			return
+		}
+		if err != nil {
+			b.err = err
+			b.cond.Broadcast()
+			b.mu.Unlock()
+			return
+		}
+
+		for b.full(msg) && !b.closed {
+			if b.opts.Backpressure == BackpressureDropOldest {
+				oldest := b.queue[0]
+				b.queue[0] = nil
+				b.queue = b.queue[1:]
+				b.bytes -= len(oldest.Text)
+				b.dropped++
+				b.total++
+				b.last = oldest.Time
+				continue
+			}
+			b.cond.Wait()
+		}
+		if b.closed {
+			b.mu.Unlock()
+			return
+		}
+		b.queue = append(b.queue, msg)
+		b.bytes += len(msg.Text)
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	}
+}
+
+// full returns true if there's no room to buffer a message.
+func (b *BufferedReader) full(msg *Message) bool {
+	if len(b.queue) == 0 {
+		return false
+	}
+	return len(b.queue) >= b.opts.Messages ||
+		(b.opts.Bytes > 0 && b.bytes+len(msg.Text) > b.opts.Bytes)
+}
+
+// droppedMarker replaces the messages dropped since the last marker. It takes
+// the time of the last of them so that it sorts before the messages after.
+func (b *BufferedReader) droppedMarker() *Message {
+	text := fmt.Sprintf("[%d messages dropped by backpressure]\n", b.dropped)
+	if b.dropped == 1 {
+		text = "[1 message dropped by backpressure]\n"
+	}
+	b.dropped = 0
+	return &Message{Stream: Stderr, Time: b.last, Text: text}
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func numbered(n int) []Message {
+	t0 := time.Date(2021, time.August, 1, 0, 0, 0, 0, time.UTC)
+	messages := make([]Message, n)
+	for i := range messages {
+		messages[i] = Message{Stream: Stdout, Time: t0.Add(time.Duration(i) * time.Second), Text: fmt.Sprintf("%d\n", i)}
+	}
+	return messages
+}
+
+// blockingReader blocks reads until it's closed.
+type blockingReader struct{ closed chan struct{} }
+
+func (r *blockingReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func (r *blockingReader) ReadMessage() (*Message, error) {
+	<-r.closed
+	return nil, io.ErrClosedPipe
+}
+
+func TestBufferedReader(t *testing.T) {
+	t.Run("Block", func(t *testing.T) {
+		messages := numbered(100)
+		r := NewBufferedReader(&sliceReader{messages: messages}, BufferOpts{Messages: 4})
+		assert.Equal(t, messages, readAll(t, r))
+		assert.Zero(t, r.Dropped())
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		messages := numbered(10)
+		r := NewBufferedReader(&sliceReader{messages: messages}, BufferOpts{
+			Messages:     3,
+			Backpressure: BackpressureDropOldest,
+		})
+		require.Eventually(t, func() bool { return r.Dropped() == 7 }, time.Second, time.Millisecond)
+
+		marker := Message{Stream: Stderr, Time: messages[6].Time, Text: "[7 messages dropped by backpressure]\n"}
+		assert.Equal(t, append([]Message{marker}, messages[7:]...), readAll(t, r))
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		messages := []Message{{Text: "aaaa"}, {Text: "bbbb"}, {Text: "cccccccccc"}}
+		r := NewBufferedReader(&sliceReader{messages: messages}, BufferOpts{
+			Bytes:        8,
+			Backpressure: BackpressureDropOldest,
+		})
+		require.Eventually(t, func() bool { return r.Dropped() == 2 }, time.Second, time.Millisecond)
+
+		// Messages longer than the limit are buffered alone.
+		assert.Equal(t, []Message{
+			{Stream: Stderr, Text: "[2 messages dropped by backpressure]\n"},
+			{Text: "cccccccccc"},
+		}, readAll(t, r))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		failure := errors.New("failed")
+		r := NewBufferedReader(&failingReader{messages: numbered(2), err: failure}, BufferOpts{})
+		for i := 0; i < 2; i++ {
+			_, err := r.ReadMessage()
+			require.NoError(t, err)
+		}
+		_, err := r.ReadMessage()
+		assert.Equal(t, failure, err)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		r := NewBufferedReader(&blockingReader{closed: make(chan struct{})}, BufferOpts{})
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			r.Close()
+		}()
+		_, err := r.ReadMessage()
+		assert.Equal(t, io.EOF, err)
+	})
+}
+
+// failingReader returns messages, then an error.
+type failingReader struct {
+	messages []Message
+	err      error
+}
+
+func (r *failingReader) Close() error { return nil }
+
+func (r *failingReader) ReadMessage() (*Message, error) {
+	if len(r.messages) == 0 {
+		return nil, r.err
+	}
+	msg := r.messages[0]
+	r.messages = r.messages[1:]
+	return &msg, nil
+}
+
+func TestBufferOptsValidate(t *testing.T) {
+	assert.NoError(t, BufferOpts{}.Validate())
+	assert.NoError(t, BufferOpts{Messages: 10, Bytes: 1024, Backpressure: BackpressureDropOldest}.Validate())
+	assert.Error(t, BufferOpts{Messages: -1}.Validate())
+	assert.Error(t, BufferOpts{Bytes: -1}.Validate())
+	assert.Error(t, BufferOpts{Backpressure: "drop-newest"}.Validate())
+}
+
+func TestMeasureThroughput(t *testing.T) {
+	tp, err := MeasureThroughput(&sliceReader{messages: numbered(10)}, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, tp.Messages)
+	assert.EqualValues(t, 20, tp.Bytes)
+	assert.Greater(t, tp.MessagesPerSecond(), 0.0)
+
+	assert.Zero(t, Throughput{Messages: 1}.MessagesPerSecond())
+}
+
+func BenchmarkBufferedReader(b *testing.B) {
+	for _, policy := range []Backpressure{BackpressureBlock, BackpressureDropOldest} {
+		b.Run(string(policy), func(b *testing.B) {
+			messages := numbered(b.N)
+			b.ResetTimer()
+			r := NewBufferedReader(&sliceReader{messages: messages}, BufferOpts{Backpressure: policy})
+			tp, err := MeasureThroughput(r, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(tp.MessagesPerSecond(), "msgs/s")
+		})
+	}
+}
@@ -7,7 +7,12 @@ import (
 	"github.com/vmihailenco/msgpack"
 )
 
-const fieldCount = 3
+// Messages are encoded as arrays of their stream, time, and text, followed by
+// their encoding unless it's TextPlain.
+const (
+	fieldCount        = 3
+	encodedFieldCount = 4
+)
 
 // An Encoder writes structured log messages to an output stream.
 type Encoder struct {
@@ -21,11 +26,15 @@ func NewEncoder(w io.Writer) *Encoder {
 
 // Encode writes the binary encoding of v to the stream.
 func (e *Encoder) Encode(v Message) error {
-	if v.Stream != Stdout && v.Stream != Stderr {
+	if !v.Stream.valid() {
 		return errors.New("logging: invalid IO stream")
 	}
 
-	if err := e.e.EncodeArrayLen(fieldCount); err != nil {
+	n := fieldCount
+	if v.Encoding != TextPlain {
+		n = encodedFieldCount
+	}
+	if err := e.e.EncodeArrayLen(n); err != nil {
 		return err
 	}
 	if err := e.e.EncodeInt(int64(v.Stream)); err != nil {
@@ -37,6 +46,11 @@ func (e *Encoder) Encode(v Message) error {
 	if err := e.e.EncodeString(v.Text); err != nil {
 		return err
 	}
+	if n == encodedFieldCount {
+		if err := e.e.EncodeString(string(v.Encoding)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -56,7 +70,7 @@ func (d *Decoder) Decode(v *Message) error {
 	if err != nil {
 		return err
 	}
-	if l != fieldCount {
+	if l != fieldCount && l != encodedFieldCount {
 		return errors.New("logging: possible corruption or invalid encoding")
 	}
 
@@ -65,7 +79,7 @@ func (d *Decoder) Decode(v *Message) error {
 		return err
 	}
 	v.Stream = IOStream(stream)
-	if v.Stream != Stdout && v.Stream != Stderr {
+	if !v.Stream.valid() {
 		return errors.New("logging: invalid IO stream")
 	}
 
@@ -75,6 +89,17 @@ func (d *Decoder) Decode(v *Message) error {
 	}
 	v.Time = t.UTC()
 
-	v.Text, err = d.d.DecodeString()
-	return err
+	if v.Text, err = d.d.DecodeString(); err != nil {
+		return err
+	}
+
+	v.Encoding = TextPlain
+	if l == encodedFieldCount {
+		encoding, err := d.d.DecodeString()
+		if err != nil {
+			return err
+		}
+		v.Encoding = TextEncoding(encoding)
+	}
+	return nil
 }
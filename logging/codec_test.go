@@ -35,3 +35,41 @@ func TestCodec(t *testing.T) {
 	require.NoError(t, NewDecoder(&buf).Decode(&out))
 	assert.Equal(t, message, out)
 }
+
+func TestCodecBinary(t *testing.T) {
+	// Msgpack strings are arbitrary bytes, so binary text round-trips as-is.
+	message := Message{Stream: Stderr, Time: time.Unix(0, 0).UTC(), Text: "a\x00b\xff\n"}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf).Encode(message))
+
+	var out Message
+	require.NoError(t, NewDecoder(&buf).Decode(&out))
+	assert.Equal(t, message, out)
+}
+
+func TestCodecEncoding(t *testing.T) {
+	message := Message{Stream: Stdout, Time: time.Unix(0, 0).UTC(), Text: "YQBi/wo=", Encoding: TextBase64}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf).Encode(message))
+	assert.Equal(t, byte(0x94), buf.Bytes()[0]) // Array length
+
+	var out Message
+	require.NoError(t, NewDecoder(&buf).Decode(&out))
+	assert.Equal(t, message, out)
+}
+
+func TestCodecStreams(t *testing.T) {
+	for _, stream := range []IOStream{Stdin, Stdout, Stderr} {
+		message := Message{Stream: stream, Time: time.Unix(0, 0).UTC(), Text: "text"}
+
+		var buf bytes.Buffer
+		require.NoError(t, NewEncoder(&buf).Encode(message))
+		var out Message
+		require.NoError(t, NewDecoder(&buf).Decode(&out))
+		assert.Equal(t, message, out)
+	}
+
+	assert.Error(t, NewEncoder(&bytes.Buffer{}).Encode(Message{Stream: IOStream(3)}))
+}
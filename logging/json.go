@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// jsonMessage is the JSON representation of a Message. Binary text can't be
+// represented faithfully as a JSON string, so it's base64-encoded instead.
+type jsonMessage struct {
+	Stream     string `json:"stream"`
+	Time       string `json:"time"`
+	Text       string `json:"text"`
+	TextBase64 string `json:"textBase64,omitempty"`
+}
+
+// A JSONEncoder writes log messages to an output stream as newline-delimited
+// JSON objects, e.g. {"stream":"stdout","time":"2006-01-02T15:04:05Z","text":"hi\n"}
+//
+// Text which isn't valid UTF-8 or contains NUL bytes, or which is already
+// encoded as TextBase64, is written to a "textBase64" field in place of "text".
+// It's decoded as plain text.
+type JSONEncoder struct {
+	e *json.Encoder
+}
+
+// NewJSONEncoder returns a new encoder that writes to w.
+func NewJSONEncoder(w io.Writer) *JSONEncoder {
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	return &JSONEncoder{e: e}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline.
+func (e *JSONEncoder) Encode(v Message) error {
+	var stream string
+	switch v.Stream {
+	case Stdin:
+		stream = "stdin"
+	case Stdout:
+		stream = "stdout"
+	case Stderr:
+		stream = "stderr"
+	default:
+		return errors.New("logging: invalid IO stream")
+	}
+
+	m := jsonMessage{Stream: stream, Time: v.Time.Format(time.RFC3339Nano)}
+	switch {
+	case v.Encoding == TextBase64:
+		m.TextBase64 = v.Text
+	case v.Encoding != TextPlain:
+		return fmt.Errorf("logging: invalid text encoding %q", v.Encoding)
+	case IsBinary(v.Text):
+		m.TextBase64 = base64.StdEncoding.EncodeToString([]byte(v.Text))
+	default:
+		m.Text = v.Text
+	}
+	return e.e.Encode(m)
+}
+
+// A JSONDecoder reads log messages encoded by a JSONEncoder. Times keep the
+// offset they were encoded with; see ReaderOpts.NormalizeTime.
+type JSONDecoder struct {
+	d *json.Decoder
+}
+
+// NewJSONDecoder returns a new decoder that reads from r.
+func NewJSONDecoder(r io.Reader) *JSONDecoder {
+	return &JSONDecoder{d: json.NewDecoder(r)}
+}
+
+// Decode reads the next message from its input and stores it in v.
+func (d *JSONDecoder) Decode(v *Message) error {
+	var m jsonMessage
+	if err := d.d.Decode(&m); err != nil {
+		return err
+	}
+
+	switch m.Stream {
+	case "stdin":
+		v.Stream = Stdin
+	case "stdout":
+		v.Stream = Stdout
+	case "stderr":
+		v.Stream = Stderr
+	default:
+		return errors.New("logging: invalid IO stream")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, m.Time)
+	if err != nil {
+		return err
+	}
+	v.Time = t
+
+	v.Text, v.Encoding = m.Text, TextPlain
+	if m.TextBase64 != "" {
+		text, err := base64.StdEncoding.DecodeString(m.TextBase64)
+		if err != nil {
+			return err
+		}
+		v.Text = string(text)
+	}
+	return nil
+}
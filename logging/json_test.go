@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONCodec(t *testing.T) {
+	messages := []Message{
+		{Stream: Stdout, Time: time.Date(1983, time.June, 21, 8, 42, 0, 12345, time.UTC), Text: "<a & b>\n"},
+		{Stream: Stderr, Time: time.Date(1983, time.June, 21, 8, 42, 1, 0, time.UTC), Text: "\"quoted\"\n"},
+	}
+
+	var buf bytes.Buffer
+	enc := NewJSONEncoder(&buf)
+	for _, m := range messages {
+		require.NoError(t, enc.Encode(m))
+	}
+	assert.Equal(t, `{"stream":"stdout","time":"1983-06-21T08:42:00.000012345Z","text":"<a & b>\n"}`+"\n"+
+		`{"stream":"stderr","time":"1983-06-21T08:42:01Z","text":"\"quoted\"\n"}`+"\n", buf.String())
+
+	dec := NewJSONDecoder(&buf)
+	for _, want := range messages {
+		var out Message
+		require.NoError(t, dec.Decode(&out))
+		assert.Equal(t, want, out)
+	}
+	var out Message
+	assert.Equal(t, io.EOF, dec.Decode(&out))
+}
+
+func TestJSONCodecZone(t *testing.T) {
+	zone := time.FixedZone("", -7*60*60)
+	message := Message{Stream: Stdout, Time: time.Date(1983, time.June, 21, 1, 42, 0, 0, zone), Text: "hi\n"}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONEncoder(&buf).Encode(message))
+	assert.Contains(t, buf.String(), `"time":"1983-06-21T01:42:00-07:00"`)
+
+	var out Message
+	require.NoError(t, NewJSONDecoder(&buf).Decode(&out))
+	assert.True(t, message.Time.Equal(out.Time))
+	_, offset := out.Time.Zone()
+	assert.Equal(t, -7*60*60, offset)
+	assert.Equal(t, out.Time, ReaderOpts{PreserveZone: true}.NormalizeTime(out.Time, time.Time{}))
+}
+
+func TestJSONCodecStdin(t *testing.T) {
+	message := Message{Stream: Stdin, Time: time.Unix(0, 0).UTC(), Text: "ls\r"}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONEncoder(&buf).Encode(message))
+	assert.Equal(t, `{"stream":"stdin","time":"1970-01-01T00:00:00Z","text":"ls\r"}`+"\n", buf.String())
+
+	var out Message
+	require.NoError(t, NewJSONDecoder(&buf).Decode(&out))
+	assert.Equal(t, message, out)
+}
+
+func TestJSONCodecEncoding(t *testing.T) {
+	message := Message{Stream: Stdout, Time: time.Unix(0, 0).UTC(), Text: "YQBi/wo=", Encoding: TextBase64}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONEncoder(&buf).Encode(message))
+	assert.Equal(t, `{"stream":"stdout","time":"1970-01-01T00:00:00Z","text":"","textBase64":"YQBi/wo="}`+"\n", buf.String())
+
+	var out Message
+	require.NoError(t, NewJSONDecoder(&buf).Decode(&out))
+	assert.Equal(t, Message{Stream: Stdout, Time: time.Unix(0, 0).UTC(), Text: "a\x00b\xff\n"}, out)
+
+	assert.Error(t, NewJSONEncoder(io.Discard).Encode(Message{Stream: Stdout, Encoding: "hex"}))
+}
+
+func TestJSONCodecInvalidStream(t *testing.T) {
+	assert.Error(t, NewJSONEncoder(io.Discard).Encode(Message{Stream: IOStream(3)}))
+
+	var out Message
+	assert.Error(t, NewJSONDecoder(bytes.NewBufferString(`{"stream":"tty"}`)).Decode(&out))
+}
+
+func TestJSONCodecBinary(t *testing.T) {
+	message := Message{Stream: Stdout, Time: time.Unix(0, 0).UTC(), Text: "a\x00b\xff\n"}
+
+	var buf bytes.Buffer
+	require.NoError(t, NewJSONEncoder(&buf).Encode(message))
+	assert.Equal(t, `{"stream":"stdout","time":"1970-01-01T00:00:00Z","text":"","textBase64":"YQBi/wo="}`+"\n", buf.String())
+
+	var out Message
+	require.NoError(t, NewJSONDecoder(&buf).Decode(&out))
+	assert.Equal(t, message, out)
+}
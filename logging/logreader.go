@@ -10,16 +10,27 @@ type IOStream int
 
 // IOStream flag definitions
 const (
+	// Stdin tags input typed into an interactive session. Container logs
+	// never include it, but session transcripts do.
 	Stdin IOStream = iota
 	Stdout
 	Stderr
 )
 
+// valid returns true if the stream is one of the defined streams.
+func (s IOStream) valid() bool {
+	return s == Stdin || s == Stdout || s == Stderr
+}
+
 // A Message is a structured log message. Text includes trailing newlines if present.
 type Message struct {
 	Stream IOStream
 	Time   time.Time
 	Text   string
+
+	// Encoding identifies how Text is encoded. Text is plain unless the reader
+	// was configured with BinaryBase64 and the container wrote binary output.
+	Encoding TextEncoding
 }
 
 // LogReader provides ReadMessage() which reads a structured log message in
@@ -28,9 +39,51 @@ type LogReader interface {
 	io.Closer
 
 	// ReadMessage reads the next log message emitted by container.
-	// Note: Time field in message is set in UTC.
+	// Note: Time field in message is set in UTC unless the reader was
+	// configured with ReaderOpts.PreserveZone.
 	//
 	// Returns nil, io.EOF if all log messages emitted by container
 	// have been consumed.
 	ReadMessage() (*Message, error)
 }
+
+// ReaderOpts controls how a LogReader presents messages.
+type ReaderOpts struct {
+	// PreserveZone reports each time in the zone it was logged in. By default,
+	// times are converted to UTC.
+	PreserveZone bool
+
+	// Monotonic moves each time forward as needed to be strictly after the
+	// previous message's, so that sorting by time preserves emission order.
+	// Identical times are common when a container flushes output in bursts.
+	Monotonic bool
+
+	// Binary controls how text which isn't valid UTF-8 or contains NUL bytes
+	// is presented. By default, text is passed through unmodified.
+	Binary BinaryMode
+
+	// StripTerminal removes ANSI escape sequences and collapses lines which
+	// were overwritten with carriage returns. See StripTerminal.
+	StripTerminal bool
+}
+
+// NormalizeText normalizes a message's text according to the options,
+// returning it along with its encoding.
+func (o ReaderOpts) NormalizeText(text string) (string, TextEncoding) {
+	if o.StripTerminal {
+		text = StripTerminal(text)
+	}
+	return o.Binary.Sanitize(text)
+}
+
+// NormalizeTime normalizes a message's time according to the options. The
+// previous message's normalized time, if any, must be provided as last.
+func (o ReaderOpts) NormalizeTime(t, last time.Time) time.Time {
+	if !o.PreserveZone {
+		t = t.UTC()
+	}
+	if o.Monotonic && !last.IsZero() && !t.After(last) {
+		t = last.Add(time.Nanosecond).In(t.Location())
+	}
+	return t
+}
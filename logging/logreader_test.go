@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderOpts(t *testing.T) {
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	t0 := time.Date(2021, time.August, 1, 12, 0, 0, 0, zone)
+
+	tests := map[string]struct {
+		opts     ReaderOpts
+		t, last  time.Time
+		expected time.Time
+	}{
+		"Default":         {ReaderOpts{}, t0, time.Time{}, t0.UTC()},
+		"IgnoresLast":     {ReaderOpts{}, t0, t0.Add(time.Hour), t0.UTC()},
+		"PreserveZone":    {ReaderOpts{PreserveZone: true}, t0, time.Time{}, t0},
+		"MonotonicFirst":  {ReaderOpts{Monotonic: true}, t0, time.Time{}, t0.UTC()},
+		"MonotonicAfter":  {ReaderOpts{Monotonic: true}, t0, t0.Add(-1), t0.UTC()},
+		"MonotonicEqual":  {ReaderOpts{Monotonic: true}, t0, t0.UTC(), t0.Add(1).UTC()},
+		"MonotonicBefore": {ReaderOpts{Monotonic: true}, t0, t0.Add(5), t0.Add(6).UTC()},
+		"MonotonicZone":   {ReaderOpts{PreserveZone: true, Monotonic: true}, t0, t0.UTC(), t0.Add(1)},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := tt.opts.NormalizeTime(tt.t, tt.last)
+			assert.Equal(t, tt.expected.String(), actual.String())
+		})
+	}
+}
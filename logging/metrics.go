@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Metric is a numeric value extracted from a log message.
+type Metric struct {
+	Name  string
+	Value float64
+
+	// Time is the time of the message from which the metric was extracted.
+	Time time.Time
+}
+
+// A MetricExtractor finds metrics in log messages.
+type MetricExtractor interface {
+	// Extract returns all metrics found in a message, if any.
+	Extract(msg *Message) []Metric
+}
+
+// KeyValuePattern matches metrics written as "name=value", e.g. "loss=0.42".
+var KeyValuePattern = regexp.MustCompile(
+	`(?P<name>[A-Za-z_][A-Za-z0-9_./-]*)=(?P<value>[-+]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?)`)
+
+// RegexExtractor extracts metrics with a regular expression.
+//
+// If the pattern has subexpressions named "name" and "value", each match is a
+// metric with the given name, as with KeyValuePattern. Otherwise, each named
+// subexpression is a metric named after it; for example, `epoch (?P<epoch>\d+)`
+// extracts an "epoch" counter. Values which aren't numbers are ignored.
+type RegexExtractor struct {
+	Pattern *regexp.Regexp
+}
+
+// Extract implements the MetricExtractor interface.
+func (e *RegexExtractor) Extract(msg *Message) []Metric {
+	names := e.Pattern.SubexpNames()
+	nameIndex, valueIndex := e.Pattern.SubexpIndex("name"), e.Pattern.SubexpIndex("value")
+
+	var metrics []Metric
+	for _, match := range e.Pattern.FindAllStringSubmatch(msg.Text, -1) {
+		if nameIndex >= 0 && valueIndex >= 0 {
+			if v, err := strconv.ParseFloat(match[valueIndex], 64); err == nil {
+				metrics = append(metrics, Metric{Name: match[nameIndex], Value: v, Time: msg.Time})
+			}
+			continue
+		}
+
+		for i, name := range names {
+			if name == "" || match[i] == "" {
+				continue
+			}
+			if v, err := strconv.ParseFloat(match[i], 64); err == nil {
+				metrics = append(metrics, Metric{Name: name, Value: v, Time: msg.Time})
+			}
+		}
+	}
+	return metrics
+}
+
+// JSONExtractor extracts numeric fields from messages which are JSON objects,
+// as written by many structured loggers. Other messages are ignored.
+type JSONExtractor struct {
+	// (optional) Fields limits extraction to the named top-level fields. If
+	// empty, all numeric top-level fields are extracted.
+	Fields []string
+}
+
+// Extract implements the MetricExtractor interface. Metrics are ordered by name.
+func (e *JSONExtractor) Extract(msg *Message) []Metric {
+	text := strings.TrimSpace(msg.Text)
+	if !strings.HasPrefix(text, "{") {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &fields); err != nil {
+		return nil
+	}
+
+	var metrics []Metric
+	add := func(name string) {
+		if v, ok := fields[name].(float64); ok {
+			metrics = append(metrics, Metric{Name: name, Value: v, Time: msg.Time})
+		}
+	}
+	if len(e.Fields) != 0 {
+		for _, name := range e.Fields {
+			add(name)
+		}
+		return metrics
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add(name)
+	}
+	return metrics
+}
+
+// MetricsReader passes each message read through it to a set of extractors,
+// reporting the metrics they find to a callback.
+type MetricsReader struct {
+	r          LogReader
+	extractors []MetricExtractor
+	fn         func(Metric)
+}
+
+// NewMetricsReader wraps a log reader to extract metrics from its messages.
+// The callback is invoked synchronously from ReadMessage.
+func NewMetricsReader(r LogReader, fn func(Metric), extractors ...MetricExtractor) *MetricsReader {
+	return &MetricsReader{r: r, extractors: extractors, fn: fn}
+}
+
+// Close implements the io.Closer interface.
+func (r *MetricsReader) Close() error {
+	return r.r.Close()
+}
+
+// ReadMessage implements the LogReader interface.
+func (r *MetricsReader) ReadMessage() (*Message, error) {
+	msg, err := r.r.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range r.extractors {
+		for _, m := range e.Extract(msg) {
+			r.fn(m)
+		}
+	}
+	return msg, nil
+}
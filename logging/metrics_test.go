@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexExtractor(t *testing.T) {
+	now := time.Now()
+
+	t.Run("KeyValue", func(t *testing.T) {
+		e := &RegexExtractor{Pattern: KeyValuePattern}
+		metrics := e.Extract(&Message{Time: now, Text: "step 10: loss=0.42 lr=1e-4 acc=.9 name=resnet\n"})
+		assert.Equal(t, []Metric{
+			{Name: "loss", Value: 0.42, Time: now},
+			{Name: "lr", Value: 1e-4, Time: now},
+			{Name: "acc", Value: 0.9, Time: now},
+		}, metrics)
+	})
+
+	t.Run("NamedGroups", func(t *testing.T) {
+		e := &RegexExtractor{Pattern: regexp.MustCompile(`Epoch (?P<epoch>\d+)/\d+.*?(?:loss: (?P<loss>[\d.]+))?$`)}
+		metrics := e.Extract(&Message{Time: now, Text: "Epoch 3/10 - loss: 1.5"})
+		assert.Equal(t, []Metric{
+			{Name: "epoch", Value: 3, Time: now},
+			{Name: "loss", Value: 1.5, Time: now},
+		}, metrics)
+
+		assert.Equal(t, []Metric{{Name: "epoch", Value: 4, Time: now}},
+			e.Extract(&Message{Time: now, Text: "Epoch 4/10"}))
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		e := &RegexExtractor{Pattern: KeyValuePattern}
+		assert.Empty(t, e.Extract(&Message{Text: "nothing to see here\n"}))
+	})
+}
+
+func TestJSONExtractor(t *testing.T) {
+	now := time.Now()
+	msg := &Message{Time: now, Text: `{"step": 7, "loss": 0.5, "tag": "train", "nested": {"x": 1}}` + "\n"}
+
+	t.Run("All", func(t *testing.T) {
+		assert.Equal(t, []Metric{
+			{Name: "loss", Value: 0.5, Time: now},
+			{Name: "step", Value: 7, Time: now},
+		}, (&JSONExtractor{}).Extract(msg))
+	})
+
+	t.Run("Fields", func(t *testing.T) {
+		assert.Equal(t, []Metric{{Name: "step", Value: 7, Time: now}},
+			(&JSONExtractor{Fields: []string{"step", "tag"}}).Extract(msg))
+	})
+
+	t.Run("NotJSON", func(t *testing.T) {
+		assert.Empty(t, (&JSONExtractor{}).Extract(&Message{Text: "{not json\n"}))
+		assert.Empty(t, (&JSONExtractor{}).Extract(&Message{Text: "[1, 2]\n"}))
+	})
+}
+
+// sliceReader reads messages from a slice.
+type sliceReader struct{ messages []Message }
+
+func (r *sliceReader) Close() error { return nil }
+
+func (r *sliceReader) ReadMessage() (*Message, error) {
+	if len(r.messages) == 0 {
+		return nil, io.EOF
+	}
+	msg := r.messages[0]
+	r.messages = r.messages[1:]
+	return &msg, nil
+}
+
+func TestMetricsReader(t *testing.T) {
+	messages := []Message{{Text: "loss=1\n"}, {Text: "hello\n"}, {Text: `{"loss": 2}`}}
+
+	var metrics []Metric
+	r := NewMetricsReader(&sliceReader{messages: messages}, func(m Metric) {
+		metrics = append(metrics, m)
+	}, &RegexExtractor{Pattern: KeyValuePattern}, &JSONExtractor{})
+
+	for i := range messages {
+		msg, err := r.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, messages[i], *msg)
+	}
+	_, err := r.ReadMessage()
+	assert.Equal(t, io.EOF, err)
+
+	assert.Equal(t, []Metric{{Name: "loss", Value: 1}, {Name: "loss", Value: 2}}, metrics)
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// RateLimit bounds the rate of a container's log output. Zero values are
+// unlimited. Each limit allows bursts of up to one second's worth of output.
+type RateLimit struct {
+	MessagesPerSecond float64
+	BytesPerSecond    float64
+}
+
+// RateLimitedReader drops messages which exceed a rate limit. Dropped messages
+// are replaced with a single marker on stderr noting how many were dropped,
+// e.g. "[12 messages dropped by rate limit]".
+//
+// Rates are measured by message time rather than the time messages are read,
+// so historical logs are limited the same way as followed logs.
+type RateLimitedReader struct {
+	r     LogReader
+	limit RateLimit
+
+	// Available tokens for each limit, refilled as message time passes.
+	messages float64
+	bytes    float64
+	last     time.Time
+
+	dropped int
+	pending *Message
+}
+
+// NewRateLimitedReader wraps a log reader to limit the rate of its messages.
+func NewRateLimitedReader(r LogReader, limit RateLimit) *RateLimitedReader {
+	return &RateLimitedReader{r: r, limit: limit}
+}
+
+// Close implements the io.Closer interface.
+func (r *RateLimitedReader) Close() error {
+	return r.r.Close()
+}
+
+// ReadMessage implements the LogReader interface.
+func (r *RateLimitedReader) ReadMessage() (*Message, error) {
+	if r.pending != nil {
+		msg := r.pending
+		r.pending = nil
+		return msg, nil
+	}
+
+	for {
+		msg, err := r.r.ReadMessage()
+		if err == io.EOF && r.dropped != 0 {
+			return r.droppedMarker(r.last), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		r.refill(msg.Time)
+		if !r.allow(msg) {
+			r.dropped++
+			continue
+		}
+
+		if r.dropped != 0 {
+			r.pending = msg
+			return r.droppedMarker(msg.Time), nil
+		}
+		return msg, nil
+	}
+}
+
+func (r *RateLimitedReader) refill(t time.Time) {
+	if r.last.IsZero() {
+		r.messages = r.limit.MessagesPerSecond
+		r.bytes = r.limit.BytesPerSecond
+	} else if elapsed := t.Sub(r.last).Seconds(); elapsed > 0 {
+		r.messages = math.Min(r.messages+elapsed*r.limit.MessagesPerSecond, r.limit.MessagesPerSecond)
+		r.bytes = math.Min(r.bytes+elapsed*r.limit.BytesPerSecond, r.limit.BytesPerSecond)
+	}
+	if t.After(r.last) {
+		r.last = t
+	}
+}
+
+func (r *RateLimitedReader) allow(msg *Message) bool {
+	if r.limit.MessagesPerSecond > 0 && r.messages < 1 {
+		return false
+	}
+	// Messages may overdraw the byte limit so that messages larger than the
+	// limit aren't dropped unconditionally.
+	if r.limit.BytesPerSecond > 0 && r.bytes <= 0 {
+		return false
+	}
+	r.messages--
+	r.bytes -= float64(len(msg.Text))
+	return true
+}
+
+func (r *RateLimitedReader) droppedMarker(t time.Time) *Message {
+	text := fmt.Sprintf("[%d messages dropped by rate limit]\n", r.dropped)
+	if r.dropped == 1 {
+		text = "[1 message dropped by rate limit]\n"
+	}
+	r.dropped = 0
+	return &Message{Stream: Stderr, Time: t, Text: text}
+}
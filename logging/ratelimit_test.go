@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, r LogReader) []Message {
+	var messages []Message
+	for {
+		msg, err := r.ReadMessage()
+		if err == io.EOF {
+			return messages
+		}
+		require.NoError(t, err)
+		messages = append(messages, *msg)
+	}
+}
+
+func TestRateLimitedReader(t *testing.T) {
+	t0 := time.Date(2021, time.August, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int, text string) Message {
+		return Message{Stream: Stdout, Time: t0.Add(time.Duration(ms) * time.Millisecond), Text: text}
+	}
+
+	t.Run("Unlimited", func(t *testing.T) {
+		messages := []Message{at(0, "a\n"), at(0, "b\n"), at(0, "c\n")}
+		r := NewRateLimitedReader(&sliceReader{messages: messages}, RateLimit{})
+		assert.Equal(t, messages, readAll(t, r))
+	})
+
+	t.Run("Messages", func(t *testing.T) {
+		r := NewRateLimitedReader(&sliceReader{messages: []Message{
+			at(0, "a\n"), at(0, "b\n"), at(0, "c\n"), at(100, "d\n"), at(500, "e\n"), at(1500, "f\n"),
+		}}, RateLimit{MessagesPerSecond: 2})
+		assert.Equal(t, []Message{
+			at(0, "a\n"),
+			at(0, "b\n"),
+			{Stream: Stderr, Time: t0.Add(500 * time.Millisecond), Text: "[2 messages dropped by rate limit]\n"},
+			at(500, "e\n"),
+			at(1500, "f\n"),
+		}, readAll(t, r))
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		big := strings.Repeat("x", 15) + "\n"
+		r := NewRateLimitedReader(&sliceReader{messages: []Message{
+			at(0, big), at(0, "a\n"), at(1000, "b\n"),
+		}}, RateLimit{BytesPerSecond: 10})
+		assert.Equal(t, []Message{
+			at(0, big),
+			{Stream: Stderr, Time: t0.Add(time.Second), Text: "[1 message dropped by rate limit]\n"},
+			at(1000, "b\n"),
+		}, readAll(t, r))
+	})
+
+	t.Run("DroppedAtEOF", func(t *testing.T) {
+		r := NewRateLimitedReader(&sliceReader{messages: []Message{
+			at(0, "a\n"), at(10, "b\n"), at(20, "c\n"),
+		}}, RateLimit{MessagesPerSecond: 1})
+		assert.Equal(t, []Message{
+			at(0, "a\n"),
+			{Stream: Stderr, Time: t0.Add(20 * time.Millisecond), Text: "[2 messages dropped by rate limit]\n"},
+		}, readAll(t, r))
+	})
+}
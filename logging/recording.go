@@ -0,0 +1,189 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// A Recorder records the streams of an interactive session, such as an
+// attached shell, e.g. for auditing access to shared machines. Recorders are
+// safe for concurrent use since each stream is copied separately.
+type Recorder interface {
+	io.Closer
+
+	// Record records a chunk of a stream. Input is recorded as Stdin.
+	Record(msg Message) error
+}
+
+// RecordWriter returns a writer which passes writes through to w and records
+// them as the given stream. Recording errors are returned from Write.
+func RecordWriter(w io.Writer, r Recorder, stream IOStream) io.Writer {
+	return &recordingWriter{w: w, r: r, stream: stream}
+}
+
+type recordingWriter struct {
+	w      io.Writer
+	r      Recorder
+	stream IOStream
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if n > 0 {
+		msg := Message{Stream: w.stream, Time: time.Now(), Text: string(p[:n])}
+		if rerr := w.r.Record(msg); rerr != nil && err == nil {
+			err = fmt.Errorf("recording session: %w", rerr)
+		}
+	}
+	return n, err
+}
+
+// RecordReader returns a reader which passes reads through from r and records
+// them as Stdin.
+func RecordReader(r io.Reader, rec Recorder) io.Reader {
+	return &recordingReader{r: r, rec: rec}
+}
+
+type recordingReader struct {
+	r   io.Reader
+	rec Recorder
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		msg := Message{Stream: Stdin, Time: time.Now(), Text: string(p[:n])}
+		if rerr := r.rec.Record(msg); rerr != nil && err == nil {
+			err = fmt.Errorf("recording session: %w", rerr)
+		}
+	}
+	return n, err
+}
+
+// AsciicastRecorder records a session in asciinema's asciicast v2 format,
+// which replays with its original timing. Output is recorded as "o" events and
+// input as "i" events. See https://docs.asciinema.org/manual/asciicast/v2/.
+type AsciicastRecorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+// NewAsciicastRecorder writes an asciicast header for a terminal of the given
+// size to w and returns a recorder for events starting at the given time.
+func NewAsciicastRecorder(w io.Writer, width, height int, start time.Time) (*AsciicastRecorder, error) {
+	header := struct {
+		Version   int   `json:"version"`
+		Width     int   `json:"width"`
+		Height    int   `json:"height"`
+		Timestamp int64 `json:"timestamp"`
+	}{Version: 2, Width: width, Height: height, Timestamp: start.Unix()}
+
+	b, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		return nil, fmt.Errorf("writing asciicast header: %w", err)
+	}
+	return &AsciicastRecorder{w: w, start: start}, nil
+}
+
+// Record writes an event. Text which isn't valid UTF-8, such as a multi-byte
+// character split between chunks, is replaced with U+FFFD.
+func (r *AsciicastRecorder) Record(msg Message) error {
+	code := "o"
+	if msg.Stream == Stdin {
+		code = "i"
+	}
+	elapsed := msg.Time.Sub(r.start).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	b, err := json.Marshal([]interface{}{elapsed, code, msg.Text})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the underlying writer if it's an io.Closer.
+func (r *AsciicastRecorder) Close() error {
+	return closeWriter(r.w)
+}
+
+// TranscriptRecorder records a session as structured log messages with an
+// Encoder, so that transcripts can be read like container logs. Input is
+// recorded as Stdin messages; see TranscriptReader.
+type TranscriptRecorder struct {
+	mu sync.Mutex
+	w  io.Writer
+	e  *Encoder
+}
+
+// NewTranscriptRecorder returns a recorder which writes to w.
+func NewTranscriptRecorder(w io.Writer) *TranscriptRecorder {
+	return &TranscriptRecorder{w: w, e: NewEncoder(w)}
+}
+
+// Record encodes a message.
+func (r *TranscriptRecorder) Record(msg Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.e.Encode(msg)
+}
+
+// Close closes the underlying writer if it's an io.Closer.
+func (r *TranscriptRecorder) Close() error {
+	return closeWriter(r.w)
+}
+
+// TranscriptReader reads a transcript written by a TranscriptRecorder. It's
+// not safe for concurrent use.
+type TranscriptReader struct {
+	r        io.Reader
+	d        *Decoder
+	opts     ReaderOpts
+	lastTime time.Time
+}
+
+// NewTranscriptReader reads a transcript from r. Messages are normalized
+// according to opts.
+func NewTranscriptReader(r io.Reader, opts ReaderOpts) *TranscriptReader {
+	return &TranscriptReader{r: r, d: NewDecoder(r), opts: opts}
+}
+
+// Close implements the io.Closer interface.
+func (r *TranscriptReader) Close() error {
+	if c, ok := r.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ReadMessage implements the LogReader interface.
+func (r *TranscriptReader) ReadMessage() (*Message, error) {
+	var msg Message
+	if err := r.d.Decode(&msg); err != nil {
+		return nil, err
+	}
+	r.lastTime = r.opts.NormalizeTime(msg.Time, r.lastTime)
+	msg.Time = r.lastTime
+	msg.Text, msg.Encoding = r.opts.NormalizeText(msg.Text)
+	return &msg, nil
+}
+
+func closeWriter(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
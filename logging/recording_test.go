@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsciicastRecorder(t *testing.T) {
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	r, err := NewAsciicastRecorder(&buf, 80, 24, start)
+	require.NoError(t, err)
+	require.NoError(t, r.Record(Message{Stream: Stdout, Time: start.Add(100 * time.Millisecond), Text: "$ "}))
+	require.NoError(t, r.Record(Message{Stream: Stdin, Time: start.Add(1500 * time.Millisecond), Text: "ls\r"}))
+	require.NoError(t, r.Record(Message{Stream: Stderr, Time: start.Add(2 * time.Second), Text: "\x1b[31merror\x1b[0m\n"}))
+	require.NoError(t, r.Close())
+
+	assert.Equal(t, `{"version":2,"width":80,"height":24,"timestamp":1627776000}
+[0.1,"o","$ "]
+[1.5,"i","ls\r"]
+[2,"o","\u001b[31merror\u001b[0m\n"]
+`, buf.String())
+}
+
+func TestTranscriptRecorder(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	messages := []Message{
+		{Stream: Stdout, Time: now, Text: "$ "},
+		{Stream: Stdin, Time: now.Add(time.Second), Text: "ls\r"},
+		{Stream: Stderr, Time: now.Add(2 * time.Second), Text: "oops\n"},
+	}
+
+	var buf bytes.Buffer
+	r := NewTranscriptRecorder(&buf)
+	for _, msg := range messages {
+		require.NoError(t, r.Record(msg))
+	}
+
+	reader := NewTranscriptReader(&buf, ReaderOpts{})
+	for _, expected := range messages {
+		msg, err := reader.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, expected, *msg)
+	}
+	_, err := reader.ReadMessage()
+	assert.Equal(t, io.EOF, err)
+}
+
+type memoryRecorder struct{ messages []Message }
+
+func (r *memoryRecorder) Record(msg Message) error {
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func (r *memoryRecorder) Close() error { return nil }
+
+func TestRecordStreams(t *testing.T) {
+	rec := &memoryRecorder{}
+
+	var out bytes.Buffer
+	_, err := io.WriteString(RecordWriter(&out, rec, Stderr), "output")
+	require.NoError(t, err)
+	assert.Equal(t, "output", out.String())
+
+	in, err := ioutil.ReadAll(RecordReader(strings.NewReader("input"), rec))
+	require.NoError(t, err)
+	assert.Equal(t, "input", string(in))
+
+	require.Len(t, rec.messages, 2)
+	assert.Equal(t, Stderr, rec.messages[0].Stream)
+	assert.Equal(t, "output", rec.messages[0].Text)
+	assert.Equal(t, Stdin, rec.messages[1].Stream)
+	assert.Equal(t, "input", rec.messages[1].Text)
+}
@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxLineLen bounds the text of a StreamReader message. Longer lines are split.
+const maxLineLen = 64 * 1024
+
+// StreamReader is a LogReader of output captured live from a process's
+// standard streams, such as a command run in a container, so that it can be
+// consumed the same way as a container's logs. Output written to each stream
+// is split into lines and timestamped as it's written.
+//
+// Writes block until the messages they complete are read, so a reader which
+// stops reading applies backpressure to the process.
+type StreamReader struct {
+	opts   ReaderOpts
+	closer io.Closer
+
+	messages chan *Message
+	closed   chan struct{}
+	once     sync.Once
+	writers  []*streamWriter
+	err      error
+	lastTime time.Time
+}
+
+// NewStreamReader creates a reader. If closer is set, it's closed along with
+// the reader to interrupt the source of the streams.
+func NewStreamReader(opts ReaderOpts, closer io.Closer) *StreamReader {
+	return &StreamReader{
+		opts:     opts,
+		closer:   closer,
+		messages: make(chan *Message),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Writer returns a writer for the given stream. Each stream should have one
+// writer, which must not be used concurrently.
+func (r *StreamReader) Writer(stream IOStream) io.Writer {
+	w := &streamWriter{r: r, stream: stream}
+	r.writers = append(r.writers, w)
+	return w
+}
+
+// CloseWithError ends the streams once all writes have returned. Unterminated
+// lines are flushed, then ReadMessage returns err, or io.EOF if err is nil.
+func (r *StreamReader) CloseWithError(err error) {
+	for _, w := range r.writers {
+		if w.buf.Len() != 0 {
+			w.send(w.buf.String())
+		}
+	}
+	r.err = err
+	close(r.messages)
+}
+
+// Close implements the io.Closer interface. Writes in progress fail with
+// io.ErrClosedPipe.
+func (r *StreamReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		close(r.closed)
+		if r.closer != nil {
+			err = r.closer.Close()
+		}
+	})
+	return err
+}
+
+// ReadMessage implements the LogReader interface.
+func (r *StreamReader) ReadMessage() (*Message, error) {
+	select {
+	case msg, ok := <-r.messages:
+		if !ok {
+			if r.err != nil {
+				return nil, r.err
+			}
+			return nil, io.EOF
+		}
+		r.lastTime = r.opts.NormalizeTime(msg.Time, r.lastTime)
+		msg.Time = r.lastTime
+		msg.Text, msg.Encoding = r.opts.NormalizeText(msg.Text)
+		return msg, nil
+
+	case <-r.closed:
+		return nil, io.EOF
+	}
+}
+
+type streamWriter struct {
+	r      *StreamReader
+	stream IOStream
+	buf    bytes.Buffer
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	for i := 0; i < len(p); {
+		// Copy through the end of the line or until the buffer is full.
+		end := len(p)
+		if j := bytes.IndexByte(p[i:], '\n'); j >= 0 {
+			end = i + j + 1
+		}
+		if room := maxLineLen - w.buf.Len(); end-i > room {
+			end = i + room
+		}
+		w.buf.Write(p[i:end])
+		i = end
+
+		if w.buf.Len() == maxLineLen || bytes.HasSuffix(w.buf.Bytes(), []byte{'\n'}) {
+			if !w.send(w.buf.String()) {
+				return i, io.ErrClosedPipe
+			}
+			w.buf.Reset()
+		}
+	}
+	return len(p), nil
+}
+
+// send delivers a message, returning false if the reader was closed.
+func (w *streamWriter) send(text string) bool {
+	select {
+	case w.r.messages <- &Message{Stream: w.stream, Time: time.Now(), Text: text}:
+		return true
+	case <-w.r.closed:
+		return false
+	}
+}
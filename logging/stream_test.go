@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamReader(t *testing.T) {
+	r := NewStreamReader(ReaderOpts{Monotonic: true}, nil)
+	stdout, stderr := r.Writer(Stdout), r.Writer(Stderr)
+	go func() {
+		io.WriteString(stdout, "first ")
+		io.WriteString(stdout, "line\nsecond line\npartial")
+		io.WriteString(stderr, "error\n")
+		r.CloseWithError(nil)
+	}()
+
+	var messages []Message
+	for {
+		msg, err := r.ReadMessage()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if len(messages) != 0 {
+			assert.True(t, msg.Time.After(messages[len(messages)-1].Time))
+		}
+		messages = append(messages, *msg)
+	}
+
+	require.Len(t, messages, 4)
+	for i, expected := range []struct {
+		stream IOStream
+		text   string
+	}{
+		{Stdout, "first line\n"},
+		{Stdout, "second line\n"},
+		{Stderr, "error\n"},
+		{Stdout, "partial"},
+	} {
+		assert.Equal(t, expected.stream, messages[i].Stream)
+		assert.Equal(t, expected.text, messages[i].Text)
+	}
+}
+
+func TestStreamReaderLongLine(t *testing.T) {
+	r := NewStreamReader(ReaderOpts{}, nil)
+	w := r.Writer(Stdout)
+	go func() {
+		io.WriteString(w, strings.Repeat("x", maxLineLen+1)+"\n")
+		r.CloseWithError(nil)
+	}()
+
+	msg, err := r.ReadMessage()
+	require.NoError(t, err)
+	assert.Len(t, msg.Text, maxLineLen)
+	msg, err = r.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "x\n", msg.Text)
+}
+
+type closeFunc func() error
+
+func (f closeFunc) Close() error { return f() }
+
+func TestStreamReaderClose(t *testing.T) {
+	var sourceClosed bool
+	r := NewStreamReader(ReaderOpts{}, closeFunc(func() error {
+		sourceClosed = true
+		return nil
+	}))
+	w := r.Writer(Stdout)
+
+	require.NoError(t, r.Close())
+	assert.True(t, sourceClosed)
+	_, err := io.WriteString(w, "line\n")
+	assert.Equal(t, io.ErrClosedPipe, err)
+	_, err = r.ReadMessage()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStreamReaderError(t *testing.T) {
+	r := NewStreamReader(ReaderOpts{}, nil)
+	failed := errors.New("exited with code 1")
+	go r.CloseWithError(failed)
+
+	_, err := r.ReadMessage()
+	assert.Equal(t, failed, err)
+}
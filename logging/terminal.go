@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ansiRegex matches ANSI escape sequences: control sequences such as colors
+// and cursor movement, operating system commands such as window titles, and
+// two-character escapes.
+var ansiRegex = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// StripTerminal removes terminal control from text so it reads as it would
+// have been displayed. ANSI escape sequences are removed, and each line which
+// was overwritten with carriage returns, e.g. by a progress bar, is collapsed
+// to its final state.
+func StripTerminal(text string) string {
+	text = ansiRegex.ReplaceAllString(text, "")
+	if !strings.Contains(text, "\r") {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		if !strings.Contains(body, "\r") {
+			continue
+		}
+		newline := line[len(body):]
+
+		// Keep the last non-empty segment since that's what a terminal shows.
+		// This also handles CRLF line endings.
+		segments := strings.Split(body, "\r")
+		body = ""
+		for j := len(segments) - 1; j >= 0; j-- {
+			if segments[j] != "" {
+				body = segments[j]
+				break
+			}
+		}
+		lines[i] = body + newline
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripTerminal(t *testing.T) {
+	tests := map[string]struct {
+		text     string
+		expected string
+	}{
+		"Empty":       {"", ""},
+		"Plain":       {"hello\n", "hello\n"},
+		"Color":       {"\x1b[1;31merror\x1b[0m: failed\n", "error: failed\n"},
+		"EraseLine":   {"\x1b[2K\x1b[1Gdone\n", "done\n"},
+		"Title":       {"\x1b]0;title\x07text", "text"},
+		"Progress":    {"\r 10%|#         |\r 50%|#####     |\r100%|##########|\n", "100%|##########|\n"},
+		"TrailingCR":  {"epoch 1\r", "epoch 1"},
+		"CRLF":        {"one\r\ntwo\r\n", "one\ntwo\n"},
+		"MultiLine":   {"a\rb\nc\rd\n", "b\nd\n"},
+		"ColoredBars": {"\x1b[32m 50%\x1b[0m\r\x1b[32m100%\x1b[0m\n", "100%\n"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, StripTerminal(tt.text))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"io"
+	"time"
+)
+
+// Throughput is the rate at which messages were read from a log.
+type Throughput struct {
+	Messages int64
+	Bytes    int64
+	Elapsed  time.Duration
+}
+
+// MessagesPerSecond returns the average rate of messages.
+func (t Throughput) MessagesPerSecond() float64 {
+	if t.Elapsed <= 0 {
+		return 0
+	}
+	return float64(t.Messages) / t.Elapsed.Seconds()
+}
+
+// BytesPerSecond returns the average rate of text.
+func (t Throughput) BytesPerSecond() float64 {
+	if t.Elapsed <= 0 {
+		return 0
+	}
+	return float64(t.Bytes) / t.Elapsed.Seconds()
+}
+
+// MeasureThroughput reads messages as fast as possible until the reader ends
+// or the duration passes, and reports how many were read. A zero duration
+// reads until the end. The duration is checked between messages, so a reader
+// which stalls should be bounded by its own context as well.
+//
+// It's meant for benchmarking the sustained log throughput of a backend, e.g.
+// by following the logs of a container which writes continuously.
+func MeasureThroughput(r LogReader, duration time.Duration) (Throughput, error) {
+	var t Throughput
+	start := time.Now()
+	for {
+		msg, err := r.ReadMessage()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Elapsed = time.Since(start)
+			return t, err
+		}
+		t.Messages++
+		t.Bytes += int64(len(msg.Text))
+		if duration > 0 && time.Since(start) >= duration {
+			break
+		}
+	}
+	t.Elapsed = time.Since(start)
+	return t, nil
+}
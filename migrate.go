@@ -0,0 +1,262 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// BackendLabel is set on containers created through a MigrationRuntime to the
+// name of the backend which runs them.
+const BackendLabel = "beaker.org/backend"
+
+// Backend is a named runtime, e.g. "docker" or "containerd".
+type Backend struct {
+	Name string
+	Runtime
+}
+
+// MigrationRuntime manages containers on two backends while a node migrates
+// from one to the other, e.g. from Docker to containerd, so that workloads
+// already running on the old backend aren't orphaned. Containers on both are
+// listed and found by name, and each is managed through the backend which owns
+// it. New containers are created on the target and labeled with BackendLabel,
+// except those which must join a source container's namespaces or wait for it
+// to exit, which are created on the source beside it.
+//
+// Once Drained reports true the source can be retired.
+type MigrationRuntime struct {
+	source, target Backend
+}
+
+// WithMigration combines a node's source and target backends.
+func WithMigration(source, target Backend) (*MigrationRuntime, error) {
+	for _, b := range []Backend{source, target} {
+		if !tenantName.MatchString(b.Name) {
+			return nil, fmt.Errorf("invalid backend name %q", b.Name)
+		}
+	}
+	if source.Name == target.Name {
+		return nil, fmt.Errorf("source and target are both named %q", source.Name)
+	}
+	return &MigrationRuntime{source: source, target: target}, nil
+}
+
+// Close implements the io.Closer interface. It closes both backends.
+func (r *MigrationRuntime) Close() error {
+	err := r.target.Close()
+	if sourceErr := r.source.Close(); err == nil {
+		err = sourceErr
+	}
+	return err
+}
+
+// Ping verifies that both backends are reachable and healthy.
+func (r *MigrationRuntime) Ping(ctx context.Context) error {
+	for _, b := range []Backend{r.target, r.source} {
+		if err := b.Ping(ctx); err != nil {
+			return fmt.Errorf("%s: %w", b.Name, err)
+		}
+	}
+	return nil
+}
+
+// PullImage pulls an image on the target, where new containers are created.
+func (r *MigrationRuntime) PullImage(ctx context.Context, image *DockerImage, policy PullPolicy, quiet bool) error {
+	return r.target.PullImage(ctx, image, policy, quiet)
+}
+
+// CreateContainer creates a container on the backend which owns the containers
+// it depends on, or the target if it has no dependencies. Images are pulled
+// on the source as needed. A container with an idempotency key which already
+// exists on either backend is returned instead of creating another.
+func (r *MigrationRuntime) CreateContainer(ctx context.Context, opts *ContainerOpts) (Container, error) {
+	if _, ok := opts.Labels[BackendLabel]; ok {
+		return nil, fmt.Errorf("forbidden label: %s", BackendLabel)
+	}
+
+	var backend *Backend
+	for _, name := range opts.dependencies() {
+		owner, _, err := r.find(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("container %s: %w", name, err)
+		}
+		if backend != nil && backend.Name != owner.Name {
+			return nil, errors.New("a container can't depend on containers of both backends")
+		}
+		backend = &owner
+	}
+	if backend == nil {
+		backend = &r.target
+	}
+
+	if opts.IdempotencyKey != "" {
+		// Each backend finds its own containers by key, so only the other
+		// needs to be searched.
+		other := r.source
+		if backend.Name == r.source.Name {
+			other = r.target
+		}
+		existing, err := findByIdempotencyKey(ctx, other, opts.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			configHash, err := opts.Hash()
+			if err != nil {
+				return nil, err
+			}
+			if err := CheckIdempotent(ctx, existing, configHash); err != nil {
+				return nil, err
+			}
+			return existing, nil
+		}
+	}
+
+	if backend.Name == r.source.Name && opts.Image != nil {
+		if err := backend.PullImage(ctx, opts.Image, PullIfMissing, true); err != nil {
+			return nil, fmt.Errorf("pulling image on %s: %w", backend.Name, err)
+		}
+	}
+
+	labeled := *opts
+	labeled.Labels = make(map[string]string, len(opts.Labels)+1)
+	for k, v := range opts.Labels {
+		labeled.Labels[k] = v
+	}
+	labeled.Labels[BackendLabel] = backend.Name
+	return backend.CreateContainer(ctx, &labeled)
+}
+
+// ListContainers lists the containers of both backends by creation time, then
+// name.
+func (r *MigrationRuntime) ListContainers(ctx context.Context) ([]Container, error) {
+	var containers []Container
+	for _, b := range []Backend{r.target, r.source} {
+		list, err := b.ListContainers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name, err)
+		}
+		containers = append(containers, list...)
+	}
+	return SortContainers(ctx, containers)
+}
+
+// Container finds a container on either backend by name. It returns
+// ErrNotFound if neither has it.
+func (r *MigrationRuntime) Container(ctx context.Context, name string) (Container, error) {
+	_, c, err := r.find(ctx, name)
+	return c, err
+}
+
+// Owner returns the name of the backend which runs a container.
+func (r *MigrationRuntime) Owner(ctx context.Context, name string) (string, error) {
+	b, _, err := r.find(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return b.Name, nil
+}
+
+// Drained returns true once the source has no containers left, after which
+// it can be retired.
+func (r *MigrationRuntime) Drained(ctx context.Context) (bool, error) {
+	containers, err := r.source.ListContainers(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", r.source.Name, err)
+	}
+	return len(containers) == 0, nil
+}
+
+// History implements Historian, merging the histories of backends which
+// retain them, most recently removed first.
+func (r *MigrationRuntime) History(ctx context.Context, filter HistoryFilter) ([]HistoryRecord, error) {
+	var records []HistoryRecord
+	supported := false
+	for _, b := range []Backend{r.target, r.source} {
+		historian, ok := b.Runtime.(Historian)
+		if !ok {
+			continue
+		}
+		supported = true
+		list, err := historian.History(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name, err)
+		}
+		records = append(records, list...)
+	}
+	if !supported {
+		return nil, fmt.Errorf("container history is not supported (%w)", ErrNotImplemented)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].RemovedAt.After(records[j].RemovedAt)
+	})
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// find returns a container and the backend which owns it, searching the target
+// and then the source.
+func (r *MigrationRuntime) find(ctx context.Context, name string) (Backend, Container, error) {
+	for _, b := range []Backend{r.target, r.source} {
+		c, err := findContainer(ctx, b.Runtime, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return Backend{}, nil, fmt.Errorf("%s: %w", b.Name, err)
+		}
+
+		// Lookups may return handles to containers which don't exist.
+		if _, err := c.Info(ctx); errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return Backend{}, nil, fmt.Errorf("%s: %w", b.Name, err)
+		}
+		return b, c, nil
+	}
+	return Backend{}, nil, ErrNotFound
+}
+
+// findContainer returns a handle to a container by name, using the runtime's
+// lookup if it has one or searching its containers otherwise. Handles from a
+// lookup may refer to containers which don't exist.
+func findContainer(ctx context.Context, rt Runtime, name string) (Container, error) {
+	if getter, ok := rt.(interface{ Container(id string) Container }); ok {
+		return getter.Container(name), nil
+	}
+	all, err := rt.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range all {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// findByIdempotencyKey returns a runtime's container created with the given
+// idempotency key, or nil if there is none.
+func findByIdempotencyKey(ctx context.Context, rt Runtime, key string) (Container, error) {
+	all, err := rt.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range all {
+		info, err := c.Info(ctx)
+		if errors.Is(err, ErrNotFound) {
+			continue // Removed while listing.
+		} else if err != nil {
+			return nil, err
+		}
+		if info.Labels[IdempotencyKeyLabel] == key {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,81 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationRuntime(t *testing.T) {
+	ctx := context.Background()
+	docker, containerd := &memoryRuntime{}, &memoryRuntime{}
+	_, err := docker.CreateContainer(ctx, &ContainerOpts{
+		Name:   "old",
+		Labels: map[string]string{IdempotencyKeyLabel: "old-job"},
+	})
+	require.NoError(t, err)
+
+	_, err = WithMigration(Backend{"docker", docker}, Backend{"docker", containerd})
+	assert.Error(t, err)
+	rt, err := WithMigration(Backend{"docker", docker}, Backend{"containerd", containerd})
+	require.NoError(t, err)
+
+	// New containers go to the target.
+	c, err := rt.CreateContainer(ctx, &ContainerOpts{Name: "new"})
+	require.NoError(t, err)
+	assert.Equal(t, "new", c.Name())
+	require.Len(t, containerd.containers, 1)
+	assert.Equal(t, "containerd", containerd.containers[0].opts.Labels[BackendLabel])
+
+	// Containers which join a source container's namespaces go beside it.
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{Name: "sidecar", NetworkFrom: "old"})
+	require.NoError(t, err)
+	require.Len(t, docker.containers, 2)
+	assert.Equal(t, "docker", docker.containers[1].opts.Labels[BackendLabel])
+
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{NetworkFrom: "old", PIDFrom: "new"})
+	assert.Error(t, err)
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{NetworkFrom: "old", IPCMode: "container:new"})
+	assert.Error(t, err)
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{IPCMode: "container:missing"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{NetworkFrom: "missing"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{Labels: map[string]string{BackendLabel: "docker"}})
+	assert.Error(t, err)
+
+	// Idempotency keys are checked against the other backend too.
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{IdempotencyKey: "old-job"})
+	assert.Error(t, err)
+
+	owner, err := rt.Owner(ctx, "old")
+	require.NoError(t, err)
+	assert.Equal(t, "docker", owner)
+	owner, err = rt.Owner(ctx, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "containerd", owner)
+	_, err = rt.Container(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	all, err := rt.ListContainers(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, c := range all {
+		names = append(names, c.Name())
+	}
+	assert.Equal(t, []string{"new", "old", "sidecar"}, names)
+
+	drained, err := rt.Drained(ctx)
+	require.NoError(t, err)
+	assert.False(t, drained)
+	docker.containers = nil
+	drained, err = rt.Drained(ctx)
+	require.NoError(t, err)
+	assert.True(t, drained)
+
+	require.NoError(t, rt.Close())
+	assert.True(t, docker.closed)
+	assert.True(t, containerd.closed)
+}
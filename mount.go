@@ -0,0 +1,192 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultDeniedPaths lists sensitive host paths which should never be mounted
+// into a container. The root directory is denied too, since it contains them.
+var DefaultDeniedPaths = []string{"/etc", "/var/run/docker.sock"}
+
+// MountPolicy describes rules for host paths mounted into containers.
+type MountPolicy struct {
+	// RequireExisting rejects mounts whose host path does not exist.
+	RequireExisting bool
+
+	// CreateMissing creates missing host paths as directories. It takes
+	// precedence over RequireExisting.
+	CreateMissing bool
+
+	// DeniedPaths lists host paths which may not be mounted. A mount is
+	// rejected if its host path is, contains, or lies beneath any denied path.
+	// Denying "/" therefore denies every mount.
+	DeniedPaths []string
+}
+
+// Validate checks mounts against the policy and returns a copy of them with
+// each host path normalized to a clean, absolute path.
+func (p *MountPolicy) Validate(mounts []Mount) ([]Mount, error) {
+	if len(mounts) == 0 {
+		return mounts, nil
+	}
+
+	denied, err := p.deniedPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Mount, len(mounts))
+	for i, m := range mounts {
+		source, err := checkHostPath(m.HostPath, denied)
+		if err != nil {
+			return nil, fmt.Errorf("mount %q: %w", m.HostPath, err)
+		}
+
+		if _, err := os.Stat(source); os.IsNotExist(err) {
+			switch {
+			case p.CreateMissing:
+				if err := os.MkdirAll(source, 0755); err != nil {
+					return nil, fmt.Errorf("mount %q: %w", m.HostPath, err)
+				}
+			case p.RequireExisting:
+				return nil, fmt.Errorf("mount %q: path does not exist", m.HostPath)
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("mount %q: %w", m.HostPath, err)
+		}
+
+		result[i] = m
+		result[i].HostPath = source
+	}
+	return result, nil
+}
+
+// CheckHostPath normalizes a host path as Validate does and checks it against
+// the policy's denied paths. Unlike Validate, it neither requires nor creates
+// the path. It suits files mounted on a container's behalf, such as sockets.
+func (p *MountPolicy) CheckHostPath(path string) (string, error) {
+	denied, err := p.deniedPaths()
+	if err != nil {
+		return "", err
+	}
+	source, err := checkHostPath(path, denied)
+	if err != nil {
+		return "", fmt.Errorf("mount %q: %w", path, err)
+	}
+	return source, nil
+}
+
+// deniedPaths resolves the policy's denied paths like mounts, so that aliases
+// such as /var/run -> /run still match.
+func (p *MountPolicy) deniedPaths() ([]string, error) {
+	denied := make([]string, len(p.DeniedPaths))
+	for i, path := range p.DeniedPaths {
+		resolved, err := normalizeHostPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("denied path %q: %w", path, err)
+		}
+		denied[i] = resolved
+	}
+	return denied, nil
+}
+
+// checkHostPath normalizes a host path and rejects it if it's, contains, or
+// lies beneath a denied path.
+func checkHostPath(path string, denied []string) (string, error) {
+	source, err := normalizeHostPath(path)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range denied {
+		if containsPath(source, d) || containsPath(d, source) {
+			return "", fmt.Errorf("host path %s is not allowed", d)
+		}
+	}
+	return source, nil
+}
+
+// normalizeHostPath converts a path to a clean absolute path. Symbolic links
+// are resolved so they can't be used to evade a denylist. If the path doesn't
+// exist, links are resolved in its nearest existing ancestor.
+func normalizeHostPath(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("host path is empty")
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("translating to absolute path: %w", err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if os.IsNotExist(err) {
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return abs, nil
+		}
+		if parent, err = normalizeHostPath(parent); err != nil {
+			return "", err
+		}
+		return filepath.Join(parent, filepath.Base(abs)), nil
+	} else if err != nil {
+		return "", fmt.Errorf("resolving symbolic links: %w", err)
+	}
+	return resolved, nil
+}
+
+// containsPath returns true if child is equal to or beneath parent.
+func containsPath(parent, child string) bool {
+	parent, child = filepath.Clean(parent), filepath.Clean(child)
+	if parent == child || parent == string(filepath.Separator) {
+		return true
+	}
+	return strings.HasPrefix(child, parent+string(filepath.Separator))
+}
+
+// WithMountPolicy wraps a runtime so that every container's mounts are
+// validated and normalized by policy before reaching the underlying runtime.
+// Host paths mounted on a container's behalf, such as its CoreDumpDir,
+// SSHAgent, and X11 authority file, are checked too.
+func WithMountPolicy(rt Runtime, policy MountPolicy) Runtime {
+	return &mountPolicyRuntime{Runtime: rt, policy: policy}
+}
+
+type mountPolicyRuntime struct {
+	Runtime
+	policy MountPolicy
+}
+
+func (r *mountPolicyRuntime) CreateContainer(ctx context.Context, opts *ContainerOpts) (Container, error) {
+	mounts, err := r.policy.Validate(opts.Mounts)
+	if err != nil {
+		return nil, err
+	}
+
+	validated := *opts
+	validated.Mounts = mounts
+	if opts.CoreDumpDir != "" {
+		// Core dumps are written through a read-write bind mount.
+		dumps, err := r.policy.Validate([]Mount{{HostPath: opts.CoreDumpDir, ContainerPath: "/cores"}})
+		if err != nil {
+			return nil, fmt.Errorf("core dump directory: %w", err)
+		}
+		validated.CoreDumpDir = dumps[0].HostPath
+	}
+	if opts.SSHAgent != "" {
+		if validated.SSHAgent, err = r.policy.CheckHostPath(opts.SSHAgent); err != nil {
+			return nil, fmt.Errorf("SSH agent: %w", err)
+		}
+	}
+	if opts.X11 != nil {
+		for _, m := range opts.X11.Mounts() {
+			if _, err := r.policy.CheckHostPath(m.HostPath); err != nil {
+				return nil, fmt.Errorf("X display: %w", err)
+			}
+		}
+	}
+	return r.Runtime.CreateContainer(ctx, &validated)
+}
@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountPolicy(t *testing.T) {
+	// Resolve the temporary directory in case it's a symbolic link itself.
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	t.Run("Empty", func(t *testing.T) {
+		policy := MountPolicy{DeniedPaths: DefaultDeniedPaths}
+		mounts, err := policy.Validate(nil)
+		require.NoError(t, err)
+		assert.Empty(t, mounts)
+	})
+
+	t.Run("Normalize", func(t *testing.T) {
+		policy := MountPolicy{DeniedPaths: DefaultDeniedPaths}
+		mounts, err := policy.Validate([]Mount{{HostPath: dir + "/a/../b/", ContainerPath: "/b"}})
+		require.NoError(t, err)
+		assert.Equal(t, []Mount{{HostPath: filepath.Join(dir, "b"), ContainerPath: "/b"}}, mounts)
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		policy := MountPolicy{DeniedPaths: DefaultDeniedPaths}
+		for _, path := range []string{
+			"/", "/etc", "/etc/", "/etc/shadow", "/etc/ssh/", "/tmp/../etc/passwd",
+			"/var/run", "/var/run/docker.sock",
+		} {
+			_, err := policy.Validate([]Mount{{HostPath: path, ContainerPath: "/mnt"}})
+			assert.Error(t, err, path)
+		}
+	})
+
+	t.Run("DeniedSymlink", func(t *testing.T) {
+		link := filepath.Join(dir, "link")
+		require.NoError(t, os.Symlink("/etc", link))
+		policy := MountPolicy{DeniedPaths: DefaultDeniedPaths}
+		_, err := policy.Validate([]Mount{{HostPath: link, ContainerPath: "/mnt"}})
+		assert.EqualError(t, err, `mount "`+link+`": host path /etc is not allowed`)
+	})
+
+	t.Run("DeniedRoot", func(t *testing.T) {
+		policy := MountPolicy{DeniedPaths: []string{"/"}}
+		_, err := policy.Validate([]Mount{{HostPath: dir, ContainerPath: "/mnt"}})
+		assert.Error(t, err)
+	})
+
+	t.Run("RequireExisting", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing")
+		policy := MountPolicy{RequireExisting: true}
+		_, err := policy.Validate([]Mount{{HostPath: missing, ContainerPath: "/mnt"}})
+		assert.EqualError(t, err, `mount "`+missing+`": path does not exist`)
+	})
+
+	t.Run("CreateMissing", func(t *testing.T) {
+		missing := filepath.Join(dir, "created", "nested")
+		policy := MountPolicy{RequireExisting: true, CreateMissing: true}
+		_, err := policy.Validate([]Mount{{HostPath: missing, ContainerPath: "/mnt"}})
+		require.NoError(t, err)
+		assert.DirExists(t, missing)
+	})
+}
+
+func TestMountPolicyRuntime(t *testing.T) {
+	ctx := context.Background()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+
+	mem := &memoryRuntime{}
+	rt := WithMountPolicy(mem, MountPolicy{DeniedPaths: DefaultDeniedPaths})
+
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{CoreDumpDir: "/etc"})
+	assert.Error(t, err)
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{CoreDumpDir: "/etc/cron.d"})
+	assert.Error(t, err)
+	assert.Empty(t, mem.containers)
+
+	// Sockets such as Docker's can't be forwarded as SSH agents.
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{SSHAgent: "/var/run/docker.sock", Interactive: true})
+	assert.Error(t, err)
+	assert.Empty(t, mem.containers)
+
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{X11: &X11Display{Display: ":0", XAuthority: "/etc/shadow"}})
+	assert.Error(t, err)
+	assert.Empty(t, mem.containers)
+
+	_, err = rt.CreateContainer(ctx, &ContainerOpts{CoreDumpDir: dir + "/cores/../dumps"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "dumps"), mem.containers[0].opts.CoreDumpDir)
+}
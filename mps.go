@@ -0,0 +1,222 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GPUSharing selects how a container shares its GPUs with other containers
+// assigned the same GPUs.
+type GPUSharing string
+
+const (
+	// GPUSharingDefault leaves sharing to the driver, which time-slices GPUs
+	// between the containers using them.
+	GPUSharingDefault GPUSharing = ""
+
+	// GPUSharingMPS runs the container's CUDA work through an NVIDIA
+	// Multi-Process Service daemon for each of its GPUs, so that small jobs
+	// such as inference servers run on a GPU concurrently rather than taking
+	// turns. Requires a runtime with MPS enabled, and the host's IPC namespace,
+	// which is used if the container doesn't set an IPC mode.
+	GPUSharingMPS GPUSharing = "mps"
+)
+
+// GPUSharingLabel is set on containers which share GPUs through MPS, so that
+// their GPUs can be released when they're removed.
+const GPUSharingLabel = "beaker.org/gpu-sharing"
+
+// Environment variables which configure MPS clients and daemons.
+const (
+	MPSPipeDirectoryEnv       = "CUDA_MPS_PIPE_DIRECTORY"
+	MPSLogDirectoryEnv        = "CUDA_MPS_LOG_DIRECTORY"
+	MPSActiveThreadPercentEnv = "CUDA_MPS_ACTIVE_THREAD_PERCENTAGE"
+)
+
+// MPSContainerPipeDir is where a GPU's MPS pipe directory is mounted in
+// containers which share it.
+const MPSContainerPipeDir = "/tmp/nvidia-mps"
+
+// ValidateGPUSharing checks that the container's GPU sharing options are
+// consistent.
+func (o *ContainerOpts) ValidateGPUSharing() error {
+	switch o.GPUSharing {
+	case GPUSharingDefault:
+		if o.MPSThreadPercent != 0 {
+			return errors.New("an MPS thread percentage requires MPS sharing")
+		}
+		return nil
+	case GPUSharingMPS:
+	default:
+		return fmt.Errorf("%q is not a valid GPU sharing mode", o.GPUSharing)
+	}
+
+	if len(o.GPUs) != 1 {
+		// Each GPU has its own daemon, and a client can connect to only one.
+		return errors.New("MPS sharing requires exactly one GPU")
+	}
+	if o.IPCMode != "" && o.IPCMode != IPCHost {
+		return fmt.Errorf("MPS sharing requires the host's IPC namespace, not %q", o.IPCMode)
+	}
+	if o.MPSThreadPercent < 0 || o.MPSThreadPercent > 100 {
+		return fmt.Errorf("invalid MPS thread percentage %d", o.MPSThreadPercent)
+	}
+	for _, name := range []string{MPSPipeDirectoryEnv, MPSLogDirectoryEnv, MPSActiveThreadPercentEnv} {
+		if _, ok := o.Env[name]; ok {
+			return fmt.Errorf("MPS sharing conflicts with environment variable %s", name)
+		}
+	}
+	return nil
+}
+
+// MPS manages NVIDIA Multi-Process Service control daemons on a node, one for
+// each GPU in use by containers which share it. A daemon is started when the
+// first such container is created and stopped when the last is removed. An MPS
+// is safe for concurrent use.
+//
+// Daemons outlive the process which started them, so a restarted process
+// reuses any daemon it finds running. Reference counts are kept in memory, so
+// a restarted process must rebuild them with Restore before containers are
+// created or removed.
+type MPS struct {
+	dir string
+
+	// run executes an MPS control command, replaced in tests.
+	run func(ctx context.Context, env []string, stdin string, name string, args ...string) error
+
+	mu    sync.Mutex
+	users map[string]int // By GPU UUID
+}
+
+// NewMPS creates a manager which keeps each daemon's pipes and logs under a
+// host directory, e.g. "/var/run/beaker/mps".
+func NewMPS(dir string) *MPS {
+	return &MPS{dir: dir, run: runCommand, users: make(map[string]int)}
+}
+
+// PipeDir returns the host directory of a GPU's daemon pipes.
+func (m *MPS) PipeDir(gpu string) string {
+	return filepath.Join(m.dir, gpu, "pipe")
+}
+
+// Acquire starts a GPU's daemon if it isn't already running and counts a
+// container as using it. Each call must be matched by a call to Release.
+func (m *MPS) Acquire(ctx context.Context, gpu string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.users[gpu] == 0 && !m.running(gpu) {
+		pipeDir, logDir := m.PipeDir(gpu), filepath.Join(m.dir, gpu, "log")
+		for _, dir := range []string{pipeDir, logDir} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("starting MPS daemon for %s: %w", gpu, err)
+			}
+		}
+		env := []string{
+			"CUDA_VISIBLE_DEVICES=" + gpu,
+			MPSPipeDirectoryEnv + "=" + pipeDir,
+			MPSLogDirectoryEnv + "=" + logDir,
+		}
+		if err := m.run(ctx, env, "", "nvidia-cuda-mps-control", "-d"); err != nil {
+			return fmt.Errorf("starting MPS daemon for %s: %w", gpu, err)
+		}
+	}
+	m.users[gpu]++
+	return nil
+}
+
+// Release counts a container as no longer using a GPU, stopping the GPU's
+// daemon once no containers use it.
+func (m *MPS) Release(ctx context.Context, gpu string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.users[gpu] == 0 {
+		return nil
+	}
+	if m.users[gpu]--; m.users[gpu] != 0 {
+		return nil
+	}
+	delete(m.users, gpu)
+
+	env := []string{MPSPipeDirectoryEnv + "=" + m.PipeDir(gpu)}
+	if err := m.run(ctx, env, "quit\n", "nvidia-cuda-mps-control"); err != nil {
+		return fmt.Errorf("stopping MPS daemon for %s: %w", gpu, err)
+	}
+	return nil
+}
+
+// Restore rebuilds the reference counts after a restart from the GPU of each
+// existing container which shares one through MPS. Daemons left running for
+// GPUs which no longer have any such containers are stopped.
+func (m *MPS) Restore(ctx context.Context, gpus []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users = make(map[string]int, len(gpus))
+	for _, gpu := range gpus {
+		m.users[gpu]++
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("restoring MPS daemons: %w", err)
+	}
+	for _, entry := range entries {
+		gpu := entry.Name()
+		if !entry.IsDir() || m.users[gpu] != 0 || !m.running(gpu) {
+			continue
+		}
+		env := []string{MPSPipeDirectoryEnv + "=" + m.PipeDir(gpu)}
+		if err := m.run(ctx, env, "quit\n", "nvidia-cuda-mps-control"); err != nil {
+			return fmt.Errorf("stopping MPS daemon for %s: %w", gpu, err)
+		}
+	}
+	return nil
+}
+
+// Users returns the number of containers using a GPU's daemon.
+func (m *MPS) Users(gpu string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users[gpu]
+}
+
+// running returns true if a GPU's daemon appears to be running, i.e. its
+// control pipe exists.
+func (m *MPS) running(gpu string) bool {
+	_, err := os.Stat(filepath.Join(m.PipeDir(gpu), "control"))
+	return err == nil
+}
+
+// ContainerEnv returns the environment which connects a container to its
+// GPU's daemon, as mounted at MPSContainerPipeDir.
+func (m *MPS) ContainerEnv(opts *ContainerOpts) map[string]string {
+	env := map[string]string{MPSPipeDirectoryEnv: MPSContainerPipeDir}
+	if opts.MPSThreadPercent != 0 {
+		env[MPSActiveThreadPercentEnv] = strconv.Itoa(opts.MPSThreadPercent)
+	}
+	return env
+}
+
+func runCommand(ctx context.Context, env []string, stdin string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
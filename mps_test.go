@@ -0,0 +1,135 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGPUSharing(t *testing.T) {
+	valid := []ContainerOpts{
+		{},
+		{GPUs: []string{"0", "1"}},
+		{GPUs: []string{"GPU-1"}, GPUSharing: GPUSharingMPS},
+		{GPUs: []string{"GPU-1"}, GPUSharing: GPUSharingMPS, IPCMode: IPCHost, MPSThreadPercent: 50},
+	}
+	for _, opts := range valid {
+		assert.NoError(t, opts.ValidateGPUSharing(), "%+v", opts)
+	}
+
+	invalid := []ContainerOpts{
+		{MPSThreadPercent: 50},
+		{GPUs: []string{"GPU-1"}, GPUSharing: "vgpu"},
+		{GPUSharing: GPUSharingMPS},
+		{GPUs: []string{"GPU-1", "GPU-2"}, GPUSharing: GPUSharingMPS},
+		{GPUs: []string{"GPU-1"}, GPUSharing: GPUSharingMPS, IPCMode: "private"},
+		{GPUs: []string{"GPU-1"}, GPUSharing: GPUSharingMPS, MPSThreadPercent: 101},
+		{GPUs: []string{"GPU-1"}, GPUSharing: GPUSharingMPS, Env: map[string]string{MPSPipeDirectoryEnv: "/tmp"}},
+	}
+	for _, opts := range invalid {
+		assert.Error(t, opts.ValidateGPUSharing(), "%+v", opts)
+	}
+}
+
+// mpsCommands records the MPS control commands run, followed by their input.
+type mpsCommands struct {
+	calls []string
+	err   error
+}
+
+func (c *mpsCommands) run(ctx context.Context, env []string, stdin string, name string, args ...string) error {
+	call := append([]string{name}, args...)
+	if stdin != "" {
+		call = append(call, strings.TrimSpace(stdin))
+	}
+	c.calls = append(c.calls, strings.Join(call, " "))
+	return c.err
+}
+
+func TestMPS(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "mps")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	commands := &mpsCommands{}
+	m := NewMPS(dir)
+	m.run = commands.run
+
+	t.Run("Shared", func(t *testing.T) {
+		require.NoError(t, m.Acquire(ctx, "GPU-1"))
+		require.NoError(t, m.Acquire(ctx, "GPU-1"))
+		assert.Equal(t, 2, m.Users("GPU-1"))
+		assert.Equal(t, []string{"nvidia-cuda-mps-control -d"}, commands.calls)
+		assert.DirExists(t, m.PipeDir("GPU-1"))
+
+		require.NoError(t, m.Release(ctx, "GPU-1"))
+		assert.Len(t, commands.calls, 1)
+		require.NoError(t, m.Release(ctx, "GPU-1"))
+		assert.Equal(t, []string{"nvidia-cuda-mps-control -d", "nvidia-cuda-mps-control quit"}, commands.calls)
+		assert.Equal(t, 0, m.Users("GPU-1"))
+
+		// Extra releases are ignored.
+		require.NoError(t, m.Release(ctx, "GPU-1"))
+		assert.Len(t, commands.calls, 2)
+	})
+
+	t.Run("AlreadyRunning", func(t *testing.T) {
+		commands.calls = nil
+		require.NoError(t, os.MkdirAll(m.PipeDir("GPU-2"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(m.PipeDir("GPU-2"), "control"), nil, 0644))
+
+		require.NoError(t, m.Acquire(ctx, "GPU-2"))
+		assert.Empty(t, commands.calls)
+		assert.Equal(t, 1, m.Users("GPU-2"))
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		// Simulate a restart with daemons left running for GPU-2, which is
+		// still in use, and GPU-4, which isn't.
+		commands.calls = nil
+		require.NoError(t, os.MkdirAll(m.PipeDir("GPU-4"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(m.PipeDir("GPU-4"), "control"), nil, 0644))
+		restarted := NewMPS(dir)
+		restarted.run = commands.run
+
+		require.NoError(t, restarted.Restore(ctx, []string{"GPU-2", "GPU-2"}))
+		assert.Equal(t, 2, restarted.Users("GPU-2"))
+		assert.Equal(t, 0, restarted.Users("GPU-4"))
+		assert.Equal(t, []string{"nvidia-cuda-mps-control quit"}, commands.calls)
+
+		// The daemon is stopped once the last container from before the
+		// restart is released.
+		commands.calls = nil
+		require.NoError(t, restarted.Release(ctx, "GPU-2"))
+		assert.Empty(t, commands.calls)
+		require.NoError(t, restarted.Release(ctx, "GPU-2"))
+		assert.Equal(t, []string{"nvidia-cuda-mps-control quit"}, commands.calls)
+	})
+
+	t.Run("StartFails", func(t *testing.T) {
+		commands.calls, commands.err = nil, errors.New("no such device")
+		defer func() { commands.err = nil }()
+
+		assert.Error(t, m.Acquire(ctx, "GPU-3"))
+		assert.Equal(t, 0, m.Users("GPU-3"))
+	})
+}
+
+func TestMPSContainerEnv(t *testing.T) {
+	m := NewMPS("/var/run/mps")
+	assert.Equal(t, map[string]string{
+		MPSPipeDirectoryEnv: MPSContainerPipeDir,
+	}, m.ContainerEnv(&ContainerOpts{}))
+	assert.Equal(t, map[string]string{
+		MPSPipeDirectoryEnv:       MPSContainerPipeDir,
+		MPSActiveThreadPercentEnv: "25",
+	}, m.ContainerEnv(&ContainerOpts{MPSThreadPercent: 25}))
+}
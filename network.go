@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// NetworkCreator is implemented by runtimes which can create user-defined
+// networks for containers to join with ContainerOpts.Network.
+type NetworkCreator interface {
+	// CreateNetwork creates a network. It fails if the name is taken.
+	CreateNetwork(ctx context.Context, opts *NetworkOpts) error
+
+	// RemoveNetwork deletes a network. It returns ErrNotFound if the network
+	// doesn't exist.
+	RemoveNetwork(ctx context.Context, name string) error
+}
+
+// NetworkOpts configures a user-defined network.
+type NetworkOpts struct {
+	// (required) Name identifies the network.
+	Name string
+
+	// (optional) EnableIPv6 makes the network dual-stack, so that containers
+	// are assigned an IPv6 address in addition to an IPv4 one.
+	EnableIPv6 bool
+
+	// (optional) Subnets lists the network's address ranges in CIDR notation,
+	// at most one per IP family. IPv6 subnets require EnableIPv6. Subnets are
+	// chosen by the runtime if empty, but static container addresses are only
+	// assignable within explicit subnets.
+	Subnets []string
+
+	// (optional) Labels are applied to the network.
+	Labels map[string]string
+}
+
+// Validate checks that network options are well formed and consistent.
+func (o *NetworkOpts) Validate() error {
+	if o.Name == "" {
+		return errors.New("networks require a name")
+	}
+	var v4, v6 int
+	for _, subnet := range o.Subnets {
+		ip, _, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid subnet", subnet)
+		}
+		if ip.To4() != nil {
+			v4++
+		} else {
+			v6++
+		}
+	}
+	if v6 != 0 && !o.EnableIPv6 {
+		return errors.New("IPv6 subnets require EnableIPv6")
+	}
+	if v4 > 1 || v6 > 1 {
+		return errors.New("networks have at most one subnet per IP family")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNetworkOpts(t *testing.T) {
+	tests := map[string]struct {
+		Opts  NetworkOpts
+		Valid bool
+	}{
+		"Default":       {NetworkOpts{Name: "net"}, true},
+		"NoName":        {NetworkOpts{}, false},
+		"IPv4":          {NetworkOpts{Name: "net", Subnets: []string{"10.1.0.0/16"}}, true},
+		"IPv6":          {NetworkOpts{Name: "net", EnableIPv6: true, Subnets: []string{"fd00:1::/64"}}, true},
+		"DualStack":     {NetworkOpts{Name: "net", EnableIPv6: true, Subnets: []string{"10.1.0.0/16", "fd00:1::/64"}}, true},
+		"IPv6Disabled":  {NetworkOpts{Name: "net", Subnets: []string{"fd00:1::/64"}}, false},
+		"InvalidSubnet": {NetworkOpts{Name: "net", Subnets: []string{"10.1.0.0"}}, false},
+		"TwoIPv4":       {NetworkOpts{Name: "net", Subnets: []string{"10.1.0.0/16", "10.2.0.0/16"}}, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Valid, test.Opts.Validate() == nil)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package runtime
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the user ID which owns a file.
+func fileOwner(info os.FileInfo) (int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Uid), true
+}
@@ -0,0 +1,380 @@
+package runtime
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beaker/runtime/clock"
+)
+
+// Execer is implemented by containers which can run a command inside
+// themselves to completion.
+type Execer interface {
+	// ExecSync runs a command in the container and waits up to the timeout for
+	// it to exit. A nonzero exit code is not an error.
+	ExecSync(ctx context.Context, cmd []string, timeout time.Duration) (*ExecResult, error)
+}
+
+// ExecResult is the outcome of a command run with Execer.
+type ExecResult struct {
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+}
+
+// ProbeKind distinguishes how a probe's result should be interpreted.
+type ProbeKind string
+
+const (
+	// ProbeReadiness checks whether a container is ready to serve. Containers
+	// are assumed not ready until the probe succeeds.
+	ProbeReadiness ProbeKind = "readiness"
+
+	// ProbeLiveness checks whether a container is still functioning. Containers
+	// are assumed alive until the probe fails.
+	ProbeLiveness ProbeKind = "liveness"
+)
+
+// Probe defaults, matching Kubernetes.
+const (
+	defaultProbePeriod           = 10 * time.Second
+	defaultProbeTimeout          = time.Second
+	defaultProbeSuccessThreshold = 1
+	defaultProbeFailureThreshold = 3
+)
+
+// Probe periodically checks a container's health. Exactly one of Exec, TCPPort,
+// or HTTPGet must be set.
+type Probe struct {
+	Kind ProbeKind
+
+	// Exec runs a command in the container, which is healthy if the command
+	// exits with code 0. The container must implement Execer.
+	Exec []string
+
+	// TCPPort checks that a port on the container's IP address accepts
+	// connections.
+	TCPPort int
+
+	// HTTPGet checks that an HTTP endpoint on the container's IP address
+	// responds with a status from 200 through 399.
+	HTTPGet *HTTPGetProbe
+
+	// (optional) InitialDelay is the time to wait after a probe is added before
+	// the container is first checked.
+	InitialDelay time.Duration
+
+	// (optional) Period is the time between checks. Defaults to 10 seconds.
+	Period time.Duration
+
+	// (optional) Timeout bounds each check. Defaults to 1 second.
+	Timeout time.Duration
+
+	// (optional) SuccessThreshold is the number of consecutive successes
+	// required to become healthy. Defaults to 1.
+	SuccessThreshold int
+
+	// (optional) FailureThreshold is the number of consecutive failures
+	// required to become unhealthy. Defaults to 3.
+	FailureThreshold int
+}
+
+// HTTPGetProbe describes an HTTP request made by a probe.
+type HTTPGetProbe struct {
+	Port int
+
+	// (optional) Path of the request. Defaults to "/".
+	Path string
+
+	// (optional) Scheme is "http" or "https". Defaults to "http". Certificates
+	// aren't verified since containers are addressed by IP.
+	Scheme string
+}
+
+// Validate checks that a probe is well formed.
+func (p *Probe) Validate() error {
+	if p.Kind != ProbeReadiness && p.Kind != ProbeLiveness {
+		return fmt.Errorf("invalid probe kind %q", p.Kind)
+	}
+
+	handlers := 0
+	if len(p.Exec) != 0 {
+		handlers++
+	}
+	if p.TCPPort != 0 {
+		handlers++
+		if err := validatePort(p.TCPPort); err != nil {
+			return err
+		}
+	}
+	if p.HTTPGet != nil {
+		handlers++
+		if err := validatePort(p.HTTPGet.Port); err != nil {
+			return err
+		}
+		if s := p.HTTPGet.Scheme; s != "" && s != "http" && s != "https" {
+			return fmt.Errorf("invalid probe scheme %q", s)
+		}
+	}
+	if handlers != 1 {
+		return errors.New("a probe must have exactly one of exec, TCP port, or HTTP get")
+	}
+
+	if p.InitialDelay < 0 || p.Period < 0 || p.Timeout < 0 {
+		return errors.New("probe durations can't be negative")
+	}
+	if p.SuccessThreshold < 0 || p.FailureThreshold < 0 {
+		return errors.New("probe thresholds can't be negative")
+	}
+	return nil
+}
+
+func validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid probe port %d", port)
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the probe with unset options filled in.
+func (p Probe) withDefaults() Probe {
+	if p.Period == 0 {
+		p.Period = defaultProbePeriod
+	}
+	if p.Timeout == 0 {
+		p.Timeout = defaultProbeTimeout
+	}
+	if p.SuccessThreshold == 0 {
+		p.SuccessThreshold = defaultProbeSuccessThreshold
+	}
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = defaultProbeFailureThreshold
+	}
+	return p
+}
+
+// HealthEvent reports that a container's health changed.
+type HealthEvent struct {
+	// Container is the name of the probed container.
+	Container string
+
+	Kind    ProbeKind
+	Healthy bool
+	Time    time.Time
+
+	// Message describes the result of the check which caused the change, e.g.
+	// "exit code 1: connection refused".
+	Message string
+}
+
+// Prober runs probes against containers, for runtimes without native health
+// checks such as CRI. Each probe runs in the background until its container
+// exits or is removed from the prober.
+type Prober struct {
+	onChange func(HealthEvent)
+	client   *http.Client
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewProber creates a prober which reports health transitions to a callback.
+// The callback may be invoked concurrently for different probes.
+func NewProber(onChange func(HealthEvent)) *Prober {
+	return &Prober{
+		onChange: onChange,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+				DisableKeepAlives: true,
+			},
+		},
+		cancels: make(map[string]context.CancelFunc),
+		clock:   clock.System,
+	}
+}
+
+// SetClock replaces the clock which paces checks and timestamps events, e.g.
+// with a fake clock in tests. It must be called before probes are added.
+func (p *Prober) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// Add starts probing a container, replacing any probes it already has.
+func (p *Prober) Add(c Container, probes ...Probe) error {
+	for i := range probes {
+		if err := probes[i].Validate(); err != nil {
+			return err
+		}
+		if _, ok := c.(Execer); len(probes[i].Exec) != 0 && !ok {
+			return fmt.Errorf("container can't run exec probes (%w)", ErrNotImplemented)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if prev, ok := p.cancels[c.Name()]; ok {
+		prev()
+	}
+	p.cancels[c.Name()] = cancel
+
+	for _, probe := range probes {
+		p.wg.Add(1)
+		go func(probe Probe) {
+			defer p.wg.Done()
+			p.run(ctx, c, probe.withDefaults())
+		}(probe)
+	}
+	return nil
+}
+
+// Remove stops probing a container.
+func (p *Prober) Remove(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.cancels[name]; ok {
+		cancel()
+		delete(p.cancels, name)
+	}
+}
+
+// Close stops all probes and waits for them to finish.
+func (p *Prober) Close() error {
+	p.mu.Lock()
+	for name, cancel := range p.cancels {
+		cancel()
+		delete(p.cancels, name)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return nil
+}
+
+// run checks a container until it exits or the context ends.
+func (p *Prober) run(ctx context.Context, c Container, probe Probe) {
+	delay := p.clock.NewTimer(probe.InitialDelay)
+	select {
+	case <-ctx.Done():
+		delay.Stop()
+		return
+	case <-delay.C():
+	}
+
+	ticker := p.clock.NewTicker(probe.Period)
+	defer ticker.Stop()
+
+	healthy := probe.Kind == ProbeLiveness
+	var successes, failures int
+	for {
+		info, err := c.Info(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrNotFound):
+			return
+		case err == nil && info.Status == StatusExited:
+			return
+		case err == nil && info.Status == StatusRunning:
+			message, ok := p.check(ctx, c, info, probe)
+			if ctx.Err() != nil {
+				return
+			}
+			if ok {
+				successes, failures = successes+1, 0
+			} else {
+				successes, failures = 0, failures+1
+			}
+
+			if (!healthy && successes >= probe.SuccessThreshold) ||
+				(healthy && failures >= probe.FailureThreshold) {
+				healthy = !healthy
+				p.onChange(HealthEvent{
+					Container: c.Name(),
+					Kind:      probe.Kind,
+					Healthy:   healthy,
+					Time:      p.clock.Now(),
+					Message:   message,
+				})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// check runs a probe once, returning a description of the result and whether
+// it succeeded.
+func (p *Prober) check(ctx context.Context, c Container, info *ContainerInfo, probe Probe) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, probe.Timeout)
+	defer cancel()
+
+	switch {
+	case len(probe.Exec) != 0:
+		result, err := c.(Execer).ExecSync(ctx, probe.Exec, probe.Timeout)
+		if err != nil {
+			return err.Error(), false
+		}
+		output := strings.TrimSpace(string(result.Stdout) + string(result.Stderr))
+		if result.ExitCode != 0 {
+			return fmt.Sprintf("exit code %d: %s", result.ExitCode, output), false
+		}
+		return output, true
+
+	case probe.TCPPort != 0:
+		if len(info.IPAddresses) == 0 {
+			return "container has no IP address", false
+		}
+		addr := net.JoinHostPort(info.IPAddresses[0], strconv.Itoa(probe.TCPPort))
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err.Error(), false
+		}
+		conn.Close()
+		return "connected to " + addr, true
+
+	default:
+		if len(info.IPAddresses) == 0 {
+			return "container has no IP address", false
+		}
+		scheme, path := probe.HTTPGet.Scheme, probe.HTTPGet.Path
+		if scheme == "" {
+			scheme = "http"
+		}
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		url := scheme + "://" + net.JoinHostPort(info.IPAddresses[0], strconv.Itoa(probe.HTTPGet.Port)) + path
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err.Error(), false
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return err.Error(), false
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Sprintf("GET %s: %s", url, resp.Status), false
+		}
+		return fmt.Sprintf("GET %s: %s", url, resp.Status), true
+	}
+}
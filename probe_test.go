@@ -0,0 +1,180 @@
+package runtime
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// probedContainer is a running container whose exec probes exit with exitCode.
+type probedContainer struct {
+	fakeContainer
+
+	mu       sync.Mutex
+	exitCode int
+}
+
+func (c *probedContainer) Info(ctx context.Context) (*ContainerInfo, error) {
+	info, err := c.fakeContainer.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info.IPAddresses = []string{"127.0.0.1"}
+	return info, nil
+}
+
+func (c *probedContainer) ExecSync(
+	ctx context.Context,
+	cmd []string,
+	timeout time.Duration,
+) (*ExecResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &ExecResult{ExitCode: c.exitCode}, nil
+}
+
+func (c *probedContainer) setExitCode(code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exitCode = code
+}
+
+func TestProbeValidate(t *testing.T) {
+	tests := map[string]struct {
+		probe Probe
+		valid bool
+	}{
+		"Exec":          {Probe{Kind: ProbeLiveness, Exec: []string{"true"}}, true},
+		"TCP":           {Probe{Kind: ProbeReadiness, TCPPort: 8080}, true},
+		"HTTP":          {Probe{Kind: ProbeReadiness, HTTPGet: &HTTPGetProbe{Port: 80, Scheme: "https"}}, true},
+		"NoKind":        {Probe{Exec: []string{"true"}}, false},
+		"NoHandler":     {Probe{Kind: ProbeLiveness}, false},
+		"TwoHandlers":   {Probe{Kind: ProbeLiveness, Exec: []string{"true"}, TCPPort: 80}, false},
+		"BadPort":       {Probe{Kind: ProbeLiveness, TCPPort: 70000}, false},
+		"BadScheme":     {Probe{Kind: ProbeLiveness, HTTPGet: &HTTPGetProbe{Port: 80, Scheme: "ftp"}}, false},
+		"NegativeDelay": {Probe{Kind: ProbeLiveness, TCPPort: 80, InitialDelay: -time.Second}, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.probe.Validate()
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestProber(t *testing.T) {
+	const period = 5 * time.Millisecond
+
+	events := make(chan HealthEvent, 10)
+	prober := NewProber(func(e HealthEvent) { events <- e })
+	defer prober.Close()
+
+	next := func() HealthEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			require.FailNow(t, "timed out waiting for health event")
+			return HealthEvent{}
+		}
+	}
+
+	t.Run("Exec", func(t *testing.T) {
+		c := &probedContainer{exitCode: 1}
+		require.NoError(t, prober.Add(c,
+			Probe{Kind: ProbeLiveness, Exec: []string{"check"}, Period: period, FailureThreshold: 2},
+		))
+
+		e := next()
+		assert.Equal(t, ProbeLiveness, e.Kind)
+		assert.False(t, e.Healthy)
+		assert.Equal(t, "exit code 1: ", e.Message)
+
+		c.setExitCode(0)
+		e = next()
+		assert.True(t, e.Healthy)
+		prober.Remove(c.Name())
+	})
+
+	t.Run("TCP", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+
+		c := &probedContainer{}
+		require.NoError(t, prober.Add(c,
+			Probe{Kind: ProbeReadiness, TCPPort: l.Addr().(*net.TCPAddr).Port, Period: period},
+		))
+
+		e := next()
+		assert.Equal(t, ProbeReadiness, e.Kind)
+		assert.True(t, e.Healthy)
+		prober.Remove(c.Name())
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		var mu sync.Mutex
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, "/healthz", r.URL.Path)
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+		require.NoError(t, err)
+		portNum, err := strconv.Atoi(port)
+		require.NoError(t, err)
+
+		c := &probedContainer{}
+		require.NoError(t, prober.Add(c, Probe{
+			Kind:             ProbeLiveness,
+			HTTPGet:          &HTTPGetProbe{Port: portNum, Path: "healthz"},
+			Period:           period,
+			FailureThreshold: 1,
+		}))
+
+		mu.Lock()
+		status = http.StatusServiceUnavailable
+		mu.Unlock()
+
+		e := next()
+		assert.False(t, e.Healthy)
+		assert.Contains(t, e.Message, "503")
+		prober.Remove(c.Name())
+	})
+
+	t.Run("Exited", func(t *testing.T) {
+		c := &probedContainer{exitCode: 1}
+		c.exited = true
+		require.NoError(t, prober.Add(c,
+			Probe{Kind: ProbeLiveness, Exec: []string{"check"}, Period: period, FailureThreshold: 1},
+		))
+
+		select {
+		case e := <-events:
+			assert.Failf(t, "unexpected health event", "%+v", e)
+		case <-time.After(10 * period):
+		}
+	})
+
+	t.Run("NoExec", func(t *testing.T) {
+		err := prober.Add(&fakeContainer{}, Probe{Kind: ProbeLiveness, Exec: []string{"check"}})
+		assert.ErrorIs(t, err, ErrNotImplemented)
+	})
+}
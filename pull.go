@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PullMetrics describes an image pull. Pulls skipped by their pull policy
+// aren't reported.
+type PullMetrics struct {
+	// Image is the tag of the pulled image.
+	Image string
+
+	// Registry is the host from which the image was pulled, e.g. "docker.io".
+	Registry string
+
+	Duration time.Duration
+
+	// Err is the reason the pull failed, or nil if it succeeded.
+	Err error
+
+	// LayersDownloaded and LayersCached count the image's layers which were
+	// downloaded and reused from the node's cache, respectively.
+	LayersDownloaded int
+	LayersCached     int
+
+	// BytesDownloaded is the compressed size of the downloaded layers, i.e.
+	// the bytes transferred from the registry.
+	BytesDownloaded int64
+
+	// BytesCached is the uncompressed size of the layers reused from the
+	// node's cache. It's zero if the sizes couldn't be determined.
+	BytesCached int64
+}
+
+// ImageRegistry returns the registry host of an image tag, following Docker's
+// rules for references without an explicit registry.
+func ImageRegistry(tag string) string {
+	i := strings.IndexByte(tag, '/')
+	if i == -1 {
+		return "docker.io"
+	}
+	host := tag[:i]
+	if host != "localhost" && !strings.ContainsAny(host, ".:") {
+		return "docker.io"
+	}
+	return host
+}
+
+// RegistryPullStats aggregates the pulls from one registry.
+type RegistryPullStats struct {
+	Pulls    int
+	Failures int
+
+	// Duration is the total time spent pulling, including failed pulls.
+	Duration time.Duration
+
+	LayersDownloaded int
+	LayersCached     int
+	BytesDownloaded  int64
+	BytesCached      int64
+}
+
+// ErrorRate returns the fraction of pulls which failed.
+func (s RegistryPullStats) ErrorRate() float64 {
+	if s.Pulls == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Pulls)
+}
+
+// CacheHitRate returns the fraction of layers which were reused from cache.
+func (s RegistryPullStats) CacheHitRate() float64 {
+	layers := s.LayersDownloaded + s.LayersCached
+	if layers == 0 {
+		return 0
+	}
+	return float64(s.LayersCached) / float64(layers)
+}
+
+// PullStats aggregates pull metrics by registry. It's safe for concurrent use,
+// so its Observe method may be passed directly to a runtime.
+type PullStats struct {
+	mu         sync.Mutex
+	registries map[string]*RegistryPullStats
+}
+
+// NewPullStats creates an empty set of pull statistics.
+func NewPullStats() *PullStats {
+	return &PullStats{registries: make(map[string]*RegistryPullStats)}
+}
+
+// Observe records a pull.
+func (s *PullStats) Observe(m PullMetrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.registries[m.Registry]
+	if !ok {
+		stats = &RegistryPullStats{}
+		s.registries[m.Registry] = stats
+	}
+	stats.Pulls++
+	if m.Err != nil {
+		stats.Failures++
+	}
+	stats.Duration += m.Duration
+	stats.LayersDownloaded += m.LayersDownloaded
+	stats.LayersCached += m.LayersCached
+	stats.BytesDownloaded += m.BytesDownloaded
+	stats.BytesCached += m.BytesCached
+}
+
+// Registries returns the names of all registries pulled from, sorted.
+func (s *PullStats) Registries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.registries))
+	for name := range s.registries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Registry returns the statistics for a registry.
+func (s *PullStats) Registry(name string) RegistryPullStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stats, ok := s.registries[name]; ok {
+		return *stats
+	}
+	return RegistryPullStats{}
+}
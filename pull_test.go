@@ -0,0 +1,54 @@
+package runtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageRegistry(t *testing.T) {
+	tests := map[string]string{
+		"python":                         "docker.io",
+		"library/python:3.9":             "docker.io",
+		"gcr.io/project/image:tag":       "gcr.io",
+		"localhost/image":                "localhost",
+		"registry:5000/image@sha256:abc": "registry:5000",
+	}
+	for tag, expected := range tests {
+		assert.Equal(t, expected, ImageRegistry(tag), tag)
+	}
+}
+
+func TestPullStats(t *testing.T) {
+	stats := NewPullStats()
+	stats.Observe(PullMetrics{
+		Registry:         "docker.io",
+		Duration:         time.Second,
+		LayersDownloaded: 1,
+		LayersCached:     3,
+		BytesDownloaded:  100,
+		BytesCached:      300,
+	})
+	stats.Observe(PullMetrics{Registry: "docker.io", Duration: time.Second, Err: errors.New("boom")})
+	stats.Observe(PullMetrics{Registry: "gcr.io"})
+
+	assert.Equal(t, []string{"docker.io", "gcr.io"}, stats.Registries())
+
+	docker := stats.Registry("docker.io")
+	assert.Equal(t, RegistryPullStats{
+		Pulls:            2,
+		Failures:         1,
+		Duration:         2 * time.Second,
+		LayersDownloaded: 1,
+		LayersCached:     3,
+		BytesDownloaded:  100,
+		BytesCached:      300,
+	}, docker)
+	assert.Equal(t, 0.5, docker.ErrorRate())
+	assert.Equal(t, 0.75, docker.CacheHitRate())
+
+	assert.Equal(t, RegistryPullStats{}, stats.Registry("quay.io"))
+	assert.Zero(t, stats.Registry("quay.io").ErrorRate())
+}
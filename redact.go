@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"fmt"
+	"path"
+)
+
+// RedactedValue replaces the values of sensitive environment variables.
+const RedactedValue = "[redacted]"
+
+// ValidateSensitiveEnv checks that sensitive environment patterns are well
+// formed.
+func (o *ContainerOpts) ValidateSensitiveEnv() error {
+	for _, pattern := range o.SensitiveEnv {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid sensitive environment pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// IsSensitiveEnv returns true if an environment variable's value should be
+// masked. Malformed patterns match nothing.
+func (o *ContainerOpts) IsSensitiveEnv(key string) bool {
+	for _, pattern := range o.SensitiveEnv {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactedEnv returns the container's environment with templates expanded and
+// sensitive values replaced by RedactedValue.
+func (o *ContainerOpts) RedactedEnv() map[string]string {
+	env := o.ResolveEnv()
+	if len(o.SensitiveEnv) == 0 {
+		return env
+	}
+	redacted := make(map[string]string, len(env))
+	for k, v := range env {
+		if o.IsSensitiveEnv(k) {
+			v = RedactedValue
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// Redacted returns a copy of the options which is safe to log or include in
+// bug reports. Templates are expanded and their variables dropped, so that
+// values they contribute to sensitive variables are masked too, and registry
+// credentials are removed. The copy shouldn't be used to create containers.
+func (o *ContainerOpts) Redacted() *ContainerOpts {
+	redacted := *o
+	if entrypoint, args, err := o.ResolveCommand(); err == nil {
+		redacted.Entrypoint, redacted.Args = entrypoint, args
+		redacted.Command, redacted.Arguments = nil, nil
+	}
+	redacted.Env = o.RedactedEnv()
+	redacted.TemplateVars = nil
+	if o.Image != nil {
+		redacted.Image = &DockerImage{Tag: o.Image.Tag}
+	}
+	return &redacted
+}
+
+// String formats the options as Redacted, so that they're safe to log with
+// verbs such as "%v" and "%+v".
+func (o ContainerOpts) String() string {
+	type plain ContainerOpts // Without methods, so formatting doesn't recurse.
+	return fmt.Sprintf("%+v", plain(*o.Redacted()))
+}
+
+// GoString formats the options as Redacted for the "%#v" verb.
+func (o ContainerOpts) GoString() string {
+	type plain ContainerOpts
+	return fmt.Sprintf("%#v", plain(*o.Redacted()))
+}
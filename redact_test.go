@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedacted(t *testing.T) {
+	opts := &ContainerOpts{
+		Image: &DockerImage{Tag: "private/image", Auth: &RegistryAuth{Username: "user", Password: "hunter2"}},
+		Args:  []string{"--token", "${TOKEN}"},
+		Env: map[string]string{
+			"API_TOKEN": "${TOKEN}",
+			"DB_SECRET": "swordfish",
+			"LOG_LEVEL": "debug",
+		},
+		TemplateVars: map[string]string{"TOKEN": "abc123"},
+		SensitiveEnv: []string{"API_TOKEN", "*_SECRET"},
+	}
+
+	redacted := opts.Redacted()
+	assert.Equal(t, map[string]string{
+		"API_TOKEN": RedactedValue,
+		"DB_SECRET": RedactedValue,
+		"LOG_LEVEL": "debug",
+	}, redacted.Env)
+	assert.Equal(t, &DockerImage{Tag: "private/image"}, redacted.Image)
+	assert.Equal(t, []string{"--token", "abc123"}, redacted.Args)
+	assert.Nil(t, redacted.TemplateVars)
+
+	// The original options are untouched.
+	assert.Equal(t, "${TOKEN}", opts.Env["API_TOKEN"])
+	assert.Equal(t, "hunter2", opts.Image.Auth.Password)
+
+	// Sensitivity doesn't change the configuration.
+	hash, err := opts.Hash()
+	assert.NoError(t, err)
+	opts.SensitiveEnv = nil
+	unmarked, err := opts.Hash()
+	assert.NoError(t, err)
+	assert.Equal(t, hash, unmarked)
+}
+
+func TestRedactedFormat(t *testing.T) {
+	opts := &ContainerOpts{
+		Image:        &DockerImage{Tag: "private/image", Auth: &RegistryAuth{Password: "hunter2"}},
+		Env:          map[string]string{"DB_SECRET": "swordfish", "LOG_LEVEL": "debug"},
+		SensitiveEnv: []string{"*_SECRET"},
+	}
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+		for _, v := range []interface{}{opts, *opts} {
+			formatted := fmt.Sprintf(verb, v)
+			assert.NotContains(t, formatted, "swordfish", verb)
+			assert.Contains(t, formatted, "debug", verb)
+		}
+	}
+}
+
+func TestValidateSensitiveEnv(t *testing.T) {
+	assert.NoError(t, (&ContainerOpts{SensitiveEnv: []string{"TOKEN", "AWS_*"}}).ValidateSensitiveEnv())
+	assert.Error(t, (&ContainerOpts{SensitiveEnv: []string{"[TOKEN"}}).ValidateSensitiveEnv())
+}
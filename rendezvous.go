@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Rendezvous describes a container's place in a multi-node distributed job.
+// It's applied to a container's options as the environment variables used by
+// torch.distributed, Horovod, and similar frameworks to find their peers.
+type Rendezvous struct {
+	// WorldSize is the total number of processes in the job.
+	WorldSize int
+
+	// Rank is this container's index among all processes, from 0.
+	Rank int
+
+	// (optional) LocalRank is this container's index among the processes on
+	// its node.
+	LocalRank int
+
+	// MasterAddr and MasterPort locate the rank 0 process, which coordinates
+	// the job. The master's port is published on its host.
+	MasterAddr string
+	MasterPort int
+
+	// (optional) NCCLSocketInterface restricts NCCL to a network interface,
+	// e.g. "eth0". NCCL's traffic between ranks uses ephemeral ports, so the
+	// container must be reachable from other nodes, e.g. on a Network.
+	NCCLSocketInterface string
+
+	// (optional) NCCLDebug sets NCCL's log level, e.g. "INFO".
+	NCCLDebug string
+}
+
+// Validate checks that a rendezvous is consistent.
+func (r *Rendezvous) Validate() error {
+	if r.WorldSize < 1 {
+		return errors.New("world size must be positive")
+	}
+	if r.Rank < 0 || r.Rank >= r.WorldSize {
+		return fmt.Errorf("rank %d is outside the world size of %d", r.Rank, r.WorldSize)
+	}
+	if r.LocalRank < 0 || r.LocalRank > r.Rank {
+		return fmt.Errorf("local rank %d is invalid for rank %d", r.LocalRank, r.Rank)
+	}
+	if r.MasterAddr == "" {
+		return errors.New("master address is required")
+	}
+	if r.MasterPort < 1 || r.MasterPort > 65535 {
+		return fmt.Errorf("invalid master port %d", r.MasterPort)
+	}
+	return nil
+}
+
+// Env returns the environment variables which describe the rendezvous.
+func (r *Rendezvous) Env() map[string]string {
+	env := map[string]string{
+		"MASTER_ADDR": r.MasterAddr,
+		"MASTER_PORT": strconv.Itoa(r.MasterPort),
+		"WORLD_SIZE":  strconv.Itoa(r.WorldSize),
+		"RANK":        strconv.Itoa(r.Rank),
+		"LOCAL_RANK":  strconv.Itoa(r.LocalRank),
+	}
+	if r.NCCLSocketInterface != "" {
+		env["NCCL_SOCKET_IFNAME"] = r.NCCLSocketInterface
+	}
+	if r.NCCLDebug != "" {
+		env["NCCL_DEBUG"] = r.NCCLDebug
+	}
+	return env
+}
+
+// Apply adds the rendezvous to a container's options. The master's port is
+// published if the container is rank 0. Environment variables set by the
+// caller aren't overwritten; conflicting values are an error.
+func (r *Rendezvous) Apply(opts *ContainerOpts) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	env := r.Env()
+	for k, v := range env {
+		if existing, ok := opts.Env[k]; ok && existing != v {
+			return fmt.Errorf("environment variable %s=%q conflicts with rendezvous value %q", k, existing, v)
+		}
+	}
+	if opts.Env == nil {
+		opts.Env = make(map[string]string, len(env))
+	}
+	for k, v := range env {
+		opts.Env[k] = v
+	}
+
+	if r.Rank == 0 {
+		for _, p := range opts.Ports {
+			if p.ContainerPort == r.MasterPort {
+				return nil
+			}
+		}
+		opts.Ports = append(opts.Ports, Port{ContainerPort: r.MasterPort})
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRendezvous(t *testing.T) {
+	r := Rendezvous{WorldSize: 16, MasterAddr: "10.0.0.1", MasterPort: 29500, NCCLSocketInterface: "eth0"}
+
+	t.Run("Master", func(t *testing.T) {
+		opts := &ContainerOpts{Env: map[string]string{"FOO": "bar"}}
+		require.NoError(t, r.Apply(opts))
+		assert.Equal(t, map[string]string{
+			"FOO":                "bar",
+			"MASTER_ADDR":        "10.0.0.1",
+			"MASTER_PORT":        "29500",
+			"WORLD_SIZE":         "16",
+			"RANK":               "0",
+			"LOCAL_RANK":         "0",
+			"NCCL_SOCKET_IFNAME": "eth0",
+		}, opts.Env)
+		assert.Equal(t, []Port{{ContainerPort: 29500}}, opts.Ports)
+
+		// Applying again is harmless.
+		require.NoError(t, r.Apply(opts))
+		assert.Len(t, opts.Ports, 1)
+	})
+
+	t.Run("Worker", func(t *testing.T) {
+		worker := r
+		worker.Rank, worker.LocalRank = 9, 1
+		opts := &ContainerOpts{}
+		require.NoError(t, worker.Apply(opts))
+		assert.Equal(t, "9", opts.Env["RANK"])
+		assert.Equal(t, "1", opts.Env["LOCAL_RANK"])
+		assert.Empty(t, opts.Ports)
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		opts := &ContainerOpts{Env: map[string]string{"RANK": "3"}}
+		assert.Error(t, r.Apply(opts))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, invalid := range []Rendezvous{
+			{WorldSize: 0, MasterAddr: "a", MasterPort: 1},
+			{WorldSize: 2, Rank: 2, MasterAddr: "a", MasterPort: 1},
+			{WorldSize: 2, Rank: 1, LocalRank: 2, MasterAddr: "a", MasterPort: 1},
+			{WorldSize: 2, MasterPort: 1},
+			{WorldSize: 2, MasterAddr: "a"},
+		} {
+			assert.Error(t, invalid.Validate(), "%+v", invalid)
+		}
+	})
+}
+
+func TestResolvePorts(t *testing.T) {
+	opts := &ContainerOpts{Ports: []Port{{ContainerPort: 80}, {ContainerPort: 53, HostPort: 5353, Protocol: "udp"}}}
+	ports, err := opts.ResolvePorts()
+	require.NoError(t, err)
+	assert.Equal(t, []Port{
+		{ContainerPort: 80, HostPort: 80, Protocol: "tcp"},
+		{ContainerPort: 53, HostPort: 5353, Protocol: "udp"},
+	}, ports)
+
+	_, err = (&ContainerOpts{Ports: []Port{{ContainerPort: 0}}}).ResolvePorts()
+	assert.Error(t, err)
+	_, err = (&ContainerOpts{Ports: []Port{{ContainerPort: 80, Protocol: "sctp"}}}).ResolvePorts()
+	assert.Error(t, err)
+
+	// Ports may be published on IPv6 addresses alone.
+	ports, err = (&ContainerOpts{Ports: []Port{{ContainerPort: 80, HostIP: "::"}}}).ResolvePorts()
+	require.NoError(t, err)
+	assert.Equal(t, "::", ports[0].HostIP)
+	_, err = (&ContainerOpts{Ports: []Port{{ContainerPort: 80, HostIP: "localhost"}}}).ResolvePorts()
+	assert.Error(t, err)
+}
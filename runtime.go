@@ -2,7 +2,13 @@ package runtime
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/beaker/runtime/logging"
@@ -26,31 +32,135 @@ const (
 	PullNever PullPolicy = "never"
 )
 
+// Validate returns an error if the policy is not recognized.
+func (p PullPolicy) Validate() error {
+	switch p {
+	case PullAlways, PullIfMissing, PullNever:
+		return nil
+	default:
+		return fmt.Errorf("%q is not a valid image pull policy", p)
+	}
+}
+
 // Runtime abstracts the specifics of interacting with the underlying container
 // runtime (e.g. Docker) for execution.
 type Runtime interface {
 	io.Closer
 
+	// Ping verifies that the underlying runtime is reachable and healthy.
+	Ping(ctx context.Context) error
+
 	PullImage(ctx context.Context, image *DockerImage, policy PullPolicy, quiet bool) error
 	CreateContainer(ctx context.Context, opts *ContainerOpts) (Container, error)
+
+	// ListContainers lists containers by creation time, oldest first, then by
+	// name. New containers are listed after those which existed before, so
+	// successive lists differ only by additions and removals. See Pager.
 	ListContainers(ctx context.Context) ([]Container, error)
 }
 
 // ContainerOpts allows a caller to specify options during container creation.
 type ContainerOpts struct {
-	// (optional) Name to give the container; randomly generated if absent.
+	// (optional) Name to give the container. If absent, it's derived from the
+	// IdempotencyKey if set, or else randomly generated.
 	Name string
 
-	Image     *DockerImage
-	Command   []string
+	// (optional) IdempotencyKey identifies a creation request so that it can
+	// be retried safely, e.g. after a timeout. If a container with the same
+	// key already exists it's returned instead of creating another. Reusing a
+	// key with different options is an error.
+	//
+	// Concurrent requests with the same key create one container as long as
+	// they share a name, since the runtime rejects all but one. See
+	// IdempotentName.
+	IdempotencyKey string
+
+	Image *DockerImage
+
+	// (optional) Entrypoint replaces the image's entrypoint, equivalent to
+	// Docker's ENTRYPOINT and Kubernetes' command. Setting an entrypoint also
+	// discards the image's default arguments in every runtime.
+	Entrypoint []string
+
+	// (optional) Args replace the image's default arguments, equivalent to
+	// Docker's CMD and Kubernetes' args. They are passed to Entrypoint if set,
+	// or the image's entrypoint otherwise.
+	Args []string
+
+	// Command is an alias of Entrypoint kept for compatibility.
+	//
+	// Deprecated: Use Entrypoint.
+	Command []string
+
+	// Arguments is an alias of Args kept for compatibility.
+	//
+	// Deprecated: Use Args.
 	Arguments []string
-	Env       map[string]string
-	Labels    map[string]string
-	Mounts    []Mount
+
+	Env    map[string]string
+	Labels map[string]string
+	Mounts []Mount
+
+	// (optional) SensitiveEnv names environment variables whose values are
+	// masked when the options are formatted, e.g. "API_TOKEN". Names may be
+	// patterns in path.Match syntax, e.g. "*_SECRET". Callers which record the
+	// options other than with fmt, e.g. as JSON, must call Redacted first. It
+	// doesn't affect the container or its configuration hash.
+	SensitiveEnv []string `json:"-"`
+
+	// (optional) TemplateVars are substituted for "${NAME}" references in
+	// entrypoint, arguments, and environment values when the container is
+	// created, e.g. {"BEAKER_NODE": "node-1"}. See ExpandTemplate for details.
+	TemplateVars map[string]string
 
 	// Attach STDIN/STDOUT/STDERR and shell into the container.
 	Interactive bool
 
+	// (optional) SSHAgent is the host socket of an SSH agent to forward into an
+	// interactive container, e.g. from HostSSHAgent, so that private
+	// repositories can be cloned without copying keys. The socket is mounted at
+	// SSHAgentPath and must be accessible to the container's user.
+	SSHAgent string
+
+	// (optional) X11 forwards an X display on the host to the container.
+	X11 *X11Display
+
+	// (optional) Init runs a minimal init process as the container's main
+	// process to forward signals and reap zombie processes. Interactive
+	// containers always use an init process where supported.
+	Init bool
+
+	// (optional) StopSignal overrides the signal, such as "SIGINT", sent to
+	// the container's main process when it is stopped. If empty, the image's
+	// stop signal is used, defaulting to SIGTERM.
+	StopSignal string
+
+	// AutoRemove removes the container, including its logs, once it exits.
+	// This suits fire-and-forget utility containers which would otherwise
+	// accumulate until garbage collected. Logs are never archived on
+	// automatic removal; see RemoveOpts.LogArchiveDir.
+	//
+	// Docker removes the container itself unless the runtime retains history,
+	// in which case the container couldn't be recorded. Otherwise runtimes
+	// remove it themselves, resuming after a restart once the container is
+	// looked up or listed; see Removals.
+	AutoRemove bool
+
+	// (optional) CoreDumpDir is a host directory which collects core dumps
+	// from the container's crashed processes. The container's core size limit
+	// is lifted, and the directory is mounted where kernel.core_pattern writes
+	// dumps; see CoreDumpPath and CollectCoreDumps.
+	CoreDumpDir string
+
+	// (optional) MaxRuntime limits how long the container may run. Once it's
+	// exceeded the container is stopped as with Stop, and its info reports an
+	// InterruptionDeadlineExceeded interruption. Zero means no limit.
+	MaxRuntime time.Duration
+
+	// (optional) Schedule delays the container's start. Scheduled containers
+	// are started by the runtime and must not be started by the caller.
+	Schedule *StartSchedule
+
 	// Memory is a hard limit on the amount of memory a container can use.
 	// Expressed as a number of bytes.
 	Memory int64
@@ -67,18 +177,266 @@ type ContainerOpts struct {
 	// shares can use twice as much CPU as one with 1024 shares.
 	//
 	// CPUShares are ignored in the Kubernetes runtime.
-	// CPUShares take precedence over CPUCount in the Docker and CRI runtimes.
 	CPUShares int64
 
-	// GPUs assigned to the container as IDs or indices.
+	// (optional) CPUPeriod is the CFS scheduler period over which CPUCount is
+	// enforced. Shorter periods reduce throttling latency for latency-sensitive
+	// workloads at the cost of scheduling overhead. Defaults to 100ms.
+	//
+	// CPUPeriod is ignored in the Kubernetes runtime.
+	CPUPeriod time.Duration
+
+	// (optional) CPUBurst lets a container bank quota it leaves unused and
+	// spend up to this much extra CPU time in a later period, so bursty work
+	// such as an interactive session stays responsive while CPUCount still
+	// caps its average. It can't exceed the quota of a single period. Requires
+	// CPUCount and a kernel with CFS burst support, 5.14 or later. It's applied
+	// to the container's cgroup once the container starts.
+	//
+	// CPUBurst is not implemented in the Kubernetes runtime.
+	CPUBurst time.Duration
+
+	// GPUs assigned to the container as UUIDs or indices. Indices are resolved
+	// to UUIDs at creation where possible; see ContainerInfo.GPUs.
 	GPUs []string
 
+	// (optional) GPUCapabilities selects the NVIDIA driver capabilities granted
+	// to the container, e.g. GPUVideo for hardware video decoding. If empty, the
+	// image's NVIDIA_DRIVER_CAPABILITIES applies, which the NVIDIA container
+	// toolkit defaults to compute and utility. Requires GPUs.
+	GPUCapabilities []GPUCapability
+
+	// (optional) GPUSharing selects how the container shares its GPUs with
+	// other containers assigned the same GPUs. Requires GPUs.
+	//
+	// GPUSharingMPS is only implemented in the Docker runtime.
+	GPUSharing GPUSharing
+
+	// (optional) MPSThreadPercent limits the fraction of a GPU's streaming
+	// multiprocessors available to the container, from 1 to 100. Requires
+	// GPUSharingMPS.
+	MPSThreadPercent int
+
 	// (optional) User that will run commands inside the container. Also supports "user:group".
 	// If not provided, the container is run as root.
 	User string
 
 	// (optional) WorkingDir where the command will be launched.
 	WorkingDir string
+
+	// (optional) IPCMode configures the container's IPC namespace. If not
+	// provided, the runtime's default is used.
+	IPCMode IPCMode
+
+	// (optional) NetworkFrom names an existing managed container whose network
+	// namespace is joined instead of creating a new one.
+	NetworkFrom string
+
+	// (optional) PIDFrom names an existing managed container whose PID
+	// namespace is joined, making its processes visible to this container.
+	PIDFrom string
+
+	// (optional) Network names a user-defined network to attach the container
+	// to. On Kubernetes, this is a NetworkAttachmentDefinition name.
+	Network string
+
+	// (optional) IPAddress is a static IPv4 address to assign the container on
+	// its network. Requires Network. It may be given in CIDR notation, e.g.
+	// "10.1.2.3/24", as static IPAM on Kubernetes requires. The prefix length
+	// is passed through on Kubernetes and ignored on Docker, where the network
+	// defines it.
+	IPAddress string
+
+	// (optional) IPv6Address is a static IPv6 address to assign the container
+	// on its network, optionally in CIDR notation as with IPAddress. The
+	// network must have IPv6 enabled. Requires Network.
+	IPv6Address string
+
+	// (optional) MACAddress is a static MAC address to assign the container on
+	// its network. Requires Network.
+	MACAddress string
+
+	// (optional) DNSPolicy selects the base of the container's resolver
+	// configuration. Defaults to the runtime's default. DNS options aren't
+	// supported on CRI, where they're configured per pod sandbox.
+	DNSPolicy DNSPolicy
+
+	// (optional) DNSConfig adds nameservers, search domains, and resolver
+	// options to those selected by DNSPolicy. On Kubernetes, they're merged;
+	// on Docker, each non-empty list replaces the node's.
+	DNSConfig *DNSConfig
+
+	// (optional) Ports are published on the host so that the container can
+	// accept connections from other nodes.
+	Ports []Port
+
+	// (optional) IngressBandwidth and EgressBandwidth limit the rate at which
+	// the container receives and sends over the network, so that bulk
+	// transfers such as dataset downloads can't saturate the node's network.
+	// On Docker, they're applied with tc once the container starts; on
+	// Kubernetes, they require the CNI bandwidth plugin.
+	//
+	// Bandwidth limits are not implemented in the CRI runtime.
+	IngressBandwidth Bandwidth
+	EgressBandwidth  Bandwidth
+
+	// (optional) PostStart is run inside the container once it starts. If it
+	// fails, the container is stopped and Start returns an error.
+	PostStart *Hook
+
+	// (optional) PreStop is run inside the container before it's stopped,
+	// counting against the stop's grace period. The container is stopped
+	// whether or not it succeeds. It isn't run if the container is killed
+	// without a grace period.
+	PreStop *Hook
+
+	// (optional) OnWarning is called with each non-fatal warning raised while
+	// creating the container, such as a resource limit being adjusted. Warnings
+	// are discarded if absent.
+	OnWarning func(message string) `json:"-"`
+}
+
+// ConfigHashLabel is set on all containers to the hash of the options they
+// were created with. See ContainerOpts.Hash.
+const ConfigHashLabel = "beaker.org/config-hash"
+
+// IdempotencyKeyLabel is set on containers created with an idempotency key.
+// See ContainerOpts.IdempotencyKey.
+const IdempotencyKeyLabel = "beaker.org/idempotency-key"
+
+// AutoRemoveLabel is set on containers created with AutoRemove on runtimes
+// which remove them themselves. See Removals.
+const AutoRemoveLabel = "beaker.org/auto-remove"
+
+// MaxRuntimeLabel is set on containers created with a maximum runtime on
+// runtimes which enforce it themselves. See ContainerOpts.MaxRuntime.
+const MaxRuntimeLabel = "beaker.org/max-runtime"
+
+// CPUBurstLabel is set on containers created with a CPU burst on runtimes
+// which apply it themselves. See ContainerOpts.CPUBurst.
+const CPUBurstLabel = "beaker.org/cpu-burst"
+
+// IdempotentName returns the name of a container created with an idempotency
+// key but no name. It's derived from the key so that concurrent requests with
+// the same key conflict on the name rather than each creating a container.
+func IdempotentName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "idempotent-" + hex.EncodeToString(sum[:16])
+}
+
+// CheckIdempotent verifies that an existing container found by its idempotency
+// key was created with the same options, identified by their hash.
+func CheckIdempotent(ctx context.Context, existing Container, configHash string) error {
+	info, err := existing.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if info.ConfigHash != configHash {
+		return fmt.Errorf("idempotency key was already used to create container %s with different options", existing.Name())
+	}
+	return nil
+}
+
+// Warn reports a non-fatal creation warning to the caller, if requested.
+func (o *ContainerOpts) Warn(format string, args ...interface{}) {
+	if o.OnWarning != nil {
+		o.OnWarning(fmt.Sprintf(format, args...))
+	}
+}
+
+// ResolveCommand returns the effective entrypoint and arguments, accounting
+// for the deprecated Command and Arguments fields and expanding templates.
+func (o *ContainerOpts) ResolveCommand() (entrypoint, args []string, err error) {
+	entrypoint, args = o.Entrypoint, o.Args
+	if len(o.Command) != 0 {
+		if len(entrypoint) != 0 {
+			return nil, nil, errors.New("Command and Entrypoint are mutually exclusive")
+		}
+		entrypoint = o.Command
+	}
+	if len(o.Arguments) != 0 {
+		if len(args) != 0 {
+			return nil, nil, errors.New("Arguments and Args are mutually exclusive")
+		}
+		args = o.Arguments
+	}
+	return expandAll(entrypoint, o.TemplateVars), expandAll(args, o.TemplateVars), nil
+}
+
+// ResolveEnv returns the container's environment with templates expanded.
+func (o *ContainerOpts) ResolveEnv() map[string]string {
+	if len(o.TemplateVars) == 0 {
+		return o.Env
+	}
+	env := make(map[string]string, len(o.Env))
+	for k, v := range o.Env {
+		env[k] = ExpandTemplate(v, o.TemplateVars)
+	}
+	return env
+}
+
+// ValidateNetwork checks that network options are well formed and consistent.
+func (o *ContainerOpts) ValidateNetwork() error {
+	if o.Network == "" {
+		if o.IPAddress != "" || o.IPv6Address != "" || o.MACAddress != "" {
+			return errors.New("static addresses require a network")
+		}
+		return nil
+	}
+	if o.NetworkFrom != "" {
+		return errors.New("a container can't both join a network and another container's network namespace")
+	}
+	if o.IPAddress != "" {
+		if ip := parseStaticIP(o.IPAddress); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("%q is not a valid IPv4 address", o.IPAddress)
+		}
+	}
+	if o.IPv6Address != "" {
+		if ip := parseStaticIP(o.IPv6Address); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("%q is not a valid IPv6 address", o.IPv6Address)
+		}
+	}
+	if o.MACAddress != "" {
+		if _, err := net.ParseMAC(o.MACAddress); err != nil {
+			return fmt.Errorf("%q is not a valid MAC address", o.MACAddress)
+		}
+	}
+	return nil
+}
+
+// parseStaticIP parses a static address, which may be in CIDR notation. It
+// returns nil if the address is invalid.
+func parseStaticIP(s string) net.IP {
+	if strings.Contains(s, "/") {
+		ip, _, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil
+		}
+		return ip
+	}
+	return net.ParseIP(s)
+}
+
+// ValidateCPUBurst checks that a CPU burst fits within the container's CPU
+// quota. The kernel rejects bursts larger than a single period's quota.
+func (o *ContainerOpts) ValidateCPUBurst() error {
+	if o.CPUBurst == 0 {
+		return nil
+	}
+	if o.CPUBurst < 0 {
+		return fmt.Errorf("invalid CPU burst %s", o.CPUBurst)
+	}
+	if o.CPUCount == 0 {
+		return errors.New("CPU burst requires a CPU count")
+	}
+	period := o.CPUPeriod
+	if period == 0 {
+		period = 100 * time.Millisecond
+	}
+	if quota := time.Duration(o.CPUCount * float64(period)); o.CPUBurst > quota {
+		return fmt.Errorf("CPU burst %s exceeds the CPU quota of %s per period", o.CPUBurst, quota)
+	}
+	return nil
 }
 
 // IsEvictable returns true if a container is evictable. Evictable containers are the first to be killed
@@ -87,6 +445,51 @@ func (o *ContainerOpts) IsEvictable() bool {
 	return o.Memory == 0 && o.CPUCount == 0 && o.CPUShares == 0 && len(o.GPUs) == 0
 }
 
+// IPCMode describes how a container's IPC namespace, including SysV shared
+// memory and semaphores, is created or shared.
+type IPCMode string
+
+const (
+	// IPCPrivate gives the container its own IPC namespace which can't be shared.
+	IPCPrivate IPCMode = "private"
+
+	// IPCShareable gives the container its own IPC namespace which other
+	// containers may join.
+	IPCShareable IPCMode = "shareable"
+
+	// IPCHost shares the host's IPC namespace with the container.
+	IPCHost IPCMode = "host"
+)
+
+const ipcContainerPrefix = "container:"
+
+// IPCContainer returns a mode which joins the IPC namespace of another
+// container. The other container must have been created with IPCShareable.
+func IPCContainer(name string) IPCMode {
+	return IPCMode(ipcContainerPrefix + name)
+}
+
+// Container returns the name of the container whose IPC namespace is joined,
+// or false if the mode doesn't join another container.
+func (m IPCMode) Container() (string, bool) {
+	if !strings.HasPrefix(string(m), ipcContainerPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(string(m), ipcContainerPrefix), true
+}
+
+// Validate returns an error if the mode is not recognized.
+func (m IPCMode) Validate() error {
+	switch m {
+	case "", IPCPrivate, IPCShareable, IPCHost:
+		return nil
+	}
+	if name, ok := m.Container(); ok && name != "" {
+		return nil
+	}
+	return fmt.Errorf("%q is not a valid IPC mode", m)
+}
+
 // DockerImage specifies a Docker-based container image.
 type DockerImage struct {
 	// (required) Tag is a docker image refspec, as a tag or resolvable image hash.
@@ -94,6 +497,14 @@ type DockerImage struct {
 
 	// (optional) Auth contains credentials for private registry access.
 	Auth *RegistryAuth
+
+	// (optional) PullPolicy, if set, is applied when a container is created
+	// from the image, pulling it as PullImage would first. If empty, the image
+	// must already be present, e.g. pulled separately with PullImage.
+	//
+	// On Kubernetes, the policy is the pod's image pull policy. CRI can't pull
+	// images, so it rejects containers which set a policy.
+	PullPolicy PullPolicy
 }
 
 // RegistryAuth describes credentials for private Docker registry access.
@@ -110,15 +521,126 @@ type Mount struct {
 	ReadOnly      bool
 }
 
+// Port describes a container port published on the host.
+type Port struct {
+	ContainerPort int
+
+	// (optional) HostPort defaults to ContainerPort.
+	HostPort int
+
+	// (optional) Protocol is "tcp" or "udp". Defaults to "tcp".
+	Protocol string
+
+	// (optional) HostIP is the host address the port is published on, e.g.
+	// "::" for every IPv6 address. Defaults to every address of both families.
+	HostIP string
+}
+
+// ResolvePorts validates ports and fills in their defaults.
+func (o *ContainerOpts) ResolvePorts() ([]Port, error) {
+	ports := make([]Port, len(o.Ports))
+	for i, p := range o.Ports {
+		if p.HostPort == 0 {
+			p.HostPort = p.ContainerPort
+		}
+		if p.Protocol == "" {
+			p.Protocol = "tcp"
+		}
+		if p.ContainerPort < 1 || p.ContainerPort > 65535 || p.HostPort < 1 || p.HostPort > 65535 {
+			return nil, fmt.Errorf("invalid port mapping %d:%d", p.HostPort, p.ContainerPort)
+		}
+		if p.Protocol != "tcp" && p.Protocol != "udp" {
+			return nil, fmt.Errorf("invalid port protocol %q", p.Protocol)
+		}
+		if p.HostIP != "" && net.ParseIP(p.HostIP) == nil {
+			return nil, fmt.Errorf("%q is not a valid host IP", p.HostIP)
+		}
+		ports[i] = p
+	}
+	return ports, nil
+}
+
 // Container is a containerized process.
 type Container interface {
 	Name() string
 	Start(ctx context.Context) error
 	Info(ctx context.Context) (*ContainerInfo, error)
-	Logs(ctx context.Context, since time.Time) (logging.LogReader, error)
+	Logs(ctx context.Context, opts LogsOpts) (logging.LogReader, error)
 	Stats(ctx context.Context) (*ContainerStats, error)
 	Stop(ctx context.Context, timeout *time.Duration) error
-	Remove(ctx context.Context) error
+	Remove(ctx context.Context, opts RemoveOpts) error
+}
+
+// LogsOpts configures which of a container's logs are read.
+type LogsOpts struct {
+	// (optional) Since limits logs to those emitted at or after the given time.
+	// The zero value reads the full log.
+	Since time.Time
+
+	// (optional) ReaderOpts controls how log times and binary text are presented.
+	ReaderOpts logging.ReaderOpts
+
+	// (optional) Follow streams output as it's written until the container
+	// exits, rather than ending at the current end of the log.
+	Follow bool
+
+	// (optional) Buffer reads logs ahead of the caller into a bounded buffer.
+	// Followed logs of bursty containers should be buffered with
+	// logging.BackpressureDropOldest so that a slow reader neither grows
+	// without bound nor stalls the runtime.
+	Buffer *logging.BufferOpts
+}
+
+// BufferLogs wraps a log reader with the buffer described by the options, if
+// any. Runtimes call it on the readers they return from Logs.
+func BufferLogs(r logging.LogReader, opts LogsOpts) logging.LogReader {
+	if opts.Buffer == nil {
+		return r
+	}
+	return logging.NewBufferedReader(r, *opts.Buffer)
+}
+
+// Validate checks that the options are well formed.
+func (o *LogsOpts) Validate() error {
+	if o.Buffer != nil {
+		return o.Buffer.Validate()
+	}
+	return nil
+}
+
+// RemoveOpts configures how a container is removed. The zero value forcibly
+// removes a container with no grace period.
+type RemoveOpts struct {
+	// (optional) GracePeriod allows a running container to exit gracefully, as
+	// with Stop, before it's forcibly removed.
+	GracePeriod time.Duration
+
+	// (optional) RemoveVolumes removes anonymous volumes associated with the
+	// container. It has no effect on runtimes without anonymous volumes.
+	RemoveVolumes bool
+
+	// (optional) LogArchiveDir is a directory to which the container's logs
+	// are archived with ArchiveLogs before it's removed. If archival fails,
+	// the container is not removed.
+	LogArchiveDir string
+
+	// (optional) LogArchiveOpts controls how archived logs are presented.
+	LogArchiveOpts logging.ReaderOpts
+
+	// (optional) WaitTimeout waits up to the given duration for the container
+	// to be fully deleted, e.g. while a pod's volumes detach. If it's still
+	// present at the deadline, an error is returned. Runtimes which delete
+	// containers synchronously ignore it. Zero doesn't wait.
+	WaitTimeout time.Duration
+
+	// (optional) OnProgress is called with a description of what's holding up
+	// deletion each time it changes while waiting.
+	OnProgress func(status string)
+
+	// (optional) ForceFinalize removes the finalizers of a container which is
+	// still present at WaitTimeout so that its deletion completes. Resources
+	// guarded by the finalizers may leak. Kubernetes only.
+	ForceFinalize bool
 }
 
 // ContainerInfo describes a container's details.
@@ -133,10 +655,110 @@ type ContainerInfo struct {
 	Message  string
 	ExitCode *int
 
+	// RestartCount is the number of times the container has been restarted in
+	// place. Only the current instance's logs are available. It's always zero
+	// on Kubernetes, whose pods never restart their containers.
+	RestartCount int
+
+	// Interruption is set if the container was stopped by its infrastructure,
+	// e.g. by eviction or preemption, rather than exiting on its own.
+	Interruption *Interruption
+
+	// PID of the container's main process as seen from the host, or zero if the
+	// container isn't running.
+	PID int
+
+	// IPAddresses lists the IPv4 and IPv6 addresses assigned to the container.
+	// Dual-stack containers have one of each.
+	IPAddresses []string
+
+	// CgroupPath is the container's cgroup relative to the cgroupfs root, e.g.
+	// "/docker/<id>". It's empty if the container isn't running or the path
+	// couldn't be determined.
+	CgroupPath string
+
+	// ConfigHash is the hash of the options the container was created with, or
+	// empty if the container predates configuration hashes.
+	ConfigHash string
+
 	// Resource limits
 	Memory   int64 // In bytes
 	CPUCount float64
-	// TODO: Add GPUs so caller doesn't have to parse labels.
+
+	// GPUs assigned to the container. GPUs are identified by UUID where the
+	// runtime could resolve them, or as requested otherwise.
+	GPUs []string
+
+	// MaxRuntime is the container's time limit, or zero if it has none.
+	MaxRuntime time.Duration
+
+	// CPUBurst is the CPU time the container may bank beyond its limit, or
+	// zero if it has none. See ContainerOpts.CPUBurst.
+	CPUBurst time.Duration
+
+	// Network bandwidth limits, or zero where unlimited. See
+	// ContainerOpts.IngressBandwidth.
+	IngressBandwidth Bandwidth
+	EgressBandwidth  Bandwidth
+
+	// Hooks run inside the container, if any. See ContainerOpts.PostStart.
+	PostStart *Hook
+	PreStop   *Hook
+}
+
+// Interruption describes why the infrastructure stopped a container.
+type Interruption struct {
+	Reason InterruptionReason
+
+	// Resource is the node resource under pressure when the container was
+	// evicted, e.g. "memory". It's empty if unknown or not applicable.
+	Resource string
+}
+
+// InterruptionReason classifies infrastructure-caused container failures.
+type InterruptionReason string
+
+const (
+	// InterruptionEvicted indicates the container was evicted, typically
+	// because its node was under resource pressure.
+	InterruptionEvicted InterruptionReason = "evicted"
+
+	// InterruptionPreempted indicates the container was stopped to make room
+	// for a higher priority workload.
+	InterruptionPreempted InterruptionReason = "preempted"
+
+	// InterruptionNodeShutdown indicates the container's node shut down.
+	InterruptionNodeShutdown InterruptionReason = "node-shutdown"
+
+	// InterruptionNodeLost indicates the container's node became unreachable.
+	InterruptionNodeLost InterruptionReason = "node-lost"
+
+	// InterruptionAdmission indicates the node rejected the container after it
+	// was scheduled, e.g. because the node ran out of a resource.
+	InterruptionAdmission InterruptionReason = "admission"
+
+	// InterruptionDeadlineExceeded indicates the container was stopped because
+	// it ran longer than its MaxRuntime.
+	InterruptionDeadlineExceeded InterruptionReason = "deadline-exceeded"
+)
+
+// Event is a notable occurrence in a container's lifecycle reported by its
+// runtime, such as a failure to pull its image or mount a volume.
+type Event struct {
+	// Time is when the event was last observed.
+	Time time.Time
+
+	// Warning is true if the event indicates a problem.
+	Warning bool
+
+	// Reason is a short, machine-readable cause, e.g. "FailedMount".
+	Reason string
+
+	// Message is a human-readable description of the event.
+	Message string
+
+	// Count is the number of times the event has occurred.
+	Count int
 }
 
 // ContainerStatus describes the runtime status of a containerized process.
@@ -156,6 +778,8 @@ const (
 // String converts the container status to a human-readable string, useful for diagnostics.
 func (s ContainerStatus) String() string {
 	switch s {
+	case StatusCreated:
+		return "created"
 	case StatusRunning:
 		return "running"
 	case StatusExited:
@@ -179,11 +803,24 @@ type ContainerStats struct {
 type StatType string
 
 const (
-	// CPUUsagePercentStat counts CPU usage as a percentage of the container's
-	// limit. If the container has no limit, the percentage is relative to total
-	// CPU capacity of the host.
+	// CPUUsagePercentStat counts CPU usage as a percentage of a single CPU,
+	// e.g. 250 for two and a half cores, regardless of the container's limit.
+	//
+	// Prefer CPUUsageCoresStat, CPUUsageOfLimitPercentStat, or
+	// CPUUsageOfHostPercentStat, whose meanings are unambiguous.
 	CPUUsagePercentStat = StatType("CPUUsagePercent")
 
+	// CPUUsageCoresStat counts CPU usage in cores, e.g. 2.5.
+	CPUUsageCoresStat = StatType("CPUUsageCores")
+
+	// CPUUsageOfLimitPercentStat counts CPU usage as a percentage of the
+	// container's CPU limit. It's absent if the container has no limit.
+	CPUUsageOfLimitPercentStat = StatType("CPUUsageOfLimitPercent")
+
+	// CPUUsageOfHostPercentStat counts CPU usage as a percentage of the host's
+	// total CPU capacity.
+	CPUUsageOfHostPercentStat = StatType("CPUUsageOfHostPercent")
+
 	// MemoryUsageBytesStat counts memory usage in absolute bytes.
 	MemoryUsageBytesStat = StatType("MemoryUsageBytes")
 
@@ -203,4 +840,61 @@ const (
 
 	// BlockWriteBytesStat counts total bytes written to block devices.
 	BlockWriteBytesStat = StatType("BlockWriteBytes")
+
+	// TCPRetransmitsStat counts TCP segments retransmitted by the container.
+	// It's only reported by the ebpf package's probes.
+	TCPRetransmitsStat = StatType("TCPRetransmits")
+
+	// TCPConnectionsStat counts TCP connections the container has established,
+	// whether opened or accepted. It's only reported by the ebpf package's
+	// probes.
+	TCPConnectionsStat = StatType("TCPConnections")
+
+	// FileIOCallsStat counts the container's file read and write system calls.
+	// It's only reported by the ebpf package's probes.
+	FileIOCallsStat = StatType("FileIOCalls")
+
+	// FileIOLatencyStat is the mean latency in seconds of the container's file
+	// read and write system calls since the previous sample. It's absent if
+	// there were none. It's only reported by the ebpf package's probes.
+	FileIOLatencyStat = StatType("FileIOLatencySeconds")
+
+	// GPUUtilizationPercentStat is the mean utilization of the container's
+	// GPUs as a percentage. GPUs shared with other containers report their
+	// combined use. Runtimes don't report it; sample the node's GPUs once with
+	// QueryGPUUtilization and add it to each container's stats with
+	// SetGPUStats.
+	GPUUtilizationPercentStat = StatType("GPUUtilizationPercent")
 )
+
+// SetCPUStats records CPU usage in cores along with each percentage derived
+// from it. The container's limit in cores and the host's CPU count are omitted
+// from the percentages if zero.
+func SetCPUStats(stats map[StatType]float64, cores, limit float64, hostCPUs int) {
+	stats[CPUUsageCoresStat] = cores
+	stats[CPUUsagePercentStat] = cores * 100
+	if limit > 0 {
+		stats[CPUUsageOfLimitPercentStat] = cores / limit * 100
+	}
+	if hostCPUs > 0 {
+		stats[CPUUsageOfHostPercentStat] = cores / float64(hostCPUs) * 100
+	}
+}
+
+// SetGPUStats records the mean utilization of a container's GPUs, identified
+// by UUID as in ContainerInfo.GPUs. It's omitted if the container has no GPUs
+// or any of them wasn't sampled.
+func SetGPUStats(stats map[StatType]float64, gpus []string, utilization GPUUtilization) {
+	if len(gpus) == 0 {
+		return
+	}
+	var total float64
+	for _, gpu := range gpus {
+		percent, ok := utilization[gpu]
+		if !ok {
+			return
+		}
+		total += percent
+	}
+	stats[GPUUtilizationPercentStat] = total / float64(len(gpus))
+}
@@ -0,0 +1,193 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPCMode(t *testing.T) {
+	tests := map[string]struct {
+		Mode      IPCMode
+		Valid     bool
+		Container string
+	}{
+		"Default":        {"", true, ""},
+		"Private":        {IPCPrivate, true, ""},
+		"Shareable":      {IPCShareable, true, ""},
+		"Host":           {IPCHost, true, ""},
+		"Container":      {IPCContainer("abc"), true, "abc"},
+		"EmptyContainer": {IPCContainer(""), false, ""},
+		"Unknown":        {"none", false, ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Valid, test.Mode.Validate() == nil)
+			container, _ := test.Mode.Container()
+			assert.Equal(t, test.Container, container)
+		})
+	}
+}
+
+func TestPullPolicy(t *testing.T) {
+	for _, policy := range []PullPolicy{PullAlways, PullIfMissing, PullNever} {
+		assert.NoError(t, policy.Validate(), policy)
+	}
+	for _, policy := range []PullPolicy{"", "Always", "sometimes"} {
+		assert.Error(t, policy.Validate(), policy)
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	tests := map[string]struct {
+		Opts  ContainerOpts
+		Valid bool
+	}{
+		"Default":        {ContainerOpts{}, true},
+		"Network":        {ContainerOpts{Network: "net"}, true},
+		"StaticIP":       {ContainerOpts{Network: "net", IPAddress: "10.0.0.2"}, true},
+		"StaticMAC":      {ContainerOpts{Network: "net", MACAddress: "02:42:ac:11:00:02"}, true},
+		"NoNetwork":      {ContainerOpts{IPAddress: "10.0.0.2"}, false},
+		"InvalidIP":      {ContainerOpts{Network: "net", IPAddress: "10.0.0.256"}, false},
+		"IPv6AsIPv4":     {ContainerOpts{Network: "net", IPAddress: "fd00::2"}, false},
+		"IPv6":           {ContainerOpts{Network: "net", IPv6Address: "fd00::2"}, true},
+		"DualStack":      {ContainerOpts{Network: "net", IPAddress: "10.0.0.2", IPv6Address: "fd00::2"}, true},
+		"IPv4AsIPv6":     {ContainerOpts{Network: "net", IPv6Address: "10.0.0.2"}, false},
+		"CIDR":           {ContainerOpts{Network: "net", IPAddress: "10.1.2.3/24", IPv6Address: "fd00::2/64"}, true},
+		"InvalidCIDR":    {ContainerOpts{Network: "net", IPAddress: "10.1.2.3/33"}, false},
+		"IPv6CIDRAsIPv4": {ContainerOpts{Network: "net", IPAddress: "fd00::2/64"}, false},
+		"InvalidMAC":     {ContainerOpts{Network: "net", MACAddress: "02:42"}, false},
+		"NetworkAndNet":  {ContainerOpts{Network: "net", NetworkFrom: "other"}, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Valid, test.Opts.ValidateNetwork() == nil)
+		})
+	}
+}
+
+func TestValidateCPUBurst(t *testing.T) {
+	ms := time.Millisecond
+	tests := map[string]struct {
+		Opts  ContainerOpts
+		Valid bool
+	}{
+		"Default":       {ContainerOpts{}, true},
+		"Burst":         {ContainerOpts{CPUCount: 2, CPUBurst: 50 * ms}, true},
+		"FullQuota":     {ContainerOpts{CPUCount: 2, CPUBurst: 200 * ms}, true},
+		"OverQuota":     {ContainerOpts{CPUCount: 2, CPUBurst: 201 * ms}, false},
+		"ShortPeriod":   {ContainerOpts{CPUCount: 2, CPUPeriod: 10 * ms, CPUBurst: 50 * ms}, false},
+		"NoCPUCount":    {ContainerOpts{CPUBurst: 50 * ms}, false},
+		"NegativeBurst": {ContainerOpts{CPUCount: 2, CPUBurst: -ms}, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Valid, test.Opts.ValidateCPUBurst() == nil)
+		})
+	}
+}
+
+func TestResolveCommand(t *testing.T) {
+	tests := map[string]struct {
+		Opts       ContainerOpts
+		Entrypoint []string
+		Args       []string
+		Valid      bool
+	}{
+		"Default":    {ContainerOpts{}, nil, nil, true},
+		"Entrypoint": {ContainerOpts{Entrypoint: []string{"sh"}, Args: []string{"-c"}}, []string{"sh"}, []string{"-c"}, true},
+		"Deprecated": {ContainerOpts{Command: []string{"sh"}, Arguments: []string{"-c"}}, []string{"sh"}, []string{"-c"}, true},
+		"Mixed":      {ContainerOpts{Command: []string{"sh"}, Args: []string{"-c"}}, []string{"sh"}, []string{"-c"}, true},
+		"TwoEntries": {ContainerOpts{Command: []string{"sh"}, Entrypoint: []string{"sh"}}, nil, nil, false},
+		"TwoArgs":    {ContainerOpts{Arguments: []string{"-c"}, Args: []string{"-c"}}, nil, nil, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entrypoint, args, err := test.Opts.ResolveCommand()
+			assert.Equal(t, test.Valid, err == nil)
+			assert.Equal(t, test.Entrypoint, entrypoint)
+			assert.Equal(t, test.Args, args)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	base := ContainerOpts{
+		Image:   &DockerImage{Tag: "busybox"},
+		Command: []string{"sh", "-c"},
+		Env:     map[string]string{"A": "1", "B": "${X}"},
+	}
+	hash, err := base.Hash()
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	t.Run("Deterministic", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			other, err := base.Hash()
+			require.NoError(t, err)
+			assert.Equal(t, hash, other)
+		}
+	})
+
+	t.Run("Equivalent", func(t *testing.T) {
+		equivalent := base
+		equivalent.Image = &DockerImage{Tag: "busybox", Auth: &RegistryAuth{Username: "user"}, PullPolicy: PullAlways}
+		equivalent.Command = nil
+		equivalent.Entrypoint = []string{"sh", "-c"}
+		equivalent.OnWarning = func(string) {}
+		equivalent.Name = "other"
+		equivalent.IdempotencyKey = "key"
+		other, err := equivalent.Hash()
+		require.NoError(t, err)
+		assert.Equal(t, hash, other)
+	})
+
+	t.Run("Templated", func(t *testing.T) {
+		templated := base
+		templated.TemplateVars = map[string]string{"X": "2"}
+		other, err := templated.Hash()
+		require.NoError(t, err)
+		assert.NotEqual(t, hash, other)
+	})
+
+	t.Run("Different", func(t *testing.T) {
+		different := base
+		different.Memory = 1024
+		other, err := different.Hash()
+		require.NoError(t, err)
+		assert.NotEqual(t, hash, other)
+	})
+
+	// Every option must be hashed or deliberately excluded. A new option
+	// should be added to hashedOpts along with an increment of hashVersion.
+	t.Run("Exhaustive", func(t *testing.T) {
+		excluded := map[string]bool{
+			"Name": true, "IdempotencyKey": true, "Command": true, "Arguments": true,
+			"SensitiveEnv": true, "TemplateVars": true, "OnWarning": true,
+		}
+		hashed := reflect.TypeOf(hashedOpts{})
+		opts := reflect.TypeOf(ContainerOpts{})
+		for i := 0; i < opts.NumField(); i++ {
+			name := opts.Field(i).Name
+			if _, ok := hashed.FieldByName(name); !ok {
+				assert.True(t, excluded[name], "%s is neither hashed nor excluded", name)
+			}
+		}
+	})
+}
+
+func TestIdempotentName(t *testing.T) {
+	name := IdempotentName("team-a/job")
+	assert.Equal(t, name, IdempotentName("team-a/job"))
+	assert.NotEqual(t, name, IdempotentName("team-b/job"))
+
+	// Names must be valid for every runtime, including as Kubernetes pod names.
+	assert.Regexp(t, `^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`, name)
+}
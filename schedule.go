@@ -0,0 +1,150 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/beaker/runtime/clock"
+	log "github.com/sirupsen/logrus"
+)
+
+// StartCondition is the state another container must reach before a
+// dependent container is started.
+type StartCondition string
+
+const (
+	// StartWhenRunning starts a container once its dependency is running.
+	StartWhenRunning StartCondition = "running"
+
+	// StartWhenSucceeded starts a container once its dependency exits with
+	// code 0. If the dependency fails, the container is never started.
+	StartWhenSucceeded StartCondition = "succeeded"
+)
+
+// StartSchedule delays a container's start until a time, another container's
+// progress, or both. The container is started by the runtime once every
+// condition is met, so callers shouldn't start it themselves.
+//
+// Schedules are held in memory by the runtime which created the container. If
+// that runtime is closed or its process exits first, the container remains
+// created and must be started by the caller.
+type StartSchedule struct {
+	// (optional) At is the earliest time at which the container starts.
+	At time.Time
+
+	// (optional) After names a managed container on which this container
+	// depends.
+	After string
+
+	// (optional) Condition is the state After must reach. Defaults to
+	// StartWhenRunning.
+	Condition StartCondition
+}
+
+// Validate checks that a schedule is well formed.
+func (s *StartSchedule) Validate() error {
+	switch s.Condition {
+	case "", StartWhenRunning, StartWhenSucceeded:
+	default:
+		return fmt.Errorf("%q is not a valid start condition", s.Condition)
+	}
+	if s.Condition != "" && s.After == "" {
+		return errors.New("a start condition requires a dependency")
+	}
+	return nil
+}
+
+// dependencyPollInterval controls how often a dependency's status is checked.
+// Dependencies may take hours to finish, so this is slower than pollInterval.
+const dependencyPollInterval = time.Second
+
+// StartScheduled waits for a schedule's conditions, then starts the container.
+// The dependency is the container named by the schedule's After field, or nil
+// if the schedule has none. Time is measured by the context's clock; see
+// clock.NewContext.
+func StartScheduled(ctx context.Context, c Container, dependency Container, s *StartSchedule) error {
+	clk := clock.FromContext(ctx)
+	if delay := s.At.Sub(clk.Now()); delay > 0 {
+		timer := clk.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+
+	if dependency != nil {
+		if err := waitForCondition(ctx, dependency, s.Condition); err != nil {
+			return fmt.Errorf("waiting for %s: %w", dependency.Name(), err)
+		}
+	}
+	return c.Start(ctx)
+}
+
+// ScheduleStart starts a container in the background once its schedule's
+// conditions are met. The schedule is abandoned if the context ends first,
+// e.g. because the runtime is shutting down. Failures are logged since there's
+// no caller left to report them to.
+func ScheduleStart(ctx context.Context, c Container, dependency Container, s *StartSchedule) {
+	go func() {
+		err := StartScheduled(ctx, c, dependency, s)
+		if err == nil || ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			return
+		}
+		if _, infoErr := c.Info(ctx); errors.Is(infoErr, ErrNotFound) {
+			// The container was removed, so its schedule no longer matters.
+			return
+		}
+		log.WithError(err).Warnf("Failed to start scheduled container %s", c.Name())
+	}()
+}
+
+// waitForCondition polls a container until it reaches the given condition.
+func waitForCondition(ctx context.Context, c Container, condition StartCondition) error {
+	ticker := clock.FromContext(ctx).NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := c.Info(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch info.Status {
+		case StatusRunning:
+			if condition != StartWhenSucceeded {
+				return nil
+			}
+		case StatusExited:
+			if condition != StartWhenSucceeded {
+				// The dependency ran, however briefly.
+				return nil
+			}
+			if info.ExitCode == nil || *info.ExitCode != 0 {
+				return fmt.Errorf("dependency failed: %s", describeExit(info))
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// describeExit summarizes how a container exited.
+func describeExit(info *ContainerInfo) string {
+	description := "unknown exit code"
+	if info.ExitCode != nil {
+		description = fmt.Sprintf("exit code %d", *info.ExitCode)
+	}
+	if info.Message != "" {
+		description += " (" + info.Message + ")"
+	}
+	return description
+}
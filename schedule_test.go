@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/beaker/runtime/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticContainer reports fixed details and records whether it was started.
+type staticContainer struct {
+	fakeContainer
+	info    ContainerInfo
+	started bool
+}
+
+func (c *staticContainer) Info(ctx context.Context) (*ContainerInfo, error) {
+	info := c.info
+	return &info, nil
+}
+
+func (c *staticContainer) Start(ctx context.Context) error {
+	c.started = true
+	return nil
+}
+
+func TestStartScheduled(t *testing.T) {
+	ctx := context.Background()
+	exitCode := func(code int) *int { return &code }
+
+	t.Run("At", func(t *testing.T) {
+		clk := clock.NewFake(time.Unix(1000, 0))
+		c := &staticContainer{}
+		done := make(chan error)
+		go func() {
+			done <- StartScheduled(clock.NewContext(ctx, clk), c, nil, &StartSchedule{At: time.Unix(1060, 0)})
+		}()
+
+		require.Eventually(t, func() bool { return clk.Waiters() == 1 }, time.Second, time.Millisecond)
+		clk.Advance(59 * time.Second)
+		select {
+		case <-done:
+			t.Fatal("started early")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		clk.Advance(time.Second)
+		require.NoError(t, <-done)
+		assert.True(t, c.started)
+	})
+
+	t.Run("AfterRunning", func(t *testing.T) {
+		c := &staticContainer{}
+		dep := &staticContainer{info: ContainerInfo{Status: StatusRunning}}
+		require.NoError(t, StartScheduled(ctx, c, dep, &StartSchedule{After: "dep"}))
+		assert.True(t, c.started)
+	})
+
+	t.Run("AfterSucceeded", func(t *testing.T) {
+		c := &staticContainer{}
+		dep := &staticContainer{info: ContainerInfo{Status: StatusExited, ExitCode: exitCode(0)}}
+		schedule := &StartSchedule{After: "dep", Condition: StartWhenSucceeded}
+		require.NoError(t, StartScheduled(ctx, c, dep, schedule))
+		assert.True(t, c.started)
+	})
+
+	t.Run("AfterFailed", func(t *testing.T) {
+		c := &staticContainer{}
+		dep := &staticContainer{info: ContainerInfo{Status: StatusExited, ExitCode: exitCode(1), Message: "Error"}}
+		schedule := &StartSchedule{After: "dep", Condition: StartWhenSucceeded}
+		err := StartScheduled(ctx, c, dep, schedule)
+		assert.EqualError(t, err, "waiting for fake: dependency failed: exit code 1 (Error)")
+		assert.False(t, c.started)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		c := &staticContainer{}
+		dep := &staticContainer{info: ContainerInfo{Status: StatusRunning}}
+		schedule := &StartSchedule{After: "dep", Condition: StartWhenSucceeded}
+		assert.ErrorIs(t, StartScheduled(ctx, c, dep, schedule), context.DeadlineExceeded)
+		assert.False(t, c.started)
+	})
+}
+
+func TestStartScheduleValidate(t *testing.T) {
+	assert.NoError(t, (&StartSchedule{}).Validate())
+	assert.NoError(t, (&StartSchedule{After: "a", Condition: StartWhenSucceeded}).Validate())
+	assert.Error(t, (&StartSchedule{Condition: StartWhenRunning}).Validate())
+	assert.Error(t, (&StartSchedule{After: "a", Condition: "exited"}).Validate())
+}
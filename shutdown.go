@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ShutdownTimeout bounds how long Close waits for in-flight operations on
+// runtimes which drain them. Use Shutdown directly to choose another deadline.
+const ShutdownTimeout = 30 * time.Second
+
+// Shutdowner is implemented by runtimes which can shut down gracefully.
+type Shutdowner interface {
+	// Shutdown stops the runtime's background tasks and rejects new operations
+	// with ErrClosed, then waits for in-flight operations to finish until the
+	// context ends. The runtime's clients are closed either way, so operations
+	// still running at the deadline fail.
+	Shutdown(ctx context.Context) error
+}
+
+// Lifecycle tracks a runtime's in-flight operations and background tasks so
+// that it can shut down gracefully. Runtimes share one Lifecycle with the
+// containers they create. The zero value is not usable; call NewLifecycle.
+type Lifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+	active sync.WaitGroup
+}
+
+// NewLifecycle creates a Lifecycle which accepts operations until Shutdown.
+func NewLifecycle() *Lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Lifecycle{ctx: ctx, cancel: cancel}
+}
+
+// Context returns a context which is canceled when shutdown begins. Background
+// tasks should run with it rather than context.Background.
+func (l *Lifecycle) Context() context.Context {
+	return l.ctx
+}
+
+// lifecycleKey marks contexts of operations registered with a Lifecycle.
+type lifecycleKey struct{}
+
+// Begin registers an in-flight operation, returning a function to call when
+// it finishes. It returns ErrClosed once shutdown has begun.
+//
+// The returned context should be passed to nested operations, such as a Remove
+// which calls Stop, so they aren't rejected while their caller drains.
+func (l *Lifecycle) Begin(ctx context.Context) (context.Context, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed && ctx.Value(lifecycleKey{}) != l {
+		return nil, nil, ErrClosed
+	}
+	l.active.Add(1)
+	return context.WithValue(ctx, lifecycleKey{}, l), l.active.Done, nil
+}
+
+// Go runs a background task with a context which is canceled when shutdown
+// begins. Shutdown waits for the task to return. The task isn't run if
+// shutdown has already begun, in which case Go returns false.
+func (l *Lifecycle) Go(task func(ctx context.Context)) bool {
+	ctx, end, err := l.Begin(l.ctx)
+	if err != nil {
+		return false
+	}
+	go func() {
+		defer end()
+		task(ctx)
+	}()
+	return true
+}
+
+// hold registers a task which shutdown waits for even if it has begun, such as
+// cleanup which mustn't be abandoned. It must only be called while an
+// operation registered with Begin is in flight.
+func (l *Lifecycle) hold() func() {
+	l.active.Add(1)
+	return l.active.Done
+}
+
+// Shutdown rejects new operations, cancels background tasks, and waits for
+// in-flight operations and tasks to finish until the context ends.
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for in-flight operations: %w", ctx.Err())
+	}
+}
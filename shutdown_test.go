@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Drain", func(t *testing.T) {
+		l := NewLifecycle()
+		opCtx, end, err := l.Begin(ctx)
+		require.NoError(t, err)
+
+		var taskCanceled bool
+		l.Go(func(ctx context.Context) {
+			<-ctx.Done()
+			taskCanceled = true
+		})
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- l.Shutdown(ctx) }()
+
+		// New operations are rejected, but nested ones are allowed to drain.
+		assert.Eventually(t, func() bool {
+			_, _, err := l.Begin(ctx)
+			return err == ErrClosed
+		}, time.Second, time.Millisecond)
+		_, nestedEnd, err := l.Begin(opCtx)
+		require.NoError(t, err)
+		nestedEnd()
+
+		select {
+		case <-shutdown:
+			t.Fatal("Shutdown returned before the operation ended.")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		end()
+		require.NoError(t, <-shutdown)
+		assert.True(t, taskCanceled)
+		assert.Error(t, l.Context().Err())
+	})
+
+	t.Run("Cleanup", func(t *testing.T) {
+		l := NewLifecycle()
+		_, end, err := l.Begin(ctx)
+		require.NoError(t, err)
+
+		release := make(chan struct{})
+		var cleaned bool
+		CleanupAsync(l, "test", func(ctx context.Context) error {
+			<-release
+			cleaned = true
+			return ctx.Err()
+		})
+		end()
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- l.Shutdown(ctx) }()
+		select {
+		case <-shutdown:
+			t.Fatal("Shutdown returned before the cleanup ended.")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		// Cleanup isn't canceled by shutdown.
+		close(release)
+		require.NoError(t, <-shutdown)
+		assert.True(t, cleaned)
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		l := NewLifecycle()
+		_, end, err := l.Begin(ctx)
+		require.NoError(t, err)
+		defer end()
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, l.Shutdown(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("GoAfterShutdown", func(t *testing.T) {
+		l := NewLifecycle()
+		require.NoError(t, l.Shutdown(ctx))
+		assert.False(t, l.Go(func(ctx context.Context) { t.Error("Task shouldn't run after shutdown.") }))
+	})
+}
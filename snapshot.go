@@ -0,0 +1,75 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Snapshot is a prepared root filesystem from which containers can be created,
+// such as a committed image with pre-warmed package caches. Creating short,
+// repeated tasks from a snapshot avoids repeating their setup each time.
+type Snapshot struct {
+	// ID identifies the snapshot to its backend, e.g. a Docker image ID.
+	ID string
+
+	// Name is the reference under which the snapshot was saved, if any, e.g.
+	// "beaker/pip-cache:v1".
+	Name string
+
+	// Source is the name of the container the snapshot was committed from.
+	Source string
+
+	CreatedAt time.Time
+}
+
+// Snapshotter is implemented by runtimes which can save a container's
+// filesystem and create containers from it. Use CreateContainerFromSnapshot to
+// fall back gracefully on runtimes which can't.
+type Snapshotter interface {
+	// CommitSnapshot saves a container's filesystem, including changes made
+	// since it was created, as a snapshot. The container is paused while it's
+	// committed. The name is optional.
+	CommitSnapshot(ctx context.Context, c Container, name string) (*Snapshot, error)
+
+	// CreateContainerFromSnapshot creates a container as CreateContainer does,
+	// with the snapshot as its root filesystem. Options must not set an image.
+	CreateContainerFromSnapshot(ctx context.Context, snapshot *Snapshot, opts *ContainerOpts) (Container, error)
+
+	// RemoveSnapshot deletes a snapshot. Containers created from it are
+	// unaffected. It returns ErrNotFound if the snapshot doesn't exist.
+	RemoveSnapshot(ctx context.Context, snapshot *Snapshot) error
+}
+
+// CreateContainerFromSnapshot creates a container from a snapshot if the
+// runtime supports snapshots, or returns an error wrapping ErrNotImplemented
+// if it doesn't, in which case the caller should create the container from its
+// image instead.
+func CreateContainerFromSnapshot(
+	ctx context.Context,
+	rt Runtime,
+	snapshot *Snapshot,
+	opts *ContainerOpts,
+) (Container, error) {
+	snapshotter, ok := rt.(Snapshotter)
+	if !ok {
+		return nil, fmt.Errorf("snapshots are not supported (%w)", ErrNotImplemented)
+	}
+	if err := ValidateSnapshotOpts(snapshot, opts); err != nil {
+		return nil, err
+	}
+	return snapshotter.CreateContainerFromSnapshot(ctx, snapshot, opts)
+}
+
+// ValidateSnapshotOpts checks that options are suitable for creating a
+// container from a snapshot.
+func ValidateSnapshotOpts(snapshot *Snapshot, opts *ContainerOpts) error {
+	if snapshot == nil || snapshot.ID == "" {
+		return errors.New("snapshot must have an ID")
+	}
+	if opts.Image != nil {
+		return errors.New("a container can't be created from both an image and a snapshot")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotRuntime creates containers from snapshots as images named after
+// their IDs.
+type snapshotRuntime struct {
+	memoryRuntime
+}
+
+func (r *snapshotRuntime) CommitSnapshot(ctx context.Context, c Container, name string) (*Snapshot, error) {
+	return &Snapshot{ID: "sha256:" + c.Name(), Name: name, Source: c.Name()}, nil
+}
+
+func (r *snapshotRuntime) CreateContainerFromSnapshot(
+	ctx context.Context,
+	snapshot *Snapshot,
+	opts *ContainerOpts,
+) (Container, error) {
+	fromSnapshot := *opts
+	fromSnapshot.Image = &DockerImage{Tag: snapshot.ID}
+	return r.CreateContainer(ctx, &fromSnapshot)
+}
+
+func (r *snapshotRuntime) RemoveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	return nil
+}
+
+func TestCreateContainerFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	snapshot := &Snapshot{ID: "sha256:abc"}
+
+	_, err := CreateContainerFromSnapshot(ctx, &memoryRuntime{}, snapshot, &ContainerOpts{Name: "a"})
+	assert.ErrorIs(t, err, ErrNotImplemented)
+
+	rt := &snapshotRuntime{}
+	c, err := CreateContainerFromSnapshot(ctx, rt, snapshot, &ContainerOpts{Name: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:abc", c.(*memoryContainer).opts.Image.Tag)
+
+	_, err = CreateContainerFromSnapshot(ctx, rt, snapshot, &ContainerOpts{Image: &DockerImage{Tag: "busybox"}})
+	assert.EqualError(t, err, "a container can't be created from both an image and a snapshot")
+	_, err = CreateContainerFromSnapshot(ctx, rt, &Snapshot{}, &ContainerOpts{})
+	assert.EqualError(t, err, "snapshot must have an ID")
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SSHAgentPath is where a forwarded SSH agent socket is mounted in containers.
+// SSH_AUTH_SOCK is set to this path so that ssh and git find the agent.
+const SSHAgentPath = "/run/beaker/ssh-agent.sock"
+
+const sshAuthSockEnv = "SSH_AUTH_SOCK"
+
+// HostSSHAgent returns the socket of the SSH agent serving the current
+// process, as named by SSH_AUTH_SOCK.
+func HostSSHAgent() (string, error) {
+	socket := os.Getenv(sshAuthSockEnv)
+	if socket == "" {
+		return "", errors.New("no SSH agent is running; SSH_AUTH_SOCK is unset")
+	}
+	return socket, nil
+}
+
+// ValidateSSHAgent checks that a forwarded SSH agent socket exists and that
+// the container is interactive.
+func (o *ContainerOpts) ValidateSSHAgent() error {
+	if o.SSHAgent == "" {
+		return nil
+	}
+	if !o.Interactive {
+		return errors.New("SSH agent forwarding requires an interactive container")
+	}
+	if _, ok := o.Env[sshAuthSockEnv]; ok {
+		return fmt.Errorf("%s can't be set when forwarding an SSH agent", sshAuthSockEnv)
+	}
+
+	info, err := os.Stat(o.SSHAgent)
+	if err != nil {
+		return fmt.Errorf("forwarding SSH agent: %w", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("forwarding SSH agent: %s is not a socket", o.SSHAgent)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSSHAgent(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer l.Close()
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+
+	assert.NoError(t, (&ContainerOpts{}).ValidateSSHAgent())
+	assert.NoError(t, (&ContainerOpts{Interactive: true, SSHAgent: socket}).ValidateSSHAgent())
+
+	assert.Error(t, (&ContainerOpts{SSHAgent: socket}).ValidateSSHAgent(), "Not interactive")
+	assert.Error(t, (&ContainerOpts{
+		Interactive: true,
+		SSHAgent:    socket,
+		Env:         map[string]string{"SSH_AUTH_SOCK": "/tmp/agent"},
+	}).ValidateSSHAgent())
+	assert.Error(t, (&ContainerOpts{Interactive: true, SSHAgent: file}).ValidateSSHAgent())
+	assert.Error(t, (&ContainerOpts{Interactive: true, SSHAgent: filepath.Join(dir, "missing")}).ValidateSSHAgent())
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Signaler is implemented by containers which can deliver arbitrary signals to
+// their main process.
+type Signaler interface {
+	// Signal sends a signal by name, e.g. "SIGUSR1", to the container's main process.
+	Signal(ctx context.Context, signal string) error
+}
+
+// StopStage is one step of a stop escalation. Its signal is sent to the
+// container, which then has until the stage's timeout to exit before the next
+// stage begins.
+type StopStage struct {
+	Signal  string
+	Timeout time.Duration
+}
+
+// StopPolicy describes an escalating sequence of signals used to stop a
+// container. If the container survives every stage it is killed with SIGKILL.
+//
+// For example, a framework which checkpoints on SIGUSR1 might use:
+//
+//	StopPolicy{{"SIGUSR1", 30 * time.Second}, {"SIGTERM", 10 * time.Second}}
+type StopPolicy []StopStage
+
+// StopWithPolicy stops a container by escalating through the policy's stages.
+//
+// Stages are shortened as needed to finish before the context's deadline so
+// that the final SIGKILL is always sent. If the container can't deliver
+// arbitrary signals, the policy degrades to a single Stop with a timeout equal
+// to the policy's total duration.
+func StopWithPolicy(ctx context.Context, c Container, policy StopPolicy) error {
+	signaler, ok := c.(Signaler)
+	if !ok {
+		return stopWithTimeout(ctx, c, policy.total())
+	}
+
+	for i, stage := range policy {
+		if err := signaler.Signal(ctx, stage.Signal); err != nil {
+			if errors.Is(err, ErrNotImplemented) {
+				return stopWithTimeout(ctx, c, policy[i:].total())
+			}
+			return err
+		}
+
+		exited, err := waitForExit(ctx, c, stage.Timeout)
+		if err != nil || exited {
+			return err
+		}
+	}
+
+	var zero time.Duration
+	return c.Stop(ctx, &zero)
+}
+
+// total returns the sum of the policy's stage timeouts.
+func (p StopPolicy) total() time.Duration {
+	var total time.Duration
+	for _, stage := range p {
+		total += stage.Timeout
+	}
+	return total
+}
+
+// stopWithTimeout stops a container, shortening the timeout to respect the
+// context's deadline.
+func stopWithTimeout(ctx context.Context, c Container, timeout time.Duration) error {
+	timeout = capTimeout(ctx, timeout)
+	return c.Stop(ctx, &timeout)
+}
+
+// waitForExit waits for a container to exit until the timeout elapses. The
+// timeout is shortened to leave time for a final kill before the context's
+// deadline. It returns true if the container exited.
+func waitForExit(ctx context.Context, c Container, timeout time.Duration) (bool, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, capTimeout(ctx, timeout))
+	defer cancel()
+
+	_, err := WaitForExit(waitCtx, c)
+	switch {
+	case err == nil:
+		return true, nil
+	case waitCtx.Err() != nil && ctx.Err() == nil:
+		// The stage timed out; the caller's context is still valid.
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// capTimeout shortens a timeout to end before the context's deadline, reserving
+// a margin in which to forcibly stop the container.
+func capTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	const killMargin = time.Second
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
+	}
+	remaining := time.Until(deadline) - killMargin
+	if remaining < 0 {
+		remaining = 0
+	}
+	if timeout > remaining {
+		return remaining
+	}
+	return timeout
+}
@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/beaker/runtime/logging"
+)
+
+// fakeContainer records signals and exits when it receives exitSignal.
+type fakeContainer struct {
+	exitSignal string
+
+	mu      sync.Mutex
+	signals []string
+	stopped *time.Duration
+	exited  bool
+}
+
+func (c *fakeContainer) Name() string                    { return "fake" }
+func (c *fakeContainer) Start(ctx context.Context) error { return nil }
+func (c *fakeContainer) Remove(ctx context.Context, opts RemoveOpts) error {
+	return nil
+}
+
+func (c *fakeContainer) Logs(ctx context.Context, opts LogsOpts) (logging.LogReader, error) {
+	return nil, ErrNotImplemented
+}
+
+func (c *fakeContainer) Stats(ctx context.Context) (*ContainerStats, error) {
+	return nil, ErrNotImplemented
+}
+
+func (c *fakeContainer) Info(ctx context.Context) (*ContainerInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.exited {
+		return &ContainerInfo{Status: StatusExited}, nil
+	}
+	return &ContainerInfo{Status: StatusRunning}, nil
+}
+
+func (c *fakeContainer) Stop(ctx context.Context, timeout *time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = timeout
+	c.exited = true
+	return nil
+}
+
+// signalingContainer is a fakeContainer which implements Signaler.
+type signalingContainer struct{ fakeContainer }
+
+func (c *signalingContainer) Signal(ctx context.Context, signal string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.signals = append(c.signals, signal)
+	if signal == c.exitSignal {
+		c.exited = true
+	}
+	return nil
+}
+
+func TestStopWithPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy := StopPolicy{
+		{Signal: "SIGUSR1", Timeout: 10 * time.Millisecond},
+		{Signal: "SIGTERM", Timeout: 20 * time.Millisecond},
+	}
+
+	t.Run("ExitEarly", func(t *testing.T) {
+		c := &signalingContainer{fakeContainer{exitSignal: "SIGUSR1"}}
+		require.NoError(t, StopWithPolicy(ctx, c, policy))
+		assert.Equal(t, []string{"SIGUSR1"}, c.signals)
+		assert.Nil(t, c.stopped)
+	})
+
+	t.Run("Escalate", func(t *testing.T) {
+		c := &signalingContainer{fakeContainer{exitSignal: "SIGTERM"}}
+		require.NoError(t, StopWithPolicy(ctx, c, policy))
+		assert.Equal(t, []string{"SIGUSR1", "SIGTERM"}, c.signals)
+		assert.Nil(t, c.stopped)
+	})
+
+	t.Run("Kill", func(t *testing.T) {
+		c := &signalingContainer{}
+		require.NoError(t, StopWithPolicy(ctx, c, policy))
+		assert.Equal(t, []string{"SIGUSR1", "SIGTERM"}, c.signals)
+		require.NotNil(t, c.stopped)
+		assert.Zero(t, *c.stopped)
+	})
+
+	t.Run("NoSignals", func(t *testing.T) {
+		c := &fakeContainer{}
+		require.NoError(t, StopWithPolicy(ctx, c, policy))
+		require.NotNil(t, c.stopped)
+		assert.Equal(t, 30*time.Millisecond, *c.stopped)
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		defer cancel()
+		c := &fakeContainer{}
+		require.NoError(t, StopWithPolicy(ctx, c, StopPolicy{{Signal: "SIGTERM", Timeout: time.Hour}}))
+		require.NotNil(t, c.stopped)
+		assert.Zero(t, *c.stopped, "Timeout should leave time to kill before the deadline.")
+	})
+}
@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	"strings"
+)
+
+// ExpandTemplate replaces each "${NAME}" in s with the value of NAME in vars.
+// References to names not in vars are left unchanged so they can still be
+// expanded by a shell inside the container. Use "$${NAME}" for a literal
+// "${NAME}" when NAME is a template variable.
+func ExpandTemplate(s string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(s, "${") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i == -1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end == -1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end += i
+
+		name := s[i+2 : end]
+		value, ok := vars[name]
+		switch {
+		case i > 0 && s[i-1] == '$' && ok:
+			// Escaped reference; drop the escaping '$'.
+			b.WriteString(s[:i-1])
+			b.WriteString(s[i : end+1])
+		case ok:
+			b.WriteString(s[:i])
+			b.WriteString(value)
+		default:
+			b.WriteString(s[:end+1])
+		}
+		s = s[end+1:]
+	}
+}
+
+// expandAll applies ExpandTemplate to each string in a slice.
+func expandAll(values []string, vars map[string]string) []string {
+	if len(vars) == 0 || values == nil {
+		return values
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = ExpandTemplate(v, vars)
+	}
+	return result
+}
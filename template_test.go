@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	vars := map[string]string{"BEAKER_NODE": "node-1", "GPU_IDS": "0,1"}
+	tests := map[string]struct {
+		Input    string
+		Expected string
+	}{
+		"Empty":      {"", ""},
+		"Plain":      {"no templates", "no templates"},
+		"Single":     {"${BEAKER_NODE}", "node-1"},
+		"Multiple":   {"--node=${BEAKER_NODE} --gpus=${GPU_IDS}", "--node=node-1 --gpus=0,1"},
+		"Unknown":    {"echo ${HOME}", "echo ${HOME}"},
+		"Escaped":    {"$${BEAKER_NODE}", "${BEAKER_NODE}"},
+		"Unescaped":  {"$${HOME}", "$${HOME}"},
+		"Unclosed":   {"${BEAKER_NODE", "${BEAKER_NODE"},
+		"NoBraces":   {"$BEAKER_NODE", "$BEAKER_NODE"},
+		"Surrounded": {"a${GPU_IDS}b", "a0,1b"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, ExpandTemplate(test.Input, vars))
+		})
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	opts := ContainerOpts{
+		Env:          map[string]string{"NODE": "${BEAKER_NODE}", "HOME": "/root"},
+		TemplateVars: map[string]string{"BEAKER_NODE": "node-1"},
+	}
+	assert.Equal(t, map[string]string{"NODE": "node-1", "HOME": "/root"}, opts.ResolveEnv())
+	assert.Equal(t, "${BEAKER_NODE}", opts.Env["NODE"], "Options must not be modified.")
+}
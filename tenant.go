@@ -0,0 +1,159 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// TenantLabel is set on containers created through a TenantRuntime to the
+// name of their tenant.
+const TenantLabel = "beaker.org/tenant"
+
+// tenantName matches tenant names, which must be valid label values on every
+// runtime.
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+
+// TenantRuntime is a view of a shared runtime scoped to one tenant, so that a
+// single process can serve several local services without them seeing or
+// affecting each other's containers. Containers created through the view are
+// labeled with TenantLabel, and only containers with the tenant's label are
+// listed or found.
+//
+// The view doesn't own the underlying runtime; closing it has no effect. It
+// implements Historian, scoped to the tenant, but not Snapshotter or
+// Shutdowner, since snapshots and the runtime's lifecycle are shared between
+// tenants. Use the underlying runtime for those.
+type TenantRuntime struct {
+	Runtime
+	tenant string
+}
+
+// WithTenant scopes a runtime to a tenant.
+func WithTenant(rt Runtime, tenant string) (*TenantRuntime, error) {
+	if !tenantName.MatchString(tenant) {
+		return nil, fmt.Errorf("invalid tenant name %q", tenant)
+	}
+	return &TenantRuntime{Runtime: rt, tenant: tenant}, nil
+}
+
+// Tenant returns the name of the view's tenant.
+func (r *TenantRuntime) Tenant() string {
+	return r.tenant
+}
+
+// Close implements the io.Closer interface. The underlying runtime is shared,
+// so it's left open.
+func (r *TenantRuntime) Close() error {
+	return nil
+}
+
+// CreateContainer creates a container labeled with the view's tenant. Other
+// containers named by the options, such as NetworkFrom or a container IPCMode,
+// must belong to the same tenant. Idempotency keys are scoped to the tenant.
+func (r *TenantRuntime) CreateContainer(ctx context.Context, opts *ContainerOpts) (Container, error) {
+	if tenant, ok := opts.Labels[TenantLabel]; ok && tenant != r.tenant {
+		return nil, fmt.Errorf("forbidden label: %s", TenantLabel)
+	}
+	for _, name := range opts.dependencies() {
+		if _, err := r.Container(ctx, name); err != nil {
+			return nil, fmt.Errorf("container %s: %w", name, err)
+		}
+	}
+
+	scoped := *opts
+	scoped.Labels = make(map[string]string, len(opts.Labels)+1)
+	for k, v := range opts.Labels {
+		scoped.Labels[k] = v
+	}
+	scoped.Labels[TenantLabel] = r.tenant
+	if opts.IdempotencyKey != "" {
+		scoped.IdempotencyKey = r.tenant + "/" + opts.IdempotencyKey
+	}
+	return r.Runtime.CreateContainer(ctx, &scoped)
+}
+
+// dependencies lists the other containers named by the options, whose
+// namespaces the container joins or whose exit it waits for.
+func (o *ContainerOpts) dependencies() []string {
+	var names []string
+	if o.NetworkFrom != "" {
+		names = append(names, o.NetworkFrom)
+	}
+	if o.PIDFrom != "" {
+		names = append(names, o.PIDFrom)
+	}
+	if name, ok := o.IPCMode.Container(); ok && name != "" {
+		names = append(names, name)
+	}
+	if o.Schedule != nil && o.Schedule.After != "" {
+		names = append(names, o.Schedule.After)
+	}
+	return names
+}
+
+// ListContainers lists the tenant's containers.
+func (r *TenantRuntime) ListContainers(ctx context.Context) ([]Container, error) {
+	all, err := r.Runtime.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []Container
+	for _, c := range all {
+		ok, err := r.owns(ctx, c)
+		if errors.Is(err, ErrNotFound) {
+			continue // Removed while listing.
+		} else if err != nil {
+			return nil, err
+		}
+		if ok {
+			containers = append(containers, c)
+		}
+	}
+	return containers, nil
+}
+
+// Container finds one of the tenant's containers by name. It returns
+// ErrNotFound if the container doesn't exist or belongs to another tenant.
+func (r *TenantRuntime) Container(ctx context.Context, name string) (Container, error) {
+	c, err := findContainer(ctx, r.Runtime, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := r.owns(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+// History implements Historian if the underlying runtime does, listing only
+// the tenant's containers.
+func (r *TenantRuntime) History(ctx context.Context, filter HistoryFilter) ([]HistoryRecord, error) {
+	historian, ok := r.Runtime.(Historian)
+	if !ok {
+		return nil, fmt.Errorf("container history is not supported (%w)", ErrNotImplemented)
+	}
+
+	scoped := filter
+	scoped.Labels = make(map[string]string, len(filter.Labels)+1)
+	for k, v := range filter.Labels {
+		scoped.Labels[k] = v
+	}
+	scoped.Labels[TenantLabel] = r.tenant
+	return historian.History(ctx, scoped)
+}
+
+func (r *TenantRuntime) owns(ctx context.Context, c Container) (bool, error) {
+	info, err := c.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+	return info.Labels[TenantLabel] == r.tenant, nil
+}
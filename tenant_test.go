@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryContainer is a container of a memoryRuntime.
+type memoryContainer struct {
+	fakeContainer
+	name string
+	opts ContainerOpts
+}
+
+func (c *memoryContainer) Name() string { return c.name }
+
+func (c *memoryContainer) Info(ctx context.Context) (*ContainerInfo, error) {
+	return &ContainerInfo{Status: StatusCreated, Labels: c.opts.Labels}, nil
+}
+
+// memoryRuntime creates containers in memory. It doesn't support lookups by
+// name, so they fall back to listing.
+type memoryRuntime struct {
+	containers []*memoryContainer
+	closed     bool
+}
+
+func (r *memoryRuntime) Close() error                   { r.closed = true; return nil }
+func (r *memoryRuntime) Ping(ctx context.Context) error { return nil }
+
+func (r *memoryRuntime) PullImage(ctx context.Context, image *DockerImage, policy PullPolicy, quiet bool) error {
+	return nil
+}
+
+func (r *memoryRuntime) CreateContainer(ctx context.Context, opts *ContainerOpts) (Container, error) {
+	c := &memoryContainer{name: opts.Name, opts: *opts}
+	r.containers = append(r.containers, c)
+	return c, nil
+}
+
+func (r *memoryRuntime) ListContainers(ctx context.Context) ([]Container, error) {
+	containers := make([]Container, len(r.containers))
+	for i, c := range r.containers {
+		containers[i] = c
+	}
+	return containers, nil
+}
+
+func TestTenantRuntime(t *testing.T) {
+	ctx := context.Background()
+	shared := &memoryRuntime{}
+	a, err := WithTenant(shared, "team-a")
+	require.NoError(t, err)
+	b, err := WithTenant(shared, "team-b")
+	require.NoError(t, err)
+
+	_, err = a.CreateContainer(ctx, &ContainerOpts{Name: "a1", IdempotencyKey: "job", Labels: map[string]string{"app": "x"}})
+	require.NoError(t, err)
+	_, err = b.CreateContainer(ctx, &ContainerOpts{Name: "b1"})
+	require.NoError(t, err)
+
+	created := shared.containers[0].opts
+	assert.Equal(t, map[string]string{"app": "x", TenantLabel: "team-a"}, created.Labels)
+	assert.Equal(t, "team-a/job", created.IdempotencyKey)
+
+	listed, err := a.ListContainers(ctx)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "a1", listed[0].Name())
+
+	c, err := b.Container(ctx, "b1")
+	require.NoError(t, err)
+	assert.Equal(t, "b1", c.Name())
+	_, err = b.Container(ctx, "a1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Tenants can't reach each other's containers through options.
+	_, err = b.CreateContainer(ctx, &ContainerOpts{Name: "b2", NetworkFrom: "a1"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = b.CreateContainer(ctx, &ContainerOpts{Name: "b2", IPCMode: "container:a1"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = b.CreateContainer(ctx, &ContainerOpts{Labels: map[string]string{TenantLabel: "team-a"}})
+	assert.Error(t, err)
+
+	require.NoError(t, a.Close())
+	assert.False(t, shared.closed)
+
+	_, err = WithTenant(shared, "not a label")
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
+// ByteSize is a quantity of bytes which may be written in configuration as a
+// number of bytes or a human-readable string such as "16GiB". See
+// ParseByteSize for the accepted forms.
+type ByteSize int64
+
+// ParseByteSize parses a size such as "512MiB", "1.5g", or "4096". IEC units
+// (KiB, MiB, GiB, ...) and Docker's single-letter units (k, m, g, ...) are
+// binary; SI units (kB, MB, GB, ...) are decimal. Units are case-insensitive.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	unit := strings.ToLower(strings.TrimLeft(s, "0123456789. "))
+
+	parse := units.RAMInBytes
+	if len(unit) == 2 && unit[1] == 'b' {
+		parse = units.FromHumanSize
+	}
+	n, err := parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid size", s)
+	}
+	return ByteSize(n), nil
+}
+
+// String formats the size with binary units, rounded to four significant
+// digits, e.g. "1.5GiB".
+func (b ByteSize) String() string {
+	return units.BytesSize(float64(b))
+}
+
+// MarshalText encodes the size exactly, in the largest binary unit which
+// divides it, e.g. "16GiB" or "1000".
+func (b ByteSize) MarshalText() ([]byte, error) {
+	for _, u := range []struct {
+		size int64
+		name string
+	}{
+		{units.PiB, "PiB"},
+		{units.TiB, "TiB"},
+		{units.GiB, "GiB"},
+		{units.MiB, "MiB"},
+		{units.KiB, "KiB"},
+	} {
+		if b != 0 && int64(b)%u.size == 0 {
+			return []byte(strconv.FormatInt(int64(b)/u.size, 10) + u.name), nil
+		}
+	}
+	return []byte(strconv.FormatInt(int64(b), 10)), nil
+}
+
+// UnmarshalText decodes a size with ParseByteSize.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// UnmarshalJSON decodes a size from either a number of bytes or a string.
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("size must be a number or string: %w", err)
+	}
+	return b.UnmarshalText([]byte(s))
+}
+
+// Duration is a length of time which may be written in configuration as a
+// number of seconds or a string such as "90m" or "2d12h". See ParseDuration
+// for the accepted forms.
+type Duration time.Duration
+
+var daysRegex = regexp.MustCompile(`^(\d+)d(.*)$`)
+
+// ParseDuration parses a duration as time.ParseDuration does, additionally
+// accepting a leading number of days, e.g. "2d" or "1d12h".
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var days time.Duration
+	if m := daysRegex.FindStringSubmatch(s); m != nil {
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a valid duration", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		if s = m[2]; s == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return days + d, nil
+}
+
+// String formats the duration as time.Duration does.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText encodes the duration as time.Duration's string form.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a duration with ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalJSON decodes a duration from either a number of seconds or a
+// string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a number or string: %w", err)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// ResourceLimits are a container's limits as written in configuration, with
+// human-readable sizes and durations. Zero values leave limits unset.
+type ResourceLimits struct {
+	CPUCount     float64  `json:"cpuCount,omitempty"`
+	Memory       ByteSize `json:"memory,omitempty"`
+	SharedMemory ByteSize `json:"sharedMemory,omitempty"`
+	MaxRuntime   Duration `json:"maxRuntime,omitempty"`
+}
+
+// Apply sets each of the limits which is non-zero on the container options.
+func (l *ResourceLimits) Apply(o *ContainerOpts) {
+	if l.CPUCount != 0 {
+		o.CPUCount = l.CPUCount
+	}
+	if l.Memory != 0 {
+		o.Memory = int64(l.Memory)
+	}
+	if l.SharedMemory != 0 {
+		o.SharedMemory = int64(l.SharedMemory)
+	}
+	if l.MaxRuntime != 0 {
+		o.MaxRuntime = time.Duration(l.MaxRuntime)
+	}
+}
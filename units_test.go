@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]ByteSize{
+		"4096":   4096,
+		"512b":   512,
+		"16GiB":  16 << 30,
+		"16gib":  16 << 30,
+		"16g":    16 << 30,
+		"16 GB":  16e9,
+		"1.5MiB": 3 << 19,
+		"64kB":   64000,
+		" 2Ti ":  2 << 40,
+	}
+	for s, expected := range tests {
+		size, err := ParseByteSize(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, size, s)
+		}
+	}
+
+	for _, invalid := range []string{"", "GiB", "-1GiB", "16XB", "sixteen"} {
+		_, err := ParseByteSize(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestByteSizeText(t *testing.T) {
+	for size, expected := range map[ByteSize]string{
+		0:         "0",
+		1000:      "1000",
+		16 << 30:  "16GiB",
+		3 << 19:   "1536KiB",
+		1<<20 + 1: "1048577",
+	} {
+		text, err := size.MarshalText()
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(text))
+
+		var decoded ByteSize
+		require.NoError(t, decoded.UnmarshalText(text))
+		assert.Equal(t, size, decoded)
+	}
+	assert.Equal(t, "1.5GiB", ByteSize(3<<29).String())
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90m":   90 * time.Minute,
+		"2d":    48 * time.Hour,
+		"1d12h": 36 * time.Hour,
+		"1h30m": 90 * time.Minute,
+		"1.5s":  1500 * time.Millisecond,
+	}
+	for s, expected := range tests {
+		d, err := ParseDuration(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, d, s)
+		}
+	}
+
+	for _, invalid := range []string{"", "d", "2days", "1x"} {
+		_, err := ParseDuration(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestResourceLimits(t *testing.T) {
+	var limits ResourceLimits
+	require.NoError(t, json.Unmarshal([]byte(
+		`{"cpuCount":2,"memory":"16GiB","sharedMemory":1048576,"maxRuntime":"1d"}`,
+	), &limits))
+	assert.Equal(t, ResourceLimits{
+		CPUCount:     2,
+		Memory:       16 << 30,
+		SharedMemory: 1 << 20,
+		MaxRuntime:   Duration(24 * time.Hour),
+	}, limits)
+
+	b, err := json.Marshal(&limits)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"cpuCount":2,"memory":"16GiB","sharedMemory":"1MiB","maxRuntime":"24h0m0s"}`, string(b))
+
+	var seconds ResourceLimits
+	require.NoError(t, json.Unmarshal([]byte(`{"maxRuntime":90}`), &seconds))
+	assert.Equal(t, Duration(90*time.Second), seconds.MaxRuntime)
+	assert.Error(t, json.Unmarshal([]byte(`{"memory":"lots"}`), &seconds))
+
+	opts := ContainerOpts{Memory: 1, CPUCount: 1}
+	(&ResourceLimits{Memory: 2 << 30}).Apply(&opts)
+	assert.Equal(t, ContainerOpts{Memory: 2 << 30, CPUCount: 1}, opts)
+}
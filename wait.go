@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/beaker/runtime/clock"
+	log "github.com/sirupsen/logrus"
+)
+
+// pollInterval controls how often a container's status is checked while
+// waiting for it to exit.
+const pollInterval = 100 * time.Millisecond
+
+// WaitForExit polls a container until it exits and returns its final details.
+// It returns early with the context's error if the context ends first. Polls
+// are paced by the context's clock; see clock.NewContext.
+func WaitForExit(ctx context.Context, c Container) (*ContainerInfo, error) {
+	ticker := clock.FromContext(ctx).NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		info, err := c.Info(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if info.Status == StatusExited {
+			return info, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// removeOnExitMaxInterval caps the backoff between checks of a container which
+// is to be removed on exit. Containers may run for days, and checks may cost an
+// API call, so they slow down the longer a container runs.
+const removeOnExitMaxInterval = 30 * time.Second
+
+// RemoveOnExit waits in the background for a container to exit, then removes
+// it. Checks back off from pollInterval to removeOnExitMaxInterval. Failures
+// are retried, since giving up would leak the container, until the container
+// is found to be gone. The task runs under the lifecycle, so it's canceled
+// when the runtime shuts down.
+func RemoveOnExit(life *Lifecycle, c Container) {
+	life.Go(func(ctx context.Context) { removeOnExit(ctx, c) })
+}
+
+func removeOnExit(ctx context.Context, c Container) {
+	clk := clock.FromContext(ctx)
+	for interval := pollInterval; ; interval *= 2 {
+		removed, err := removeIfExited(ctx, c)
+		switch {
+		case removed, errors.Is(err, ErrNotFound), errors.Is(err, ErrClosed), ctx.Err() != nil:
+			return
+		case err != nil:
+			log.WithError(err).Debugf("Failed to remove container %s on exit; retrying", c.Name())
+		}
+
+		if interval > removeOnExitMaxInterval {
+			interval = removeOnExitMaxInterval
+		}
+		timer := clk.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+// Removals tracks the containers being removed on exit by a runtime which
+// removes them itself. Such runtimes label the containers with AutoRemoveLabel
+// and re-arm their removal whenever they're listed or looked up, so that a
+// runtime resumes removing them after its process restarts. A runtime and its
+// containers share one. Removals is safe for concurrent use.
+type Removals struct {
+	mu    sync.Mutex
+	armed map[string]bool
+}
+
+// NewRemovals creates an empty set of removals.
+func NewRemovals() *Removals {
+	return &Removals{armed: make(map[string]bool)}
+}
+
+// Arm removes a container on exit as RemoveOnExit does, unless its removal is
+// already armed.
+func (r *Removals) Arm(life *Lifecycle, c Container) {
+	if !r.arm(c.Name()) {
+		return
+	}
+	life.Go(func(ctx context.Context) {
+		defer r.disarm(c.Name())
+		removeOnExit(ctx, c)
+	})
+}
+
+// IsArmed returns true if a container's removal is armed.
+func (r *Removals) IsArmed(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.armed[name]
+}
+
+func (r *Removals) arm(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.armed[name] {
+		return false
+	}
+	r.armed[name] = true
+	return true
+}
+
+func (r *Removals) disarm(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.armed, name)
+}
+
+// removeIfExited removes a container if it has exited.
+func removeIfExited(ctx context.Context, c Container) (bool, error) {
+	info, err := c.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+	if info.Status != StatusExited {
+		return false, nil
+	}
+	if err := c.Remove(ctx, RemoveOpts{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
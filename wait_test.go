@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedContainer reports a scripted sequence of statuses and errors from
+// Info, repeating the last one, and records its removal.
+type scriptedContainer struct {
+	fakeContainer
+
+	mu      sync.Mutex
+	script  []error // A nil error reports the next status.
+	status  []ContainerStatus
+	removed chan struct{}
+}
+
+func (c *scriptedContainer) Info(ctx context.Context) (*ContainerInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err, status := c.script[0], c.status[0]
+	if len(c.script) > 1 {
+		c.script, c.status = c.script[1:], c.status[1:]
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ContainerInfo{Status: status}, nil
+}
+
+func (c *scriptedContainer) Remove(ctx context.Context, opts RemoveOpts) error {
+	close(c.removed)
+	return nil
+}
+
+func TestRemoveOnExit(t *testing.T) {
+	life := NewLifecycle()
+	c := &scriptedContainer{
+		script:  []error{errors.New("transient"), nil, nil},
+		status:  []ContainerStatus{0, StatusRunning, StatusExited},
+		removed: make(chan struct{}),
+	}
+
+	// Transient failures don't abandon the container.
+	RemoveOnExit(life, c)
+	select {
+	case <-c.removed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("container wasn't removed")
+	}
+	require.NoError(t, life.Shutdown(context.Background()))
+}
+
+func TestRemoveOnExitShutdown(t *testing.T) {
+	life := NewLifecycle()
+	c := &scriptedContainer{
+		script:  []error{nil},
+		status:  []ContainerStatus{StatusRunning},
+		removed: make(chan struct{}),
+	}
+	RemoveOnExit(life, c)
+
+	// Shutdown stops the watcher and waits for it.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, life.Shutdown(ctx))
+
+	// Nothing is watched after shutdown.
+	RemoveOnExit(life, c)
+	select {
+	case <-c.removed:
+		t.Fatal("container was removed")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestRemovals(t *testing.T) {
+	life := NewLifecycle()
+	removals := NewRemovals()
+	c := &scriptedContainer{
+		script:  []error{nil, nil},
+		status:  []ContainerStatus{StatusRunning, StatusExited},
+		removed: make(chan struct{}),
+	}
+
+	// Re-arming a container, e.g. each time it's listed, watches it once.
+	// A second watcher would close the removed channel again and panic.
+	removals.Arm(life, c)
+	removals.Arm(life, c)
+	select {
+	case <-c.removed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("container wasn't removed")
+	}
+	require.NoError(t, life.Shutdown(context.Background()))
+	require.False(t, removals.IsArmed(c.Name()))
+}
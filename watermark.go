@@ -0,0 +1,145 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Watermark is a threshold on a container statistic which, once crossed for
+// long enough, raises an event. For example, a watermark on
+// MemoryUsagePercentStat above 90 for 30 seconds warns of an imminent OOM, and
+// one on GPUUtilizationPercentStat below 5 for 30 minutes flags a GPU
+// reservation which is going to waste.
+type Watermark struct {
+	// Reason identifies the watermark in its events, e.g. "MemoryHigh".
+	Reason string
+
+	Stat      StatType
+	Threshold float64
+
+	// Below crosses the watermark when the statistic falls below the
+	// threshold, e.g. to detect idle resources. Otherwise the watermark is
+	// crossed when the statistic rises above the threshold.
+	Below bool
+
+	// (optional) For is how long the watermark must remain crossed before an
+	// event is raised. Brief spikes shorter than this are ignored.
+	For time.Duration
+}
+
+func (w *Watermark) crossed(value float64) bool {
+	if w.Below {
+		return value < w.Threshold
+	}
+	return value > w.Threshold
+}
+
+// watermarkState tracks one watermark for one container.
+type watermarkState struct {
+	// since is when the watermark was crossed, or zero if it isn't.
+	since time.Time
+
+	// raised is true if an event was raised and the watermark hasn't yet
+	// recovered.
+	raised bool
+	count  int
+}
+
+// WatermarkMonitor raises events as containers cross watermarks. It's fed
+// samples from Container.Stats, so watermarks are evaluated as often as the
+// caller collects stats. Watermarks on statistics a runtime doesn't report
+// never fire, so GPU watermarks require the caller to add GPU stats to each
+// sample with SetGPUStats.
+//
+// A warning event is raised once the watermark has been crossed for its
+// duration, and a normal event with the same reason is raised when it
+// recovers. A WatermarkMonitor is safe for concurrent use.
+type WatermarkMonitor struct {
+	watermarks []Watermark
+	onEvent    func(container string, e Event)
+
+	mu     sync.Mutex
+	states map[string][]watermarkState
+}
+
+// NewWatermarkMonitor creates a monitor which reports events for a container,
+// identified by name, to a callback. The callback is invoked synchronously from
+// Observe.
+func NewWatermarkMonitor(watermarks []Watermark, onEvent func(container string, e Event)) *WatermarkMonitor {
+	return &WatermarkMonitor{
+		watermarks: watermarks,
+		onEvent:    onEvent,
+		states:     make(map[string][]watermarkState),
+	}
+}
+
+// Observe evaluates a sample of a container's stats against each watermark.
+func (m *WatermarkMonitor) Observe(container string, stats *ContainerStats) {
+	var events []Event
+
+	m.mu.Lock()
+	states, ok := m.states[container]
+	if !ok {
+		states = make([]watermarkState, len(m.watermarks))
+		m.states[container] = states
+	}
+	for i := range m.watermarks {
+		w, state := &m.watermarks[i], &states[i]
+		value, ok := stats.Stats[w.Stat]
+		if !ok {
+			continue
+		}
+
+		if !w.crossed(value) {
+			if state.raised {
+				events = append(events, Event{
+					Time:    stats.Time,
+					Reason:  w.Reason,
+					Message: fmt.Sprintf("%s recovered to %g", w.Stat, value),
+					Count:   state.count,
+				})
+			}
+			state.since, state.raised = time.Time{}, false
+			continue
+		}
+
+		if state.since.IsZero() {
+			state.since = stats.Time
+		}
+		if !state.raised && stats.Time.Sub(state.since) >= w.For {
+			state.raised = true
+			state.count++
+			events = append(events, Event{
+				Time:    stats.Time,
+				Warning: true,
+				Reason:  w.Reason,
+				Message: w.describe(value),
+				Count:   state.count,
+			})
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range events {
+		m.onEvent(container, e)
+	}
+}
+
+// Forget discards a container's state, e.g. once it has been removed.
+func (m *WatermarkMonitor) Forget(container string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.states, container)
+}
+
+func (w *Watermark) describe(value float64) string {
+	direction := "above"
+	if w.Below {
+		direction = "below"
+	}
+	if w.For == 0 {
+		return fmt.Sprintf("%s is %s %g (currently %g)", w.Stat, direction, w.Threshold, value)
+	}
+	return fmt.Sprintf("%s has been %s %g for %s (currently %g)", w.Stat, direction, w.Threshold, w.For, value)
+}
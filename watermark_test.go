@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatermarkMonitor(t *testing.T) {
+	type event struct {
+		container string
+		Event
+	}
+	var events []event
+	m := NewWatermarkMonitor([]Watermark{
+		{Reason: "MemoryHigh", Stat: MemoryUsagePercentStat, Threshold: 90, For: 10 * time.Second},
+		{Reason: "CPUIdle", Stat: CPUUsagePercentStat, Threshold: 1, Below: true},
+	}, func(container string, e Event) {
+		events = append(events, event{container, e})
+	})
+
+	start := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(offset time.Duration, memory float64) *ContainerStats {
+		return &ContainerStats{
+			Time:  start.Add(offset),
+			Stats: map[StatType]float64{MemoryUsagePercentStat: memory},
+		}
+	}
+
+	// Brief spikes are ignored.
+	m.Observe("a", sample(0, 95))
+	m.Observe("a", sample(5*time.Second, 80))
+	m.Observe("a", sample(10*time.Second, 95))
+	assert.Empty(t, events)
+
+	// Crossing for the full duration raises a single warning.
+	m.Observe("a", sample(15*time.Second, 96))
+	m.Observe("a", sample(20*time.Second, 97))
+	m.Observe("b", sample(20*time.Second, 99))
+	m.Observe("a", sample(25*time.Second, 98))
+	assert.Equal(t, []event{{"a", Event{
+		Time:    start.Add(20 * time.Second),
+		Warning: true,
+		Reason:  "MemoryHigh",
+		Message: "MemoryUsagePercent has been above 90 for 10s (currently 97)",
+		Count:   1,
+	}}}, events)
+
+	// Recovery raises a normal event.
+	events = nil
+	m.Observe("a", sample(30*time.Second, 50))
+	assert.Equal(t, []event{{"a", Event{
+		Time:    start.Add(30 * time.Second),
+		Reason:  "MemoryHigh",
+		Message: "MemoryUsagePercent recovered to 50",
+		Count:   1,
+	}}}, events)
+
+	// Watermarks without a duration fire immediately.
+	events = nil
+	m.Observe("a", &ContainerStats{Time: start, Stats: map[StatType]float64{CPUUsagePercentStat: 0.5}})
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "CPUIdle", events[0].Reason)
+		assert.Equal(t, "CPUUsagePercent is below 1 (currently 0.5)", events[0].Message)
+	}
+
+	// Forgotten containers start over.
+	events = nil
+	m.Forget("b")
+	m.Observe("b", sample(40*time.Second, 99))
+	assert.Empty(t, events)
+}
@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// X11SocketDir is the directory of X server sockets, which is shared with
+// containers which forward a display.
+const X11SocketDir = "/tmp/.X11-unix"
+
+// XAuthorityPath is where a forwarded X authority file is mounted in
+// containers. XAUTHORITY is set to this path.
+const XAuthorityPath = "/run/beaker/Xauthority"
+
+// localDisplay matches displays served through X11SocketDir, e.g. ":0" or ":1.0".
+var localDisplay = regexp.MustCompile(`^:\d+(\.\d+)?$`)
+
+// X11Display forwards an X server on the host to a container so that GUI and
+// rendering tools can run in it. The server may be a physical display or a
+// virtual framebuffer such as Xvfb. For hardware-accelerated rendering, e.g.
+// with VirtualGL, also request GPUs with GPUGraphics and GPUDisplay.
+type X11Display struct {
+	// Display names the host's X display, e.g. ":0". Only local displays,
+	// whose sockets are in X11SocketDir, can be forwarded.
+	Display string
+
+	// (optional) XAuthority is the absolute path of a host file of
+	// authorization cookies for the display. It must be a regular file owned
+	// by the caller. It's required unless the X server accepts local
+	// connections without authorization.
+	XAuthority string
+}
+
+// Validate checks that the display is local and its authority file is a
+// regular file owned by the caller.
+func (d *X11Display) Validate() error {
+	if !localDisplay.MatchString(d.Display) {
+		return fmt.Errorf("%q is not a local X display", d.Display)
+	}
+	if d.XAuthority != "" {
+		if !filepath.IsAbs(d.XAuthority) {
+			return fmt.Errorf("X authority file %q must be an absolute path", d.XAuthority)
+		}
+		// Links aren't followed, so they can't redirect the mount elsewhere.
+		info, err := os.Lstat(d.XAuthority)
+		if err != nil {
+			return fmt.Errorf("forwarding X display: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("X authority file %q is not a regular file", d.XAuthority)
+		}
+		if uid, ok := fileOwner(info); !ok || uid != os.Getuid() {
+			return fmt.Errorf("X authority file %q is not owned by the current user", d.XAuthority)
+		}
+	}
+	return nil
+}
+
+// Mounts returns the host paths which must be mounted to reach the display.
+func (d *X11Display) Mounts() []Mount {
+	mounts := []Mount{{HostPath: X11SocketDir, ContainerPath: X11SocketDir, ReadOnly: true}}
+	if d.XAuthority != "" {
+		mounts = append(mounts, Mount{HostPath: d.XAuthority, ContainerPath: XAuthorityPath, ReadOnly: true})
+	}
+	return mounts
+}
+
+// Env returns the environment which directs X clients to the display.
+func (d *X11Display) Env() map[string]string {
+	env := map[string]string{"DISPLAY": d.Display}
+	if d.XAuthority != "" {
+		env["XAUTHORITY"] = XAuthorityPath
+	}
+	return env
+}
+
+// ValidateX11 checks the container's forwarded display, if any, and that the
+// display's variables aren't also set in the environment.
+func (o *ContainerOpts) ValidateX11() error {
+	if o.X11 == nil {
+		return nil
+	}
+	if err := o.X11.Validate(); err != nil {
+		return err
+	}
+	for k := range o.X11.Env() {
+		if _, ok := o.Env[k]; ok {
+			return fmt.Errorf("%s can't be set when forwarding an X display", k)
+		}
+	}
+	return nil
+}
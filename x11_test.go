@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestX11Display(t *testing.T) {
+	xauth := filepath.Join(t.TempDir(), "Xauthority")
+	require.NoError(t, ioutil.WriteFile(xauth, nil, 0600))
+
+	d := &X11Display{Display: ":1.0", XAuthority: xauth}
+	require.NoError(t, d.Validate())
+	assert.Equal(t, []Mount{
+		{HostPath: "/tmp/.X11-unix", ContainerPath: "/tmp/.X11-unix", ReadOnly: true},
+		{HostPath: xauth, ContainerPath: XAuthorityPath, ReadOnly: true},
+	}, d.Mounts())
+	assert.Equal(t, map[string]string{"DISPLAY": ":1.0", "XAUTHORITY": XAuthorityPath}, d.Env())
+
+	link := filepath.Join(t.TempDir(), "link")
+	require.NoError(t, os.Symlink(xauth, link))
+
+	d = &X11Display{Display: ":0"}
+	require.NoError(t, d.Validate())
+	assert.Len(t, d.Mounts(), 1)
+	assert.Equal(t, map[string]string{"DISPLAY": ":0"}, d.Env())
+
+	for _, invalid := range []X11Display{
+		{Display: "localhost:10.0"},
+		{Display: "0"},
+		{Display: ":0", XAuthority: "Xauthority"},
+		{Display: ":0", XAuthority: filepath.Join(t.TempDir(), "missing")},
+		{Display: ":0", XAuthority: t.TempDir()},
+		{Display: ":0", XAuthority: link},
+	} {
+		assert.Error(t, invalid.Validate(), invalid)
+	}
+
+	// Only root can give a file to another user.
+	if os.Getuid() == 0 {
+		require.NoError(t, os.Chown(xauth, 12345, 12345))
+		assert.Error(t, (&X11Display{Display: ":0", XAuthority: xauth}).Validate())
+	}
+}
+
+func TestValidateX11(t *testing.T) {
+	assert.NoError(t, (&ContainerOpts{}).ValidateX11())
+	assert.NoError(t, (&ContainerOpts{X11: &X11Display{Display: ":0"}}).ValidateX11())
+	assert.Error(t, (&ContainerOpts{
+		X11: &X11Display{Display: ":0"},
+		Env: map[string]string{"DISPLAY": ":1"},
+	}).ValidateX11())
+}